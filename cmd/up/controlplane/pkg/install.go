@@ -20,8 +20,11 @@ import (
 	"time"
 
 	"github.com/alecthomas/kong"
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/pterm/pterm"
+	"github.com/spf13/afero"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -30,12 +33,14 @@ import (
 
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
 
+	"github.com/upbound/up/internal/credhelper"
 	"github.com/upbound/up/internal/kube"
 	"github.com/upbound/up/internal/resources"
 	"github.com/upbound/up/internal/upbound"
 	"github.com/upbound/up/internal/upterm"
 	"github.com/upbound/up/internal/version"
 	"github.com/upbound/up/internal/xpkg"
+	"github.com/upbound/up/internal/xpkg/sign"
 )
 
 const errUnknownPkgType = "provided package type is unknown"
@@ -107,6 +112,7 @@ type installCmd struct {
 	Name               string        `help:"Name of ${package_type}."`
 	PackagePullSecrets []string      `help:"List of secrets used to pull ${package_type}."`
 	Wait               time.Duration `short:"w" help:"Wait duration for successful ${package_type} installation."`
+	VerifyKey          string        `type:"existingfile" help:"Require the ${package_type} to carry a signature that verifies against the PEM encoded ECDSA public key at this path. The verified digest is installed."`
 }
 
 // Run executes the install command.
@@ -118,6 +124,14 @@ func (c *installCmd) Run(ctx context.Context, p pterm.TextPrinter, upCtx *upboun
 	if c.Name == "" {
 		c.Name = xpkg.ToDNSLabel(ref.Context().RepositoryStr())
 	}
+	if c.VerifyKey != "" {
+		// install the verified digest so that the tag cannot be moved to
+		// an unsigned package after verification.
+		ref, err = c.verify(ctx, ref, upCtx)
+		if err != nil {
+			return err
+		}
+	}
 	packagePullSecrets := make([]corev1.LocalObjectReference, len(c.PackagePullSecrets))
 	for i, s := range c.PackagePullSecrets {
 		packagePullSecrets[i] = corev1.LocalObjectReference{
@@ -166,3 +180,32 @@ func (c *installCmd) Run(ctx context.Context, p pterm.TextPrinter, upCtx *upboun
 	s.Success(fmt.Sprintf("%s installed and healthy", c.Name))
 	return nil
 }
+
+// verify verifies the signature of the package and returns the reference of
+// the verified digest.
+func (c *installCmd) verify(ctx context.Context, ref name.Reference, upCtx *upbound.Context) (name.Reference, error) {
+	key, err := sign.LoadPublicKey(afero.NewOsFs(), c.VerifyKey)
+	if err != nil {
+		return nil, err
+	}
+	kc := authn.NewMultiKeychain(
+		authn.NewKeychainFromHelper(
+			credhelper.New(
+				credhelper.WithDomain(upCtx.Domain.Hostname()),
+				credhelper.WithProfile(upCtx.ProfileName),
+			),
+		),
+		authn.DefaultKeychain,
+	)
+	opts := []sign.Option{
+		sign.WithRemoteOptions(remote.WithAuthFromKeychain(kc)),
+	}
+	d, err := sign.ResolveDigest(ctx, ref, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := sign.Verify(ctx, d, key, opts...); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
@@ -16,6 +16,7 @@ package migration
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/pterm/pterm"
 	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
@@ -28,6 +29,7 @@ import (
 	"github.com/upbound/up/internal/input"
 	"github.com/upbound/up/internal/upterm"
 	"github.com/upbound/up/pkg/migration"
+	"github.com/upbound/up/pkg/migration/encryption"
 	"github.com/upbound/up/pkg/migration/exporter"
 )
 
@@ -53,6 +55,12 @@ type exportCmd struct {
 	ExcludeNamespaces     []string `help:"A list of specific namespaces to exclude from the export. Defaults to 'kube-system', 'kube-public', 'kube-node-lease', and 'local-path-storage'." default:"kube-system,kube-public,kube-node-lease,local-path-storage"`
 
 	PauseBeforeExport bool `help:"When set to true, pauses all managed resources before starting the export process. This can help ensure a consistent state for the export. Defaults to false." default:"false"`
+
+	CheckpointDir string `help:"A directory in which the exported state is staged before archiving it. If the export is interrupted, running it again with the same directory and options resumes it, skipping the resource types that were already exported. The directory is removed after a successful export. Cannot be combined with --encrypt-to, as the staged state is not encrypted." type:"path" xor:"export-staging"`
+	Since         string `help:"The path to the archive of a previous export. When set, only resources that changed since that export are exported. The resulting archive can be imported on top of the previous one." type:"existingfile"`
+
+	EncryptTo []string `help:"Paths to OpenPGP public keys to encrypt the exported archive for. Only the holders of the corresponding private keys can import the archive. Cannot be combined with --checkpoint-dir." type:"path" xor:"export-staging"`
+	Identity  []string `help:"Paths to OpenPGP private keys to decrypt the archive passed to --since with, if it is encrypted." type:"path"`
 }
 
 func (c *exportCmd) Help() string {
@@ -75,6 +83,15 @@ Examples:
 
     migration export --include-extra-resources="customresource.group" --include-namespaces="crossplane-system,team-a,team-b"
         Exports the control plane state to a default file 'xp-state.tar.gz', with the additional resource specified and only using provided namespaces.
+
+    migration export --checkpoint-dir=./xp-state
+        Exports the control plane state, staging it in './xp-state'. If the export fails, running the same command again resumes it.
+
+    migration export --since=xp-state.tar.gz --output=xp-state-delta.tar.gz
+        Exports only the resources that changed since the export in 'xp-state.tar.gz' to 'xp-state-delta.tar.gz'.
+
+    migration export --encrypt-to=team.asc
+        Exports the control plane state to an archive that is encrypted for the OpenPGP public key in 'team.asc'.
 `
 }
 
@@ -115,6 +132,13 @@ func (c *exportCmd) Run(ctx context.Context, migCtx *migration.Context) error {
 		ExcludeResources:      c.ExcludeResources,
 
 		PauseBeforeExport: c.PauseBeforeExport,
+
+		CheckpointDir: c.CheckpointDir,
+		Since:         c.Since,
+
+		EncryptTo:  c.EncryptTo,
+		Identities: c.Identity,
+		Passphrase: passphrase(c.prompter),
 	})
 
 	if !c.Yes && e.IncludedExtraResource("secrets") {
@@ -142,6 +166,15 @@ func (c *exportCmd) Run(ctx context.Context, migCtx *migration.Context) error {
 	return nil
 }
 
+// passphrase returns a function that prompts for the passphrase of a private
+// key.
+func passphrase(p input.Prompter) encryption.PassphraseFunc {
+	return func(keyID string) ([]byte, error) {
+		s, err := p.Prompt(fmt.Sprintf("Passphrase for key %s", keyID), true)
+		return []byte(s), err
+	}
+}
+
 // NOTE(phisco): this is required to avoid having the pkg/migration depend on upterm to
 // allow exporting it
 type spinner struct {
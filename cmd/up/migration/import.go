@@ -41,6 +41,8 @@ type importCmd struct {
 	Input string `short:"i" help:"Specifies the file path of the archive to be imported. The default path is 'xp-state.tar.gz'." default:"xp-state.tar.gz"`
 
 	UnpauseAfterImport bool `help:"When set to true, automatically unpauses all managed resources that were paused during the import process. This helps in resuming normal operations post-import. Defaults to false, requiring manual unpausing of resources if needed." default:"false"`
+
+	Identity []string `help:"Paths to OpenPGP private keys to decrypt an archive that was exported with --encrypt-to." type:"path"`
 }
 
 func (c *importCmd) Help() string {
@@ -53,12 +55,24 @@ The 'import' command imports a control plane state from an archive file into an
 By default, all managed resources will be paused during the import process for possible manual inspection/validation.
 You can use the --unpause-after-import flag to automatically unpause all managed resources after the import process completes.
 
+Archives created with 'migration export --since' only contain the resources that changed since a previous export. Import
+them on top of a control plane that the previous export was already imported into. Resources that were deleted since the
+previous export are listed but not deleted.
+
+Archives that were encrypted with 'migration export --encrypt-to' are decrypted with the private keys passed to --identity.
+
 Examples:
     migration import --input=my-export.tar.gz
         Imports the control plane state from 'my-export.tar.gz'.
 
     migration import --unpause-after-import
         Imports and automatically unpauses managed resources after import.
+
+    migration import --input=xp-state-delta.tar.gz
+        Imports the resources that changed since the previous export on top of an earlier import.
+
+    migration import --identity=private.asc
+        Imports the control plane state from an archive that was encrypted for the public key of 'private.asc'.
 `
 }
 
@@ -94,6 +108,9 @@ func (c *importCmd) Run(ctx context.Context, migCtx *migration.Context) error {
 		InputArchive: c.Input,
 
 		UnpauseAfterImport: c.UnpauseAfterImport,
+
+		Identities: c.Identity,
+		Passphrase: passphrase(c.prompter),
 	})
 
 	errs := i.PreflightChecks(ctx)
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"strings"
 	"text/template"
+	"time"
 
 	"k8s.io/kubectl/pkg/cmd/get"
 
@@ -50,12 +51,27 @@ type cmd struct {
 	// json/yaml flags
 	ShowManagedFields bool `name:"show-managed-fields" help:"If true, keep the managedFields when printing objects in JSON or YAML format."`
 
+	// filter flags
+	Selector      string   `short:"l" name:"selector" help:"Selector (label query) to filter on, supports '=', '==', '!=', 'in', 'notin' and 'exists' (e.g. -l key1=value1,key2=value2). Only equality is evaluated by the server."`
+	FieldSelector string   `name:"field-selector" help:"Selector (field query) to filter on, supports '=', '==', and '!=' on any field path (e.g. --field-selector metadata.name=web,spec.forProvider.region!=us-east-1). Numbers and booleans match numeric and boolean fields, quote them to match string fields (e.g. metadata.name=\"123\")."`
+	Conditions    []string `name:"condition" help:"Filter on a condition of the form TYPE, TYPE=STATUS, TYPE=STATUS/REASON or TYPE!=STATUS (e.g. --condition Ready=False). Can be repeated, all conditions must match. TYPE!=STATUS also matches objects without a TYPE condition, and is evaluated by the client."`
+	NotReady      bool     `name:"not-ready" help:"Only show objects that are not ready, including objects without a Ready condition. Short for --condition Ready!=True."`
+	NotSynced     bool     `name:"not-synced" help:"Only show objects that are not synced, including objects without a Synced condition. Short for --condition Synced!=True."`
+
+	// watch flags
+	Watch         bool          `short:"w" name:"watch" help:"After listing the requested objects, watch for changes and print only added, changed or deleted objects. In JSON output, every change is printed as a watch event on a single line."`
+	WatchInterval time.Duration `name:"watch-interval" default:"5s" help:"Interval at which the query is re-run when watching."`
+
+	// summary flags
+	Summary bool `name:"summary" help:"Instead of listing the objects, print a health summary that counts their Ready and Synced states per control plane and kind, along with the oldest failing object. Supports the json and yaml output formats."`
+
 	// positional arguments
 	Resources []string `arg:"" help:"Type(s) (resource, singular or plural, category, short-name) and names: TYPE[.GROUP][,TYPE[.GROUP]...] [NAME ...] | TYPE[.GROUP]/NAME .... If no resource is specified, all resources are queried, but --all-resources must be specified."`
 
 	Flags upbound.Flags `embed:""`
 
 	printFlags *get.PrintFlags
+	filter     *queryFilter
 	namespace  string // inside the control plane
 }
 
@@ -87,6 +103,18 @@ func help(cmdName string) (string, error) {
 
   # List one or more resources by their type and names
   {{.CmdName}} vpc/prod bucket/backup providerconfig/kube
+
+  # List all managed resources that are not ready in any control plane
+  {{.CmdName}} managed --not-ready
+
+  # List buckets with the label team=platform in a region other than us-east-1
+  {{.CmdName}} buckets -l team=platform --field-selector spec.forProvider.region!=us-east-1
+
+  # Summarize the health of all managed resources in all control planes
+  {{.CmdName}} managed --summary
+
+  # Watch S3 buckets and print every change as a JSON line
+  {{.CmdName}} buckets --watch -o json
 `)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to create help template")
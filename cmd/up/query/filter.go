@@ -0,0 +1,276 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+
+	"github.com/upbound/up-sdk-go/apis/common"
+	queryv1alpha2 "github.com/upbound/up-sdk-go/apis/query/v1alpha2"
+)
+
+const (
+	conditionTrue    = "True"
+	conditionFalse   = "False"
+	conditionUnknown = "Unknown"
+)
+
+// conditionStatuses are the possible statuses of a condition.
+var conditionStatuses = []string{conditionTrue, conditionFalse, conditionUnknown}
+
+// queryFilter holds the filters supplied on the command line, split into the
+// parts that are sent to the server and the parts that are applied to the
+// returned objects.
+type queryFilter struct {
+	// labels are the label equality requirements sent to the server.
+	labels map[string]string
+	// jsonPath is the JSONPath filter expression sent to the server.
+	jsonPath string
+	// conditions are the condition filters sent to the server. Objects have
+	// to match all of them.
+	conditions []queryv1alpha2.QueryCondition
+
+	// selector holds the label requirements the server cannot evaluate.
+	selector labels.Selector
+	// notConditions are the negated condition filters. Objects must not have
+	// a condition of their type with their status. They are applied on the
+	// client, as the server cannot match objects without a condition.
+	notConditions []queryv1alpha2.QueryCondition
+}
+
+// parseFilter parses a label selector, a field selector and condition
+// filters of the form TYPE, TYPE=STATUS, TYPE=STATUS/REASON or TYPE!=STATUS.
+func parseFilter(labelSelector, fieldSelector string, conditions []string) (*queryFilter, error) {
+	f := &queryFilter{selector: labels.NewSelector()}
+
+	ls, err := labels.Parse(labelSelector)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid label selector")
+	}
+	reqs, _ := ls.Requirements()
+	for _, r := range reqs {
+		// the server only supports label equality.
+		if (r.Operator() == selection.Equals || r.Operator() == selection.DoubleEquals) && r.Values().Len() == 1 {
+			if f.labels == nil {
+				f.labels = make(map[string]string)
+			}
+			f.labels[r.Key()] = r.Values().UnsortedList()[0]
+			continue
+		}
+		f.selector = f.selector.Add(r)
+	}
+
+	fs, err := fields.ParseSelector(fieldSelector)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid field selector")
+	}
+	exprs := make([]string, 0, len(fs.Requirements()))
+	for _, r := range fs.Requirements() {
+		op := "=="
+		if r.Operator == selection.NotEquals {
+			op = "!="
+		}
+		exprs = append(exprs, fmt.Sprintf("$.%s %s %s", strings.TrimPrefix(r.Field, "."), op, fieldValue(r.Value)))
+	}
+	f.jsonPath = strings.Join(exprs, " && ")
+
+	for _, c := range conditions {
+		cond, negated, err := parseCondition(c)
+		if err != nil {
+			return nil, err
+		}
+		if negated {
+			f.notConditions = append(f.notConditions, cond)
+			continue
+		}
+		f.conditions = append(f.conditions, cond)
+	}
+
+	return f, nil
+}
+
+// fieldValue returns the JSON literal of a field selector value. Numbers and
+// booleans are compared as such, unless they are quoted.
+func fieldValue(v string) string {
+	if len(v) >= 2 && strings.HasPrefix(v, `"`) && strings.HasSuffix(v, `"`) {
+		v = v[1 : len(v)-1]
+	} else if v == "true" || v == "false" {
+		return v
+	} else if _, err := strconv.ParseFloat(v, 64); err == nil && json.Valid([]byte(v)) {
+		return v
+	}
+	s, _ := json.Marshal(v)
+	return string(s)
+}
+
+// parseCondition parses a condition filter and returns whether it is negated.
+// A negated condition matches any of the other statuses and objects without
+// a condition of its type.
+func parseCondition(s string) (queryv1alpha2.QueryCondition, bool, error) {
+	typ, status, negated := s, "", false
+	if i := strings.Index(s, "!="); i >= 0 {
+		typ, status, negated = s[:i], s[i+2:], true
+	} else if i := strings.Index(s, "="); i >= 0 {
+		typ, status = s[:i], s[i+1:]
+	}
+	status, reason, _ := strings.Cut(status, "/")
+	if typ == "" {
+		return queryv1alpha2.QueryCondition{}, false, errors.Errorf("invalid condition %q: missing type", s)
+	}
+	if negated && status == "" {
+		return queryv1alpha2.QueryCondition{}, false, errors.Errorf("invalid condition %q: a negated condition needs a status", s)
+	}
+	if negated && reason != "" {
+		return queryv1alpha2.QueryCondition{}, false, errors.Errorf("invalid condition %q: a reason cannot be negated", s)
+	}
+
+	if status != "" {
+		normalized := ""
+		for _, st := range conditionStatuses {
+			if strings.EqualFold(st, status) {
+				normalized = st
+			}
+		}
+		if normalized == "" {
+			return queryv1alpha2.QueryCondition{}, false, errors.Errorf("invalid condition %q: status must be one of %s", s, strings.Join(conditionStatuses, ", "))
+		}
+		status = normalized
+	}
+
+	return queryv1alpha2.QueryCondition{Type: typ, Status: status, Reason: reason}, negated, nil
+}
+
+// apply adds the server side filters to the supplied query spec.
+func (f *queryFilter) apply(spec *queryv1alpha2.QuerySpec) {
+	for i := range spec.Filter.Objects {
+		base := &spec.Filter.Objects[i]
+		if len(f.labels) > 0 {
+			ls := make(map[string]string, len(base.Labels)+len(f.labels))
+			for k, v := range base.Labels {
+				ls[k] = v
+			}
+			for k, v := range f.labels {
+				ls[k] = v
+			}
+			base.Labels = ls
+		}
+		if f.jsonPath != "" {
+			base.JSONPath = f.jsonPath
+		}
+		if len(f.conditions) > 0 {
+			base.Conditions = append(append([]queryv1alpha2.QueryCondition{}, base.Conditions...), f.conditions...)
+		}
+	}
+
+	// the labels and conditions are needed to apply the client side filters.
+	// Tables carry no objects unless requested, so ask for just those then.
+	o := spec.Objects
+	if !f.clientSide() || o == nil {
+		return
+	}
+	if o.Object == nil && o.Table != nil {
+		o.Object = &common.JSON{Object: map[string]interface{}{}}
+	}
+	if o.Object != nil {
+		if skel, ok := o.Object.Object.(map[string]interface{}); ok {
+			if !f.selector.Empty() {
+				requestField(skel, "metadata", "labels")
+			}
+			if len(f.notConditions) > 0 {
+				requestField(skel, "status", "conditions")
+			}
+		}
+	}
+}
+
+// requestField adds the supplied field path to an object skeleton.
+func requestField(skel map[string]interface{}, parent, field string) {
+	m, _ := skel[parent].(map[string]interface{})
+	if m == nil {
+		m = map[string]interface{}{}
+		skel[parent] = m
+	}
+	m[field] = true
+}
+
+// clientSide returns true if some filters are applied on the client.
+func (f *queryFilter) clientSide() bool {
+	return f != nil && (!f.selector.Empty() || len(f.notConditions) > 0)
+}
+
+// matches returns true if the supplied object satisfies the filters that are
+// applied on the client.
+func (f *queryFilter) matches(u *unstructured.Unstructured) bool {
+	if !f.clientSide() {
+		return true
+	}
+	var ls map[string]string
+	if u != nil {
+		ls = u.GetLabels()
+	}
+	if !f.selector.Matches(labels.Set(ls)) {
+		return false
+	}
+	for _, c := range f.notConditions {
+		if hasCondition(u, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasCondition returns true if the supplied object has a condition of the
+// type and status of the supplied condition.
+func hasCondition(u *unstructured.Unstructured, c queryv1alpha2.QueryCondition) bool {
+	if u == nil {
+		return false
+	}
+	conds, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	for _, cond := range conds {
+		m, ok := cond.(map[string]interface{})
+		if ok && m["type"] == c.Type && m["status"] == c.Status {
+			return true
+		}
+	}
+	return false
+}
+
+// rows returns the table rows whose objects satisfy the filters that are
+// applied on the client. Rows without an object are kept, as their labels
+// and conditions are unknown rather than empty.
+func (f *queryFilter) rows(rows []metav1.TableRow) []metav1.TableRow {
+	if !f.clientSide() {
+		return rows
+	}
+	kept := make([]metav1.TableRow, 0, len(rows))
+	for _, r := range rows {
+		u, ok := r.Object.Object.(*unstructured.Unstructured)
+		if !ok || f.matches(u) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,419 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/upbound/up-sdk-go/apis/common"
+	queryv1alpha2 "github.com/upbound/up-sdk-go/apis/query/v1alpha2"
+)
+
+func TestParseFilter(t *testing.T) {
+	type args struct {
+		selector      string
+		fieldSelector string
+		conditions    []string
+	}
+	type want struct {
+		labels        map[string]string
+		client        string
+		jsonPath      string
+		conditions    []queryv1alpha2.QueryCondition
+		notConditions []queryv1alpha2.QueryCondition
+		err           error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Empty": {
+			reason: "Should not filter anything without flags.",
+		},
+		"LabelSelector": {
+			reason: "Should send label equality to the server and keep other label requirements for the client.",
+			args: args{
+				selector: "team=platform,env!=prod,tier in (a,b)",
+			},
+			want: want{
+				labels: map[string]string{"team": "platform"},
+				client: "env!=prod,tier in (a,b)",
+			},
+		},
+		"FieldSelector": {
+			reason: "Should translate a field selector into a JSONPath filter expression.",
+			args: args{
+				fieldSelector: "metadata.name=web,spec.forProvider.region!=us-east-1",
+			},
+			want: want{
+				jsonPath: `$.metadata.name == "web" && $.spec.forProvider.region != "us-east-1"`,
+			},
+		},
+		"FieldSelectorLiterals": {
+			reason: "Should compare numbers and booleans as such unless they are quoted.",
+			args: args{
+				fieldSelector: `spec.replicas=3,spec.paused!=true,spec.ratio=0.5,metadata.name="123",spec.version=1.2.3,spec.size=1e`,
+			},
+			want: want{
+				jsonPath: `$.metadata.name == "123" && $.spec.paused != true && $.spec.ratio == 0.5 && $.spec.replicas == 3 && $.spec.size == "1e" && $.spec.version == "1.2.3"`,
+			},
+		},
+		"Conditions": {
+			reason: "Should send conditions to the server and keep negated conditions for the client.",
+			args: args{
+				conditions: []string{"Synced", "Ready=false/Creating", "Healthy!=true"},
+			},
+			want: want{
+				conditions: []queryv1alpha2.QueryCondition{
+					{Type: "Synced"},
+					{Type: "Ready", Status: "False", Reason: "Creating"},
+				},
+				notConditions: []queryv1alpha2.QueryCondition{
+					{Type: "Healthy", Status: "True"},
+				},
+			},
+		},
+		"ErrConditionStatus": {
+			reason: "Should return an error for an unknown condition status.",
+			args: args{
+				conditions: []string{"Ready=Maybe"},
+			},
+			want: want{
+				err: errors.New(`invalid condition "Ready=Maybe": status must be one of True, False, Unknown`),
+			},
+		},
+		"ErrNegatedEmptyStatus": {
+			reason: "Should return an error for a negated condition without a status, which would match everything.",
+			args: args{
+				conditions: []string{"Ready!="},
+			},
+			want: want{
+				err: errors.New(`invalid condition "Ready!=": a negated condition needs a status`),
+			},
+		},
+		"ErrNegatedReason": {
+			reason: "Should return an error for a negated condition with a reason.",
+			args: args{
+				conditions: []string{"Ready!=True/Available"},
+			},
+			want: want{
+				err: errors.New(`invalid condition "Ready!=True/Available": a reason cannot be negated`),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f, err := parseFilter(tc.args.selector, tc.args.fieldSelector, tc.args.conditions)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nparseFilter(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+
+			if diff := cmp.Diff(tc.want.labels, f.labels); diff != "" {
+				t.Errorf("\n%s\nparseFilter(...): -want labels, +got labels:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.client, f.selector.String()); diff != "" {
+				t.Errorf("\n%s\nparseFilter(...): -want client selector, +got client selector:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.jsonPath, f.jsonPath); diff != "" {
+				t.Errorf("\n%s\nparseFilter(...): -want JSONPath, +got JSONPath:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.conditions, f.conditions); diff != "" {
+				t.Errorf("\n%s\nparseFilter(...): -want conditions, +got conditions:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.notConditions, f.notConditions); diff != "" {
+				t.Errorf("\n%s\nparseFilter(...): -want negated conditions, +got negated conditions:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestApplyFilter(t *testing.T) {
+	type args struct {
+		selector   string
+		conditions []string
+		spec       *queryv1alpha2.QuerySpec
+	}
+
+	spec := func(obj *common.JSON, filters ...queryv1alpha2.QueryFilter) *queryv1alpha2.QuerySpec {
+		s := &queryv1alpha2.QuerySpec{}
+		s.Filter.Objects = filters
+		s.Objects = &queryv1alpha2.QueryObjects{Object: obj}
+		return s
+	}
+	table := func(obj *common.JSON, filters ...queryv1alpha2.QueryFilter) *queryv1alpha2.QuerySpec {
+		s := spec(obj, filters...)
+		s.Objects.Table = &queryv1alpha2.QueryTable{}
+		return s
+	}
+	bucket := queryv1alpha2.QueryFilter{
+		GroupKind: queryv1alpha2.QueryGroupKind{APIGroup: "s3.aws.upbound.io", Kind: "Bucket"},
+	}
+	with := func(f queryv1alpha2.QueryFilter, ls map[string]string, conds ...queryv1alpha2.QueryCondition) queryv1alpha2.QueryFilter {
+		f.Labels = ls
+		f.Conditions = conds
+		return f
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   *queryv1alpha2.QuerySpec
+	}{
+		"NoFilter": {
+			reason: "Should leave the spec unchanged without filters.",
+			args: args{
+				spec: spec(nil, bucket),
+			},
+			want: spec(nil, bucket),
+		},
+		"Conditions": {
+			reason: "Should add the labels and conditions to every object filter.",
+			args: args{
+				selector:   "team=platform",
+				conditions: []string{"Ready=False", "Synced"},
+				spec:       spec(nil, bucket),
+			},
+			want: spec(nil,
+				with(bucket, map[string]string{"team": "platform"}, queryv1alpha2.QueryCondition{Type: "Ready", Status: "False"}, queryv1alpha2.QueryCondition{Type: "Synced"}),
+			),
+		},
+		"NegatedConditions": {
+			reason: "Should not send negated conditions to the server but request the conditions of objects to apply them on the client.",
+			args: args{
+				conditions: []string{"Ready!=True"},
+				spec: spec(&common.JSON{Object: map[string]interface{}{
+					"metadata": map[string]interface{}{"name": true},
+				}}, bucket),
+			},
+			want: spec(&common.JSON{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": true},
+				"status":   map[string]interface{}{"conditions": true},
+			}}, bucket),
+		},
+		"TableNegatedConditions": {
+			reason: "Should request the conditions of the objects of table rows if negated conditions are applied on the client.",
+			args: args{
+				conditions: []string{"Synced!=True"},
+				spec:       table(nil, bucket),
+			},
+			want: table(&common.JSON{Object: map[string]interface{}{
+				"status": map[string]interface{}{"conditions": true},
+			}}, bucket),
+		},
+		"ClientSelectorLabels": {
+			reason: "Should request the labels of objects if label requirements are applied on the client.",
+			args: args{
+				selector: "env!=prod",
+				spec: spec(&common.JSON{Object: map[string]interface{}{
+					"metadata": map[string]interface{}{"name": true},
+				}}, bucket),
+			},
+			want: spec(&common.JSON{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": true, "labels": true},
+			}}, bucket),
+		},
+		"TableSelectorLabels": {
+			reason: "Should request the labels of the objects of table rows if label requirements are applied on the client.",
+			args: args{
+				selector: "env in (dev,staging)",
+				spec:     table(nil, bucket),
+			},
+			want: table(&common.JSON{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": true},
+			}}, bucket),
+		},
+		"TableServerSelector": {
+			reason: "Should not request objects for table rows if the server evaluates all label requirements.",
+			args: args{
+				selector: "team=platform",
+				spec:     table(nil, bucket),
+			},
+			want: table(nil, with(bucket, map[string]string{"team": "platform"})),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f, err := parseFilter(tc.args.selector, "", tc.args.conditions)
+			if err != nil {
+				t.Fatalf("\n%s\nparseFilter(...): unexpected error: %s", tc.reason, err)
+			}
+			f.apply(tc.args.spec)
+
+			if diff := cmp.Diff(tc.want, tc.args.spec); diff != "" {
+				t.Errorf("\n%s\napply(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	obj := func(ls map[string]string, conds ...string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		u.SetLabels(ls)
+		if len(conds) > 0 {
+			cs := make([]interface{}, 0, len(conds))
+			for _, c := range conds {
+				typ, status, _ := strings.Cut(c, "=")
+				cs = append(cs, map[string]interface{}{"type": typ, "status": status})
+			}
+			u.Object["status"] = map[string]interface{}{"conditions": cs}
+		}
+		return u
+	}
+
+	cases := map[string]struct {
+		reason     string
+		selector   string
+		conditions []string
+		obj        *unstructured.Unstructured
+		want       bool
+	}{
+		"ServerOnly": {
+			reason:     "Should match any object if the server evaluates all requirements.",
+			selector:   "team=platform",
+			conditions: []string{"Ready=True"},
+			obj:        obj(nil),
+			want:       true,
+		},
+		"Match": {
+			reason:   "Should match an object satisfying the client side requirements.",
+			selector: "env!=prod,tier",
+			obj:      obj(map[string]string{"env": "dev", "tier": "a"}),
+			want:     true,
+		},
+		"NoMatch": {
+			reason:   "Should not match an object violating the client side requirements.",
+			selector: "env notin (prod)",
+			obj:      obj(map[string]string{"env": "prod"}),
+		},
+		"NoObject": {
+			reason:     "Should evaluate the requirements against empty labels and no conditions if there is no object.",
+			selector:   "!tier",
+			conditions: []string{"Ready!=True"},
+			want:       true,
+		},
+		"NegatedConditions": {
+			reason:     "Should not match an object that has the status of any negated condition.",
+			conditions: []string{"Ready!=True", "Synced!=True"},
+			obj:        obj(nil, "Ready=False", "Synced=True"),
+		},
+		"NegatedConditionUnknown": {
+			reason:     "Should match an object whose condition has another status than the negated one.",
+			conditions: []string{"Ready!=True"},
+			obj:        obj(nil, "Ready=Unknown", "Synced=True"),
+			want:       true,
+		},
+		"NegatedConditionAbsent": {
+			reason:     "Should match an object without a condition of the negated type, e.g. one that was never reconciled.",
+			conditions: []string{"Ready!=True"},
+			obj:        obj(nil),
+			want:       true,
+		},
+		"NegatedConditionStatus": {
+			reason:     "Should not match an object whose condition has the negated status.",
+			conditions: []string{"Ready!=True"},
+			obj:        obj(nil, "Ready=True"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f, err := parseFilter(tc.selector, "", tc.conditions)
+			if err != nil {
+				t.Fatalf("\n%s\nparseFilter(...): unexpected error: %s", tc.reason, err)
+			}
+
+			if diff := cmp.Diff(tc.want, f.matches(tc.obj)); diff != "" {
+				t.Errorf("\n%s\nmatches(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestFilterRows(t *testing.T) {
+	row := func(name string, ls map[string]string) metav1.TableRow {
+		r := metav1.TableRow{Cells: []any{name}}
+		if ls != nil {
+			u := &unstructured.Unstructured{}
+			u.SetLabels(ls)
+			r.Object.Object = u
+		}
+		return r
+	}
+
+	cases := map[string]struct {
+		reason   string
+		selector string
+		rows     []metav1.TableRow
+		want     []metav1.TableRow
+	}{
+		"ServerOnly": {
+			reason:   "Should keep all rows if the server evaluates all requirements.",
+			selector: "team=platform",
+			rows:     []metav1.TableRow{row("a", nil)},
+			want:     []metav1.TableRow{row("a", nil)},
+		},
+		"Labels": {
+			reason:   "Should keep the rows whose objects satisfy the client side requirements.",
+			selector: "env in (dev,staging)",
+			rows: []metav1.TableRow{
+				row("a", map[string]string{"env": "dev"}),
+				row("b", map[string]string{"env": "prod"}),
+			},
+			want: []metav1.TableRow{
+				row("a", map[string]string{"env": "dev"}),
+			},
+		},
+		"NoObject": {
+			reason:   "Should keep rows without an object rather than matching against empty labels.",
+			selector: "env in (dev,staging)",
+			rows: []metav1.TableRow{
+				row("a", nil),
+				row("b", map[string]string{"env": "prod"}),
+			},
+			want: []metav1.TableRow{
+				row("a", nil),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f, err := parseFilter(tc.selector, "", nil)
+			if err != nil {
+				t.Fatalf("\n%s\nparseFilter(...): unexpected error: %s", tc.reason, err)
+			}
+
+			if diff := cmp.Diff(tc.want, f.rows(tc.rows)); diff != "" {
+				t.Errorf("\n%s\nrows(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
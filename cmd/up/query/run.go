@@ -23,6 +23,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/alecthomas/kong"
 	"github.com/pterm/pterm"
@@ -61,6 +62,32 @@ func (c *cmd) afterApply() error {
 	if c.ShowLabels && c.OutputFormat != "" && c.OutputFormat != "wide" {
 		return fmt.Errorf("--show-labels option cannot be used with %s printer", c.OutputFormat)
 	}
+	if c.Watch && c.WatchInterval <= 0 {
+		return errors.New("--watch-interval must be positive")
+	}
+	if c.Summary {
+		if c.Watch {
+			return errors.New("--summary cannot be used with --watch")
+		}
+		switch c.OutputFormat {
+		case "", "json", "yaml":
+		default:
+			return errors.Errorf("--summary cannot be used with %s printer", c.OutputFormat)
+		}
+	}
+
+	conditions := c.Conditions
+	if c.NotReady {
+		conditions = append(conditions, "Ready!="+conditionTrue)
+	}
+	if c.NotSynced {
+		conditions = append(conditions, "Synced!="+conditionTrue)
+	}
+	filter, err := parseFilter(c.Selector, c.FieldSelector, conditions)
+	if err != nil {
+		return err
+	}
+	c.filter = filter
 
 	c.printFlags = get.NewGetPrintFlags()
 	c.printFlags.NoHeaders = &c.NoHeaders
@@ -127,7 +154,44 @@ func (c *cmd) Run(ctx context.Context, kongCtx *kong.Context, upCtx *upbound.Con
 		}
 	}
 
+	for _, spec := range querySpecs {
+		if c.Summary {
+			setSummaryObject(spec)
+		}
+		c.filter.apply(spec)
+	}
+
 	// send queries and collect objects
+	infos, gks, err := c.fetch(ctx, kongCtx, kc, queryTemplate, querySpecs)
+	if err != nil {
+		return err
+	}
+
+	if c.Summary {
+		if len(infos) == 0 && c.OutputFormat == "" {
+			return notFound.PrintMessage()
+		}
+		return summarize(infos).print(kongCtx.Stdout, c.OutputFormat, c.NoHeaders, time.Now())
+	}
+
+	// print objects
+	showKind := c.ShowKind || gks.Len() > 1 || len(categoryNames)+len(gkNames) > 1
+	humanReadableOutput := (c.OutputFormat == "" && c.Template == "") || c.OutputFormat == "wide"
+	if c.Watch {
+		return c.watch(ctx, kongCtx, infos, showKind, func(ctx context.Context) ([]*cliresource.Info, error) {
+			infos, _, err := c.fetch(ctx, kongCtx, kc, queryTemplate, querySpecs)
+			return infos, err
+		})
+	}
+	if humanReadableOutput {
+		return c.humanReadablePrintObjects(kongCtx, infos, showKind, notFound)
+	}
+	return c.printGeneric(kongCtx, infos)
+}
+
+// fetch sends the supplied queries, following the cursor through all pages,
+// and collects the returned objects.
+func (c *cmd) fetch(ctx context.Context, kongCtx *kong.Context, kc client.Client, queryTemplate resource.QueryObject, querySpecs []*queryv1alpha2.QuerySpec) ([]*cliresource.Info, sets.Set[runtimeschema.GroupKind], error) { // nolint:gocyclo // mostly taken from kubectl get. We don't want to divert.
 	var infos []*cliresource.Info
 	gks := sets.New[runtimeschema.GroupKind]()
 	for qi, spec := range querySpecs {
@@ -142,23 +206,23 @@ func (c *cmd) Run(ctx context.Context, kongCtx *kong.Context, upCtx *upbound.Con
 			if c.Flags.Debug > 0 {
 				kinds, _, err := queryScheme.ObjectKinds(query)
 				if err != nil {
-					return errors.Wrap(err, "failed to get object kinds")
+					return nil, nil, errors.Wrap(err, "failed to get object kinds")
 				}
 				if len(kinds) != 1 {
-					return errors.Errorf("expected exactly one kind, got %d", len(kinds))
+					return nil, nil, errors.Errorf("expected exactly one kind, got %d", len(kinds))
 				}
 				query := query.DeepCopyQueryObject()
 				query.GetObjectKind().SetGroupVersionKind(queryv1alpha2.SchemeGroupVersion.WithKind(kinds[0].Kind))
 				bs, err := yaml.Marshal(query)
 				if err != nil {
-					return errors.Wrap(err, "failed to marshal query")
+					return nil, nil, errors.Wrap(err, "failed to marshal query")
 				}
 				fmt.Fprintf(kongCtx.Stderr, "Sending query:\n\n%s\n", string(bs)) // nolint:errcheck // just debug output
 			}
 
 			// send request
 			if err := kc.Create(ctx, query); err != nil {
-				return errors.Wrap(err, "SpaceQuery request failed")
+				return nil, nil, errors.Wrap(err, "SpaceQuery request failed")
 			}
 			resp := query.GetResponse()
 			for _, w := range resp.Warnings {
@@ -183,10 +247,12 @@ func (c *cmd) Run(ctx context.Context, kongCtx *kong.Context, upCtx *upbound.Con
 							u := &unstructured.Unstructured{}
 							r.Object.Object = u
 							if err := json.Unmarshal(r.Object.Raw, &u.Object); err != nil {
-								return fmt.Errorf("failed to unmarshal object: %w", err)
+								return nil, nil, fmt.Errorf("failed to unmarshal object: %w", err)
 							}
 						}
 					}
+					tbl.Rows = c.filter.rows(tbl.Rows)
+					t.Rows = tbl.Rows
 					info := &cliresource.Info{
 						Client: nil,
 						Mapping: &meta.RESTMapping{
@@ -227,10 +293,13 @@ func (c *cmd) Run(ctx context.Context, kongCtx *kong.Context, upCtx *upbound.Con
 			} else {
 				for _, obj := range resp.Objects {
 					if obj.Object == nil {
-						return fmt.Errorf("received unexpected nil object in response")
+						return nil, nil, fmt.Errorf("received unexpected nil object in response")
 					}
 
 					u := &unstructured.Unstructured{Object: obj.Object.Object}
+					if !c.filter.matches(u) {
+						continue
+					}
 					infos = append(infos, &cliresource.Info{
 						Client: nil,
 						Mapping: &meta.RESTMapping{
@@ -262,13 +331,7 @@ func (c *cmd) Run(ctx context.Context, kongCtx *kong.Context, upCtx *upbound.Con
 		}
 	}
 
-	// print objects
-	showKind := c.ShowKind || gks.Len() > 1 || len(categoryNames)+len(gkNames) > 1
-	humanReadableOutput := (c.OutputFormat == "" && c.Template == "") || c.OutputFormat == "wide"
-	if humanReadableOutput {
-		return c.humanReadablePrintObjects(kongCtx, infos, showKind, notFound)
-	}
-	return c.printGeneric(kongCtx, infos)
+	return infos, gks, nil
 }
 
 func (c *cmd) humanReadablePrintObjects(kongCtx *kong.Context, infos []*cliresource.Info, printWithKind bool, notFound NotFound) error { // nolint:gocyclo // mostly taken from kubectl get. We don't want to divert.
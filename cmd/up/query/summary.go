@@ -0,0 +1,285 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/cli-runtime/pkg/printers"
+	cliresource "k8s.io/cli-runtime/pkg/resource"
+	"sigs.k8s.io/yaml"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+
+	"github.com/upbound/up-sdk-go/apis/common"
+	queryv1alpha2 "github.com/upbound/up-sdk-go/apis/query/v1alpha2"
+)
+
+// summaryColumns are the columns of the summary table.
+var summaryColumns = []string{
+	"GROUP", "CONTROLPLANE", "KIND", "TOTAL",
+	"READY", "NOT-READY", "READY-UNKNOWN",
+	"SYNCED", "NOT-SYNCED", "SYNCED-UNKNOWN",
+	"OLDEST-FAILING", "AGE", "MESSAGE",
+}
+
+// summaryObject is the object skeleton requested from the server when
+// summarizing. It holds everything needed to aggregate the health of the
+// objects, but nothing else.
+func summaryObject() *common.JSON {
+	return &common.JSON{Object: map[string]interface{}{
+		"kind":       true,
+		"apiVersion": true,
+		"metadata": map[string]interface{}{
+			"name":              true,
+			"namespace":         true,
+			"creationTimestamp": true,
+		},
+		"status": map[string]interface{}{
+			"conditions": true,
+		},
+	}}
+}
+
+// setSummaryObject makes the supplied query return the objects required to
+// build a health summary.
+func setSummaryObject(spec *queryv1alpha2.QuerySpec) {
+	spec.Objects = &queryv1alpha2.QueryObjects{
+		ControlPlane: true,
+		Object:       summaryObject(),
+	}
+}
+
+// healthSummary is the health of the queried objects, aggregated by control
+// plane and kind.
+type healthSummary struct {
+	Items []healthSummaryItem `json:"items"`
+}
+
+// healthSummaryItem is the health of all objects of one kind in one control
+// plane.
+type healthSummaryItem struct {
+	Group        string `json:"group"`
+	ControlPlane string `json:"controlPlane"`
+	APIGroup     string `json:"apiGroup,omitempty"`
+	Kind         string `json:"kind"`
+	Total        int    `json:"total"`
+
+	Ready  conditionCounts `json:"ready"`
+	Synced conditionCounts `json:"synced"`
+
+	// OldestFailing is the object that has been failing for the longest
+	// time, if any.
+	OldestFailing *failingObject `json:"oldestFailing,omitempty"`
+}
+
+// conditionCounts counts the objects by the status of one condition type.
+// Objects without the condition are counted as unknown.
+type conditionCounts struct {
+	True    int `json:"true"`
+	False   int `json:"false"`
+	Unknown int `json:"unknown"`
+}
+
+// failingObject is an object whose Ready or Synced condition is not true.
+type failingObject struct {
+	Namespace string               `json:"namespace,omitempty"`
+	Name      string               `json:"name"`
+	Condition xpv1.ConditionType   `json:"condition"`
+	Status    string               `json:"status"`
+	Reason    xpv1.ConditionReason `json:"reason,omitempty"`
+	Message   string               `json:"message,omitempty"`
+	Since     time.Time            `json:"since"`
+}
+
+func (c *conditionCounts) add(cond xpv1.Condition, found bool) {
+	switch {
+	case found && string(cond.Status) == conditionTrue:
+		c.True++
+	case found && string(cond.Status) == conditionFalse:
+		c.False++
+	default:
+		c.Unknown++
+	}
+}
+
+// summarize aggregates the health of the supplied objects by control plane
+// and kind. Objects without a Ready or Synced condition are counted as
+// unknown, but only objects that have one of these conditions set to a
+// status other than True are considered failing.
+func summarize(infos []*cliresource.Info) *healthSummary {
+	items := map[string]*healthSummaryItem{}
+	for _, info := range infos {
+		u, ok := info.Object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		group, ctp, _ := strings.Cut(info.Source, "/")
+		gvk := u.GroupVersionKind()
+
+		key := strings.Join([]string{group, ctp, gvk.Group, gvk.Kind}, "/")
+		item, ok := items[key]
+		if !ok {
+			item = &healthSummaryItem{
+				Group:        group,
+				ControlPlane: ctp,
+				APIGroup:     gvk.Group,
+				Kind:         gvk.Kind,
+			}
+			items[key] = item
+		}
+		item.Total++
+
+		conditioned := xpv1.ConditionedStatus{}
+		_ = fieldpath.Pave(u.Object).GetValueInto("status", &conditioned)
+		ready, hasReady := condition(conditioned, xpv1.TypeReady)
+		synced, hasSynced := condition(conditioned, xpv1.TypeSynced)
+		item.Ready.add(ready, hasReady)
+		item.Synced.add(synced, hasSynced)
+
+		var failing *xpv1.Condition
+		switch {
+		case hasReady && string(ready.Status) != conditionTrue:
+			failing = &ready
+		case hasSynced && string(synced.Status) != conditionTrue:
+			failing = &synced
+		default:
+			continue
+		}
+		since := failing.LastTransitionTime.Time
+		if since.IsZero() {
+			since = u.GetCreationTimestamp().Time
+		}
+		if item.OldestFailing != nil && !since.Before(item.OldestFailing.Since) {
+			continue
+		}
+		item.OldestFailing = &failingObject{
+			Namespace: u.GetNamespace(),
+			Name:      u.GetName(),
+			Condition: failing.Type,
+			Status:    string(failing.Status),
+			Reason:    failing.Reason,
+			Message:   failing.Message,
+			Since:     since,
+		}
+	}
+
+	s := &healthSummary{Items: make([]healthSummaryItem, 0, len(items))}
+	for _, item := range items {
+		s.Items = append(s.Items, *item)
+	}
+	sort.Slice(s.Items, func(i, j int) bool {
+		a, b := s.Items[i], s.Items[j]
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		if a.ControlPlane != b.ControlPlane {
+			return a.ControlPlane < b.ControlPlane
+		}
+		if a.APIGroup != b.APIGroup {
+			return a.APIGroup < b.APIGroup
+		}
+		return a.Kind < b.Kind
+	})
+	return s
+}
+
+// condition returns the condition of the supplied type and whether it is set.
+func condition(s xpv1.ConditionedStatus, ct xpv1.ConditionType) (xpv1.Condition, bool) {
+	for _, c := range s.Conditions {
+		if c.Type == ct {
+			return c, true
+		}
+	}
+	return xpv1.Condition{}, false
+}
+
+// print prints the summary in the supplied output format, i.e. json, yaml or
+// a table if empty.
+func (s *healthSummary) print(w io.Writer, format string, noHeaders bool, now time.Time) error {
+	switch format {
+	case "json":
+		bs, err := json.Marshal(s)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal summary")
+		}
+		_, err = fmt.Fprintln(w, string(bs))
+		return err
+	case "yaml":
+		bs, err := yaml.Marshal(s)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal summary")
+		}
+		_, err = w.Write(bs)
+		return err
+	case "":
+	default:
+		return errors.Errorf("--summary does not support output format %q. Use json or yaml", format)
+	}
+
+	tw := printers.GetNewTabWriter(w)
+	if !noHeaders {
+		if _, err := fmt.Fprintln(tw, strings.Join(summaryColumns, "\t")); err != nil {
+			return err
+		}
+	}
+	for _, item := range s.Items {
+		kind := item.Kind
+		if item.APIGroup != "" {
+			kind += "." + item.APIGroup
+		}
+		cells := []string{
+			item.Group, item.ControlPlane, kind, strconv.Itoa(item.Total),
+			strconv.Itoa(item.Ready.True), strconv.Itoa(item.Ready.False), strconv.Itoa(item.Ready.Unknown),
+			strconv.Itoa(item.Synced.True), strconv.Itoa(item.Synced.False), strconv.Itoa(item.Synced.Unknown),
+			"", "", "",
+		}
+		if f := item.OldestFailing; f != nil {
+			name := f.Name
+			if f.Namespace != "" {
+				name = f.Namespace + "/" + name
+			}
+			cells[10] = name
+			cells[11] = duration.HumanDuration(now.Sub(f.Since))
+			cells[12] = fmt.Sprintf("%s=%s", f.Condition, f.Status)
+			if f.Reason != "" {
+				cells[12] += "/" + string(f.Reason)
+			}
+			if f.Message != "" {
+				cells[12] += ": " + firstLine(f.Message)
+			}
+		}
+		if _, err := fmt.Fprintln(tw, strings.Join(cells, "\t")); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+// firstLine returns the first line of the supplied message.
+func firstLine(s string) string {
+	l, _, _ := strings.Cut(s, "\n")
+	return l
+}
@@ -0,0 +1,189 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	runtimeschema "k8s.io/apimachinery/pkg/runtime/schema"
+	cliresource "k8s.io/cli-runtime/pkg/resource"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+var (
+	summaryNow = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	vpcGVK     = runtimeschema.GroupVersionKind{Group: "ec2.aws.upbound.io", Version: "v1beta1", Kind: "VPC"}
+)
+
+func conditioned(gvk runtimeschema.GroupVersionKind, source, name string, conds ...xpv1.Condition) *cliresource.Info {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	u.SetGroupVersionKind(gvk)
+	u.SetName(name)
+	u.SetCreationTimestamp(metav1.NewTime(summaryNow.Add(-24 * time.Hour)))
+	if len(conds) > 0 {
+		cs := make([]interface{}, 0, len(conds))
+		for _, c := range conds {
+			m := map[string]interface{}{
+				"type":    string(c.Type),
+				"status":  string(c.Status),
+				"reason":  string(c.Reason),
+				"message": c.Message,
+			}
+			if !c.LastTransitionTime.IsZero() {
+				m["lastTransitionTime"] = c.LastTransitionTime.UTC().Format(time.RFC3339)
+			}
+			cs = append(cs, m)
+		}
+		u.Object["status"] = map[string]interface{}{"conditions": cs}
+	}
+	return &cliresource.Info{Name: name, Source: source, Object: u}
+}
+
+func cond(t xpv1.ConditionType, s, reason, msg string, ago time.Duration) xpv1.Condition {
+	c := xpv1.Condition{Type: t, Status: corev1.ConditionStatus(s), Reason: xpv1.ConditionReason(reason), Message: msg}
+	if ago > 0 {
+		c.LastTransitionTime = metav1.NewTime(summaryNow.Add(-ago))
+	}
+	return c
+}
+
+func TestSummarize(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		infos  []*cliresource.Info
+		want   *healthSummary
+	}{
+		"Empty": {
+			reason: "Should return an empty summary if there are no objects.",
+			want:   &healthSummary{Items: []healthSummaryItem{}},
+		},
+		"Counts": {
+			reason: "Should count the Ready and Synced states per control plane and kind, treating missing conditions as unknown.",
+			infos: []*cliresource.Info{
+				conditioned(bucketGVK, "default/ctp", "a", cond(xpv1.TypeReady, "True", "Available", "", time.Hour), cond(xpv1.TypeSynced, "True", "ReconcileSuccess", "", time.Hour)),
+				conditioned(bucketGVK, "default/ctp", "b", cond(xpv1.TypeSynced, "True", "ReconcileSuccess", "", time.Hour)),
+				conditioned(bucketGVK, "default/other", "c", cond(xpv1.TypeReady, "True", "Available", "", time.Hour), cond(xpv1.TypeSynced, "Unknown", "", "", time.Hour)),
+				conditioned(vpcGVK, "default/ctp", "d"),
+			},
+			want: &healthSummary{Items: []healthSummaryItem{
+				{
+					Group: "default", ControlPlane: "ctp", APIGroup: "ec2.aws.upbound.io", Kind: "VPC", Total: 1,
+					Ready:  conditionCounts{Unknown: 1},
+					Synced: conditionCounts{Unknown: 1},
+				},
+				{
+					Group: "default", ControlPlane: "ctp", APIGroup: "s3.aws.upbound.io", Kind: "Bucket", Total: 2,
+					Ready:  conditionCounts{True: 1, Unknown: 1},
+					Synced: conditionCounts{True: 2},
+				},
+				{
+					Group: "default", ControlPlane: "other", APIGroup: "s3.aws.upbound.io", Kind: "Bucket", Total: 1,
+					Ready:  conditionCounts{True: 1},
+					Synced: conditionCounts{Unknown: 1},
+					OldestFailing: &failingObject{
+						Name:      "c",
+						Condition: xpv1.TypeSynced,
+						Status:    "Unknown",
+						Since:     summaryNow.Add(-time.Hour),
+					},
+				},
+			}},
+		},
+		"OldestFailing": {
+			reason: "Should report the object that has been failing for the longest time, preferring its Ready condition.",
+			infos: []*cliresource.Info{
+				conditioned(bucketGVK, "default/ctp", "recent", cond(xpv1.TypeReady, "False", "Creating", "", time.Minute)),
+				conditioned(bucketGVK, "default/ctp", "old", cond(xpv1.TypeReady, "False", "Unavailable", "bucket is gone", 2*time.Hour), cond(xpv1.TypeSynced, "False", "ReconcileError", "access denied", 3*time.Hour)),
+				conditioned(bucketGVK, "default/ctp", "untimed", cond(xpv1.TypeReady, "False", "", "", 0)),
+			},
+			want: &healthSummary{Items: []healthSummaryItem{
+				{
+					Group: "default", ControlPlane: "ctp", APIGroup: "s3.aws.upbound.io", Kind: "Bucket", Total: 3,
+					Ready:  conditionCounts{False: 3},
+					Synced: conditionCounts{False: 1, Unknown: 2},
+					OldestFailing: &failingObject{
+						Name:      "untimed",
+						Condition: xpv1.TypeReady,
+						Status:    "False",
+						Since:     summaryNow.Add(-24 * time.Hour),
+					},
+				},
+			}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := summarize(tc.infos)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nsummarize(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPrintSummary(t *testing.T) {
+	s := &healthSummary{Items: []healthSummaryItem{
+		{
+			Group: "default", ControlPlane: "ctp", APIGroup: "s3.aws.upbound.io", Kind: "Bucket", Total: 2,
+			Ready:  conditionCounts{True: 1, False: 1},
+			Synced: conditionCounts{True: 2},
+			OldestFailing: &failingObject{
+				Name:      "b",
+				Condition: xpv1.TypeReady,
+				Status:    "False",
+				Reason:    "Unavailable",
+				Message:   "bucket is gone\nretrying",
+				Since:     summaryNow.Add(-2 * time.Hour),
+			},
+		},
+	}}
+
+	cases := map[string]struct {
+		reason string
+		format string
+		want   string
+	}{
+		"Table": {
+			reason: "Should print one row per control plane and kind with the first line of the failure message.",
+			want: "GROUP     CONTROLPLANE   KIND                       TOTAL   READY   NOT-READY   READY-UNKNOWN   SYNCED   NOT-SYNCED   SYNCED-UNKNOWN   OLDEST-FAILING   AGE    MESSAGE\n" +
+				"default   ctp            Bucket.s3.aws.upbound.io   2       1       1           0               2        0            0                b                120m   Ready=False/Unavailable: bucket is gone\n",
+		},
+		"JSON": {
+			reason: "Should print the summary as a single JSON document.",
+			format: "json",
+			want:   `{"items":[{"group":"default","controlPlane":"ctp","apiGroup":"s3.aws.upbound.io","kind":"Bucket","total":2,"ready":{"true":1,"false":1,"unknown":0},"synced":{"true":2,"false":0,"unknown":0},"oldestFailing":{"name":"b","condition":"Ready","status":"False","reason":"Unavailable","message":"bucket is gone\nretrying","since":"2024-05-01T10:00:00Z"}}]}` + "\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := s.print(&buf, tc.format, false, summaryNow); err != nil {
+				t.Fatalf("\n%s\nprint(...): unexpected error: %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, buf.String()); diff != "" {
+				t.Errorf("\n%s\nprint(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
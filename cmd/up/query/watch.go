@@ -0,0 +1,345 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/alecthomas/kong"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/cli-runtime/pkg/printers"
+	cliresource "k8s.io/cli-runtime/pkg/resource"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+const (
+	// ageColumn is the name of the table column holding the age of an object.
+	ageColumn = "Age"
+	// nameColumn and namespaceColumn are the names of the table columns
+	// holding the name and namespace of an object.
+	nameColumn      = "Name"
+	namespaceColumn = "Namespace"
+)
+
+// fetchFunc re-runs the queries of a watch.
+type fetchFunc func(ctx context.Context) ([]*cliresource.Info, error)
+
+// watchRow is a single object of a query result, either as a table row or as
+// a complete object.
+type watchRow struct {
+	// key identifies the object across query results.
+	key string
+	// version changes whenever the object changes.
+	version string
+
+	info *cliresource.Info
+	// row is the table row of the object if the query returned tables.
+	row *metav1.TableRow
+}
+
+// watchEvent is a change of a row between two query results.
+type watchEvent struct {
+	Type watch.EventType
+	Row  watchRow
+}
+
+// watch prints the supplied objects and then re-runs the queries at the watch
+// interval, printing only the objects that were added, changed or deleted
+// since the previous run, like kubectl get --watch.
+func (c *cmd) watch(ctx context.Context, kongCtx *kong.Context, infos []*cliresource.Info, showKind bool, fetch fetchFunc) error {
+	p := &watchPrinter{
+		cmd:      c,
+		out:      kongCtx.Stdout,
+		tw:       printers.GetNewTabWriter(kongCtx.Stdout),
+		showKind: showKind,
+		printers: make(map[string]printers.ResourcePrinterFunc),
+	}
+
+	prev := watchRows(infos)
+	if err := p.print(diffRows(nil, prev)); err != nil {
+		return err
+	}
+
+	t := time.NewTicker(c.WatchInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+
+		infos, err := fetch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		rows := watchRows(infos)
+		if err := p.print(diffRows(prev, rows)); err != nil {
+			return err
+		}
+		prev = rows
+	}
+}
+
+// watchRows flattens the supplied query results into rows.
+func watchRows(infos []*cliresource.Info) []watchRow {
+	var rows []watchRow
+	for _, info := range infos {
+		gk := info.Mapping.GroupVersionKind.GroupKind().String()
+		t, ok := info.Object.(*metav1.Table)
+		if !ok {
+			rows = append(rows, watchRow{
+				key:     fmt.Sprintf("%s/%s/%s/%s", info.Source, gk, info.Namespace, info.Name),
+				version: objectVersion(info.Object),
+				info:    info,
+			})
+			continue
+		}
+		for i := range t.Rows {
+			r := &t.Rows[i]
+			rows = append(rows, watchRow{
+				key:     rowKey(info.Source, gk, t.ColumnDefinitions, r),
+				version: rowVersion(t.ColumnDefinitions, r),
+				info:    info,
+				row:     r,
+			})
+		}
+	}
+	return rows
+}
+
+// rowKey identifies a table row of the supplied control plane by the UID of
+// its object, falling back to its namespace and name. Rows without an object
+// are identified by their name and namespace columns or, if there are none,
+// by their cells except for the age.
+func rowKey(source, gk string, cols []metav1.TableColumnDefinition, r *metav1.TableRow) string {
+	if u, ok := r.Object.Object.(*unstructured.Unstructured); ok {
+		if uid := u.GetUID(); uid != "" {
+			return fmt.Sprintf("%s/%s/%s", source, gk, uid)
+		}
+		return fmt.Sprintf("%s/%s/%s/%s", source, gk, u.GetNamespace(), u.GetName())
+	}
+	if name, ok := cell(cols, r, nameColumn); ok {
+		ns, _ := cell(cols, r, namespaceColumn)
+		return fmt.Sprintf("%s/%s/%v/%v", source, gk, ns, name)
+	}
+	return fmt.Sprintf("%s/%s/%s", source, gk, cellsWithoutAge(cols, r))
+}
+
+// rowVersion returns the resource version of the object of a table row. If
+// there is none the cells are used instead, except for the age which changes
+// on every query.
+func rowVersion(cols []metav1.TableColumnDefinition, r *metav1.TableRow) string {
+	if u, ok := r.Object.Object.(*unstructured.Unstructured); ok && u.GetResourceVersion() != "" {
+		return u.GetResourceVersion()
+	}
+	return cellsWithoutAge(cols, r)
+}
+
+// cellsWithoutAge returns the cells of the supplied row except for the age,
+// which changes on every query.
+func cellsWithoutAge(cols []metav1.TableColumnDefinition, r *metav1.TableRow) string {
+	cells := make([]any, 0, len(r.Cells))
+	for i, cell := range r.Cells {
+		if i < len(cols) && cols[i].Name == ageColumn {
+			continue
+		}
+		cells = append(cells, cell)
+	}
+	return fmt.Sprintf("%v", cells)
+}
+
+// cell returns the cell of the supplied row in the column with the supplied
+// name.
+func cell(cols []metav1.TableColumnDefinition, r *metav1.TableRow, name string) (any, bool) {
+	for i, col := range cols {
+		if col.Name == name && i < len(r.Cells) {
+			return r.Cells[i], true
+		}
+	}
+	return nil, false
+}
+
+// objectVersion returns the resource version of the supplied object or, if it
+// has none, its serialized form.
+func objectVersion(obj kruntime.Object) string {
+	u, ok := obj.(*unstructured.Unstructured)
+	if ok && u.GetResourceVersion() != "" {
+		return u.GetResourceVersion()
+	}
+	bs, _ := json.Marshal(obj) //nolint:errchkjson // a failure just reports the object as changed.
+	return string(bs)
+}
+
+// diffRows returns the events that turn the previous rows into the current
+// ones. Added and modified rows are returned in the order of the current
+// rows, followed by the deleted rows in their previous order.
+func diffRows(prev, cur []watchRow) []watchEvent {
+	versions := make(map[string]string, len(prev))
+	for _, r := range prev {
+		versions[r.key] = r.version
+	}
+	seen := make(map[string]bool, len(cur))
+
+	var events []watchEvent
+	for _, r := range cur {
+		seen[r.key] = true
+		v, ok := versions[r.key]
+		switch {
+		case !ok:
+			events = append(events, watchEvent{Type: watch.Added, Row: r})
+		case v != r.version:
+			events = append(events, watchEvent{Type: watch.Modified, Row: r})
+		}
+	}
+	for _, r := range prev {
+		if !seen[r.key] {
+			events = append(events, watchEvent{Type: watch.Deleted, Row: r})
+		}
+	}
+	return events
+}
+
+// flushWriter is a writer that buffers until it is flushed, like a tab
+// writer.
+type flushWriter interface {
+	io.Writer
+	Flush() error
+}
+
+// watchPrinter prints watch events. Printers are kept across calls so that
+// table headers are only printed once per kind.
+type watchPrinter struct {
+	cmd      *cmd
+	out      io.Writer
+	tw       flushWriter
+	showKind bool
+	printers map[string]printers.ResourcePrinterFunc
+	docs     int
+}
+
+func (p *watchPrinter) print(events []watchEvent) error {
+	switch p.cmd.OutputFormat {
+	case "json", "yaml":
+		return p.printEvents(events)
+	}
+	for _, e := range events {
+		if e.Row.row != nil {
+			if err := p.printRow(e); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := p.printObject(e); err != nil {
+			return err
+		}
+	}
+	return p.tw.Flush()
+}
+
+// printRow prints a table row, prefixed with the event type.
+func (p *watchPrinter) printRow(e watchEvent) error {
+	src := e.Row.info.Object.(*metav1.Table) //nolint:forcetypeassert // rows are only set for tables.
+	r := e.Row.row.DeepCopy()
+	r.Cells = append([]any{string(e.Type)}, r.Cells...)
+	t := &metav1.Table{
+		TypeMeta:          src.TypeMeta,
+		ColumnDefinitions: append([]metav1.TableColumnDefinition{{Name: "Event", Type: "string"}}, src.ColumnDefinitions...),
+		Rows:              []metav1.TableRow{*r},
+	}
+
+	printer, err := p.printer(e.Row.info, true)
+	if err != nil {
+		return err
+	}
+	return printer.PrintObj(t, p.tw)
+}
+
+// printObject prints an object with the printer of the output format.
+func (p *watchPrinter) printObject(e watchEvent) error {
+	printer, err := p.printer(e.Row.info, false)
+	if err != nil {
+		return err
+	}
+	return printer.PrintObj(e.Row.info.Object, p.out)
+}
+
+// printEvents prints the supplied events as watch events, one line per event
+// for JSON and one document per event for YAML.
+func (p *watchPrinter) printEvents(events []watchEvent) error {
+	for _, e := range events {
+		obj := e.Row.info.Object
+		if u, ok := obj.(*unstructured.Unstructured); ok && !p.cmd.ShowManagedFields {
+			u = u.DeepCopy()
+			u.SetManagedFields(nil)
+			obj = u
+		}
+		raw, err := json.Marshal(obj)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal object")
+		}
+		ev := &metav1.WatchEvent{Type: string(e.Type), Object: kruntime.RawExtension{Raw: raw}}
+
+		var bs []byte
+		if p.cmd.OutputFormat == "json" {
+			bs, err = json.Marshal(ev)
+			bs = append(bs, '\n')
+		} else {
+			bs, err = yaml.Marshal(ev)
+			if p.docs > 0 {
+				bs = append([]byte("---\n"), bs...)
+			}
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal watch event")
+		}
+		if _, err := p.out.Write(bs); err != nil {
+			return err
+		}
+		p.docs++
+	}
+	return nil
+}
+
+// printer returns the printer for the kind of the supplied object.
+func (p *watchPrinter) printer(info *cliresource.Info, table bool) (printers.ResourcePrinterFunc, error) {
+	key := fmt.Sprintf("%s/%t", info.Mapping.GroupVersionKind.GroupKind(), table)
+	if printer, ok := p.printers[key]; ok {
+		return printer, nil
+	}
+
+	mapping := info.Mapping
+	if !table {
+		mapping = nil
+	}
+	printer, err := p.cmd.createPrinter(mapping, false, p.showKind && table)
+	if err != nil {
+		return nil, err
+	}
+	p.printers[key] = printer
+	return printer, nil
+}
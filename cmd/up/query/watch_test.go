@@ -0,0 +1,237 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+	runtimeschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/cli-runtime/pkg/printers"
+	cliresource "k8s.io/cli-runtime/pkg/resource"
+)
+
+var bucketGVK = runtimeschema.GroupVersionKind{Group: "s3.aws.upbound.io", Version: "v1beta1", Kind: "Bucket"}
+
+func bucket(name, rv string) *cliresource.Info {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(bucketGVK)
+	u.SetName(name)
+	u.SetResourceVersion(rv)
+	return &cliresource.Info{
+		Mapping:         &meta.RESTMapping{GroupVersionKind: bucketGVK},
+		Name:            name,
+		Source:          "default/ctp",
+		Object:          u,
+		ResourceVersion: rv,
+	}
+}
+
+func bucketTable(rows ...[3]string) *cliresource.Info {
+	t := &metav1.Table{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: metav1.SchemeGroupVersion.String(),
+			Kind:       "Table",
+		},
+		ColumnDefinitions: []metav1.TableColumnDefinition{
+			{Name: "Name", Type: "string"},
+			{Name: "Ready", Type: "string"},
+			{Name: "Age", Type: "string"},
+		},
+	}
+	for _, r := range rows {
+		u := &unstructured.Unstructured{}
+		u.SetName(r[0])
+		t.Rows = append(t.Rows, metav1.TableRow{
+			Cells:  []any{r[0], r[1], r[2]},
+			Object: kruntime.RawExtension{Object: u},
+		})
+	}
+	return &cliresource.Info{
+		Mapping: &meta.RESTMapping{
+			Resource:         runtimeschema.GroupVersionResource{Group: bucketGVK.Group, Version: bucketGVK.Version, Resource: "table-0"},
+			GroupVersionKind: bucketGVK,
+			Scope:            RESTScopeNameFunc(meta.RESTScopeNameRoot),
+		},
+		Object: t,
+	}
+}
+
+// objectlessTable returns a table of the supplied control plane whose rows
+// have no object, like the tables returned when no object is requested.
+func objectlessTable(source string, rows ...[3]string) *cliresource.Info {
+	info := bucketTable(rows...)
+	info.Source = source
+	t := info.Object.(*metav1.Table)
+	for i := range t.Rows {
+		t.Rows[i].Object = kruntime.RawExtension{}
+	}
+	return info
+}
+
+func TestDiffRows(t *testing.T) {
+	type event struct {
+		Type watch.EventType
+		Name string
+	}
+
+	cases := map[string]struct {
+		reason string
+		prev   []*cliresource.Info
+		cur    []*cliresource.Info
+		want   []event
+	}{
+		"Initial": {
+			reason: "Should report all objects as added on the first run.",
+			cur:    []*cliresource.Info{bucket("a", "1"), bucket("b", "1")},
+			want: []event{
+				{Type: watch.Added, Name: "a"},
+				{Type: watch.Added, Name: "b"},
+			},
+		},
+		"Unchanged": {
+			reason: "Should not report objects whose resource version did not change.",
+			prev:   []*cliresource.Info{bucket("a", "1")},
+			cur:    []*cliresource.Info{bucket("a", "1")},
+		},
+		"Changes": {
+			reason: "Should report added and modified objects in order, followed by deleted objects.",
+			prev:   []*cliresource.Info{bucket("a", "1"), bucket("b", "1"), bucket("c", "1")},
+			cur:    []*cliresource.Info{bucket("d", "1"), bucket("b", "2"), bucket("c", "1")},
+			want: []event{
+				{Type: watch.Added, Name: "d"},
+				{Type: watch.Modified, Name: "b"},
+				{Type: watch.Deleted, Name: "a"},
+			},
+		},
+		"TableRows": {
+			reason: "Should compare the cells except for the age of table rows whose objects have no resource version.",
+			prev:   []*cliresource.Info{bucketTable([3]string{"a", "False", "1m"}, [3]string{"b", "True", "1m"})},
+			cur:    []*cliresource.Info{bucketTable([3]string{"a", "True", "2m"}, [3]string{"b", "True", "2m"})},
+			want: []event{
+				{Type: watch.Modified, Name: "a"},
+			},
+		},
+		"TableRowsWithoutObject": {
+			reason: "Should identify table rows without an object by their name rather than by cells including the age.",
+			prev:   []*cliresource.Info{objectlessTable("default/ctp", [3]string{"a", "False", "1m"}, [3]string{"b", "True", "1m"})},
+			cur:    []*cliresource.Info{objectlessTable("default/ctp", [3]string{"a", "True", "2m"}, [3]string{"b", "True", "2m"})},
+			want: []event{
+				{Type: watch.Modified, Name: "a"},
+			},
+		},
+		"TableRowsOfControlPlanes": {
+			reason: "Should not mix up table rows of objects with the same name in different control planes.",
+			prev: []*cliresource.Info{
+				objectlessTable("default/ctp1", [3]string{"a", "True", "1m"}),
+			},
+			cur: []*cliresource.Info{
+				objectlessTable("default/ctp1", [3]string{"a", "True", "2m"}),
+				objectlessTable("default/ctp2", [3]string{"a", "True", "2m"}),
+			},
+			want: []event{
+				{Type: watch.Added, Name: "a"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []event
+			for _, e := range diffRows(watchRows(tc.prev), watchRows(tc.cur)) {
+				n := e.Row.info.Name
+				if e.Row.row != nil {
+					n = e.Row.row.Cells[0].(string)
+				}
+				got = append(got, event{Type: e.Type, Name: n})
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ndiffRows(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWatchPrinter(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		output string
+		runs   [][]*cliresource.Info
+		want   string
+	}{
+		"JSON": {
+			reason: "Should print one watch event per line.",
+			output: "json",
+			runs: [][]*cliresource.Info{
+				{bucket("a", "1")},
+				{bucket("a", "2")},
+				{},
+			},
+			want: `{"type":"ADDED","object":{"apiVersion":"s3.aws.upbound.io/v1beta1","kind":"Bucket","metadata":{"name":"a","resourceVersion":"1"}}}
+{"type":"MODIFIED","object":{"apiVersion":"s3.aws.upbound.io/v1beta1","kind":"Bucket","metadata":{"name":"a","resourceVersion":"2"}}}
+{"type":"DELETED","object":{"apiVersion":"s3.aws.upbound.io/v1beta1","kind":"Bucket","metadata":{"name":"a","resourceVersion":"2"}}}
+`,
+		},
+		"Table": {
+			reason: "Should print the changed rows with their event and the headers only once.",
+			runs: [][]*cliresource.Info{
+				{bucketTable([3]string{"a", "False", "1m"})},
+				{bucketTable([3]string{"a", "False", "2m"})},
+				{bucketTable([3]string{"a", "True", "2m"})},
+			},
+			// column widths only grow as rows are streamed.
+			want: `EVENT   NAME   READY   AGE
+ADDED   a      False   1m
+MODIFIED   a      True    2m
+`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := &cmd{OutputFormat: tc.output}
+			if err := c.afterApply(); err != nil {
+				t.Fatalf("\n%s\nafterApply(): unexpected error: %s", tc.reason, err)
+			}
+
+			out := &bytes.Buffer{}
+			p := &watchPrinter{
+				cmd:      c,
+				out:      out,
+				tw:       printers.GetNewTabWriter(out),
+				printers: make(map[string]printers.ResourcePrinterFunc),
+			}
+			var prev []watchRow
+			for _, infos := range tc.runs {
+				rows := watchRows(infos)
+				if err := p.print(diffRows(prev, rows)); err != nil {
+					t.Fatalf("\n%s\nprint(...): unexpected error: %s", tc.reason, err)
+				}
+				prev = rows
+			}
+
+			if diff := cmp.Diff(tc.want, out.String()); diff != "" {
+				t.Errorf("\n%s\nprint(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -48,6 +48,7 @@ type App struct {
 	timeline *views.TimeLine
 	status   *views.Status
 	details  *views.Details
+	events   *views.Events
 
 	grid     *tview.Grid
 	topLevel *upviews.TopLevel
@@ -69,18 +70,20 @@ func NewApp(title string, resources []string, gkns query.GroupKindNames, cns que
 	app.timeline = views.NewTimeLine(app.tree, app.model)
 	app.status = views.NewStatus(app.tree)
 	app.details = views.NewDetails(app.tree)
+	app.events = views.NewEvents(app.tree)
 
 	app.tree.AddScroller(app.timeline, app.status)
 
 	app.grid = tview.NewGrid().
-		SetRows(1, 0, 4).
+		SetRows(1, 0, 8, 4).
 		SetBorders(true).
 		SetBordersColor(tcell.ColorDarkGray).
 		SetColumns(40, 0, 75).
 		AddItem(app.header, 0, 0, 1, 3, 0, 0, false).
-		AddItem(app.tree, 1, 0, 1, 1, 0, 0, true).
+		AddItem(app.tree, 1, 0, 2, 1, 0, 0, true).
 		AddItem(app.timeline, 1, 1, 1, 1, 0, 0, true).
-		AddItem(app.details, 2, 0, 1, 3, 0, 0, false)
+		AddItem(app.events, 2, 1, 1, 2, 0, 0, false).
+		AddItem(app.details, 3, 0, 1, 3, 0, 0, false)
 	app.Unzoom()
 	app.topLevel = upviews.NewTopLevel(title, app.grid, app.Application).
 		SetTitles(
@@ -100,8 +103,9 @@ func NewApp(title string, resources []string, gkns query.GroupKindNames, cns que
 				}
 			}},
 			upviews.GridTitle{Col: 2, Row: 1, Text: "── Progress ── Synced Ready  Message ", Color: tcell.ColorDarkGray, Align: tview.AlignLeft},
-			upviews.GridTitle{Col: 0, Row: 2, Text: " Details ", Color: tcell.ColorDarkGray, Align: tview.AlignCenter},
-			upviews.GridTitle{Col: 0, Row: 2, Fn: func(screen tcell.Screen, x, y, w int) {
+			upviews.GridTitle{Col: 1, Row: 2, Text: " Events ", Color: tcell.ColorDarkGray, Align: tview.AlignLeft},
+			upviews.GridTitle{Col: 0, Row: 3, Text: " Details ", Color: tcell.ColorDarkGray, Align: tview.AlignCenter},
+			upviews.GridTitle{Col: 0, Row: 3, Fn: func(screen tcell.Screen, x, y, w int) {
 				var b strings.Builder
 				if app.model.Tree.AutoCollapse {
 					b.WriteString("AutoCollapse ")
@@ -124,6 +128,28 @@ func NewApp(title string, resources []string, gkns query.GroupKindNames, cns que
 	return app
 }
 
+// NewReplayApp returns an App that shows a recorded trace session instead of
+// polling control planes. The timeline is stopped at the last snapshot.
+func NewReplayApp(title string, resources []string, snaps []snapshot) *App {
+	objs := recordedObjects(snaps)
+	app := NewApp(title, resources, nil, nil, nil, func(id string) (*unstructured.Unstructured, error) {
+		o, ok := objs[id]
+		if !ok {
+			return nil, errors.Errorf("object %s not found in recording", id)
+		}
+		return o, nil
+	})
+
+	for _, snap := range snaps {
+		app.model.Tree.Update(snap.Objects)
+	}
+	end := snaps[len(snaps)-1].Time
+	app.model.TimeLine.End = end
+	app.model.TimeLine.FixedTime = end
+
+	return app
+}
+
 func (a *App) Zoom() {
 	a.grid.RemoveItem(a.timeline)
 	a.grid.AddItem(a.timeline, 1, 1, 1, 2, 0, 0, true)
@@ -165,12 +191,14 @@ func (a *App) TopLevelInputHandler(event *tcell.EventKey, setFocus func(p tview.
 			a.model.TimeLine.FixedTime = time.Now()
 		}
 		a.model.TimeLine.FixedTime = a.model.TimeLine.FixedTime.Add(a.model.TimeLine.Scale / 10)
-		if a.model.TimeLine.FixedTime.After(time.Now()) {
+		if end := a.model.TimeLine.End; !end.IsZero() && a.model.TimeLine.FixedTime.After(end) {
+			a.model.TimeLine.FixedTime = end // stay at the end of the recording
+		} else if a.model.TimeLine.FixedTime.After(time.Now()) {
 			a.model.TimeLine.FixedTime = time.Time{} // back to auto-scrolling
 		}
 		return true
 	case tcell.KeyEnd:
-		a.model.TimeLine.FixedTime = time.Time{}
+		a.model.TimeLine.FixedTime = a.model.TimeLine.End
 	case tcell.KeyRune:
 		switch event.Rune() {
 		case 'q':
@@ -205,6 +233,9 @@ func (a *App) TopLevelInputHandler(event *tcell.EventKey, setFocus func(p tview.
 		default:
 		}
 	case tcell.KeyF2:
+		if a.pollFn == nil {
+			return true // nothing to query when replaying
+		}
 		resources := *a.model.Resources.Load()
 
 		oldRoot := dialogs.GetRoot(a.Application)
@@ -279,6 +310,10 @@ func (a *App) Run(ctx context.Context) error {
 		}
 	}()
 
+	if a.pollFn == nil {
+		return a.Application.Run()
+	}
+
 	resp, err := a.pollFn(*a.model.GroupKindNames.Load(), *a.model.CategoryNames.Load())
 	if err != nil {
 		return err
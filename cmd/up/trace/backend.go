@@ -0,0 +1,99 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+
+	queryv1alpha2 "github.com/upbound/up-sdk-go/apis/query/v1alpha2"
+	"github.com/upbound/up/cmd/up/query"
+	"github.com/upbound/up/cmd/up/trace/walker"
+	"github.com/upbound/up/internal/upbound"
+)
+
+const (
+	backendAuto       = "auto"
+	backendQuery      = "query"
+	backendKubernetes = "kubernetes"
+)
+
+// fetchFunc returns the complete object of the supplied ID.
+type fetchFunc func(id string) (*unstructured.Unstructured, error)
+
+// useQueryAPI returns true if the objects should be traced with the Query
+// API, i.e. if it was requested or if the server serves it.
+func (c *Cmd) useQueryAPI(dc discovery.DiscoveryInterface) (bool, error) {
+	switch c.Backend {
+	case backendQuery:
+		return true, nil
+	case backendKubernetes:
+		return false, nil
+	}
+
+	groups, err := dc.ServerGroups()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to discover server groups")
+	}
+	for _, g := range groups.Groups {
+		if g.Name == queryv1alpha2.Group {
+			return true, nil
+		}
+	}
+	if c.ControlPlane != "" || c.AllGroups {
+		return false, errors.New("the server does not serve the Query API, which is required for --controlplane and --all-groups")
+	}
+	return false, nil
+}
+
+// walk returns poll and fetch functions that trace objects of the current
+// kubeconfig context with a dynamic client instead of the Query API.
+func (c *Cmd) walk(ctx context.Context, upCtx *upbound.Context, kubeconfig *rest.Config, dc discovery.DiscoveryInterface) (pollFunc, fetchFunc, error) {
+	dyn, err := dynamic.NewForConfig(kubeconfig)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create dynamic client")
+	}
+	cached := memory.NewMemCacheClient(dc)
+	mapper := restmapper.NewShortcutExpander(restmapper.NewDeferredDiscoveryRESTMapper(cached), cached, nil)
+
+	// report the control plane the kubeconfig points to.
+	var ctpNamespace, ctpName string
+	if _, ctp, isSpace := upCtx.GetCurrentSpaceContextScope(); isSpace && ctp.Name != "" {
+		ctpNamespace, ctpName = ctp.Namespace, ctp.Name
+	} else if raw, err := upCtx.Kubecfg.RawConfig(); err == nil {
+		ctpName = raw.CurrentContext
+	}
+
+	w := walker.New(dyn, mapper,
+		walker.WithNamespace(c.Namespace),
+		walker.WithControlPlane(ctpNamespace, ctpName),
+		walker.WithCategoryExpander(restmapper.NewDiscoveryCategoryExpander(cached)),
+	)
+	poll := func(gkns query.GroupKindNames, cns query.CategoryNames) ([]queryv1alpha2.QueryResponseObject, error) {
+		return w.Poll(ctx, gkns, cns)
+	}
+	fetch := func(id string) (*unstructured.Unstructured, error) {
+		return w.Fetch(ctx, id)
+	}
+	return poll, fetch, nil
+}
@@ -17,18 +17,24 @@ package trace
 import (
 	"context"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/alecthomas/kong"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/discovery"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+
 	"github.com/upbound/up-sdk-go/apis/common"
 	queryv1alpha2 "github.com/upbound/up-sdk-go/apis/query/v1alpha2"
 	"github.com/upbound/up/cmd/up/query"
 	"github.com/upbound/up/cmd/up/query/resource"
+	"github.com/upbound/up/cmd/up/trace/model"
 	"github.com/upbound/up/internal/upbound"
 	"github.com/upbound/up/internal/version"
 )
@@ -39,8 +45,17 @@ type Cmd struct {
 	Namespace    string `short:"n" long:"namespace" env:"UPBOUND_NAMESPACE" description:"Namespace of objects to query (defaults to all namespaces)"`
 	AllGroups    bool   `short:"A" name:"all-groups" help:"Query in all groups."`
 
+	Output    string        `short:"o" name:"output" help:"Print the trace once instead of running interactively. One of: tree, json, yaml."`
+	WaitReady bool          `name:"wait-ready" help:"Wait until all traced objects are synced and ready, and fail if they are not within the timeout. Implies --output=tree unless another output is given."`
+	Timeout   time.Duration `name:"timeout" default:"5m" help:"How long to wait for the traced objects to become ready when --wait-ready is set."`
+
+	Backend string `name:"backend" enum:"auto,query,kubernetes" default:"auto" help:"How to find the traced objects. \"query\" uses the Query API of the Space, \"kubernetes\" follows the references between objects of the current kubeconfig context directly. \"auto\" uses the Query API if the server serves it."`
+
+	Record string `name:"record" type:"path" help:"Record every polled snapshot of the traced objects to the given file, to be replayed later with --replay. Complete objects are recorded, so that their YAML can be viewed when replaying."`
+	Replay string `name:"replay" type:"existingfile" help:"Replay a session recorded with --record instead of querying control planes."`
+
 	// positional arguments
-	Resources []string `arg:"" help:"Type(s) (resource, singular or plural, category, short-name) and names: TYPE[.GROUP][,TYPE[.GROUP]...] [NAME ...] | TYPE[.GROUP]/NAME .... If no resource is specified, all resources are queried, but --all-resources must be specified."`
+	Resources []string `arg:"" optional:"" help:"Type(s) (resource, singular or plural, category, short-name) and names: TYPE[.GROUP][,TYPE[.GROUP]...] [NAME ...] | TYPE[.GROUP]/NAME .... If no resource is specified, all resources are queried, but --all-resources must be specified."`
 
 	Flags upbound.Flags `embed:""`
 }
@@ -61,6 +76,21 @@ func (c *Cmd) Help() string {
 
   # Trace the bucket prod and the vpc default.
   up alpha trace bucket/prod vpc/default 
+
+  # Print the trace of all claims as JSON.
+  up alpha trace claims -o json
+
+  # Trace a claim in a cluster without the Query API, e.g. a kind cluster.
+  up alpha trace clusters prod --backend kubernetes
+
+  # Record a trace session to a file and replay it later, e.g. on another
+  # machine.
+  up alpha trace claims --record session.jsonl
+  up alpha trace --replay session.jsonl
+
+  # Wait up to 10 minutes for the claim prod and everything it composes to
+  # become ready.
+  up alpha trace clusters prod --wait-ready --timeout 10m
 `
 }
 
@@ -74,16 +104,58 @@ func (c *Cmd) AfterApply(kongCtx *kong.Context) error {
 	kongCtx.Bind(upCtx)
 	upCtx.SetupLogging()
 
+	if c.WaitReady && c.Output == "" {
+		c.Output = outputTree
+	}
+	switch c.Output {
+	case "", outputTree, outputJSON, outputYAML:
+	default:
+		return errors.Errorf("unknown output format %q. Must be one of: tree, json, yaml", c.Output)
+	}
+	if c.WaitReady && c.Timeout <= 0 {
+		return errors.New("--timeout must be positive")
+	}
+	switch {
+	case c.Replay != "" && c.Record != "":
+		return errors.New("cannot use --record and --replay together")
+	case c.Replay != "" && c.WaitReady:
+		return errors.New("cannot use --wait-ready when replaying a recording")
+	case c.Replay != "" && len(c.Resources) > 0:
+		return errors.New("cannot query resources when replaying a recording")
+	case c.Backend == backendKubernetes && (c.ControlPlane != "" || c.AllGroups):
+		return errors.New("cannot use --controlplane or --all-groups with the kubernetes backend")
+	case c.Replay == "" && len(c.Resources) == 0:
+		return errors.New("expected at least one resource type to trace")
+	}
+
 	return nil
 }
 
 func (c *Cmd) Run(ctx context.Context, kongCtx *kong.Context, upCtx *upbound.Context) error { // nolint:gocyclo // TODO: split up
+	if c.Replay != "" {
+		return c.replay(ctx, kongCtx, upCtx)
+	}
+
+	if c.WaitReady {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
 	// create client
 	kubeconfig, err := upCtx.Kubecfg.ClientConfig()
 	if err != nil {
 		return err
 	}
 	kubeconfig.UserAgent = version.UserAgent()
+	dc, err := discovery.NewDiscoveryClientForConfig(kubeconfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to create discovery client")
+	}
+	useQueryAPI, err := c.useQueryAPI(dc)
+	if err != nil {
+		return err
+	}
 
 	_, ctp, isSpace := upCtx.GetCurrentSpaceContextScope()
 
@@ -128,12 +200,12 @@ func (c *Cmd) Run(ctx context.Context, kongCtx *kong.Context, upCtx *upbound.Con
 		var querySpecs []*queryv1alpha2.QuerySpec
 		for gk, names := range gkns {
 			if len(names) == 0 {
-				query := createQuerySpec(types.NamespacedName{Namespace: c.Namespace}, gk, nil)
+				query := createQuerySpec(types.NamespacedName{Namespace: c.Namespace}, gk, nil, c.Record != "")
 				querySpecs = append(querySpecs, query)
 				continue
 			}
 			for _, name := range names {
-				query := createQuerySpec(types.NamespacedName{Namespace: c.Namespace, Name: name}, gk, nil)
+				query := createQuerySpec(types.NamespacedName{Namespace: c.Namespace, Name: name}, gk, nil, c.Record != "")
 				querySpecs = append(querySpecs, query)
 			}
 		}
@@ -143,12 +215,12 @@ func (c *Cmd) Run(ctx context.Context, kongCtx *kong.Context, upCtx *upbound.Con
 				catList = nil
 			}
 			if len(names) == 0 {
-				query := createQuerySpec(types.NamespacedName{Namespace: c.Namespace}, metav1.GroupKind{}, catList)
+				query := createQuerySpec(types.NamespacedName{Namespace: c.Namespace}, metav1.GroupKind{}, catList, c.Record != "")
 				querySpecs = append(querySpecs, query)
 				continue
 			}
 			for _, name := range names {
-				query := createQuerySpec(types.NamespacedName{Namespace: c.Namespace, Name: name}, metav1.GroupKind{}, catList)
+				query := createQuerySpec(types.NamespacedName{Namespace: c.Namespace, Name: name}, metav1.GroupKind{}, catList, c.Record != "")
 				querySpecs = append(querySpecs, query)
 			}
 		}
@@ -213,12 +285,33 @@ func (c *Cmd) Run(ctx context.Context, kongCtx *kong.Context, upCtx *upbound.Con
 		return &unstructured.Unstructured{Object: query.GetResponse().Objects[0].Object.Object}, nil
 	}
 
+	if !useQueryAPI {
+		if poll, fetch, err = c.walk(ctx, upCtx, kubeconfig, dc); err != nil {
+			return err
+		}
+	}
+
+	if c.Record != "" {
+		f, err := os.Create(c.Record)
+		if err != nil {
+			return errors.Wrap(err, "failed to create recording")
+		}
+		defer f.Close() // nolint:errcheck // nothing we can do
+		poll = recordPoll(f, poll)
+	}
+
+	if c.Output != "" {
+		return c.print(ctx, kongCtx.Stdout, gkNames, categoryNames, poll)
+	}
+
 	upCtx.HideLogging()
 	app := NewApp("upbound trace", c.Resources, gkNames, categoryNames, poll, fetch)
 	return app.Run(ctx)
 }
 
-func createQuerySpec(obj types.NamespacedName, gk metav1.GroupKind, categories []string) *queryv1alpha2.QuerySpec {
+// createQuerySpec returns the query of the supplied objects and the resources
+// they compose. Complete objects are only queried if fullObjects is true.
+func createQuerySpec(obj types.NamespacedName, gk metav1.GroupKind, categories []string, fullObjects bool) *queryv1alpha2.QuerySpec {
 	return &queryv1alpha2.QuerySpec{
 		QueryTopLevelResources: queryv1alpha2.QueryTopLevelResources{
 			Filter: queryv1alpha2.QueryTopLevelFilter{
@@ -240,21 +333,7 @@ func createQuerySpec(obj types.NamespacedName, gk metav1.GroupKind, categories [
 				Objects: &queryv1alpha2.QueryObjects{
 					ID:           true,
 					ControlPlane: true,
-					Object: &common.JSON{
-						Object: map[string]interface{}{
-							"kind":       true,
-							"apiVersion": true,
-							"metadata": map[string]interface{}{
-								"creationTimestamp": true,
-								"deletionTimestamp": true,
-								"name":              true,
-								"namespace":         true,
-							},
-							"status": map[string]interface{}{
-								"conditions": true,
-							},
-						},
-					},
+					Object:       objectSkeleton(fullObjects),
 					Relations: map[string]queryv1alpha2.QueryRelation{
 						"events": {
 							QueryNestedResources: queryv1alpha2.QueryNestedResources{
@@ -266,6 +345,7 @@ func createQuerySpec(obj types.NamespacedName, gk metav1.GroupKind, categories [
 											Object: map[string]interface{}{
 												"lastTimestamp": true,
 												"message":       true,
+												"reason":        true,
 												"count":         true,
 												"type":          true,
 											},
@@ -281,21 +361,7 @@ func createQuerySpec(obj types.NamespacedName, gk metav1.GroupKind, categories [
 									Objects: &queryv1alpha2.QueryObjects{
 										ID:           true,
 										ControlPlane: true,
-										Object: &common.JSON{
-											Object: map[string]interface{}{
-												"kind":       true,
-												"apiVersion": true,
-												"metadata": map[string]interface{}{
-													"creationTimestamp": true,
-													"deletionTimestamp": true,
-													"name":              true,
-													"namespace":         true,
-												},
-												"status": map[string]interface{}{
-													"conditions": true,
-												},
-											},
-										},
+										Object:       objectSkeleton(fullObjects),
 										Relations: map[string]queryv1alpha2.QueryRelation{
 											"events": {
 												QueryNestedResources: queryv1alpha2.QueryNestedResources{
@@ -307,6 +373,7 @@ func createQuerySpec(obj types.NamespacedName, gk metav1.GroupKind, categories [
 																Object: map[string]interface{}{
 																	"lastTimestamp": true,
 																	"message":       true,
+																	"reason":        true,
 																	"count":         true,
 																	"type":          true,
 																},
@@ -326,3 +393,33 @@ func createQuerySpec(obj types.NamespacedName, gk metav1.GroupKind, categories [
 		},
 	}
 }
+
+// objectSkeleton returns the fields of the traced objects that are queried,
+// including the spec fields that tell whether an object is healthy without
+// conditions. Recordings carry complete objects, so that their YAML can be
+// shown when replaying them.
+func objectSkeleton(fullObjects bool) *common.JSON {
+	if fullObjects {
+		return &common.JSON{Object: true}
+	}
+	spec := make(map[string]interface{}, len(model.ReconciledSpecFields))
+	for _, f := range model.ReconciledSpecFields {
+		spec[f] = true
+	}
+	return &common.JSON{
+		Object: map[string]interface{}{
+			"kind":       true,
+			"apiVersion": true,
+			"metadata": map[string]interface{}{
+				"creationTimestamp": true,
+				"deletionTimestamp": true,
+				"name":              true,
+				"namespace":         true,
+			},
+			"spec": spec,
+			"status": map[string]interface{}{
+				"conditions": true,
+			},
+		},
+	}
+}
@@ -16,13 +16,34 @@ package model
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+
+	xpkgv1 "github.com/crossplane/crossplane/apis/pkg/v1"
 
 	"github.com/upbound/up-sdk-go/apis/common"
 )
 
+const (
+	packageField   = "package"
+	revisionSuffix = "Revision"
+	usageGroup     = "apiextensions.crossplane.io"
+	usageKind      = "Usage"
+)
+
+// ReconciledSpecFields are the spec fields by which objects are recognized
+// that get synced and ready conditions once reconciled: claims reference
+// their composite, composites their composition or composed resources, and
+// managed resources their provider config. Packages are recognized by their
+// package field.
+var ReconciledSpecFields = []string{"resourceRef", "resourceRefs", "compositionRef", "compositionSelector", "providerConfigRef", packageField}
+
 type Object struct {
 	Id string
 
@@ -51,6 +72,7 @@ type ControlPlane struct {
 type Event struct {
 	LastTimestamp metav1.Time `json:"lastTimestamp"`
 	Message       string      `json:"message"`
+	Reason        string      `json:"reason"`
 	Count         int         `json:"count"`
 	Type          string      `json:"type"`
 }
@@ -89,3 +111,80 @@ func (o *Object) IsReady(ts time.Time) bool {
 	}
 	return false
 }
+
+// SyncedCondition returns the Synced condition of the object, or the
+// Installed condition of packages. It returns false if neither is set.
+func (o *Object) SyncedCondition() (xpv1.Condition, bool) {
+	return o.condition(xpv1.TypeSynced, xpkgv1.TypeInstalled)
+}
+
+// ReadyCondition returns the Ready condition of the object, or the Healthy
+// condition of packages. It returns false if neither is set.
+func (o *Object) ReadyCondition() (xpv1.Condition, bool) {
+	return o.condition(xpv1.TypeReady, xpkgv1.TypeHealthy)
+}
+
+// Healthy returns true if the synced and ready conditions of the object are
+// true. Objects without these conditions are healthy, unless they are claims,
+// composites, managed resources or packages, which are not healthy until
+// they have been reconciled.
+func (o *Object) Healthy() bool {
+	synced, ready := o.expectedConditions()
+	if c, ok := o.SyncedCondition(); ok && c.Status != corev1.ConditionTrue || !ok && synced {
+		return false
+	}
+	if c, ok := o.ReadyCondition(); ok && c.Status != corev1.ConditionTrue || !ok && ready {
+		return false
+	}
+	return true
+}
+
+// expectedConditions returns whether the object is expected to get a synced
+// and a ready condition once it has been reconciled. It is decided by the
+// shape of the object, as composed resources may be of any kind.
+func (o *Object) expectedConditions() (synced, ready bool) {
+	switch {
+	case o.Group == xpkgv1.Group && strings.HasSuffix(o.Kind, revisionSuffix):
+		// package revisions are healthy, but not installed.
+		return false, true
+	case o.Group == usageGroup && o.Kind == usageKind:
+		return false, true
+	case o.Group == xpkgv1.Group:
+		return o.hasSpecField(packageField), o.hasSpecField(packageField)
+	}
+	for _, f := range ReconciledSpecFields {
+		if f != packageField && o.hasSpecField(f) {
+			return true, true
+		}
+	}
+	return false, false
+}
+
+func (o *Object) hasSpecField(f string) bool {
+	_, ok, _ := unstructured.NestedFieldNoCopy(o.JSON.Object, "spec", f)
+	return ok
+}
+
+// FailureMessage returns the message of the synced condition if it is false,
+// and the message of the ready condition otherwise. It falls back to the
+// reason if the condition has no message.
+func (o *Object) FailureMessage() string {
+	for _, fn := range []func() (xpv1.Condition, bool){o.SyncedCondition, o.ReadyCondition} {
+		if c, ok := fn(); ok && c.Status == corev1.ConditionFalse {
+			if c.Message != "" {
+				return c.Message
+			}
+			return string(c.Reason)
+		}
+	}
+	return ""
+}
+
+func (o *Object) condition(types ...xpv1.ConditionType) (xpv1.Condition, bool) {
+	for _, t := range types {
+		if c := o.JSON.GetCondition(t); c.Status != corev1.ConditionUnknown || c.Reason != "" || c.Message != "" {
+			return c, true
+		}
+	}
+	return xpv1.Condition{}, false
+}
@@ -23,4 +23,6 @@ type TimeLine struct {
 	Scale time.Duration
 	// the time at the right of the timeline, or zero when following current time.
 	FixedTime time.Time
+	// the end of a replayed recording, or zero when live.
+	End time.Time
 }
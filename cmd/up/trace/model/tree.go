@@ -21,6 +21,7 @@ import (
 
 	"github.com/rivo/tview"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
@@ -44,6 +45,15 @@ func (t *Tree) Root() *tview.TreeNode {
 	return t.root
 }
 
+// Objects returns the top-level objects of the tree.
+func (t *Tree) Objects() []*Object {
+	objs := make([]*Object, 0, len(t.root.GetChildren()))
+	for _, n := range t.root.GetChildren() {
+		objs = append(objs, n.GetReference().(*Object))
+	}
+	return objs
+}
+
 func (t *Tree) Update(objs []queryv1alpha2.QueryResponseObject) {
 	t.update(t.root, objs, 0)
 }
@@ -135,12 +145,17 @@ func (t *Tree) update(parent *tview.TreeNode, respObjs []queryv1alpha2.QueryResp
 			var ev Event
 
 			ev.Message, _, _ = unstructured.NestedString(respEv.Object.Object, "message")
+			ev.Reason, _, _ = unstructured.NestedString(respEv.Object.Object, "reason")
 			ev.Type, _, _ = unstructured.NestedString(respEv.Object.Object, "type")
 			count, _, _ := unstructured.NestedInt64(respEv.Object.Object, "count")
 			ev.Count = int(count)
 			ts, _, _ := unstructured.NestedString(respEv.Object.Object, "lastTimestamp")
-			if err := ev.LastTimestamp.Unmarshal([]byte(ts)); err != nil {
-				continue // ignore this event
+			if ts != "" {
+				lastTimestamp, err := time.Parse(time.RFC3339, ts)
+				if err != nil {
+					continue // ignore this event
+				}
+				ev.LastTimestamp = metav1.NewTime(lastTimestamp)
 			}
 
 			obj.Events = append(obj.Events, ev)
@@ -0,0 +1,259 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/cli-runtime/pkg/printers"
+	"sigs.k8s.io/yaml"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+
+	queryv1alpha2 "github.com/upbound/up-sdk-go/apis/query/v1alpha2"
+	"github.com/upbound/up/cmd/up/query"
+	"github.com/upbound/up/cmd/up/trace/model"
+)
+
+const (
+	outputTree = "tree"
+	outputJSON = "json"
+	outputYAML = "yaml"
+
+	// waitInterval is the interval at which the objects are polled when
+	// waiting for them to become ready.
+	waitInterval = time.Second
+
+	errFmtUnhealthy = "timed out after %s waiting for %d of %d objects to become ready"
+)
+
+// pollFunc queries the objects to trace along with their composed resources.
+type pollFunc func(gkns query.GroupKindNames, cns query.CategoryNames) ([]queryv1alpha2.QueryResponseObject, error)
+
+// traceList is the structured output of a trace.
+type traceList struct {
+	Items []traceObject `json:"items"`
+}
+
+// traceObject is an object of a trace along with the resources it composes.
+type traceObject struct {
+	Group        string            `json:"group,omitempty"`
+	Kind         string            `json:"kind"`
+	ControlPlane traceControlPlane `json:"controlPlane"`
+	Namespace    string            `json:"namespace,omitempty"`
+	Name         string            `json:"name"`
+	Deleting     bool              `json:"deleting,omitempty"`
+	Synced       *xpv1.Condition   `json:"synced,omitempty"`
+	Ready        *xpv1.Condition   `json:"ready,omitempty"`
+	Children     []traceObject     `json:"children,omitempty"`
+}
+
+type traceControlPlane struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// print polls the traced objects once, or until they are ready if waiting
+// was requested, and renders them to the supplied writer.
+func (c *Cmd) print(ctx context.Context, w io.Writer, gkns query.GroupKindNames, cns query.CategoryNames, poll pollFunc) error {
+	if !c.WaitReady {
+		resp, err := poll(gkns, cns)
+		if err != nil {
+			return err
+		}
+		return render(w, c.Output, objects(resp))
+	}
+
+	var objs []*model.Object
+	for {
+		resp, err := poll(gkns, cns)
+		switch {
+		case err == nil:
+			objs = objects(resp)
+			if len(objs) > 0 && healthy(objs) {
+				return render(w, c.Output, objs)
+			}
+		case ctx.Err() == nil:
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			if err := render(w, c.Output, objs); err != nil {
+				return err
+			}
+			unhealthy, total := count(objs)
+			if total == 0 {
+				return errors.Errorf("timed out after %s waiting for objects to appear", c.Timeout)
+			}
+			return errors.Errorf(errFmtUnhealthy, c.Timeout, unhealthy, total)
+		case <-time.After(waitInterval):
+		}
+	}
+}
+
+// objects builds the object hierarchy of the supplied query response.
+func objects(resp []queryv1alpha2.QueryResponseObject) []*model.Object {
+	t := model.NewTree()
+	t.Update(resp)
+	return t.Objects()
+}
+
+// healthy returns true if all supplied objects and their children are
+// healthy.
+func healthy(objs []*model.Object) bool {
+	unhealthy, _ := count(objs)
+	return unhealthy == 0
+}
+
+// count returns the number of unhealthy objects and the total number of
+// objects in the supplied hierarchy.
+func count(objs []*model.Object) (unhealthy, total int) {
+	for _, o := range objs {
+		if !o.Healthy() {
+			unhealthy++
+		}
+		u, t := count(o.Children)
+		unhealthy += u
+		total += t + 1
+	}
+	return unhealthy, total
+}
+
+// render prints the supplied objects as a tree, as JSON or as YAML.
+func render(w io.Writer, format string, objs []*model.Object) error {
+	switch format {
+	case outputJSON:
+		bs, err := json.Marshal(traceObjects(objs))
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal trace")
+		}
+		_, err = fmt.Fprintln(w, string(bs))
+		return err
+	case outputYAML:
+		bs, err := yaml.Marshal(traceObjects(objs))
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal trace")
+		}
+		_, err = w.Write(bs)
+		return err
+	default:
+		tw := printers.GetNewTabWriter(w)
+		if _, err := fmt.Fprintln(tw, "NAME\tCONTROLPLANE\tSYNCED\tREADY\tSTATUS"); err != nil {
+			return err
+		}
+		for i, o := range objs {
+			if err := renderTree(tw, o, "", i == len(objs)-1, true); err != nil {
+				return err
+			}
+		}
+		return tw.Flush()
+	}
+}
+
+func renderTree(w io.Writer, o *model.Object, indent string, last, root bool) error {
+	prefix, childIndent := "", ""
+	if !root {
+		prefix, childIndent = indent+"├─ ", indent+"│  "
+		if last {
+			prefix, childIndent = indent+"└─ ", indent+"   "
+		}
+	}
+
+	status := o.FailureMessage()
+	if !o.DeletionTimestamp.IsZero() {
+		status = strings.TrimSuffix("Deleting: "+status, ": ")
+	}
+	cells := []string{
+		prefix + objectName(o),
+		o.ControlPlane.Namespace + "/" + o.ControlPlane.Name,
+		conditionStatus(o.SyncedCondition()),
+		conditionStatus(o.ReadyCondition()),
+		firstLine(status),
+	}
+	if _, err := fmt.Fprintln(w, strings.Join(cells, "\t")); err != nil {
+		return err
+	}
+
+	for i, child := range o.Children {
+		if err := renderTree(w, child, childIndent, i == len(o.Children)-1, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// objectName returns the name of the object in the form
+// Kind.group/name (namespace).
+func objectName(o *model.Object) string {
+	kind := o.Kind
+	if o.Group != "" {
+		kind += "." + o.Group
+	}
+	if o.Namespace == "" {
+		return kind + "/" + o.Name
+	}
+	return fmt.Sprintf("%s/%s (%s)", kind, o.Name, o.Namespace)
+}
+
+func conditionStatus(c xpv1.Condition, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return string(c.Status)
+}
+
+func firstLine(s string) string {
+	l, _, _ := strings.Cut(s, "\n")
+	return l
+}
+
+func traceObjects(objs []*model.Object) traceList {
+	l := traceList{Items: make([]traceObject, 0, len(objs))}
+	for _, o := range objs {
+		l.Items = append(l.Items, traceObjectOf(o))
+	}
+	return l
+}
+
+func traceObjectOf(o *model.Object) traceObject {
+	to := traceObject{
+		Group: o.Group,
+		Kind:  o.Kind,
+		ControlPlane: traceControlPlane{
+			Namespace: o.ControlPlane.Namespace,
+			Name:      o.ControlPlane.Name,
+		},
+		Namespace: o.Namespace,
+		Name:      o.Name,
+		Deleting:  !o.DeletionTimestamp.IsZero(),
+	}
+	if c, ok := o.SyncedCondition(); ok {
+		to.Synced = &c
+	}
+	if c, ok := o.ReadyCondition(); ok {
+		to.Ready = &c
+	}
+	for _, child := range o.Children {
+		to.Children = append(to.Children, traceObjectOf(child))
+	}
+	return to
+}
@@ -0,0 +1,214 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/upbound/up-sdk-go/apis/common"
+	queryv1alpha2 "github.com/upbound/up-sdk-go/apis/query/v1alpha2"
+	"github.com/upbound/up/cmd/up/query"
+)
+
+func respObject(id, apiVersion, kind, ns, name string, conds map[string]string, children ...queryv1alpha2.QueryResponseObject) queryv1alpha2.QueryResponseObject {
+	cs := make([]interface{}, 0, 2)
+	for _, t := range []string{"Synced", "Ready"} {
+		st, ok := conds[t]
+		if !ok {
+			continue
+		}
+		c := map[string]interface{}{"type": t, "status": st, "reason": "Testing"}
+		if st == "False" {
+			c["message"] = t + " is false\nsecond line"
+		}
+		cs = append(cs, c)
+	}
+	md := map[string]interface{}{
+		"name":              name,
+		"creationTimestamp": "2024-05-01T12:00:00Z",
+	}
+	if ns != "" {
+		md["namespace"] = ns
+	}
+	o := queryv1alpha2.QueryResponseObject{
+		ID:           id,
+		ControlPlane: &queryv1alpha2.QueryResponseControlPlane{Namespace: "default", Name: "ctp"},
+		Object: &common.JSONObject{Object: map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       kind,
+			"metadata":   md,
+			"status":     map[string]interface{}{"conditions": cs},
+		}},
+	}
+	if len(children) > 0 {
+		o.Relations = map[string]queryv1alpha2.QueryResponseRelation{
+			"resources": {QueryResponseObjects: queryv1alpha2.QueryResponseObjects{Objects: children}},
+		}
+	}
+	return o
+}
+
+var (
+	healthyClaim = respObject("1", "acme.io/v1", "Cluster", "team", "prod", map[string]string{"Synced": "True", "Ready": "True"},
+		respObject("2", "acme.io/v1", "XCluster", "", "prod-x", map[string]string{"Synced": "True", "Ready": "True"},
+			respObject("3", "ec2.aws.upbound.io/v1beta1", "VPC", "", "prod-vpc", map[string]string{"Synced": "True", "Ready": "True"}),
+		),
+	)
+	failingClaim = respObject("1", "acme.io/v1", "Cluster", "team", "prod", map[string]string{"Synced": "True", "Ready": "False"},
+		respObject("2", "acme.io/v1", "XCluster", "", "prod-x", map[string]string{"Synced": "True", "Ready": "False"},
+			respObject("3", "ec2.aws.upbound.io/v1beta1", "VPC", "", "prod-vpc", map[string]string{"Synced": "False", "Ready": "True"}),
+			respObject("4", "v1", "Secret", "crossplane-system", "creds", nil),
+		),
+	)
+	unreconciledClaim = withSpec(respObject("1", "acme.io/v1", "Cluster", "team", "prod", nil,
+		respObject("4", "v1", "Secret", "crossplane-system", "creds", nil),
+		respObject("5", "cert-manager.io/v1", "Certificate", "team", "prod-tls", nil),
+	), map[string]interface{}{"resourceRef": map[string]interface{}{"name": "prod-x"}})
+	healthyComposite = withSpec(respObject("2", "acme.io/v1", "XCluster", "", "prod-x", map[string]string{"Synced": "True", "Ready": "True"},
+		respObject("5", "cert-manager.io/v1", "Certificate", "team", "prod-tls", nil),
+	), map[string]interface{}{"compositionRef": map[string]interface{}{"name": "clusters"}})
+)
+
+func withSpec(o queryv1alpha2.QueryResponseObject, spec map[string]interface{}) queryv1alpha2.QueryResponseObject {
+	o.Object.Object["spec"] = spec
+	return o
+}
+
+func TestRender(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		format string
+		resp   []queryv1alpha2.QueryResponseObject
+		want   string
+	}{
+		"Tree": {
+			reason: "Should render the object hierarchy with the first line of the failure message.",
+			format: outputTree,
+			resp:   []queryv1alpha2.QueryResponseObject{failingClaim},
+			want: "" +
+				"NAME                                     CONTROLPLANE   SYNCED   READY   STATUS\n" +
+				"Cluster.acme.io/prod (team)              default/ctp    True     False   Ready is false\n" +
+				"└─ XCluster.acme.io/prod-x               default/ctp    True     False   Ready is false\n" +
+				"   ├─ VPC.ec2.aws.upbound.io/prod-vpc    default/ctp    False    True    Synced is false\n" +
+				"   └─ Secret/creds (crossplane-system)   default/ctp    -        -       \n",
+		},
+		"JSON": {
+			reason: "Should render the object hierarchy as a JSON document.",
+			format: outputJSON,
+			resp: []queryv1alpha2.QueryResponseObject{
+				respObject("1", "ec2.aws.upbound.io/v1beta1", "VPC", "", "prod-vpc", map[string]string{"Synced": "False"}),
+			},
+			want: `{"items":[{"group":"ec2.aws.upbound.io","kind":"VPC","controlPlane":{"namespace":"default","name":"ctp"},"name":"prod-vpc","synced":{"type":"Synced","status":"False","lastTransitionTime":null,"reason":"Testing","message":"Synced is false\nsecond line"}}]}` + "\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := render(&buf, tc.format, objects(tc.resp)); err != nil {
+				t.Fatalf("\n%s\nrender(...): unexpected error: %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, buf.String()); diff != "" {
+				t.Errorf("\n%s\nrender(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPrint(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	type want struct {
+		polls int
+		err   error
+	}
+
+	cases := map[string]struct {
+		reason string
+		cmd    *Cmd
+		resps  [][]queryv1alpha2.QueryResponseObject
+		err    error
+		want   want
+	}{
+		"Once": {
+			reason: "Should poll once and not fail on unhealthy objects if not waiting.",
+			cmd:    &Cmd{Output: outputYAML},
+			resps:  [][]queryv1alpha2.QueryResponseObject{{failingClaim}},
+			want:   want{polls: 1},
+		},
+		"PollError": {
+			reason: "Should return poll errors.",
+			cmd:    &Cmd{Output: outputTree, WaitReady: true, Timeout: time.Minute},
+			err:    errBoom,
+			want:   want{polls: 1, err: errBoom},
+		},
+		"Ready": {
+			reason: "Should return once all objects are healthy.",
+			cmd:    &Cmd{Output: outputTree, WaitReady: true, Timeout: time.Minute},
+			resps:  [][]queryv1alpha2.QueryResponseObject{{healthyClaim}},
+			want:   want{polls: 1},
+		},
+		"TimedOut": {
+			reason: "Should fail with the number of unhealthy objects if they do not become ready in time.",
+			cmd:    &Cmd{Output: outputTree, WaitReady: true, Timeout: 10 * time.Millisecond},
+			resps:  [][]queryv1alpha2.QueryResponseObject{{failingClaim}},
+			want:   want{polls: 1, err: errors.Errorf(errFmtUnhealthy, 10*time.Millisecond, 3, 4)},
+		},
+		"Unreconciled": {
+			reason: "Should not consider claims without conditions healthy, unlike other kinds without conditions.",
+			cmd:    &Cmd{Output: outputTree, WaitReady: true, Timeout: 10 * time.Millisecond},
+			resps:  [][]queryv1alpha2.QueryResponseObject{{unreconciledClaim}},
+			want:   want{polls: 1, err: errors.Errorf(errFmtUnhealthy, 10*time.Millisecond, 1, 3)},
+		},
+		"ComposedWithoutConditions": {
+			reason: "Should consider composed resources that are no Crossplane resources healthy without conditions.",
+			cmd:    &Cmd{Output: outputTree, WaitReady: true, Timeout: time.Minute},
+			resps:  [][]queryv1alpha2.QueryResponseObject{{healthyComposite}},
+			want:   want{polls: 1},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tc.cmd.Timeout)
+			defer cancel()
+
+			polls := 0
+			poll := func(_ query.GroupKindNames, _ query.CategoryNames) ([]queryv1alpha2.QueryResponseObject, error) {
+				defer func() { polls++ }()
+				if tc.err != nil {
+					return nil, tc.err
+				}
+				return tc.resps[min(polls, len(tc.resps)-1)], nil
+			}
+
+			err := tc.cmd.print(ctx, &bytes.Buffer{}, nil, nil, poll)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nprint(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.polls, polls); diff != "" {
+				t.Errorf("\n%s\nprint(...): -want polls, +got polls:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+
+	queryv1alpha2 "github.com/upbound/up-sdk-go/apis/query/v1alpha2"
+	"github.com/upbound/up/cmd/up/query"
+	"github.com/upbound/up/internal/upbound"
+)
+
+const (
+	// recordingVersion is the version of the recording format.
+	recordingVersion = "v1alpha1"
+
+	// maxSnapshotSize is the maximum size of a single recorded snapshot.
+	maxSnapshotSize = 256 << 20
+
+	errWriteSnapshot  = "failed to record snapshot"
+	errReadRecording  = "failed to read recording"
+	errEmptyRecording = "recording does not contain any snapshots"
+	errFmtBadVersion  = "unsupported recording version %q in line %d"
+	errFmtBadSnapshot = "failed to parse snapshot in line %d"
+)
+
+// snapshot is the result of a single poll of a trace session. A recording
+// is a sequence of snapshots, one JSON document per line.
+type snapshot struct {
+	Version string                              `json:"version"`
+	Time    time.Time                           `json:"time"`
+	Objects []queryv1alpha2.QueryResponseObject `json:"objects"`
+}
+
+// recordPoll returns a poll function that writes every successful poll of the
+// supplied function as a snapshot to the supplied writer.
+func recordPoll(w io.Writer, poll pollFunc) pollFunc {
+	enc := json.NewEncoder(w)
+	return func(gkns query.GroupKindNames, cns query.CategoryNames) ([]queryv1alpha2.QueryResponseObject, error) {
+		objs, err := poll(gkns, cns)
+		if err != nil {
+			return nil, err
+		}
+		if err := enc.Encode(snapshot{Version: recordingVersion, Time: time.Now(), Objects: objs}); err != nil {
+			return nil, errors.Wrap(err, errWriteSnapshot)
+		}
+		return objs, nil
+	}
+}
+
+// readRecording reads the snapshots of a recording in the order they were
+// recorded.
+func readRecording(r io.Reader) ([]snapshot, error) {
+	var snaps []snapshot
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxSnapshotSize)
+	for line := 1; s.Scan(); line++ {
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		var snap snapshot
+		if err := json.Unmarshal(s.Bytes(), &snap); err != nil {
+			return nil, errors.Wrapf(err, errFmtBadSnapshot, line)
+		}
+		if snap.Version != recordingVersion {
+			return nil, errors.Errorf(errFmtBadVersion, snap.Version, line)
+		}
+		snaps = append(snaps, snap)
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrap(err, errReadRecording)
+	}
+	if len(snaps) == 0 {
+		return nil, errors.New(errEmptyRecording)
+	}
+	return snaps, nil
+}
+
+// recordedObjects returns the last recorded state of every object of the
+// supplied snapshots, including composed resources, by ID.
+func recordedObjects(snaps []snapshot) map[string]*unstructured.Unstructured {
+	objs := map[string]*unstructured.Unstructured{}
+	var collect func(resp []queryv1alpha2.QueryResponseObject)
+	collect = func(resp []queryv1alpha2.QueryResponseObject) {
+		for _, o := range resp {
+			if o.Object != nil {
+				objs[o.ID] = &unstructured.Unstructured{Object: o.Object.Object}
+			}
+			collect(o.Relations["resources"].Objects)
+		}
+	}
+	for _, snap := range snaps {
+		collect(snap.Objects)
+	}
+	return objs
+}
+
+// replay shows a recorded session in the interactive views, or prints its
+// last snapshot if an output format is given.
+func (c *Cmd) replay(ctx context.Context, kongCtx *kong.Context, upCtx *upbound.Context) error {
+	f, err := os.Open(c.Replay)
+	if err != nil {
+		return errors.Wrap(err, errReadRecording)
+	}
+	defer f.Close() // nolint:errcheck // read-only
+	snaps, err := readRecording(f)
+	if err != nil {
+		return err
+	}
+
+	if c.Output != "" {
+		return render(kongCtx.Stdout, c.Output, objects(snaps[len(snaps)-1].Objects))
+	}
+
+	upCtx.HideLogging()
+	app := NewReplayApp(fmt.Sprintf("upbound trace replay of %s", filepath.Base(c.Replay)), nil, snaps)
+	return app.Run(ctx)
+}
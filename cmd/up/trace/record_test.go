@@ -0,0 +1,132 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	queryv1alpha2 "github.com/upbound/up-sdk-go/apis/query/v1alpha2"
+	"github.com/upbound/up/cmd/up/query"
+)
+
+func TestRecordReplay(t *testing.T) {
+	resps := [][]queryv1alpha2.QueryResponseObject{{failingClaim}, {healthyClaim}}
+	polls := 0
+	poll := func(_ query.GroupKindNames, _ query.CategoryNames) ([]queryv1alpha2.QueryResponseObject, error) {
+		defer func() { polls++ }()
+		return resps[polls], nil
+	}
+
+	var buf bytes.Buffer
+	record := recordPoll(&buf, poll)
+	for range resps {
+		if _, err := record(nil, nil); err != nil {
+			t.Fatalf("record(...): unexpected error: %s", err)
+		}
+	}
+
+	snaps, err := readRecording(&buf)
+	if err != nil {
+		t.Fatalf("readRecording(...): unexpected error: %s", err)
+	}
+	got := make([][]queryv1alpha2.QueryResponseObject, 0, len(snaps))
+	for _, s := range snaps {
+		got = append(got, s.Objects)
+	}
+	if diff := cmp.Diff(resps, got, cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("readRecording(...): -want, +got:\n%s", diff)
+	}
+	if snaps[1].Time.Before(snaps[0].Time) {
+		t.Errorf("readRecording(...): snapshots out of order: %s, %s", snaps[0].Time, snaps[1].Time)
+	}
+
+	app := NewReplayApp("replay", nil, snaps)
+	if diff := cmp.Diff(snaps[1].Time, app.model.TimeLine.End); diff != "" {
+		t.Errorf("NewReplayApp(...): -want end, +got end:\n%s", diff)
+	}
+	if diff := cmp.Diff(true, healthy(app.model.Tree.Objects())); diff != "" {
+		t.Errorf("NewReplayApp(...): -want healthy, +got healthy:\n%s", diff)
+	}
+	if _, err := app.fetchFn("4"); err != nil {
+		t.Errorf("NewReplayApp(...): fetch of an object of an earlier snapshot: unexpected error: %s", err)
+	}
+}
+
+func TestReadRecording(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		in     string
+		err    error
+	}{
+		"Empty": {
+			reason: "Should fail on a recording without snapshots.",
+			in:     "\n",
+			err:    errors.New(errEmptyRecording),
+		},
+		"BadVersion": {
+			reason: "Should fail on snapshots of an unknown version.",
+			in:     `{"version":"v1alpha1","time":"2024-05-01T12:00:00Z"}` + "\n" + `{"version":"v2","time":"2024-05-01T12:00:01Z"}`,
+			err:    errors.Errorf(errFmtBadVersion, "v2", 2),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := readRecording(strings.NewReader(tc.in))
+			if diff := cmp.Diff(tc.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nreadRecording(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCreateQuerySpecFullObjects(t *testing.T) {
+	cases := map[string]struct {
+		reason      string
+		fullObjects bool
+		want        bool
+	}{
+		"Skeleton": {
+			reason: "Should only query the fields shown in the tree.",
+		},
+		"FullObjects": {
+			reason:      "Should query complete objects and composed resources when recording, so that replays can show their YAML.",
+			fullObjects: true,
+			want:        true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			spec := createQuerySpec(types.NamespacedName{Name: "prod"}, metav1.GroupKind{}, []string{"claim"}, tc.fullObjects)
+			objs := spec.QueryTopLevelResources.QueryResources.Objects
+			composed := objs.Relations["resources+"].QueryResources.Objects
+			got := []bool{objs.Object.Object == true, composed.Object.Object == true}
+			if diff := cmp.Diff([]bool{tc.want, tc.want}, got); diff != "" {
+				t.Errorf("\n%s\ncreateQuerySpec(...): -want complete objects, +got complete objects:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
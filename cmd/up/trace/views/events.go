@@ -0,0 +1,142 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package views
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+
+	"github.com/upbound/up/cmd/up/trace/model"
+	"github.com/upbound/up/cmd/up/trace/style"
+)
+
+// Events shows the Kubernetes events of the focused object, newest first.
+// The events are part of every poll, so the view is always up to date.
+type Events struct {
+	*tview.Table
+	content *eventsContent
+}
+
+func NewEvents(scrolling Scrolling) *Events {
+	e := &Events{
+		Table: tview.NewTable(),
+		content: &eventsContent{
+			scrolling: scrolling,
+		},
+	}
+	e.Table.SetContent(e.content)
+
+	return e
+}
+
+type eventsContent struct {
+	scrolling Scrolling
+}
+
+func (t eventsContent) object() *model.Object {
+	cur := t.scrolling.GetCurrentNode()
+	if cur == nil {
+		return nil
+	}
+	o, _ := cur.GetReference().(*model.Object)
+	return o
+}
+
+func (t eventsContent) GetCell(row, column int) *tview.TableCell {
+	cell := &tview.TableCell{
+		Align:           tview.AlignLeft,
+		Color:           style.EventsNormal,
+		BackgroundColor: tcell.ColorDefault,
+		Transparent:     true,
+	}
+
+	o := t.object()
+	if o == nil {
+		return cell
+	}
+	if len(o.Events) == 0 {
+		if row == 0 && column == 0 {
+			cell.Text = "No events"
+			cell.Color = style.EventsEmpty
+		}
+		return cell
+	}
+	if row >= len(o.Events) {
+		return cell
+	}
+
+	// newest first
+	ev := o.Events[len(o.Events)-1-row]
+	switch column {
+	case 0:
+		cell.Align = tview.AlignRight
+		cell.Color = style.EventsAge
+		if !ev.LastTimestamp.IsZero() {
+			cell.Text = duration.HumanDuration(time.Since(ev.LastTimestamp.Time))
+		}
+	case 1:
+		cell.Text = ev.Type
+		if ev.Type == corev1.EventTypeWarning {
+			cell.Color = style.EventsWarning
+		}
+	case 2:
+		cell.Text = ev.Reason
+	case 3:
+		cell.Align = tview.AlignRight
+		cell.Color = style.EventsCount
+		if ev.Count > 1 {
+			cell.Text = fmt.Sprintf("x%d", ev.Count)
+		}
+	case 4:
+		cell.Text = strings.ReplaceAll(ev.Message, "\n", " ")
+	}
+	return cell
+}
+
+func (t eventsContent) GetRowCount() int {
+	o := t.object()
+	if o == nil {
+		return 0
+	}
+	return max(len(o.Events), 1)
+}
+
+func (t eventsContent) GetColumnCount() int {
+	return 5
+}
+
+func (t eventsContent) SetCell(row, column int, cell *tview.TableCell) {
+}
+
+func (t eventsContent) RemoveRow(row int) {
+}
+
+func (t eventsContent) RemoveColumn(column int) {
+}
+
+func (t eventsContent) InsertRow(row int) {
+}
+
+func (t eventsContent) InsertColumn(column int) {
+}
+
+func (t eventsContent) Clear() {
+}
@@ -0,0 +1,560 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package walker builds the object trees of up alpha trace by following the
+// references between Crossplane objects with a dynamic client, for clusters
+// that do not serve the Query API.
+package walker
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+
+	"github.com/upbound/up-sdk-go/apis/common"
+	queryv1alpha2 "github.com/upbound/up-sdk-go/apis/query/v1alpha2"
+	"github.com/upbound/up/cmd/up/query"
+)
+
+const (
+	// LabelComposite is the label Crossplane sets on composed resources to
+	// the name of their composite resource.
+	LabelComposite = "crossplane.io/composite"
+
+	relationEvents    = "events"
+	relationResources = "resources"
+
+	errAllCategory  = "tracing all resources is not supported without the Query API"
+	errFmtResolve   = "failed to resolve resource type %q"
+	errFmtList      = "failed to list %s"
+	errFmtGet       = "failed to get %s %s"
+	errListEvents   = "failed to list events"
+	errFmtNotTraced = "object %s is not part of the trace"
+)
+
+var eventsGVR = schema.GroupVersionResource{Version: "v1", Resource: "events"}
+
+// composedCategories are the categories of the kinds that may be composed by
+// a composite resource.
+var composedCategories = []string{"managed", "composite"}
+
+// A Walker polls objects and the resources they compose using a dynamic
+// client. Claims are followed by spec.resourceRef, composite resources by
+// spec.resourceRefs and by the crossplane.io/composite label of composed
+// resources of the managed and composite categories.
+type Walker struct {
+	client       dynamic.Interface
+	mapper       meta.RESTMapper
+	categories   restmapper.CategoryExpander
+	namespace    string
+	controlPlane queryv1alpha2.QueryResponseControlPlane
+
+	mu   sync.Mutex
+	refs map[string]ref
+}
+
+// ref locates an object of the last poll.
+type ref struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+	name      string
+}
+
+// Option configures a Walker.
+type Option func(w *Walker)
+
+// WithNamespace restricts the top-level objects to the supplied namespace.
+func WithNamespace(ns string) Option {
+	return func(w *Walker) {
+		w.namespace = ns
+	}
+}
+
+// WithControlPlane sets the control plane reported for all objects.
+func WithControlPlane(namespace, name string) Option {
+	return func(w *Walker) {
+		w.controlPlane = queryv1alpha2.QueryResponseControlPlane{Namespace: namespace, Name: name}
+	}
+}
+
+// WithCategoryExpander sets the expander used to resolve categories like
+// managed or claim.
+func WithCategoryExpander(ce restmapper.CategoryExpander) Option {
+	return func(w *Walker) {
+		w.categories = ce
+	}
+}
+
+// New returns a new Walker.
+func New(c dynamic.Interface, m meta.RESTMapper, opts ...Option) *Walker {
+	w := &Walker{
+		client:     c,
+		mapper:     m,
+		categories: restmapper.SimpleCategoryExpander{},
+		refs:       map[string]ref{},
+	}
+	for _, o := range opts {
+		o(w)
+	}
+	return w
+}
+
+// Poll returns the objects of the supplied kinds and categories along with
+// their events and the resources they compose, in the shape returned by the
+// Query API.
+func (w *Walker) Poll(ctx context.Context, gkns query.GroupKindNames, cns query.CategoryNames) ([]queryv1alpha2.QueryResponseObject, error) {
+	p := &poll{
+		Walker:     w,
+		seen:       map[types.UID]bool{},
+		refs:       map[string]ref{},
+		relations:  map[types.UID]map[string]queryv1alpha2.QueryResponseRelation{},
+		namespaces: map[string]bool{},
+		composed:   map[string][]child{},
+		listed:     map[schema.GroupResource]bool{},
+	}
+	var objs []queryv1alpha2.QueryResponseObject
+	for gk, names := range gkns {
+		gvrs, err := w.resolveKind(gk)
+		if err != nil {
+			return nil, err
+		}
+		for _, gvr := range gvrs {
+			roots, err := p.roots(ctx, gvr, names)
+			if err != nil {
+				return nil, err
+			}
+			objs = append(objs, roots...)
+		}
+	}
+	for cat, names := range cns {
+		gvrs, err := w.resolveCategory(cat)
+		if err != nil {
+			return nil, err
+		}
+		for _, gvr := range gvrs {
+			roots, err := p.roots(ctx, gvr, names)
+			if err != nil {
+				return nil, err
+			}
+			objs = append(objs, roots...)
+		}
+	}
+	if err := p.events(ctx); err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	w.refs = p.refs
+	w.mu.Unlock()
+
+	return objs, nil
+}
+
+// Fetch returns the complete object of the supplied ID of the last poll.
+func (w *Walker) Fetch(ctx context.Context, id string) (*unstructured.Unstructured, error) {
+	w.mu.Lock()
+	r, ok := w.refs[id]
+	w.mu.Unlock()
+	if !ok {
+		return nil, errors.Errorf(errFmtNotTraced, id)
+	}
+	u, err := w.client.Resource(r.gvr).Namespace(r.namespace).Get(ctx, r.name, metav1.GetOptions{})
+	return u, errors.Wrapf(err, errFmtGet, r.gvr.GroupResource(), r.name)
+}
+
+// resolveKind returns the resources of the supplied kind. As the Query API,
+// kinds may also be given as resource names.
+func (w *Walker) resolveKind(gk metav1.GroupKind) ([]schema.GroupVersionResource, error) {
+	if ms, err := w.mapper.RESTMappings(schema.GroupKind{Group: gk.Group, Kind: gk.Kind}); err == nil && len(ms) > 0 {
+		return []schema.GroupVersionResource{ms[0].Resource}, nil
+	}
+	return w.resolveResource(gk.Group, gk.Kind)
+}
+
+// resolveCategory returns the resources of the supplied category. As the
+// Query API, categories also include the plural, singular and short names of
+// custom resources.
+func (w *Walker) resolveCategory(cat string) ([]schema.GroupVersionResource, error) {
+	if cat == query.AllCategory {
+		return nil, errors.New(errAllCategory)
+	}
+	grs, ok := w.categories.Expand(cat)
+	if !ok {
+		return w.resolveResource("", cat)
+	}
+	gvrs := make([]schema.GroupVersionResource, 0, len(grs))
+	for _, gr := range grs {
+		gvr, err := w.mapper.ResourceFor(gr.WithVersion(""))
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtResolve, cat)
+		}
+		gvrs = append(gvrs, gvr)
+	}
+	return gvrs, nil
+}
+
+// namespaced returns false if the supplied resource is cluster scoped.
+// Resources of unknown scope are assumed to be namespaced.
+func (w *Walker) namespaced(gvr schema.GroupVersionResource) bool {
+	gvk, err := w.mapper.KindFor(gvr)
+	if err != nil {
+		return true
+	}
+	m, err := w.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return true
+	}
+	return m.Scope.Name() != meta.RESTScopeNameRoot
+}
+
+func (w *Walker) resolveResource(group, resource string) ([]schema.GroupVersionResource, error) {
+	gvrs, err := w.mapper.ResourcesFor(schema.GroupVersionResource{Group: group, Resource: strings.ToLower(resource)})
+	if err != nil {
+		return nil, errors.Wrapf(err, errFmtResolve, resource)
+	}
+	// one version per resource is enough, the first is the preferred one.
+	seen := map[schema.GroupResource]bool{}
+	res := make([]schema.GroupVersionResource, 0, len(gvrs))
+	for _, gvr := range gvrs {
+		if seen[gvr.GroupResource()] {
+			continue
+		}
+		seen[gvr.GroupResource()] = true
+		res = append(res, gvr)
+	}
+	return res, nil
+}
+
+// poll is the state of a single poll.
+type poll struct {
+	*Walker
+
+	seen map[types.UID]bool
+	refs map[string]ref
+
+	// relations are the relations of the polled objects, events are added
+	// once all objects are known. namespaces are the namespaces their events
+	// are in.
+	relations  map[types.UID]map[string]queryv1alpha2.QueryResponseRelation
+	namespaces map[string]bool
+
+	// composed are the resources carrying the composite label by its value.
+	// Each resource is listed at most once per poll, listed records which
+	// were.
+	composed map[string][]child
+	listed   map[schema.GroupResource]bool
+
+	// composedGVRs are the resources composed resources may be of. They are
+	// resolved once per poll.
+	composedGVRs     []schema.GroupVersionResource
+	composedResolved bool
+}
+
+// roots returns the top-level objects of the supplied resource.
+func (p *poll) roots(ctx context.Context, gvr schema.GroupVersionResource, names []string) ([]queryv1alpha2.QueryResponseObject, error) {
+	ns := p.namespace
+	if !p.namespaced(gvr) {
+		ns = ""
+	}
+	l, err := p.client.Resource(gvr).Namespace(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, errFmtList, gvr.GroupResource())
+	}
+	wanted := map[string]bool{}
+	for _, n := range names {
+		wanted[n] = true
+	}
+
+	var objs []queryv1alpha2.QueryResponseObject
+	for i := range l.Items {
+		u := &l.Items[i]
+		if len(wanted) > 0 && !wanted[u.GetName()] {
+			continue
+		}
+		if p.seen[u.GetUID()] {
+			continue
+		}
+		o, err := p.node(ctx, gvr, u)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, o)
+	}
+	return objs, nil
+}
+
+// node returns the supplied object with its events and the resources it
+// composes.
+func (p *poll) node(ctx context.Context, gvr schema.GroupVersionResource, u *unstructured.Unstructured) (queryv1alpha2.QueryResponseObject, error) {
+	p.seen[u.GetUID()] = true
+	p.refs[string(u.GetUID())] = ref{gvr: gvr, namespace: u.GetNamespace(), name: u.GetName()}
+	// events about cluster scoped objects are recorded in the default
+	// namespace.
+	if ns := u.GetNamespace(); ns != "" {
+		p.namespaces[ns] = true
+	} else {
+		p.namespaces[metav1.NamespaceDefault] = true
+	}
+
+	u = u.DeepCopy()
+	u.SetManagedFields(nil)
+	o := queryv1alpha2.QueryResponseObject{
+		ID:           string(u.GetUID()),
+		ControlPlane: p.controlPlane.DeepCopy(),
+		Object:       &common.JSONObject{Object: u.Object},
+		Relations: map[string]queryv1alpha2.QueryResponseRelation{
+			relationEvents: {},
+		},
+	}
+	p.relations[u.GetUID()] = o.Relations
+
+	children, err := p.children(ctx, u)
+	if err != nil {
+		return o, err
+	}
+	var resources []queryv1alpha2.QueryResponseObject
+	for _, c := range children {
+		if p.seen[c.obj.GetUID()] {
+			continue
+		}
+		child, err := p.node(ctx, c.gvr, c.obj)
+		if err != nil {
+			return o, err
+		}
+		resources = append(resources, child)
+	}
+	o.Relations[relationResources] = queryv1alpha2.QueryResponseRelation{QueryResponseObjects: queryv1alpha2.QueryResponseObjects{Objects: resources}}
+
+	return o, nil
+}
+
+type child struct {
+	gvr schema.GroupVersionResource
+	obj *unstructured.Unstructured
+}
+
+// children returns the objects referenced by spec.resourceRef and
+// spec.resourceRefs, and the objects of the referenced kinds and of the
+// managed and composite categories that carry the crossplane.io/composite
+// label of the supplied object.
+func (p *poll) children(ctx context.Context, u *unstructured.Unstructured) ([]child, error) { // nolint:gocyclo // a flat list of cases
+	var refs []map[string]interface{}
+	if r, ok, _ := unstructured.NestedMap(u.Object, "spec", "resourceRef"); ok {
+		refs = append(refs, r)
+	}
+	if rs, ok, _ := unstructured.NestedSlice(u.Object, "spec", "resourceRefs"); ok {
+		for _, r := range rs {
+			if r, ok := r.(map[string]interface{}); ok {
+				refs = append(refs, r)
+			}
+		}
+	}
+
+	var children []child
+	found := map[types.UID]bool{}
+	kinds := map[schema.GroupVersionKind]schema.GroupVersionResource{}
+	for _, r := range refs {
+		apiVersion, _ := r["apiVersion"].(string)
+		kind, _ := r["kind"].(string)
+		name, _ := r["name"].(string)
+		ns, _ := r["namespace"].(string)
+		gv, err := schema.ParseGroupVersion(apiVersion)
+		if err != nil || kind == "" || name == "" {
+			continue
+		}
+		gvk := gv.WithKind(kind)
+		m, err := p.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			continue // the CRD might not exist (yet)
+		}
+		kinds[gvk] = m.Resource
+		if m.Scope.Name() != meta.RESTScopeNameNamespace {
+			ns = ""
+		} else if ns == "" {
+			ns = u.GetNamespace()
+		}
+		obj, err := p.client.Resource(m.Resource).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			continue // not created yet or already deleted
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtGet, m.Resource.GroupResource(), name)
+		}
+		if found[obj.GetUID()] {
+			continue
+		}
+		found[obj.GetUID()] = true
+		children = append(children, child{gvr: m.Resource, obj: obj})
+	}
+
+	// composed resources not referenced yet, e.g. while they are being
+	// created. Only composites have composed resources.
+	if !isComposite(u) {
+		return children, nil
+	}
+	gvks := make([]schema.GroupVersionKind, 0, len(kinds))
+	for gvk := range kinds {
+		gvks = append(gvks, gvk)
+	}
+	sort.Slice(gvks, func(i, j int) bool { return gvks[i].String() < gvks[j].String() })
+	for _, gvk := range gvks {
+		if err := p.listComposed(ctx, kinds[gvk]); err != nil {
+			return nil, err
+		}
+	}
+	for _, gvr := range p.composedResources() {
+		if err := p.listComposed(ctx, gvr); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range p.composed[u.GetName()] {
+		if found[c.obj.GetUID()] {
+			continue
+		}
+		found[c.obj.GetUID()] = true
+		children = append(children, c)
+	}
+	return children, nil
+}
+
+// listComposed lists the objects of the supplied resource that carry the
+// crossplane.io/composite label, unless it was listed before in this poll,
+// and indexes them by the label value.
+func (p *poll) listComposed(ctx context.Context, gvr schema.GroupVersionResource) error {
+	if p.listed[gvr.GroupResource()] {
+		return nil
+	}
+	p.listed[gvr.GroupResource()] = true
+	l, err := p.client.Resource(gvr).List(ctx, metav1.ListOptions{LabelSelector: LabelComposite})
+	if kerrors.IsNotFound(err) {
+		return nil // the CRD was deleted
+	}
+	if err != nil {
+		return errors.Wrapf(err, errFmtList, gvr.GroupResource())
+	}
+	for i := range l.Items {
+		obj := &l.Items[i]
+		xr := obj.GetLabels()[LabelComposite]
+		p.composed[xr] = append(p.composed[xr], child{gvr: gvr, obj: obj})
+	}
+	return nil
+}
+
+// events adds the events about the polled objects to their relations. Only
+// the namespaces of the polled objects are listed.
+func (p *poll) events(ctx context.Context) error {
+	nss := make([]string, 0, len(p.namespaces))
+	for ns := range p.namespaces {
+		nss = append(nss, ns)
+	}
+	sort.Strings(nss)
+	for _, ns := range nss {
+		l, err := p.client.Resource(eventsGVR).Namespace(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return errors.Wrap(err, errListEvents)
+		}
+		for i := range l.Items {
+			ev := &l.Items[i]
+			uid, _, _ := unstructured.NestedString(ev.Object, "involvedObject", "uid")
+			rels, ok := p.relations[types.UID(uid)]
+			if !ok {
+				continue
+			}
+			rel := rels[relationEvents]
+			rel.Objects = append(rel.Objects, eventObject(ev))
+			rels[relationEvents] = rel
+		}
+	}
+	return nil
+}
+
+// eventObject returns the fields of the supplied event that are shown in a
+// trace.
+func eventObject(ev *unstructured.Unstructured) queryv1alpha2.QueryResponseObject {
+	obj := map[string]interface{}{}
+	for _, f := range []string{"lastTimestamp", "message", "reason", "count", "type"} {
+		if v, ok := ev.Object[f]; ok {
+			obj[f] = v
+		}
+	}
+	if obj["lastTimestamp"] == nil {
+		// events of the events.k8s.io API only have an event time.
+		if t, ok := ev.Object["eventTime"].(string); ok {
+			if ts, err := time.Parse(metav1.RFC3339Micro, t); err == nil {
+				obj["lastTimestamp"] = ts.UTC().Format(time.RFC3339)
+			}
+		}
+	}
+	return queryv1alpha2.QueryResponseObject{
+		ID:     string(ev.GetUID()),
+		Object: &common.JSONObject{Object: obj},
+	}
+}
+
+// composedResources returns the resources of the managed and composite
+// categories. Categories or resources that cannot be resolved are skipped, as
+// the referenced kinds are listed regardless.
+func (p *poll) composedResources() []schema.GroupVersionResource {
+	if p.composedResolved {
+		return p.composedGVRs
+	}
+	p.composedResolved = true
+	seen := map[schema.GroupResource]bool{}
+	for _, cat := range composedCategories {
+		grs, ok := p.categories.Expand(cat)
+		if !ok {
+			continue
+		}
+		for _, gr := range grs {
+			if seen[gr] {
+				continue
+			}
+			seen[gr] = true
+			gvr, err := p.mapper.ResourceFor(gr.WithVersion(""))
+			if err != nil {
+				continue
+			}
+			p.composedGVRs = append(p.composedGVRs, gvr)
+		}
+	}
+	return p.composedGVRs
+}
+
+// isComposite returns true if the supplied object is a composite resource,
+// i.e. a cluster scoped object that references its composed resources or
+// its composition. The resource references are omitted while there are none.
+func isComposite(u *unstructured.Unstructured) bool {
+	if u.GetNamespace() != "" {
+		return false
+	}
+	for _, f := range []string{"resourceRefs", "compositionRef", "compositionSelector"} {
+		if _, ok, _ := unstructured.NestedFieldNoCopy(u.Object, "spec", f); ok {
+			return true
+		}
+	}
+	return false
+}
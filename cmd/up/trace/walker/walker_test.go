@@ -0,0 +1,252 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package walker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/restmapper"
+	cgotesting "k8s.io/client-go/testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	queryv1alpha2 "github.com/upbound/up-sdk-go/apis/query/v1alpha2"
+	"github.com/upbound/up/cmd/up/query"
+)
+
+var (
+	clusterGVK  = schema.GroupVersionKind{Group: "acme.io", Version: "v1", Kind: "Cluster"}
+	xclusterGVK = schema.GroupVersionKind{Group: "acme.io", Version: "v1", Kind: "XCluster"}
+	vpcGVK      = schema.GroupVersionKind{Group: "ec2.aws.upbound.io", Version: "v1beta1", Kind: "VPC"}
+	subnetGVK   = schema.GroupVersionKind{Group: "ec2.aws.upbound.io", Version: "v1beta1", Kind: "Subnet"}
+	eventGVK    = schema.GroupVersionKind{Version: "v1", Kind: "Event"}
+
+	clustersGVR = schema.GroupVersionResource{Group: "acme.io", Version: "v1", Resource: "clusters"}
+)
+
+func object(gvk schema.GroupVersionKind, ns, name string, spec map[string]interface{}, labels map[string]string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	u.SetGroupVersionKind(gvk)
+	u.SetNamespace(ns)
+	u.SetName(name)
+	u.SetUID(types.UID(name + "-uid"))
+	u.SetLabels(labels)
+	if spec != nil {
+		u.Object["spec"] = spec
+	}
+	return u
+}
+
+func objRef(gvk schema.GroupVersionKind, name string) map[string]interface{} {
+	return map[string]interface{}{"apiVersion": gvk.GroupVersion().String(), "kind": gvk.Kind, "name": name}
+}
+
+func event(ns, name, involvedUID, reason string) *unstructured.Unstructured {
+	u := object(eventGVK, ns, name, nil, nil)
+	u.Object["involvedObject"] = map[string]interface{}{"uid": involvedUID}
+	u.Object["reason"] = reason
+	u.Object["type"] = "Warning"
+	u.Object["lastTimestamp"] = "2024-05-01T12:00:00Z"
+	return u
+}
+
+func newWalker(t *testing.T, opts ...Option) (*Walker, *dynamicfake.FakeDynamicClient) {
+	t.Helper()
+
+	objs := []runtime.Object{
+		object(clusterGVK, "team", "prod", map[string]interface{}{"resourceRef": objRef(xclusterGVK, "prod-x")}, nil),
+		object(clusterGVK, "team", "staging", map[string]interface{}{"resourceRef": objRef(xclusterGVK, "missing")}, nil),
+		object(xclusterGVK, "", "prod-x", map[string]interface{}{"resourceRefs": []interface{}{objRef(vpcGVK, "prod-vpc")}}, nil),
+		object(vpcGVK, "", "prod-vpc", map[string]interface{}{}, map[string]string{LabelComposite: "prod-x"}),
+		object(vpcGVK, "", "prod-vpc-new", map[string]interface{}{}, map[string]string{LabelComposite: "prod-x"}),
+		object(vpcGVK, "", "other-vpc", map[string]interface{}{}, map[string]string{LabelComposite: "other-x"}),
+		object(subnetGVK, "", "prod-subnet", map[string]interface{}{}, map[string]string{LabelComposite: "prod-x"}),
+		object(clusterGVK, "team", "dev", map[string]interface{}{"resourceRef": objRef(xclusterGVK, "dev-x")}, nil),
+		object(xclusterGVK, "", "dev-x", map[string]interface{}{"compositionRef": map[string]interface{}{"name": "clusters"}}, nil),
+		object(subnetGVK, "", "dev-subnet", map[string]interface{}{}, map[string]string{LabelComposite: "dev-x"}),
+		event("default", "prod-vpc.1", "prod-vpc-uid", "CannotCreateExternalResource"),
+		event("team", "prod.1", "prod-uid", "ConfigureCompositeResource"),
+	}
+	listKinds := map[schema.GroupVersionResource]string{
+		clustersGVR: "ClusterList",
+		{Group: "acme.io", Version: "v1", Resource: "xclusters"}:               "XClusterList",
+		{Group: "ec2.aws.upbound.io", Version: "v1beta1", Resource: "vpcs"}:    "VPCList",
+		{Group: "ec2.aws.upbound.io", Version: "v1beta1", Resource: "subnets"}: "SubnetList",
+		eventsGVR: "EventList",
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objs...)
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(clusterGVK, meta.RESTScopeNamespace)
+	mapper.Add(xclusterGVK, meta.RESTScopeRoot)
+	mapper.Add(vpcGVK, meta.RESTScopeRoot)
+	mapper.Add(subnetGVK, meta.RESTScopeRoot)
+	mapper.Add(eventGVK, meta.RESTScopeNamespace)
+
+	opts = append([]Option{
+		WithControlPlane("", "kind-kind"),
+		WithCategoryExpander(restmapper.SimpleCategoryExpander{Expansions: map[string][]schema.GroupResource{
+			"claim":   {{Group: "acme.io", Resource: "clusters"}},
+			"managed": {{Group: "ec2.aws.upbound.io", Resource: "vpcs"}, {Group: "ec2.aws.upbound.io", Resource: "subnets"}},
+		}}),
+	}, opts...)
+	return New(client, mapper, opts...), client
+}
+
+// tree renders the supplied objects as indented lines of kind, name and
+// event reasons.
+func tree(objs []queryv1alpha2.QueryResponseObject, indent string) []string {
+	var lines []string
+	for _, o := range objs {
+		u := &unstructured.Unstructured{Object: o.Object.Object}
+		line := fmt.Sprintf("%s%s/%s", indent, u.GetKind(), u.GetName())
+		for _, ev := range o.Relations[relationEvents].Objects {
+			line += fmt.Sprintf(" [%s]", ev.Object.Object["reason"])
+		}
+		if o.ControlPlane == nil || o.ControlPlane.Name != "kind-kind" {
+			line += " (wrong control plane)"
+		}
+		lines = append(lines, line)
+		lines = append(lines, tree(o.Relations[relationResources].Objects, indent+"  ")...)
+	}
+	return lines
+}
+
+func TestPoll(t *testing.T) {
+	type want struct {
+		tree []string
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		opts   []Option
+		gkns   query.GroupKindNames
+		cns    query.CategoryNames
+		want   want
+	}{
+		"Category": {
+			reason: "Should follow resourceRef, resourceRefs and composite labels from every object of a category.",
+			cns:    query.CategoryNames{"claim": {"prod"}},
+			want: want{tree: []string{
+				"Cluster/prod [ConfigureCompositeResource]",
+				"  XCluster/prod-x",
+				"    VPC/prod-vpc [CannotCreateExternalResource]",
+				"    VPC/prod-vpc-new",
+				"    Subnet/prod-subnet",
+			}},
+		},
+		"ClusterScopedInNamespace": {
+			reason: "Should not restrict cluster scoped top-level objects to the namespace.",
+			opts:   []Option{WithNamespace("team")},
+			gkns:   query.GroupKindNames{{Group: "acme.io", Kind: "XCluster"}: {"prod-x"}},
+			want: want{tree: []string{
+				"XCluster/prod-x",
+				"  VPC/prod-vpc [CannotCreateExternalResource]",
+				"  VPC/prod-vpc-new",
+				"  Subnet/prod-subnet",
+			}},
+		},
+		"UnreferencedKinds": {
+			reason: "Should find composed resources of managed kinds that a composite without resourceRefs does not reference yet.",
+			cns:    query.CategoryNames{"claim": {"dev"}},
+			want: want{tree: []string{
+				"Cluster/dev",
+				"  XCluster/dev-x",
+				"    Subnet/dev-subnet",
+			}},
+		},
+		"ResourceName": {
+			reason: "Should resolve resource names and skip references to missing objects.",
+			gkns:   query.GroupKindNames{{Group: "acme.io", Kind: "clusters"}: {"staging"}},
+			want: want{tree: []string{
+				"Cluster/staging",
+			}},
+		},
+		"AllCategory": {
+			reason: "Should refuse to trace all resources.",
+			cns:    query.CategoryNames{query.AllCategory: nil},
+			want:   want{err: errors.New(errAllCategory)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			w, _ := newWalker(t, tc.opts...)
+			objs, err := w.Poll(context.Background(), tc.gkns, tc.cns)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nPoll(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(strings.Join(tc.want.tree, "\n"), strings.Join(tree(objs, ""), "\n")); diff != "" {
+				t.Errorf("\n%s\nPoll(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	w, _ := newWalker(t)
+	if _, err := w.Poll(context.Background(), nil, query.CategoryNames{"claim": {"prod"}}); err != nil {
+		t.Fatalf("Poll(...): unexpected error: %s", err)
+	}
+
+	u, err := w.Fetch(context.Background(), "prod-vpc-uid")
+	if err != nil {
+		t.Fatalf("Fetch(...): unexpected error: %s", err)
+	}
+	if diff := cmp.Diff("prod-vpc", u.GetName()); diff != "" {
+		t.Errorf("Fetch(...): -want name, +got name:\n%s", diff)
+	}
+
+	_, err = w.Fetch(context.Background(), "other-vpc-uid")
+	if diff := cmp.Diff(errors.Errorf(errFmtNotTraced, "other-vpc-uid"), err, test.EquateErrors()); diff != "" {
+		t.Errorf("Fetch(...): -want err, +got err:\n%s", diff)
+	}
+}
+
+func TestPollLists(t *testing.T) {
+	w, client := newWalker(t)
+	if _, err := w.Poll(context.Background(), nil, query.CategoryNames{"claim": {"prod", "dev"}}); err != nil {
+		t.Fatalf("Poll(...): unexpected error: %s", err)
+	}
+
+	lists := map[string]int{}
+	for _, a := range client.Actions() {
+		if l, ok := a.(cgotesting.ListAction); ok {
+			lists[fmt.Sprintf("%s/%s?%s", a.GetNamespace(), a.GetResource().Resource, l.GetListRestrictions().Labels)]++
+		}
+	}
+	want := map[string]int{
+		"/clusters?":                 1,
+		"/vpcs?" + LabelComposite:    1,
+		"/subnets?" + LabelComposite: 1,
+		"default/events?":            1,
+		"team/events?":               1,
+	}
+	if diff := cmp.Diff(want, lists); diff != "" {
+		t.Errorf("Poll(...): -want lists, +got lists:\n%s", diff)
+	}
+}
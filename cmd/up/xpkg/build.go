@@ -17,26 +17,45 @@ package xpkg
 import (
 	"context"
 	"io"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
 	"github.com/crossplane/crossplane-runtime/pkg/parser"
+	pkgmetav1 "github.com/crossplane/crossplane/apis/pkg/meta/v1"
+	"github.com/crossplane/crossplane/apis/pkg/v1beta1"
 	"github.com/google/go-containerregistry/pkg/name"
-	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/pterm/pterm"
 	"github.com/spf13/afero"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/upbound/up/internal/xpkg"
+	"github.com/upbound/up/internal/xpkg/attest"
+	"github.com/upbound/up/internal/xpkg/dep/lock"
+	"github.com/upbound/up/internal/xpkg/dep/manager"
 	"github.com/upbound/up/internal/xpkg/parser/examples"
 	"github.com/upbound/up/internal/xpkg/parser/yaml"
+	"github.com/upbound/up/internal/xpkg/scheme"
 )
 
 const (
-	errGetNameFromMeta = "failed to get package name from crossplane.yaml"
-	errBuildPackage    = "failed to build package"
-	errImageDigest     = "failed to get package digest"
-	errCreatePackage   = "failed to create package file"
+	errGetNameFromMeta  = "failed to get package name from crossplane.yaml"
+	errBuildPackage     = "failed to build package"
+	errImageDigest      = "failed to get package digest"
+	errCreatePackage    = "failed to create package file"
+	errLockOutOfDate    = "crossplane.lock is out of date, run `up xpkg dep` to update it"
+	errSourceDigest     = "failed to compute digest of package directory"
+	errValidateExamples = "failed to validate examples"
+
+	errInvalidExamplesFmt      = "found %d error(s) in examples"
+	warnExamplesOutsideRootFmt = "WARNING: examples in %s are outside of the package root and are not validated"
+
+	errNoControllerFmt       = "no controller image supplied for platform %s; use --controller or --platform-controller"
+	errBuildPlatformFmt      = "failed to build package for platform %s"
+	errControllerPlatformFmt = "controller image %s is not built for platform %s"
 )
 
 // AfterApply constructs and binds Upbound-specific context to any subcommands
@@ -54,6 +73,7 @@ func (c *buildCmd) AfterApply() error {
 	if err != nil {
 		return err
 	}
+	c.examples = ex
 
 	var authBE parser.Backend
 	if ax, err := filepath.Abs(c.AuthExt); err == nil {
@@ -101,10 +121,11 @@ func (c *buildCmd) AfterApply() error {
 
 // buildCmd builds a crossplane package.
 type buildCmd struct {
-	fs      afero.Fs
-	builder *xpkg.Builder
-	root    string
-	fetch   fetchFn
+	fs       afero.Fs
+	builder  *xpkg.Builder
+	root     string
+	examples string
+	fetch    fetchFn
 
 	Name         string   `optional:"" xor:"xpkg-build-out" help:"[DEPRECATED: use --output] Name of the package to be built. Uses name in crossplane.yaml if not specified. Does not correspond to package tag."`
 	Output       string   `optional:"" short:"o" xor:"xpkg-build-out" help:"Path for package output."`
@@ -113,6 +134,17 @@ type buildCmd struct {
 	ExamplesRoot string   `short:"e" help:"Path to package examples directory." default:"./examples"`
 	AuthExt      string   `short:"a" help:"Path to an authentication extension file." default:"auth.yaml"`
 	Ignore       []string `help:"Paths, specified relative to --package-root, to exclude from the package."`
+	Tag          string   `help:"Tag to record in the package file, e.g. used by the dep seed command to populate the cache."`
+
+	Platform           []string          `help:"Platforms to build the package for, e.g. linux/amd64,linux/arm64. The package file then contains an OCI image index with one package image per platform."`
+	PlatformController map[string]string `help:"Controller image used as base for the package of a platform, e.g. linux/arm64=provider-foo-controller:v0.1.0-arm64. Platforms without an entry use --controller."`
+
+	SBOM       string `enum:",spdx,cyclonedx" default:"" help:"Generate an SBOM of the package in the given format and store it in the package file. One of: spdx, cyclonedx."`
+	Provenance bool   `help:"Record an in-toto SLSA provenance statement of the build in the package file."`
+
+	ValidateExamples      bool   `default:"true" negatable:"" help:"Validate examples against the schemas of the package and its dependencies."`
+	FailOnInvalidExamples bool   `help:"Fail the build if an example does not match its schema."`
+	CacheDir              string `short:"d" help:"Directory used for caching package images. Schemas of dependencies found in the cache are used to validate examples." default:"~/.up/cache/" env:"CACHE_DIR" type:"path"`
 }
 
 func (c *buildCmd) Help() string {
@@ -126,6 +158,38 @@ Only configuration and provider packages are supported at this time.
 
 Example claims can be specified in the examples directory.
 
+If a crossplane.lock file created by the dep command exists next to
+crossplane.yaml, the build fails unless it pins every dependency declared in
+crossplane.yaml.
+
+If --tag is specified, the tag is recorded in the package file. The dep seed
+command uses it to add the package to the dependency cache.
+
+Use --platform to build a multi-architecture package. The package is built
+once per platform on top of the controller image for that platform, supplied
+with --platform-controller or --controller, and the resulting images are
+stored as an OCI image index in a single package file, e.g.:
+
+  up xpkg build --platform linux/amd64,linux/arm64 \
+    --platform-controller linux/amd64=provider-foo-controller:v0.1.0-amd64 \
+    --platform-controller linux/arm64=provider-foo-controller:v0.1.0-arm64
+
+The push command pushes such a package as an image index under a single tag.
+
+Use --sbom to generate an SPDX or CycloneDX SBOM listing the CRDs, XRDs,
+Compositions, dependencies and controller image of the package, and
+--provenance to record a SLSA provenance statement holding the digest of the
+package directory, the dependencies declared in crossplane.yaml (with their
+digests from crossplane.lock, if present) and the digest of the controller
+image. Both are stored in the package file and attached to the package as
+OCI referrers by the push command.
+
+Examples are validated against the schemas of the XRDs and CRDs in the package
+and in its dependencies, which are read from the cache only; run 'up xpkg dep'
+first to populate it. Problems are printed with file, line and column. Use
+--fail-on-invalid-examples to fail the build if an example is invalid, or
+--no-validate-examples to skip the validation.
+
 For more generic information, see the xpkg parent command help. Also see the
 Crossplane documentation for more information on building packages:
 
@@ -136,26 +200,53 @@ Even more details can be found in the xpkg reference document.`
 
 // Run executes the build command.
 func (c *buildCmd) Run(ctx context.Context, p pterm.TextPrinter) error { //nolint:gocyclo
-	var buildOpts []xpkg.BuildOpt
-	if c.Controller != "" {
-		ref, err := name.ParseReference(c.Controller)
+	// tarball.Write records no tag for a nil reference.
+	var tag name.Reference
+	if c.Tag != "" {
+		t, err := name.NewTag(c.Tag)
 		if err != nil {
 			return err
 		}
-		base, err := c.fetch(ctx, ref)
+		tag = t
+	}
+
+	cfg, err := c.attestConfig()
+	if err != nil {
+		return err
+	}
+
+	var (
+		img  v1.Image
+		idx  v1.ImageIndex
+		meta runtime.Object
+		att  *attest.Attestations
+		hash v1.Hash
+	)
+	if len(c.Platform) > 0 {
+		idx, meta, att, err = c.buildIndex(ctx, cfg)
 		if err != nil {
 			return err
 		}
-		buildOpts = append(buildOpts, xpkg.WithController(base))
+		hash, err = idx.Digest()
+	} else {
+		img, meta, att, err = c.buildImage(ctx, c.Controller, cfg)
+		if err != nil {
+			return err
+		}
+		hash, err = img.Digest()
 	}
-	img, meta, err := c.builder.Build(ctx, buildOpts...)
 	if err != nil {
-		return errors.Wrap(err, errBuildPackage)
+		return errors.Wrap(err, errImageDigest)
 	}
 
-	hash, err := img.Digest()
-	if err != nil {
-		return errors.Wrap(err, errImageDigest)
+	if err := c.checkLock(meta); err != nil {
+		return err
+	}
+
+	if c.ValidateExamples {
+		if err := c.validateExamples(ctx, p); err != nil {
+			return err
+		}
 	}
 
 	output := filepath.Clean(c.Output)
@@ -177,13 +268,186 @@ func (c *buildCmd) Run(ctx context.Context, p pterm.TextPrinter) error { //nolin
 	}
 
 	defer func() { _ = f.Close() }()
-	if err := tarball.Write(nil, img, f); err != nil {
+	if idx != nil {
+		err = xpkg.WriteIndex(f, tag, idx, att)
+	} else {
+		err = xpkg.WriteImage(f, tag, img, att)
+	}
+	if err != nil {
 		return err
 	}
 	p.Printfln("xpkg saved to %s", output)
 	return nil
 }
 
+// attestConfig returns the configuration of the attestations requested for
+// the build.
+func (c *buildCmd) attestConfig() (attest.Config, error) {
+	cfg := attest.Config{
+		SBOM:       attest.SBOMFormat(c.SBOM),
+		Provenance: c.Provenance,
+	}
+	if !cfg.Enabled() {
+		return cfg, nil
+	}
+
+	d, err := attest.DirDigest(c.fs, c.root)
+	if err != nil {
+		return cfg, errors.Wrap(err, errSourceDigest)
+	}
+	cfg.Source = attest.Source{
+		URI:    "file://" + filepath.ToSlash(c.root),
+		Digest: d,
+	}
+
+	l, err := lock.Read(c.fs, lock.Path(c.root))
+	if err != nil && !os.IsNotExist(err) {
+		return cfg, err
+	}
+	if l != nil {
+		cfg.Source.Locked = make(map[string]string, len(l.Packages))
+		for _, p := range l.Packages {
+			cfg.Source.Locked[p.Source] = p.Digest
+		}
+	}
+	return cfg, nil
+}
+
+// buildImage builds the package on top of the supplied controller image, if
+// any.
+func (c *buildCmd) buildImage(ctx context.Context, controller string, cfg attest.Config) (v1.Image, runtime.Object, *attest.Attestations, error) {
+	att := &attest.Attestations{}
+	cfg.Controller = controller
+	buildOpts := []xpkg.BuildOpt{xpkg.WithAttestations(cfg, att)}
+	if controller != "" {
+		ref, err := name.ParseReference(controller)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		base, err := c.fetch(ctx, ref)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		buildOpts = append(buildOpts, xpkg.WithController(base))
+	}
+	img, meta, err := c.builder.Build(ctx, buildOpts...)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, errBuildPackage)
+	}
+	return img, meta, att, nil
+}
+
+// buildIndex builds the package for every platform and returns an image
+// index referencing the package images.
+func (c *buildCmd) buildIndex(ctx context.Context, acfg attest.Config) (v1.ImageIndex, runtime.Object, *attest.Attestations, error) {
+	var meta runtime.Object
+	att := &attest.Attestations{}
+	imgs := make([]v1.Image, 0, len(c.Platform))
+	for _, pl := range c.Platform {
+		platform, err := v1.ParsePlatform(pl)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		controller := c.Controller
+		if pc, ok := c.PlatformController[pl]; ok {
+			controller = pc
+		}
+		if controller == "" {
+			return nil, nil, nil, errors.Errorf(errNoControllerFmt, pl)
+		}
+
+		img, m, a, err := c.buildImage(ctx, controller, acfg)
+		if err != nil {
+			return nil, nil, nil, errors.Wrapf(err, errBuildPlatformFmt, pl)
+		}
+		cfg, err := img.ConfigFile()
+		if err != nil {
+			return nil, nil, nil, errors.Wrapf(err, errBuildPlatformFmt, pl)
+		}
+		if ip := cfg.Platform(); ip == nil || !ip.Satisfies(*platform) {
+			return nil, nil, nil, errors.Errorf(errControllerPlatformFmt, controller, pl)
+		}
+		imgs = append(imgs, img)
+		meta = m
+		// the SBOM of the first platform is kept, while the provenance
+		// records the controller image of every platform.
+		att.Merge(a)
+	}
+
+	idx, err := xpkg.BuildIndex(imgs...)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, errBuildPackage)
+	}
+	return idx, meta, att, nil
+}
+
+// validateExamples validates the examples of the package against the schemas
+// of the package and its dependencies and prints any problems found.
+func (c *buildCmd) validateExamples(ctx context.Context, p pterm.TextPrinter) error {
+	rel, err := filepath.Rel(c.root, c.examples)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		p.Printfln(warnExamplesOutsideRootFmt, c.ExamplesRoot)
+		return nil
+	}
+
+	snap, err := newSnapshot(ctx, c.root, c.CacheDir)
+	if err != nil {
+		return errors.Wrap(err, errValidateExamples)
+	}
+	res, err := snap.ValidateExamples(ctx)
+	if err != nil {
+		return errors.Wrap(err, errValidateExamples)
+	}
+
+	// the workspace treats every directory containing "example" as
+	// examples, only report the examples that are part of the package.
+	diags := make([]lintDiagnostic, 0)
+	for _, d := range schemaDiagnostics(c.root, res) {
+		if rel == "." || strings.HasPrefix(d.File, filepath.ToSlash(rel)+"/") {
+			diags = append(diags, d)
+		}
+	}
+	sortDiagnostics(diags)
+
+	r := newLintReport(diags)
+	for _, d := range r.Diagnostics {
+		p.Printfln("%s:%d:%d: %s: %s", d.File, d.Line, d.Column, d.Severity, d.Message)
+	}
+	if r.Errors > 0 && c.FailOnInvalidExamples {
+		return errors.Errorf(errInvalidExamplesFmt, r.Errors)
+	}
+	return nil
+}
+
+// checkLock verifies that the lock file next to the package meta file, if one
+// exists, pins every dependency declared in the meta file.
+func (c *buildCmd) checkLock(o runtime.Object) error {
+	l, err := lock.Read(c.fs, lock.Path(c.root))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// packages without dependencies, e.g. functions, have nothing to
+	// check against the lock.
+	pkg, ok := scheme.TryConvertToPkg(o, &pkgmetav1.Provider{}, &pkgmetav1.Configuration{})
+	if !ok {
+		return nil
+	}
+
+	deps := make([]v1beta1.Dependency, 0, len(pkg.GetDependencies()))
+	for _, d := range pkg.GetDependencies() {
+		if bd, ok := manager.ConvertToV1beta1(d); ok {
+			deps = append(deps, bd)
+		}
+	}
+
+	return errors.Wrap(l.Check(deps), errLockOutOfDate)
+}
+
 // default build filters skip directories, empty files, and files without YAML
 // extension in addition to any paths specified.
 func buildFilters(root string, skips []string) []parser.FilterFn {
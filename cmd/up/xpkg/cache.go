@@ -0,0 +1,416 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xpkg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane/apis/pkg/v1beta1"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/pterm/pterm"
+	"k8s.io/apimachinery/pkg/util/duration"
+
+	"github.com/upbound/up/internal/config"
+	"github.com/upbound/up/internal/upterm"
+	"github.com/upbound/up/internal/xpkg/dep/cache"
+	"github.com/upbound/up/internal/xpkg/dep/lock"
+	"github.com/upbound/up/internal/xpkg/dep/resolver/image"
+	"github.com/upbound/up/internal/xpkg/workspace"
+)
+
+const (
+	errNoPruneCriteria = "specify --older-than and/or --unreferenced"
+	errVerifyFailedFmt = "%d of %d cache entries failed verification"
+	errReadWorkspace   = "failed to read workspace %s"
+	errRemoteDigestFmt = "registry digest %s does not match recorded digest %s"
+
+	statusOK     = "OK"
+	statusFailed = "FAILED"
+)
+
+// AfterApply constructs and binds Upbound-specific context to any subcommands
+// that have Run() methods that receive it.
+func (c *cacheCmd) AfterApply(kongCtx *kong.Context) error {
+	cache, err := cache.NewLocal(c.CacheDir)
+	if err != nil {
+		return err
+	}
+	kongCtx.Bind(cache)
+
+	// workaround interfaces not being bindable ref: https://github.com/alecthomas/kong/issues/48
+	kongCtx.BindTo(context.Background(), (*context.Context)(nil))
+	return nil
+}
+
+// cacheCmd manages the package dependency cache.
+type cacheCmd struct {
+	CacheDir string `short:"d" help:"Directory used for caching package images." default:"~/.up/cache/" env:"CACHE_DIR" type:"path"`
+
+	List   cacheListCmd   `cmd:"" help:"List the cached packages and versions."`
+	Prune  cachePruneCmd  `cmd:"" help:"Remove cache entries by age or that are not referenced by any workspace."`
+	Verify cacheVerifyCmd `cmd:"" help:"Check that cache entries are intact and match their recorded digests."`
+	Du     cacheDuCmd     `cmd:"" help:"Report the disk usage of the cache."`
+}
+
+func (c *cacheCmd) Help() string {
+	return `
+The cache command manages the local package dependency cache (by default in
+~/.up/cache) that is populated by the dep command and used by the Crossplane
+language server.
+
+All subcommands support --format json and --format yaml.`
+}
+
+var cacheEntryFieldNames = []string{"PACKAGE", "VERSION", "DIGEST", "SIZE", "AGE"}
+
+func extractCacheEntryFields(obj any) []string {
+	e := obj.(cache.EntryInfo)
+	return []string{e.Package, e.Version, shortDigest(e.Digest), humanSize(e.Size), cacheEntryAge(e)}
+}
+
+func cacheEntryAge(e cache.EntryInfo) string {
+	if e.Created.IsZero() {
+		return "n/a"
+	}
+	return duration.HumanDuration(time.Since(e.Created))
+}
+
+// shortDigest shortens the hex part of a digest to 12 characters.
+func shortDigest(d string) string {
+	_, hex, ok := strings.Cut(d, ":")
+	if !ok || len(hex) < 12 {
+		return d
+	}
+	return hex[:12]
+}
+
+// humanSize formats the supplied number of bytes using binary units.
+func humanSize(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%dB", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+// cacheListCmd lists the cache entries.
+type cacheListCmd struct{}
+
+// Run executes the cache list command.
+func (c *cacheListCmd) Run(p pterm.TextPrinter, printer upterm.ObjectPrinter, local *cache.Local) error {
+	entries, err := local.Entries()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 && printer.Format == config.Default {
+		p.Printfln("No packages found in the cache")
+		return nil
+	}
+	return printer.Print(entries, cacheEntryFieldNames, extractCacheEntryFields)
+}
+
+// cachePruneCmd removes cache entries.
+type cachePruneCmd struct {
+	OlderThan    time.Duration `help:"Remove entries that were added to the cache longer ago than this duration, e.g. 720h."`
+	Unreferenced bool          `help:"Remove entries that are not referenced by any of the supplied workspaces."`
+	Workspace    []string      `short:"w" help:"Workspace directories whose crossplane.yaml and crossplane.lock reference cache entries." default:"." type:"path"`
+}
+
+func (c *cachePruneCmd) Help() string {
+	return `
+The prune command removes cache entries that were added longer ago than
+--older-than, and/or entries that are not referenced by any of the workspaces
+supplied with --workspace if --unreferenced is set.
+
+An entry is referenced by a workspace if it is pinned by the workspace's
+crossplane.lock, if its version satisfies a dependency declared in the
+workspace's crossplane.yaml, or if it is a dependency of another referenced
+entry.
+
+Use --dry-run to print the entries that would be removed without removing them.`
+}
+
+// Run executes the cache prune command.
+func (c *cachePruneCmd) Run(ctx context.Context, p pterm.TextPrinter, printer upterm.ObjectPrinter, local *cache.Local) error {
+	if c.OlderThan == 0 && !c.Unreferenced {
+		return errors.New(errNoPruneCriteria)
+	}
+
+	entries, err := local.Entries()
+	if err != nil {
+		return err
+	}
+
+	var refs map[string]bool
+	if c.Unreferenced {
+		refs, err = c.referenced(ctx, local, entries)
+		if err != nil {
+			return err
+		}
+	}
+
+	pruned := make([]cache.EntryInfo, 0)
+	for _, e := range entries {
+		if c.OlderThan != 0 && (e.Created.IsZero() || time.Since(e.Created) < c.OlderThan) {
+			continue
+		}
+		if c.Unreferenced && refs[entryKey(e.Package, e.Version)] {
+			continue
+		}
+		if !printer.DryRun {
+			if err := local.Remove(e); err != nil {
+				return err
+			}
+		}
+		pruned = append(pruned, e)
+	}
+
+	if len(pruned) == 0 && printer.Format == config.Default {
+		p.Printfln("No cache entries to prune")
+		return nil
+	}
+	return printer.Print(pruned, cacheEntryFieldNames, extractCacheEntryFields)
+}
+
+// referenced returns the keys of the entries referenced by the workspaces.
+func (c *cachePruneCmd) referenced(ctx context.Context, local *cache.Local, entries []cache.EntryInfo) (map[string]bool, error) {
+	versions := make(map[string][]string)
+	for _, e := range entries {
+		versions[e.Package] = append(versions[e.Package], e.Version)
+	}
+
+	refs := make(map[string]bool)
+	queue := make([]v1beta1.Dependency, 0)
+	for _, dir := range c.Workspace {
+		deps, err := workspaceDeps(ctx, dir)
+		if err != nil {
+			return nil, errors.Wrapf(err, errReadWorkspace, dir)
+		}
+		queue = append(queue, deps...)
+	}
+
+	for len(queue) > 0 {
+		d := queue[0]
+		queue = queue[1:]
+
+		pkg := normalizeSource(d.Package)
+		for _, v := range versions[pkg] {
+			k := entryKey(pkg, v)
+			if refs[k] || !lock.Satisfies(d.Constraints, v) {
+				continue
+			}
+			refs[k] = true
+
+			// keep the dependencies of a referenced entry as well.
+			p, err := local.Get(v1beta1.Dependency{Package: pkg, Constraints: v})
+			if err != nil {
+				continue
+			}
+			queue = append(queue, p.Dependencies()...)
+		}
+	}
+	return refs, nil
+}
+
+// workspaceDeps returns the dependencies declared in the crossplane.yaml of
+// the workspace in the supplied directory, with the versions pinned by its
+// crossplane.lock.
+func workspaceDeps(ctx context.Context, dir string) ([]v1beta1.Dependency, error) {
+	ws, err := workspace.New(dir, workspace.WithPermissiveParser())
+	if err != nil {
+		return nil, err
+	}
+	if err := ws.Parse(ctx); err != nil {
+		return nil, err
+	}
+
+	deps := make([]v1beta1.Dependency, 0)
+	if meta := ws.View().Meta(); meta != nil {
+		md, err := meta.DependsOn()
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, md...)
+	}
+
+	l, err := ws.Lock()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if l != nil {
+		for _, p := range l.Packages {
+			deps = append(deps, v1beta1.Dependency{Package: p.Source, Type: p.Type, Constraints: p.Version})
+		}
+	}
+	return deps, nil
+}
+
+// normalizeSource returns the supplied package source including its registry,
+// matching the package of a cache.EntryInfo.
+func normalizeSource(src string) string {
+	r, err := name.NewRepository(src)
+	if err != nil {
+		return src
+	}
+	return r.Name()
+}
+
+func entryKey(pkg, version string) string {
+	return pkg + "@" + version
+}
+
+// cacheVerifyCmd verifies the cache entries.
+type cacheVerifyCmd struct {
+	Remote bool `help:"Additionally check that the recorded digests match the digests in the registry."`
+}
+
+func (c *cacheVerifyCmd) Help() string {
+	return `
+Verify re-hashes the stored package content of every cache entry and compares
+it to the checksum recorded when the entry was written. It also checks that the
+version and image digest recorded for the entry are consistent. Entries written
+by older versions of up have no checksum and fail verification; remove them and
+resolve them again.
+
+The image is not pulled again, so verification can only detect content that
+changed after it was cached. Use --remote to additionally check that the
+recorded digest is still the digest of the version in the registry.`
+}
+
+// cacheVerifyResult is the verification result of a single cache entry.
+type cacheVerifyResult struct {
+	Package string `json:"package" yaml:"package"`
+	Version string `json:"version" yaml:"version"`
+	Digest  string `json:"digest" yaml:"digest"`
+	Status  string `json:"status" yaml:"status"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+var cacheVerifyFieldNames = []string{"PACKAGE", "VERSION", "DIGEST", "STATUS", "ERROR"}
+
+func extractCacheVerifyFields(obj any) []string {
+	r := obj.(cacheVerifyResult)
+	return []string{r.Package, r.Version, shortDigest(r.Digest), r.Status, r.Error}
+}
+
+// Run executes the cache verify command.
+func (c *cacheVerifyCmd) Run(ctx context.Context, printer upterm.ObjectPrinter, local *cache.Local) error {
+	entries, err := local.Entries()
+	if err != nil {
+		return err
+	}
+
+	r := image.NewResolver()
+	results := make([]cacheVerifyResult, len(entries))
+	failed := 0
+	for i, e := range entries {
+		results[i] = cacheVerifyResult{
+			Package: e.Package,
+			Version: e.Version,
+			Digest:  e.Digest,
+			Status:  statusOK,
+		}
+
+		err := local.Verify(e)
+		if err == nil && c.Remote {
+			err = verifyRemoteDigest(ctx, r, e)
+		}
+		if err != nil {
+			results[i].Status = statusFailed
+			results[i].Error = err.Error()
+			failed++
+		}
+	}
+
+	if err := printer.Print(results, cacheVerifyFieldNames, extractCacheVerifyFields); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return errors.Errorf(errVerifyFailedFmt, failed, len(entries))
+	}
+	return nil
+}
+
+func verifyRemoteDigest(ctx context.Context, r *image.Resolver, e cache.EntryInfo) error {
+	d, err := r.ResolveDigest(ctx, v1beta1.Dependency{Package: e.Package, Constraints: e.Version})
+	if err != nil {
+		return err
+	}
+	if d != e.Digest {
+		return errors.Errorf(errRemoteDigestFmt, d, e.Digest)
+	}
+	return nil
+}
+
+// cacheDuCmd reports the disk usage of the cache.
+type cacheDuCmd struct{}
+
+// cacheUsage is the disk usage of the cached versions of a package.
+type cacheUsage struct {
+	Package  string `json:"package" yaml:"package"`
+	Versions int    `json:"versions" yaml:"versions"`
+	Size     int64  `json:"size" yaml:"size"`
+}
+
+// cacheUsageReport is the disk usage of the cache.
+type cacheUsageReport struct {
+	Packages []cacheUsage `json:"packages" yaml:"packages"`
+	Total    int64        `json:"total" yaml:"total"`
+}
+
+var cacheUsageFieldNames = []string{"PACKAGE", "VERSIONS", "SIZE"}
+
+func extractCacheUsageFields(obj any) []string {
+	u := obj.(cacheUsage)
+	return []string{u.Package, fmt.Sprint(u.Versions), humanSize(u.Size)}
+}
+
+// Run executes the cache du command.
+func (c *cacheDuCmd) Run(printer upterm.ObjectPrinter, local *cache.Local) error {
+	entries, err := local.Entries()
+	if err != nil {
+		return err
+	}
+
+	report := cacheUsageReport{Packages: make([]cacheUsage, 0)}
+	for _, e := range entries {
+		// entries are sorted by package.
+		if n := len(report.Packages); n == 0 || report.Packages[n-1].Package != e.Package {
+			report.Packages = append(report.Packages, cacheUsage{Package: e.Package})
+		}
+		u := &report.Packages[len(report.Packages)-1]
+		u.Versions++
+		u.Size += e.Size
+		report.Total += e.Size
+	}
+
+	if printer.Format != config.Default {
+		return printer.Print(report, cacheUsageFieldNames, nil)
+	}
+
+	rows := append(report.Packages, cacheUsage{Package: "TOTAL", Versions: len(entries), Size: report.Total})
+	return printer.Print(rows, cacheUsageFieldNames, extractCacheUsageFields)
+}
@@ -22,6 +22,8 @@ import (
 	"github.com/alecthomas/kong"
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
 	"github.com/crossplane/crossplane/apis/pkg/v1beta1"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/pterm/pterm"
 	"github.com/spf13/afero"
 
@@ -30,11 +32,14 @@ import (
 	"github.com/upbound/up/internal/xpkg/dep/cache"
 	"github.com/upbound/up/internal/xpkg/dep/manager"
 	"github.com/upbound/up/internal/xpkg/dep/resolver/image"
+	"github.com/upbound/up/internal/xpkg/sign"
 	"github.com/upbound/up/internal/xpkg/workspace"
 )
 
 const (
 	errMetaFileNotFound = "crossplane.yaml file not found in current directory"
+	errVerifyKeyOffline = "--verify-key cannot be used with --offline; signatures can only be verified against a registry"
+	errNotResolvedFmt   = "%s was not resolved"
 )
 
 // AfterApply constructs and binds Upbound-specific context to any subcommands
@@ -44,29 +49,20 @@ func (c *depCmd) AfterApply(kongCtx *kong.Context, p pterm.TextPrinter) error {
 	ctx := context.Background()
 	fs := afero.NewOsFs()
 
+	if c.VerifyKey != "" && c.Offline {
+		return errors.New(errVerifyKeyOffline)
+	}
+
 	cache, err := cache.NewLocal(c.CacheDir)
 	if err != nil {
 		return err
 	}
-
-	c.c = cache
+	kongCtx.Bind(cache)
 
 	// only parse the workspace if we aren't attempting to clean the cache
-	if !c.CleanCache {
-
-		r := image.NewResolver()
-
-		m, err := manager.New(
-			manager.WithCache(cache),
-			manager.WithResolver(r),
-		)
-
-		if err != nil {
-			return err
-		}
-
-		c.m = m
-
+	if c.Add.CleanCache {
+		kongCtx.Bind((*workspace.Workspace)(nil), (*manager.Manager)(nil))
+	} else {
 		wd, err := os.Getwd()
 		if err != nil {
 			return err
@@ -76,11 +72,44 @@ func (c *depCmd) AfterApply(kongCtx *kong.Context, p pterm.TextPrinter) error {
 		if err != nil {
 			return err
 		}
-		c.ws = ws
 
 		if err := ws.Parse(ctx); err != nil {
 			return err
 		}
+		kongCtx.Bind(ws)
+
+		l, err := ws.Lock()
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		opts := []manager.Option{
+			manager.WithCache(cache),
+			manager.WithResolver(image.NewResolver()),
+		}
+		// when updating we ignore the current lock in order to re-resolve
+		// the whole dependency graph.
+		if l != nil && !c.Update {
+			opts = append(opts, manager.WithLock(l))
+		}
+		if c.Offline {
+			opts = append(opts, manager.WithOffline())
+		}
+		if c.VerifyKey != "" {
+			key, err := sign.LoadPublicKey(fs, c.VerifyKey)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, manager.WithVerifier(sign.NewVerifier(key,
+				sign.WithRemoteOptions(remote.WithAuthFromKeychain(authn.DefaultKeychain)),
+			)))
+		}
+
+		m, err := manager.New(opts...)
+		if err != nil {
+			return err
+		}
+		kongCtx.Bind(m)
 	}
 
 	// workaround interfaces not being bindable ref: https://github.com/alecthomas/kong/issues/48
@@ -90,17 +119,17 @@ func (c *depCmd) AfterApply(kongCtx *kong.Context, p pterm.TextPrinter) error {
 
 // depCmd manages crossplane dependencies.
 type depCmd struct {
-	c  *cache.Local
-	m  *manager.Manager
-	ws *workspace.Workspace
-
 	// TODO(@tnthornton) remove cacheDir flag. Having a user supplied flag
 	// can result in broken behavior between xpls and dep. CacheDir should
 	// only be supplied by the Config.
-	CacheDir   string `short:"d" help:"Directory used for caching package images." default:"~/.up/cache/" env:"CACHE_DIR" type:"path"`
-	CleanCache bool   `short:"c" help:"Clean dep cache."`
-
-	Package string `arg:"" optional:"" help:"Package to be added."`
+	CacheDir  string `short:"d" help:"Directory used for caching package images." default:"~/.up/cache/" env:"CACHE_DIR" type:"path"`
+	Update    bool   `short:"u" help:"Ignore the versions pinned in crossplane.lock and re-resolve all dependencies."`
+	Offline   bool   `help:"Resolve dependencies against the cache only, without contacting any registry." env:"UP_XPKG_OFFLINE"`
+	VerifyKey string `help:"Require every resolved package to carry a signature that verifies against the PEM encoded ECDSA public key at this path." type:"existingfile"`
+
+	Add  depAddCmd  `cmd:"" default:"withargs" help:"Add a dependency and populate the cache with the dependencies of the package in the current directory. This is the default subcommand."`
+	Tree depTreeCmd `cmd:"" help:"Print the resolved dependency graph of the package in the current directory."`
+	Seed depSeedCmd `cmd:"" help:"Populate the cache from package files, e.g. on air-gapped hosts."`
 }
 
 func (c *depCmd) Help() string {
@@ -112,17 +141,42 @@ server.
 
 If a package (e.g. provider-foo@v0.42.0 or provider-foo for latest) is specified,
 it will be added to the crossplane.yaml file in the current directory as dependency. 
+
+Every package in the dependency graph is resolved to the newest version that
+satisfies the constraints of all packages depending on it. If no such version
+exists, the conflicting constraints are reported.
+
+The resolved version and digest of every dependency, including transitive
+ones, are recorded in a crossplane.lock file next to crossplane.yaml. Later
+runs resolve to the locked versions as long as they satisfy the constraints
+in crossplane.yaml. Use --update to re-resolve all dependencies to the newest
+matching versions and rewrite the lock file.
+
+Use --offline on hosts without registry access. Dependencies are then resolved
+against the versions present in the cache only, and all dependencies missing
+from the cache are reported. The cache can be populated from a directory of
+package files with the seed subcommand.
+
+Use --verify-key to require a valid signature, e.g. created with
+'up xpkg sign', for every package before it is added to the cache.
 `
 }
 
+// depAddCmd adds dependencies and populates the cache.
+type depAddCmd struct {
+	CleanCache bool `short:"c" help:"Clean dep cache."`
+
+	Package string `arg:"" optional:"" help:"Package to be added."`
+}
+
 // Run executes the dep command.
-func (c *depCmd) Run(ctx context.Context, p pterm.TextPrinter, pb *pterm.BulletListPrinter) error {
+func (c *depAddCmd) Run(ctx context.Context, p pterm.TextPrinter, pb *pterm.BulletListPrinter, cache *cache.Local, m *manager.Manager, ws *workspace.Workspace) error {
 	// no need to do anything else if clean cache was called.
 
 	// TODO (@tnthornton) this feels a little out of place here. We should
 	// consider adding a separate command for doing this.
 	if c.CleanCache {
-		if err := c.c.Clean(); err != nil {
+		if err := cache.Clean(); err != nil {
 			return err
 		}
 		p.Printfln("xpkg cache cleaned")
@@ -130,14 +184,14 @@ func (c *depCmd) Run(ctx context.Context, p pterm.TextPrinter, pb *pterm.BulletL
 	}
 
 	if c.Package != "" {
-		if err := c.userSuppliedDep(ctx); err != nil {
+		if err := c.userSuppliedDep(ctx, m, ws); err != nil {
 			return err
 		}
 		p.Printfln("%s added to xpkg cache", c.Package)
 		return nil
 	}
 
-	deps, err := c.metaSuppliedDeps(ctx)
+	deps, err := metaSuppliedDeps(ws)
 	if err != nil {
 		return err
 	}
@@ -145,12 +199,23 @@ func (c *depCmd) Run(ctx context.Context, p pterm.TextPrinter, pb *pterm.BulletL
 		p.Printfln("No dependencies specified")
 		return nil
 	}
+
+	g, err := m.ResolveGraph(ctx, deps)
+	if err != nil {
+		return err
+	}
+	// the manager has walked the full dependency graph, so its lock replaces
+	// the existing one entirely.
+	if err := ws.WriteLock(m.Lock()); err != nil {
+		return err
+	}
+
 	p.Printfln("Dependencies added to xpkg cache:")
-	li := make([]pterm.BulletListItem, len(deps))
-	for i, d := range deps {
+	li := make([]pterm.BulletListItem, len(g.Roots))
+	for i, n := range g.Roots {
 		li[i] = pterm.BulletListItem{
 			Level:  0,
-			Text:   fmt.Sprintf("%s (%s)", d.Package, d.Constraints),
+			Text:   fmt.Sprintf("%s (%s)", n.Package, n.Version),
 			Bullet: "-",
 		}
 	}
@@ -159,7 +224,7 @@ func (c *depCmd) Run(ctx context.Context, p pterm.TextPrinter, pb *pterm.BulletL
 	return pb.WithItems(li).Render()
 }
 
-func (c *depCmd) userSuppliedDep(ctx context.Context) error {
+func (c *depAddCmd) userSuppliedDep(ctx context.Context, m *manager.Manager, ws *workspace.Workspace) error {
 	// exit early check if we were supplied an invalid package string
 	_, err := xpkg.ValidDep(c.Package)
 	if err != nil {
@@ -168,47 +233,70 @@ func (c *depCmd) userSuppliedDep(ctx context.Context) error {
 
 	d := dep.New(c.Package)
 
-	ud, _, err := c.m.AddAll(ctx, d)
+	meta := ws.View().Meta()
+	if meta == nil {
+		// there is no crossplane.yaml to add the dependency to, only
+		// populate the cache.
+		if _, _, err := m.AddAll(ctx, d); err != nil {
+			return errors.Wrapf(err, "in %s", c.Package)
+		}
+		return nil
+	}
+
+	// resolve the new dependency together with the existing ones so that
+	// conflicting constraints are caught before crossplane.yaml is updated.
+	deps, err := meta.DependsOn()
+	if err != nil {
+		return err
+	}
+	deps = append(withoutDep(deps, d.Package), d)
+
+	g, err := m.ResolveGraph(ctx, deps)
 	if err != nil {
 		return errors.Wrapf(err, "in %s", c.Package)
 	}
 
-	meta := c.ws.View().Meta()
+	n, ok := g.Node(d.Package)
+	if !ok {
+		return errors.Errorf(errNotResolvedFmt, d.Package)
+	}
+	ud := v1beta1.Dependency{
+		Package:     d.Package,
+		Type:        n.Type,
+		Constraints: n.Version,
+	}
 
-	if meta != nil {
-		// crossplane.yaml file exists in the workspace, upsert the new dependency
-		if err := meta.Upsert(ud); err != nil {
-			return err
-		}
+	// crossplane.yaml file exists in the workspace, upsert the new dependency
+	if err := meta.Upsert(ud); err != nil {
+		return err
+	}
 
-		if err := c.ws.Write(meta); err != nil {
-			return err
-		}
+	if err := ws.Write(meta); err != nil {
+		return err
 	}
 
-	return nil
+	// the manager has resolved the full graph of the updated crossplane.yaml.
+	return ws.WriteLock(m.Lock())
 }
 
-func (c *depCmd) metaSuppliedDeps(ctx context.Context) ([]v1beta1.Dependency, error) {
-	meta := c.ws.View().Meta()
+func metaSuppliedDeps(ws *workspace.Workspace) ([]v1beta1.Dependency, error) {
+	meta := ws.View().Meta()
 
 	if meta == nil {
 		return nil, errors.New(errMetaFileNotFound)
 	}
 
-	deps, err := meta.DependsOn()
-	if err != nil {
-		return nil, err
-	}
+	return meta.DependsOn()
+}
 
-	resolvedDeps := make([]v1beta1.Dependency, len(deps))
-	for i, d := range deps {
-		ud, _, err := c.m.AddAll(ctx, d)
-		if err != nil {
-			return nil, err
+// withoutDep returns the supplied dependencies without the dependency on the
+// supplied package.
+func withoutDep(deps []v1beta1.Dependency, pkg string) []v1beta1.Dependency {
+	out := make([]v1beta1.Dependency, 0, len(deps))
+	for _, d := range deps {
+		if d.Package != pkg {
+			out = append(out, d)
 		}
-		resolvedDeps[i] = ud
 	}
-
-	return resolvedDeps, nil
+	return out
 }
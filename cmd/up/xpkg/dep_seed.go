@@ -0,0 +1,165 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xpkg
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/pterm/pterm"
+
+	"github.com/upbound/up/internal/xpkg"
+	"github.com/upbound/up/internal/xpkg/dep/manager"
+)
+
+const (
+	errFindPackageFiles = "failed to find package files"
+	errReadPackageFile  = "failed to read package file %s"
+	errSeedPackageFmt   = "failed to add %s from %s to the cache"
+	errNoTagFmt         = "%s has no tag; build it with --tag to record the package it should be cached as"
+	errNoPackageFiles   = "no package files found"
+	errEmptyIndexFmt    = "%s contains no package images"
+)
+
+// depSeedCmd populates the cache from package files.
+type depSeedCmd struct {
+	Path string `arg:"" type:"path" help:"Package file, or directory of package files, to add to the cache."`
+}
+
+func (c *depSeedCmd) Help() string {
+	return `
+The seed command adds package files (.xpkg), e.g. produced by the build command,
+to the dependency cache so that dependencies can be resolved with --offline.
+
+Packages are cached under the tag recorded in the package file. Use the --tag
+flag of the build command to record a tag, e.g.:
+
+  up xpkg build --tag xpkg.upbound.io/acme/configuration-foo:v0.1.0
+`
+}
+
+// Run executes the dep seed command.
+func (c *depSeedCmd) Run(p pterm.TextPrinter, m *manager.Manager) error {
+	files, err := packageFiles(c.Path)
+	if err != nil {
+		return errors.Wrap(err, errFindPackageFiles)
+	}
+	if len(files) == 0 {
+		return errors.New(errNoPackageFiles)
+	}
+
+	for _, f := range files {
+		tags, err := seedPackageFile(m, f)
+		if err != nil {
+			return err
+		}
+		for _, t := range tags {
+			p.Printfln("%s added to xpkg cache from %s", t, f)
+		}
+	}
+	return nil
+}
+
+// packageFiles returns the supplied path if it is a file, or the package
+// files in it if it is a directory.
+func packageFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	return filepath.Glob(filepath.Join(path, xpkg.XpkgMatchPattern))
+}
+
+// seedPackageFile adds the package in the supplied file to the cache for
+// every tag recorded in the file.
+func seedPackageFile(m *manager.Manager, path string) ([]string, error) {
+	isIndex, err := xpkg.IsIndexFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, errReadPackageFile, path)
+	}
+	if isIndex {
+		return seedIndexFile(m, path)
+	}
+
+	opener := func() (io.ReadCloser, error) {
+		return os.Open(filepath.Clean(path))
+	}
+
+	mf, err := tarball.LoadManifest(opener)
+	if err != nil {
+		return nil, errors.Wrapf(err, errReadPackageFile, path)
+	}
+
+	var tags []string
+	for _, d := range mf {
+		tags = append(tags, d.RepoTags...)
+	}
+	if len(tags) == 0 {
+		return nil, errors.Errorf(errNoTagFmt, path)
+	}
+
+	for _, t := range tags {
+		tag, err := name.NewTag(t)
+		if err != nil {
+			return nil, errors.Wrapf(err, errReadPackageFile, path)
+		}
+		img, err := tarball.Image(opener, &tag)
+		if err != nil {
+			return nil, errors.Wrapf(err, errReadPackageFile, path)
+		}
+		if _, err := m.AddImage(tag, img); err != nil {
+			return nil, errors.Wrapf(err, errSeedPackageFmt, t, path)
+		}
+	}
+	return tags, nil
+}
+
+// seedIndexFile adds the package in the supplied multi-platform package file
+// to the cache. The package contents are identical for every platform, so
+// the first image of the index is used.
+func seedIndexFile(m *manager.Manager, path string) ([]string, error) {
+	f, err := xpkg.ReadIndexFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, errReadPackageFile, path)
+	}
+	defer f.Close() // nolint:errcheck
+
+	if f.Tag == "" {
+		return nil, errors.Errorf(errNoTagFmt, path)
+	}
+	tag, err := name.NewTag(f.Tag)
+	if err != nil {
+		return nil, errors.Wrapf(err, errReadPackageFile, path)
+	}
+
+	imgs, err := xpkg.IndexImages(f.Index)
+	if err != nil {
+		return nil, errors.Wrapf(err, errReadPackageFile, path)
+	}
+	if len(imgs) == 0 {
+		return nil, errors.Errorf(errEmptyIndexFmt, path)
+	}
+	if _, err := m.AddImage(tag, imgs[0]); err != nil {
+		return nil, errors.Wrapf(err, errSeedPackageFmt, f.Tag, path)
+	}
+	return []string{f.Tag}, nil
+}
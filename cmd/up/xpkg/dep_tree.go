@@ -0,0 +1,126 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xpkg
+
+import (
+	"context"
+	"sort"
+
+	"github.com/crossplane/crossplane/apis/pkg/v1beta1"
+
+	"github.com/upbound/up/internal/config"
+	"github.com/upbound/up/internal/upterm"
+	"github.com/upbound/up/internal/xpkg/dep/manager"
+	"github.com/upbound/up/internal/xpkg/workspace"
+)
+
+var treeFieldNames = []string{"PACKAGE", "TYPE", "VERSION", "CONSTRAINT"}
+
+// depTreeCmd prints the resolved dependency graph.
+type depTreeCmd struct{}
+
+// depTreeNode is a single package in the printed dependency tree.
+type depTreeNode struct {
+	Package string              `json:"package" yaml:"package"`
+	Type    v1beta1.PackageType `json:"type" yaml:"type"`
+	Version string              `json:"version" yaml:"version"`
+	// Constraints are the constraints placed on the package by its parent
+	// in the tree.
+	Constraints  string        `json:"constraints" yaml:"constraints"`
+	Dependencies []depTreeNode `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
+}
+
+// depTreeRow is a flattened depTreeNode printed in the default format.
+type depTreeRow struct {
+	prefix string
+	node   depTreeNode
+}
+
+// Run executes the dep tree command.
+func (c *depTreeCmd) Run(ctx context.Context, printer upterm.ObjectPrinter, m *manager.Manager, ws *workspace.Workspace) error {
+	deps, err := metaSuppliedDeps(ws)
+	if err != nil {
+		return err
+	}
+
+	g, err := m.ResolveGraph(ctx, deps)
+	if err != nil {
+		return err
+	}
+
+	tree := make([]depTreeNode, 0, len(deps))
+	for _, d := range deps {
+		n, ok := g.Node(d.Package)
+		if !ok {
+			continue
+		}
+		tree = append(tree, newDepTreeNode(n, d.Constraints, map[string]bool{}))
+	}
+
+	if printer.Format != config.Default {
+		return printer.Print(tree, treeFieldNames, nil)
+	}
+
+	rows := make([]depTreeRow, 0)
+	for _, n := range tree {
+		rows = flattenDepTree(rows, n, "", "")
+	}
+	return printer.Print(rows, treeFieldNames, extractTreeFields)
+}
+
+// newDepTreeNode builds the tree rooted at the supplied node. Packages that
+// are already part of the current path are not descended into again to guard
+// against cycles.
+func newDepTreeNode(n *manager.Node, constraints string, path map[string]bool) depTreeNode {
+	t := depTreeNode{
+		Package:     n.Package,
+		Type:        n.Type,
+		Version:     n.Version,
+		Constraints: constraints,
+	}
+	if path[n.Package] {
+		return t
+	}
+	path[n.Package] = true
+	defer delete(path, n.Package)
+
+	cs := make(map[string]string)
+	for _, d := range n.ParsedPackage().Dependencies() {
+		cs[d.Package] = d.Constraints
+	}
+	deps := append([]*manager.Node{}, n.Dependencies...)
+	sort.Slice(deps, func(i, j int) bool { return deps[i].Package < deps[j].Package })
+	for _, d := range deps {
+		t.Dependencies = append(t.Dependencies, newDepTreeNode(d, cs[d.Package], path))
+	}
+	return t
+}
+
+func flattenDepTree(rows []depTreeRow, n depTreeNode, prefix, childPrefix string) []depTreeRow {
+	rows = append(rows, depTreeRow{prefix: prefix, node: n})
+	for i, d := range n.Dependencies {
+		if i == len(n.Dependencies)-1 {
+			rows = flattenDepTree(rows, d, childPrefix+"└── ", childPrefix+"    ")
+			continue
+		}
+		rows = flattenDepTree(rows, d, childPrefix+"├── ", childPrefix+"│   ")
+	}
+	return rows
+}
+
+func extractTreeFields(obj any) []string {
+	r := obj.(depTreeRow)
+	return []string{r.prefix + r.node.Package, string(r.node.Type), r.node.Version, r.node.Constraints}
+}
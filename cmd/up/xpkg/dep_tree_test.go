@@ -0,0 +1,87 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xpkg
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFlattenDepTree(t *testing.T) {
+	type args struct {
+		node depTreeNode
+	}
+
+	type want struct {
+		packages []string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"SingleNode": {
+			reason: "A node without dependencies should be printed without a prefix.",
+			args: args{
+				node: depTreeNode{Package: "a"},
+			},
+			want: want{
+				packages: []string{"a"},
+			},
+		},
+		"Nested": {
+			reason: "Dependencies should be drawn below their parent with tree prefixes.",
+			args: args{
+				node: depTreeNode{
+					Package: "a",
+					Dependencies: []depTreeNode{
+						{
+							Package: "b",
+							Dependencies: []depTreeNode{
+								{Package: "d"},
+							},
+						},
+						{Package: "c"},
+					},
+				},
+			},
+			want: want{
+				packages: []string{
+					"a",
+					"├── b",
+					"│   └── d",
+					"└── c",
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rows := flattenDepTree(nil, tc.args.node, "", "")
+
+			got := make([]string, len(rows))
+			for i, r := range rows {
+				got[i] = extractTreeFields(r)[0]
+			}
+
+			if diff := cmp.Diff(tc.want.packages, got); diff != "" {
+				t.Errorf("\n%s\nflattenDepTree(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
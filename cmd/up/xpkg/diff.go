@@ -0,0 +1,168 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xpkg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/upbound/up/internal/upterm"
+	"github.com/upbound/up/internal/xpkg"
+	"github.com/upbound/up/internal/xpkg/dep"
+	mxpkg "github.com/upbound/up/internal/xpkg/dep/marshaler/xpkg"
+	"github.com/upbound/up/internal/xpkg/dep/resolver/image"
+	"github.com/upbound/up/internal/xpkg/diff"
+)
+
+const (
+	errLoadPackageFmt  = "failed to load package %s"
+	errBreakingFmt     = "found %d breaking change(s)"
+	errEmptyPackageFmt = "%s contains no package images"
+)
+
+// diffCmd compares the APIs of two packages.
+type diffCmd struct {
+	From string `arg:"" help:"Package to compare from, either a package file or a reference of the form source@version, where version may be a semver constraint."`
+	To   string `arg:"" help:"Package to compare to, either a package file or a reference of the form source@version, where version may be a semver constraint."`
+
+	FailOnBreaking bool `help:"Exit with a non-zero status if a breaking change is found."`
+}
+
+func (c *diffCmd) Help() string {
+	return `
+The diff command compares the APIs defined by the CRDs and XRDs of two
+versions of a package. It reports added and removed kinds and versions,
+versions that are no longer served, changed storage versions, and fields that
+were added, removed or became required. Removed kinds, versions and fields,
+versions that are no longer served and newly required fields are flagged as
+breaking.
+
+Packages are pulled from their registry or read from package files (.xpkg).
+
+Examples:
+
+  # Compare two versions of a provider.
+  up xpkg diff xpkg.upbound.io/upbound/provider-aws-s3@v1.0.0 xpkg.upbound.io/upbound/provider-aws-s3@v1.1.0
+
+  # Compare a released configuration with a local build and fail on
+  # breaking changes.
+  up xpkg diff xpkg.upbound.io/acme/configuration-db@v0.1.0 configuration-db.xpkg --fail-on-breaking
+
+  # Print the changes as JSON.
+  up xpkg diff provider-v1.xpkg provider-v2.xpkg --format json`
+}
+
+var diffFieldNames = []string{"KIND", "VERSION", "FIELD", "CHANGE", "BREAKING"}
+
+func extractDiffFields(obj any) []string {
+	c := obj.(diff.Change)
+	return []string{c.Kind, c.Version, c.Field, c.Message, strconv.FormatBool(c.Breaking)}
+}
+
+// Run executes the diff command.
+func (c *diffCmd) Run(ctx context.Context, printer upterm.ObjectPrinter) error {
+	m, err := mxpkg.NewMarshaler()
+	if err != nil {
+		return err
+	}
+	r := image.NewResolver()
+
+	from, err := loadPackage(ctx, r, m, c.From)
+	if err != nil {
+		return errors.Wrapf(err, errLoadPackageFmt, c.From)
+	}
+	to, err := loadPackage(ctx, r, m, c.To)
+	if err != nil {
+		return errors.Wrapf(err, errLoadPackageFmt, c.To)
+	}
+
+	changes, err := diff.Compare(from.Objects(), to.Objects())
+	if err != nil {
+		return err
+	}
+	if err := printer.Print(changes, diffFieldNames, extractDiffFields); err != nil {
+		return err
+	}
+	if n := diff.Breaking(changes); n > 0 && c.FailOnBreaking {
+		return errors.Errorf(errBreakingFmt, n)
+	}
+	return nil
+}
+
+// loadPackage parses the package in the supplied package file or, if no such
+// file exists, the package the supplied reference resolves to.
+func loadPackage(ctx context.Context, r *image.Resolver, m *mxpkg.Marshaler, ref string) (*mxpkg.ParsedPackage, error) {
+	if _, err := os.Stat(ref); err == nil {
+		return loadPackageFile(m, ref)
+	}
+
+	t, img, err := r.ResolveImage(ctx, dep.New(ref))
+	if err != nil {
+		return nil, err
+	}
+	return parseImage(m, img, t)
+}
+
+// loadPackageFile parses the package in the supplied package file. The
+// package contents are identical for every platform of a multi-platform
+// package, so the first image of its index is used.
+func loadPackageFile(m *mxpkg.Marshaler, path string) (*mxpkg.ParsedPackage, error) {
+	isIndex, err := xpkg.IsIndexFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !isIndex {
+		img, err := tarball.ImageFromPath(filepath.Clean(path), nil)
+		if err != nil {
+			return nil, err
+		}
+		return parseImage(m, img, "")
+	}
+
+	f, err := xpkg.ReadIndexFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() // nolint:errcheck
+
+	imgs, err := xpkg.IndexImages(f.Index)
+	if err != nil {
+		return nil, err
+	}
+	if len(imgs) == 0 {
+		return nil, errors.Errorf(errEmptyPackageFmt, path)
+	}
+	return parseImage(m, imgs[0], "")
+}
+
+func parseImage(m *mxpkg.Marshaler, img v1.Image, version string) (*mxpkg.ParsedPackage, error) {
+	d, err := img.Digest()
+	if err != nil {
+		return nil, err
+	}
+	return m.FromImage(xpkg.Image{
+		Meta: xpkg.ImageMeta{
+			Version: version,
+			Digest:  d.String(),
+		},
+		Image: img,
+	})
+}
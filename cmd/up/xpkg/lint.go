@@ -0,0 +1,435 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xpkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/alecthomas/kong"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/parser"
+	"github.com/golang/tools/lsp/protocol"
+	"github.com/golang/tools/span"
+	"github.com/spf13/afero"
+
+	"github.com/upbound/up/internal/config"
+	"github.com/upbound/up/internal/upterm"
+	"github.com/upbound/up/internal/xpkg"
+	"github.com/upbound/up/internal/xpkg/dep/cache"
+	"github.com/upbound/up/internal/xpkg/dep/manager"
+	"github.com/upbound/up/internal/xpkg/parser/yaml"
+	"github.com/upbound/up/internal/xpkg/snapshot"
+)
+
+const (
+	lintOutputText  = "text"
+	lintOutputJSON  = "json"
+	lintOutputSARIF = "sarif"
+
+	lintSeverityError   = "error"
+	lintSeverityWarning = "warning"
+	lintSeverityInfo    = "info"
+
+	// lintRulePackage identifies diagnostics of the package linters.
+	lintRulePackage = "package"
+	// lintRuleSchema identifies diagnostics of the snapshot validators.
+	lintRuleSchema = "schema"
+
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	errLintFailedFmt     = "found %d error(s)"
+	errLintFormatFmt     = "--format %s is not supported by lint, use --output text, json or sarif"
+	errLintOutputFmt     = "--format %s conflicts with --output %s"
+	errNotExactlyOneMeta = "not exactly one package meta type"
+	errUnknownPkgKind    = "unknown package kind"
+)
+
+// AfterApply constructs and binds context to any subcommands that have Run()
+// methods that receive it.
+func (c *lintCmd) AfterApply() error {
+	c.fs = afero.NewOsFs()
+
+	root, err := filepath.Abs(c.PackageRoot)
+	if err != nil {
+		return err
+	}
+	c.root = root
+	return nil
+}
+
+// lintCmd lints a package directory.
+type lintCmd struct {
+	fs   afero.Fs
+	root string
+
+	PackageRoot  string   `short:"f" help:"Path to package directory." default:"."`
+	ExamplesRoot string   `short:"e" help:"Path to package examples directory." default:"./examples"`
+	Ignore       []string `help:"Paths, specified relative to --package-root, to exclude from the package linters."`
+	CacheDir     string   `short:"d" help:"Directory used for caching package images." default:"~/.up/cache/" env:"CACHE_DIR" type:"path"`
+	Output       string   `short:"o" help:"Format of the diagnostics. One of: text, json, sarif. The global --format json implies json." default:"text" enum:"text,json,sarif"`
+}
+
+func (c *lintCmd) Help() string {
+	return `
+The lint command runs the package linters used by the build command and the
+validations of the Crossplane language server over a package directory. XRD
+schemas, composition patches, dependency versions and examples are validated
+against the schemas of the package and its dependencies. Dependencies are
+read from the cache only; run 'up xpkg dep' first to populate it.
+
+Diagnostics are printed with file, line and column as text, JSON or SARIF,
+e.g. to annotate pull requests in CI. The global --format json is honored,
+--format yaml is rejected. The command exits with a non-zero status if any
+error is found.
+
+Examples:
+
+  # Lint the package in the current directory.
+  up xpkg lint
+
+  # Write a SARIF report for code scanning.
+  up xpkg lint --output sarif > lint.sarif`
+}
+
+// lintDiagnostic is a single problem found by the lint command.
+type lintDiagnostic struct {
+	// File is the path of the file, relative to the package root.
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+	Severity  string `json:"severity"`
+	Rule      string `json:"rule"`
+	Message   string `json:"message"`
+}
+
+// lintReport is the JSON output of the lint command.
+type lintReport struct {
+	Diagnostics []lintDiagnostic `json:"diagnostics"`
+	Errors      int              `json:"errors"`
+	Warnings    int              `json:"warnings"`
+}
+
+// Run executes the lint command.
+func (c *lintCmd) Run(ctx context.Context, kongCtx *kong.Context, printer upterm.ObjectPrinter) error {
+	output, err := lintOutput(printer.Format, c.Output)
+	if err != nil {
+		return err
+	}
+
+	diags := c.lintPackage(ctx)
+
+	sdiags, err := c.validate(ctx)
+	if err != nil {
+		return err
+	}
+	diags = append(diags, sdiags...)
+	sortDiagnostics(diags)
+
+	r := newLintReport(diags)
+	if err := writeLintReport(kongCtx.Stdout, output, r); err != nil {
+		return err
+	}
+	if r.Errors > 0 {
+		return errors.Errorf(errLintFailedFmt, r.Errors)
+	}
+	return nil
+}
+
+// lintOutput returns the output format of the diagnostics. The global
+// --format json selects JSON output, other formats of get and list commands
+// are rejected rather than ignored.
+func lintOutput(format config.Format, output string) (string, error) {
+	switch format {
+	case config.Default:
+		return output, nil
+	case config.JSON:
+		if output != lintOutputText && output != lintOutputJSON {
+			return "", errors.Errorf(errLintOutputFmt, format, output)
+		}
+		return lintOutputJSON, nil
+	default:
+		return "", errors.Errorf(errLintFormatFmt, format)
+	}
+}
+
+// lintPackage runs the linters of the build command over the package.
+// Linter errors are reported on the meta file as they do not carry a
+// location.
+func (c *lintCmd) lintPackage(ctx context.Context) []lintDiagnostic {
+	diag := func(err error) []lintDiagnostic {
+		return []lintDiagnostic{{
+			File:      xpkg.MetaFile,
+			Line:      1,
+			Column:    1,
+			EndLine:   1,
+			EndColumn: 1,
+			Severity:  lintSeverityError,
+			Rule:      lintRulePackage,
+			Message:   err.Error(),
+		}}
+	}
+
+	pp, err := yaml.New()
+	if err != nil {
+		return diag(err)
+	}
+	be := parser.NewFsBackend(
+		c.fs,
+		parser.FsDir(c.root),
+		parser.FsFilters(append(buildFilters(c.root, c.Ignore), xpkg.SkipContains(c.ExamplesRoot))...),
+	)
+	r, err := be.Init(ctx)
+	if err != nil {
+		return diag(err)
+	}
+	defer r.Close() // nolint:errcheck
+
+	pkg, err := pp.Parse(ctx, r)
+	if err != nil {
+		return diag(err)
+	}
+	metas := pkg.GetMeta()
+	if len(metas) != 1 {
+		return diag(errors.New(errNotExactlyOneMeta))
+	}
+	l, ok := xpkg.LinterFor(metas[0])
+	if !ok {
+		return diag(errors.New(errUnknownPkgKind))
+	}
+	if err := l.Lint(pkg); err != nil {
+		return diag(err)
+	}
+	return nil
+}
+
+// validate runs the snapshot validations over every file of the package.
+func (c *lintCmd) validate(ctx context.Context) ([]lintDiagnostic, error) {
+	snap, err := newSnapshot(ctx, c.root, c.CacheDir)
+	if err != nil {
+		return nil, err
+	}
+	res, err := snap.ValidateAllFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return schemaDiagnostics(c.root, res), nil
+}
+
+// newSnapshot returns a snapshot of the package at the supplied root whose
+// dependencies are read from the cache only.
+func newSnapshot(ctx context.Context, root, cacheDir string) (*snapshot.Snapshot, error) {
+	local, err := cache.NewLocal(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	m, err := manager.New(manager.WithCache(local), manager.WithOffline())
+	if err != nil {
+		return nil, err
+	}
+	f, err := snapshot.NewFactory(root, snapshot.WithDepManager(m))
+	if err != nil {
+		return nil, err
+	}
+	return f.New(ctx)
+}
+
+// schemaDiagnostics converts the diagnostics of the snapshot validators to
+// lint diagnostics with 1-based positions and paths relative to the package
+// root.
+func schemaDiagnostics(root string, res map[span.URI][]protocol.Diagnostic) []lintDiagnostic {
+	diags := make([]lintDiagnostic, 0)
+	for uri, ds := range res {
+		file, err := filepath.Rel(root, uri.Filename())
+		if err != nil {
+			file = uri.Filename()
+		}
+		for _, d := range ds {
+			diags = append(diags, lintDiagnostic{
+				File:      filepath.ToSlash(file),
+				Line:      int(d.Range.Start.Line) + 1,
+				Column:    int(d.Range.Start.Character) + 1,
+				EndLine:   int(d.Range.End.Line) + 1,
+				EndColumn: int(d.Range.End.Character) + 1,
+				Severity:  lintSeverity(d.Severity),
+				Rule:      lintRuleSchema,
+				Message:   d.Message,
+			})
+		}
+	}
+	return diags
+}
+
+func lintSeverity(s protocol.DiagnosticSeverity) string {
+	switch s { // nolint:exhaustive
+	case protocol.SeverityError:
+		return lintSeverityError
+	case protocol.SeverityWarning:
+		return lintSeverityWarning
+	default:
+		return lintSeverityInfo
+	}
+}
+
+func sortDiagnostics(diags []lintDiagnostic) {
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].File != diags[j].File {
+			return diags[i].File < diags[j].File
+		}
+		if diags[i].Line != diags[j].Line {
+			return diags[i].Line < diags[j].Line
+		}
+		return diags[i].Column < diags[j].Column
+	})
+}
+
+func newLintReport(diags []lintDiagnostic) lintReport {
+	r := lintReport{Diagnostics: diags}
+	for _, d := range diags {
+		switch d.Severity {
+		case lintSeverityError:
+			r.Errors++
+		case lintSeverityWarning:
+			r.Warnings++
+		}
+	}
+	return r
+}
+
+func writeLintReport(w io.Writer, output string, r lintReport) error {
+	switch output {
+	case lintOutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case lintOutputSARIF:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(newSARIFLog(r.Diagnostics))
+	default:
+		for _, d := range r.Diagnostics {
+			if _, err := fmt.Fprintf(w, "%s:%d:%d: %s: %s\n", d.File, d.Line, d.Column, d.Severity, d.Message); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w, "%d error(s), %d warning(s)\n", r.Errors, r.Warnings)
+		return err
+	}
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+func newSARIFLog(diags []lintDiagnostic) sarifLog {
+	results := make([]sarifResult, len(diags))
+	for i, d := range diags {
+		level := d.Severity
+		if level == lintSeverityInfo {
+			level = "note"
+		}
+		results[i] = sarifResult{
+			RuleID:  d.Rule,
+			Level:   level,
+			Message: sarifMessage{Text: d.Message},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: d.File},
+					Region: sarifRegion{
+						StartLine:   d.Line,
+						StartColumn: d.Column,
+						EndLine:     d.EndLine,
+						EndColumn:   d.EndColumn,
+					},
+				},
+			}},
+		}
+	}
+	return sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "up xpkg lint",
+				InformationURI: "https://docs.upbound.io/reference/cli/",
+				Rules: []sarifRule{
+					{ID: lintRulePackage, ShortDescription: sarifMessage{Text: "Package contents do not pass the package linters."}},
+					{ID: lintRuleSchema, ShortDescription: sarifMessage{Text: "Object does not pass schema validation."}},
+				},
+			}},
+			Results: results,
+		}},
+	}
+}
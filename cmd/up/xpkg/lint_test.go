@@ -0,0 +1,163 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xpkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/upbound/up/internal/config"
+)
+
+func TestWriteLintReport(t *testing.T) {
+	diags := []lintDiagnostic{
+		{File: "xrd.yaml", Line: 12, Column: 9, EndLine: 12, EndColumn: 14, Severity: lintSeverityWarning, Rule: lintRuleSchema, Message: "deprecated"},
+		{File: "crossplane.yaml", Line: 7, Column: 17, EndLine: 7, EndColumn: 40, Severity: lintSeverityError, Rule: lintRuleSchema, Message: "invalid version"},
+		{File: "crossplane.yaml", Line: 1, Column: 1, EndLine: 1, EndColumn: 1, Severity: lintSeverityInfo, Rule: lintRulePackage, Message: "not cached"},
+	}
+	sortDiagnostics(diags)
+	r := newLintReport(diags)
+
+	type want struct {
+		out string
+	}
+
+	cases := map[string]struct {
+		reason string
+		output string
+		want   want
+	}{
+		"Text": {
+			reason: "Should print one line per diagnostic sorted by location, followed by a summary.",
+			output: lintOutputText,
+			want: want{
+				out: "crossplane.yaml:1:1: info: not cached\n" +
+					"crossplane.yaml:7:17: error: invalid version\n" +
+					"xrd.yaml:12:9: warning: deprecated\n" +
+					"1 error(s), 1 warning(s)\n",
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := writeLintReport(&b, tc.output, r); err != nil {
+				t.Fatalf("\n%s\nwriteLintReport(...): unexpected error: %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.out, b.String()); diff != "" {
+				t.Errorf("\n%s\nwriteLintReport(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSARIFLog(t *testing.T) {
+	diags := []lintDiagnostic{
+		{File: "crossplane.yaml", Line: 7, Column: 17, EndLine: 7, EndColumn: 40, Severity: lintSeverityError, Rule: lintRuleSchema, Message: "invalid version"},
+		{File: "crossplane.yaml", Line: 1, Column: 1, EndLine: 1, EndColumn: 1, Severity: lintSeverityInfo, Rule: lintRulePackage, Message: "not cached"},
+	}
+
+	var b bytes.Buffer
+	if err := writeLintReport(&b, lintOutputSARIF, newLintReport(diags)); err != nil {
+		t.Fatalf("writeLintReport(...): unexpected error: %s", err)
+	}
+	got := sarifLog{}
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("writeLintReport(...): invalid SARIF: %s", err)
+	}
+
+	want := []sarifResult{
+		{
+			RuleID:  lintRuleSchema,
+			Level:   "error",
+			Message: sarifMessage{Text: "invalid version"},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: "crossplane.yaml"},
+				Region:           sarifRegion{StartLine: 7, StartColumn: 17, EndLine: 7, EndColumn: 40},
+			}}},
+		},
+		{
+			RuleID:  lintRulePackage,
+			Level:   "note",
+			Message: sarifMessage{Text: "not cached"},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: "crossplane.yaml"},
+				Region:           sarifRegion{StartLine: 1, StartColumn: 1, EndLine: 1, EndColumn: 1},
+			}}},
+		},
+	}
+	if diff := cmp.Diff(sarifVersion, got.Version); diff != "" {
+		t.Errorf("writeLintReport(...): -want version, +got version:\n%s", diff)
+	}
+	if diff := cmp.Diff(want, got.Runs[0].Results); diff != "" {
+		t.Errorf("writeLintReport(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestLintOutput(t *testing.T) {
+	type args struct {
+		format config.Format
+		output string
+	}
+	type want struct {
+		output string
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Default": {
+			reason: "Should use --output if --format is not set.",
+			args:   args{format: config.Default, output: lintOutputSARIF},
+			want:   want{output: lintOutputSARIF},
+		},
+		"FormatJSON": {
+			reason: "Should print JSON for --format json.",
+			args:   args{format: config.JSON, output: lintOutputText},
+			want:   want{output: lintOutputJSON},
+		},
+		"ErrFormatYAML": {
+			reason: "Should reject --format yaml rather than print text.",
+			args:   args{format: config.YAML, output: lintOutputText},
+			want:   want{err: errors.Errorf(errLintFormatFmt, config.YAML)},
+		},
+		"ErrConflict": {
+			reason: "Should reject --format json together with another --output.",
+			args:   args{format: config.JSON, output: lintOutputSARIF},
+			want:   want{err: errors.Errorf(errLintOutputFmt, config.JSON, lintOutputSARIF)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			output, err := lintOutput(tc.args.format, tc.args.output)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nlintOutput(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.output, output); diff != "" {
+				t.Errorf("\n%s\nlintOutput(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
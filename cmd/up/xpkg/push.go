@@ -39,6 +39,7 @@ import (
 	"github.com/upbound/up/internal/credhelper"
 	"github.com/upbound/up/internal/upbound"
 	"github.com/upbound/up/internal/xpkg"
+	"github.com/upbound/up/internal/xpkg/attest"
 )
 
 const (
@@ -48,6 +49,7 @@ const (
 	errGetwd             = "failed to get working directory while searching for package"
 	errFindPackageinWd   = "failed to find a package in current working directory"
 	errBuildImage        = "failed to build image from layers"
+	errAttach            = "failed to attach attestations to pushed package"
 )
 
 // AfterApply constructs and binds Upbound-specific context to any subcommands
@@ -93,17 +95,76 @@ func (c *pushCmd) Run(ctx context.Context, p pterm.TextPrinter, upCtx *upbound.C
 	}
 
 	imgs := make([]v1.Image, 0, len(c.Package))
+	index := false
+	att := &attest.Attestations{}
+	// the images of multi-platform packages are read lazily from their
+	// extracted index, which therefore has to be kept until they are pushed.
+	var files []*xpkg.IndexFile
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
 	for _, p := range c.Package {
-		img, err := tarball.ImageFromPath(filepath.Clean(p), nil)
+		a, err := xpkg.ReadAttestations(p)
 		if err != nil {
 			return err
 		}
-		imgs = append(imgs, img)
+		att.Merge(a)
+
+		pimgs, f, err := readPackageImages(p)
+		if err != nil {
+			return err
+		}
+		if f != nil {
+			files = append(files, f)
+			index = true
+		}
+		imgs = append(imgs, pimgs...)
+	}
+	return pushImages(ctx, p, upCtx, imgs, c.Tag, c.Create, c.Flags.Profile, index || len(imgs) > 1, att)
+}
+
+// readPackageImages reads the images of the package file at the supplied
+// path. For multi-platform packages the images of its index are returned
+// along with the IndexFile they are read from, which must be closed once the
+// images are no longer used.
+func readPackageImages(path string) ([]v1.Image, *xpkg.IndexFile, error) {
+	isIndex, err := xpkg.IsIndexFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isIndex {
+		img, err := tarball.ImageFromPath(filepath.Clean(path), nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []v1.Image{img}, nil, nil
+	}
+
+	// multi-platform packages are pushed as an index of their images.
+	f, err := xpkg.ReadIndexFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	imgs, err := xpkg.IndexImages(f.Index)
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
 	}
-	return PushImages(ctx, p, upCtx, imgs, c.Tag, c.Create, c.Flags.Profile)
+	return imgs, f, nil
 }
 
-func PushImages(ctx context.Context, p pterm.TextPrinter, upCtx *upbound.Context, imgs []v1.Image, t string, create bool, profile string) error { //nolint:gocyclo
+// PushImages pushes the supplied images to the supplied tag. If more than one
+// image is supplied, the images are pushed by digest and an index referencing
+// them is pushed to the tag.
+func PushImages(ctx context.Context, p pterm.TextPrinter, upCtx *upbound.Context, imgs []v1.Image, t string, create bool, profile string) error {
+	return pushImages(ctx, p, upCtx, imgs, t, create, profile, len(imgs) > 1, nil)
+}
+
+// pushImages pushes the supplied images like PushImages and attaches the
+// supplied attestations, if any, to the pushed tag.
+func pushImages(ctx context.Context, p pterm.TextPrinter, upCtx *upbound.Context, imgs []v1.Image, t string, create bool, profile string, index bool, att *attest.Attestations) error { //nolint:gocyclo
 	tag, err := name.NewTag(t, name.WithDefaultRegistry(upCtx.RegistryEndpoint.Hostname()))
 	if err != nil {
 		return err
@@ -137,10 +198,11 @@ func PushImages(ctx context.Context, p pterm.TextPrinter, upCtx *upbound.Context
 	}
 
 	adds := make([]mutate.IndexAddendum, len(imgs))
+	digests := make([]v1.Hash, len(imgs))
 
 	// NOTE(hasheddan): the errgroup context is passed to each image write,
 	// meaning that if one fails it will cancel others that are in progress.
-	g, ctx := errgroup.WithContext(ctx)
+	g, gctx := errgroup.WithContext(ctx)
 	for i, img := range imgs {
 		// pin range variables for use in go func
 		i, img := i, img
@@ -150,9 +212,12 @@ func PushImages(ctx context.Context, p pterm.TextPrinter, upCtx *upbound.Context
 			if err != nil {
 				return err
 			}
+			if digests[i], err = aimg.Digest(); err != nil {
+				return err
+			}
 
 			var t name.Reference = tag
-			if len(imgs) > 1 {
+			if index {
 				d, err := aimg.Digest()
 				if err != nil {
 					return err
@@ -180,11 +245,12 @@ func PushImages(ctx context.Context, p pterm.TextPrinter, upCtx *upbound.Context
 							Architecture: conf.Architecture,
 							OS:           conf.OS,
 							OSVersion:    conf.OSVersion,
+							Variant:      conf.Variant,
 						},
 					},
 				}
 			}
-			if err := remote.Write(t, aimg, remote.WithAuthFromKeychain(kc), remote.WithContext(ctx)); err != nil {
+			if err := remote.Write(t, aimg, remote.WithAuthFromKeychain(kc), remote.WithContext(gctx)); err != nil {
 				return err
 			}
 			return nil
@@ -197,12 +263,26 @@ func PushImages(ctx context.Context, p pterm.TextPrinter, upCtx *upbound.Context
 	}
 
 	// If we pushed more than one xpkg then we need to write index.
-	if len(imgs) > 1 {
-		if err := remote.WriteIndex(tag, mutate.AppendManifests(empty.Index, adds...), remote.WithAuthFromKeychain(kc)); err != nil {
+	var pushed v1.Hash
+	if len(digests) > 0 {
+		pushed = digests[0]
+	}
+	if index {
+		idx := mutate.AppendManifests(empty.Index, adds...)
+		if err := remote.WriteIndex(tag, idx, remote.WithAuthFromKeychain(kc)); err != nil {
+			return err
+		}
+		if pushed, err = idx.Digest(); err != nil {
 			return err
 		}
 	}
 
+	if !att.Empty() {
+		if err := att.Attach(ctx, tag.Context().Digest(pushed.String()), remote.WithAuthFromKeychain(kc)); err != nil {
+			return errors.Wrap(err, errAttach)
+		}
+	}
+
 	p.Printfln("xpkg pushed to %s", tag.String())
 	return nil
 }
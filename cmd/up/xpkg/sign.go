@@ -0,0 +1,118 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xpkg
+
+import (
+	"context"
+
+	"github.com/alecthomas/kong"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/pterm/pterm"
+	"github.com/spf13/afero"
+
+	"github.com/upbound/up/internal/credhelper"
+	"github.com/upbound/up/internal/upbound"
+	"github.com/upbound/up/internal/xpkg/sign"
+)
+
+// AfterApply constructs and binds Upbound-specific context to any subcommands
+// that have Run() methods that receive it.
+func (c *signCmd) AfterApply(kongCtx *kong.Context) error {
+	c.fs = afero.NewOsFs()
+	upCtx, err := upbound.NewFromFlags(c.Flags)
+	if err != nil {
+		return err
+	}
+	kongCtx.Bind(upCtx)
+	upCtx.SetupLogging()
+
+	return nil
+}
+
+// signCmd signs a package in a registry.
+type signCmd struct {
+	fs afero.Fs
+
+	Tag     string `arg:"" help:"Reference of the package to sign. Tags are resolved to the digest they point to."`
+	Key     string `required:"" type:"path" help:"Path to the PEM encoded ECDSA private key used to sign the package."`
+	Storage string `default:"tag" enum:"tag,referrers" help:"Where to store the signature. One of: tag (sha256-<digest>.sig), referrers (OCI referrers of the package)."`
+
+	// Common Upbound API configuration
+	Flags upbound.Flags `embed:""`
+}
+
+func (c *signCmd) Help() string {
+	return `
+Sign a package that has been pushed to a registry. The signature is
+compatible with cosign key-pair signatures and is stored next to the package,
+either in an image tagged after the package digest or as an OCI referrer of
+the package.
+
+Keys are PEM encoded, unencrypted ECDSA keys, e.g. generated with:
+
+  openssl ecparam -name prime256v1 -genkey -noout | openssl pkcs8 -topk8 -nocrypt -out key.pem
+  openssl ec -in key.pem -pubout -out key.pub
+
+Examples:
+
+  # Sign a package.
+  up xpkg sign xpkg.upbound.io/my-org/my-config:v1.0.0 --key key.pem
+
+  # Sign a package and store the signature as an OCI referrer.
+  up xpkg sign xpkg.upbound.io/my-org/my-config:v1.0.0 --key key.pem --storage referrers`
+}
+
+// Run runs the sign cmd.
+func (c *signCmd) Run(ctx context.Context, p pterm.TextPrinter, upCtx *upbound.Context) error {
+	key, err := sign.LoadPrivateKey(c.fs, c.Key)
+	if err != nil {
+		return err
+	}
+	ref, err := name.ParseReference(c.Tag, name.WithDefaultRegistry(upCtx.RegistryEndpoint.Hostname()))
+	if err != nil {
+		return err
+	}
+
+	opts := []sign.Option{
+		sign.WithStorage(sign.Storage(c.Storage)),
+		sign.WithRemoteOptions(remote.WithAuthFromKeychain(keychain(upCtx))),
+	}
+	d, err := sign.ResolveDigest(ctx, ref, opts...)
+	if err != nil {
+		return err
+	}
+	if err := sign.Sign(ctx, d, key, opts...); err != nil {
+		return err
+	}
+
+	p.Printfln("xpkg %s signed", d.String())
+	return nil
+}
+
+// keychain returns the keychain used to access package registries, preferring
+// the credentials of the current Upbound profile.
+func keychain(upCtx *upbound.Context) authn.Keychain {
+	return authn.NewMultiKeychain(
+		authn.NewKeychainFromHelper(
+			credhelper.New(
+				credhelper.WithDomain(upCtx.Domain.Hostname()),
+				credhelper.WithProfile(upCtx.ProfileName),
+			),
+		),
+		authn.DefaultKeychain,
+	)
+}
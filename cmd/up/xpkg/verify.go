@@ -0,0 +1,96 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xpkg
+
+import (
+	"context"
+
+	"github.com/alecthomas/kong"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/pterm/pterm"
+	"github.com/spf13/afero"
+
+	"github.com/upbound/up/internal/upbound"
+	"github.com/upbound/up/internal/xpkg/sign"
+)
+
+// AfterApply constructs and binds Upbound-specific context to any subcommands
+// that have Run() methods that receive it.
+func (c *verifyCmd) AfterApply(kongCtx *kong.Context) error {
+	c.fs = afero.NewOsFs()
+	upCtx, err := upbound.NewFromFlags(c.Flags)
+	if err != nil {
+		return err
+	}
+	kongCtx.Bind(upCtx)
+	upCtx.SetupLogging()
+
+	return nil
+}
+
+// verifyCmd verifies the signature of a package in a registry.
+type verifyCmd struct {
+	fs afero.Fs
+
+	Tag string `arg:"" help:"Reference of the package to verify. Tags are resolved to the digest they point to."`
+	Key string `required:"" type:"path" help:"Path to the PEM encoded ECDSA public key to verify the package signature against."`
+
+	// Common Upbound API configuration
+	Flags upbound.Flags `embed:""`
+}
+
+func (c *verifyCmd) Help() string {
+	return `
+Verify that a package in a registry carries a signature made with the private
+key belonging to the supplied public key. Signatures stored by tag and as OCI
+referrers are both considered. The command fails if no valid signature is
+found.
+
+Examples:
+
+  # Verify a package.
+  up xpkg verify xpkg.upbound.io/my-org/my-config:v1.0.0 --key key.pub`
+}
+
+// Run runs the verify cmd.
+func (c *verifyCmd) Run(ctx context.Context, p pterm.TextPrinter, upCtx *upbound.Context) error {
+	key, err := sign.LoadPublicKey(c.fs, c.Key)
+	if err != nil {
+		return err
+	}
+	ref, err := name.ParseReference(c.Tag, name.WithDefaultRegistry(upCtx.RegistryEndpoint.Hostname()))
+	if err != nil {
+		return err
+	}
+
+	opts := []sign.Option{
+		sign.WithRemoteOptions(remote.WithAuthFromKeychain(keychain(upCtx))),
+	}
+	d, err := sign.ResolveDigest(ctx, ref, opts...)
+	if err != nil {
+		return err
+	}
+	sigs, err := sign.Verify(ctx, d, key, opts...)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sigs {
+		p.Printfln("verified signature stored by %s", s.Storage)
+	}
+	p.Printfln("xpkg %s verified", d.String())
+	return nil
+}
@@ -30,8 +30,13 @@ type Cmd struct {
 	Build     buildCmd     `cmd:"" help:"Build a package, by default from the current directory."`
 	XPExtract xpExtractCmd `cmd:"" maturity:"alpha" help:"Extract package contents into a Crossplane cache compatible format. Fetches from a remote registry by default."`
 	Init      initCmd      `cmd:"" help:"Initialize a package, by default in the current directory."`
+	Lint      lintCmd      `cmd:"" help:"Lint a package directory, by default the current directory."`
+	Diff      diffCmd      `cmd:"" help:"Compare the APIs of two versions of a package."`
 	Dep       depCmd       `cmd:"" help:"Manage package dependencies in the filesystem and populate the cache, e.g. used by the Crossplane Language Server."`
+	Cache     cacheCmd     `cmd:"" help:"Inspect and manage the package dependency cache."`
 	Push      pushCmd      `cmd:"" help:"Push a package."`
+	Sign      signCmd      `cmd:"" help:"Sign a package in a registry."`
+	Verify    verifyCmd    `cmd:"" help:"Verify the signature of a package in a registry."`
 	Batch     batchCmd     `cmd:"" maturity:"alpha" help:"Batch build and push a family of service-scoped provider packages."`
 }
 
@@ -16,13 +16,23 @@ package xpls
 
 import (
 	"context"
+	"net"
 
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	"github.com/sourcegraph/jsonrpc2"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	"github.com/upbound/up/internal/xpls"
 	"github.com/upbound/up/internal/xpls/handler"
+	"github.com/upbound/up/internal/xpls/server"
+)
+
+const (
+	errExitWithoutShutdown = "client exited without requesting a shutdown"
+	errListen              = "failed to listen for connections"
+	errAccept              = "failed to accept connection"
+	errHandler             = "failed to create handler for connection"
 )
 
 // serveCmd starts the language server.
@@ -33,6 +43,8 @@ type serveCmd struct {
 	// this to the config.
 	Cache   string `default:"~/.up/cache" help:"Directory path for dependency schema cache." type:"path"`
 	Verbose bool   `help:"Run server with verbose logging."`
+	Offline bool   `help:"Resolve dependencies against the cache only and do not check for updates." env:"UP_XPKG_OFFLINE"`
+	Listen  string `help:"Serve clients connecting to the given TCP address, e.g. localhost:9999, instead of a single client over stdio. Clients share the dependency cache."`
 }
 
 // Run runs the language server.
@@ -42,14 +54,74 @@ func (c *serveCmd) Run(ctx context.Context) error {
 
 	// TODO(hasheddan): move to AfterApply.
 	zl := zap.New(zap.UseDevMode(c.Verbose))
-	h, err := handler.New(
-		handler.WithLogger(logging.NewLogrLogger(zl.WithName("xpls"))),
-	)
+	log := logging.NewLogrLogger(zl.WithName("xpls"))
+	opts := []handler.Option{
+		handler.WithLogger(log),
+	}
+	if c.Offline {
+		opts = append(opts, handler.WithOffline())
+	}
+
+	if c.Listen != "" {
+		return c.serveTCP(ctx, log, opts)
+	}
+
+	h, err := handler.New(opts...)
 	if err != nil {
 		return err
 	}
 
-	// TODO(hasheddan): handle graceful shutdown.
 	<-jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(xpls.StdRWC{}, jsonrpc2.VSCodeObjectCodec{}), h).DisconnectNotify()
+	h.Close()
+	if !h.ShutdownRequested() {
+		return errors.New(errExitWithoutShutdown)
+	}
 	return nil
 }
+
+// serveTCP serves every client connecting to the listen address until the
+// supplied context is done. Each connection has its own lifecycle and
+// dependency manager, but the dependency cache is shared so that it only
+// needs to be warmed up once.
+func (c *serveCmd) serveTCP(ctx context.Context, log logging.Logger, opts []handler.Option) error {
+	sh, err := server.NewShared(log, c.Offline)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, handler.WithShared(sh))
+
+	lis, err := (&net.ListenConfig{}).Listen(ctx, "tcp", c.Listen)
+	if err != nil {
+		return errors.Wrap(err, errListen)
+	}
+	go func() {
+		<-ctx.Done()
+		_ = lis.Close()
+	}()
+	log.Info("Listening for connections", "address", lis.Addr().String())
+
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, errAccept)
+		}
+
+		h, err := handler.New(opts...)
+		if err != nil {
+			log.Info(errHandler, "error", err)
+			_ = conn.Close()
+			continue
+		}
+		log.Debug("Accepted connection", "address", conn.RemoteAddr().String())
+		jc := jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(conn, jsonrpc2.VSCodeObjectCodec{}), h)
+		go func() {
+			// a client may drop the connection without a shutdown, which
+			// must still stop the work started for it.
+			<-jc.DisconnectNotify()
+			h.Close()
+		}()
+	}
+}
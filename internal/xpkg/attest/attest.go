@@ -0,0 +1,183 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package attest generates SBOMs and build provenance for Crossplane packages
+// and attaches them to packages in OCI registries as in-toto attestations.
+package attest
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+const (
+	// File is the name of the file holding the attestations of a package in
+	// a package file.
+	File = "attestations.json"
+
+	// StatementType is the type of an in-toto statement.
+	StatementType = "https://in-toto.io/Statement/v1"
+	// ArtifactType is the artifact type of attestations attached to a
+	// package as OCI referrers.
+	ArtifactType = "application/vnd.in-toto+json"
+	// PredicateTypeAnnotation is the layer annotation holding the predicate
+	// type of an attached statement.
+	PredicateTypeAnnotation = "in-toto.io/predicate-type"
+
+	errMarshalStatement = "failed to marshal attestation statement"
+	errAttachFmt        = "failed to attach %s attestation"
+)
+
+// Attestations are the SBOM and provenance recorded while building a package.
+type Attestations struct {
+	// SBOMFormat is the format of the SBOM, if one was generated.
+	SBOMFormat SBOMFormat `json:"sbomFormat,omitempty"`
+	// SBOM is the SBOM document, if one was generated.
+	SBOM json.RawMessage `json:"sbom,omitempty"`
+	// Provenance is the provenance of the build, if it was recorded.
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// Empty returns true if the Attestations hold neither SBOM nor provenance.
+func (a *Attestations) Empty() bool {
+	return a == nil || (len(a.SBOM) == 0 && a.Provenance == nil)
+}
+
+// Merge merges the supplied Attestations, e.g. of the package built for
+// another platform, into these Attestations. The SBOM of these Attestations
+// is kept if one exists. The resolved dependencies of the provenance are
+// combined.
+func (a *Attestations) Merge(o *Attestations) {
+	if o.Empty() {
+		return
+	}
+	if len(a.SBOM) == 0 {
+		a.SBOMFormat = o.SBOMFormat
+		a.SBOM = o.SBOM
+	}
+	if o.Provenance == nil {
+		return
+	}
+	if a.Provenance == nil {
+		p := *o.Provenance
+		a.Provenance = &p
+		return
+	}
+	a.Provenance.merge(o.Provenance)
+}
+
+// Statement is an in-toto statement.
+type Statement struct {
+	Type          string    `json:"_type"`
+	Subject       []Subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     any       `json:"predicate"`
+}
+
+// Subject is the artifact an in-toto statement is about.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Statements returns the in-toto statements of the Attestations about the
+// package at the supplied digest.
+func (a *Attestations) Statements(d name.Digest) ([]Statement, error) {
+	h, err := v1.NewHash(d.DigestStr())
+	if err != nil {
+		return nil, err
+	}
+	sub := []Subject{{
+		Name:   d.Context().Name(),
+		Digest: map[string]string{h.Algorithm: h.Hex},
+	}}
+
+	stmts := make([]Statement, 0, 2)
+	if len(a.SBOM) > 0 {
+		stmts = append(stmts, Statement{
+			Type:          StatementType,
+			Subject:       sub,
+			PredicateType: a.SBOMFormat.PredicateType(),
+			Predicate:     a.SBOM,
+		})
+	}
+	if a.Provenance != nil {
+		stmts = append(stmts, Statement{
+			Type:          StatementType,
+			Subject:       sub,
+			PredicateType: ProvenancePredicateType,
+			Predicate:     a.Provenance,
+		})
+	}
+	return stmts, nil
+}
+
+// Attach attaches the Attestations to the package at the supplied digest.
+// Every statement is pushed as an OCI referrer of the package.
+func (a *Attestations) Attach(ctx context.Context, d name.Digest, opts ...remote.Option) error {
+	if a.Empty() {
+		return nil
+	}
+	opts = append([]remote.Option{remote.WithContext(ctx)}, opts...)
+
+	desc, err := remote.Head(d, opts...)
+	if err != nil {
+		return err
+	}
+	stmts, err := a.Statements(d)
+	if err != nil {
+		return err
+	}
+	for _, s := range stmts {
+		if err := attach(d, *desc, s, opts); err != nil {
+			return errors.Wrapf(err, errAttachFmt, s.PredicateType)
+		}
+	}
+	return nil
+}
+
+func attach(d name.Digest, subject v1.Descriptor, s Statement, opts []remote.Option) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return errors.Wrap(err, errMarshalStatement)
+	}
+
+	img := mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+	img = mutate.ConfigMediaType(img, ArtifactType)
+	img, err = mutate.Append(img, mutate.Addendum{
+		Layer: static.NewLayer(b, ArtifactType),
+		Annotations: map[string]string{
+			PredicateTypeAnnotation: s.PredicateType,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	img = mutate.Subject(img, subject).(v1.Image)
+
+	dig, err := img.Digest()
+	if err != nil {
+		return err
+	}
+	return remote.Write(d.Context().Digest(dig.String()), img, opts...)
+}
@@ -0,0 +1,234 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/spf13/afero"
+)
+
+var (
+	controllerDigest = "sha256:d507e508234732c6dc95d29c8a8c932fa8fa6a229231e309927641f99933892e"
+	awsDigest        = "sha256:d507e508234732c6dc95d29c8a8c932fa8fa6a229231e309927077099933707"
+
+	pkg = Package{
+		Name: "provider-foo",
+		Kind: "Provider",
+		Objects: []Object{
+			{APIVersion: "apiextensions.k8s.io/v1", Kind: "CustomResourceDefinition", Name: "buckets.foo.crossplane.io"},
+		},
+		Dependencies: []Dependency{
+			{Package: "xpkg.upbound.io/upbound/provider-aws", Type: "Provider", Constraints: ">=v0.38.0"},
+		},
+		Controller: &Image{Reference: "provider-foo-controller:v0.1.0", Digest: controllerDigest},
+	}
+)
+
+func TestNewSBOM(t *testing.T) {
+	type want struct {
+		names []string
+	}
+
+	cases := map[string]struct {
+		reason string
+		format SBOMFormat
+		want   want
+	}{
+		"SPDX": {
+			reason: "Should list the package, its objects, dependencies and controller image.",
+			format: SBOMFormatSPDX,
+			want: want{
+				names: []string{"provider-foo", "buckets.foo.crossplane.io", "xpkg.upbound.io/upbound/provider-aws", "provider-foo-controller:v0.1.0"},
+			},
+		},
+		"CycloneDX": {
+			reason: "Should list the objects, dependencies and controller image as components.",
+			format: SBOMFormatCycloneDX,
+			want: want{
+				names: []string{"buckets.foo.crossplane.io", "xpkg.upbound.io/upbound/provider-aws", "provider-foo-controller:v0.1.0"},
+			},
+		},
+	}
+
+	for n, tc := range cases {
+		t.Run(n, func(t *testing.T) {
+			b, err := NewSBOM(tc.format, pkg, time.Now())
+			if err != nil {
+				t.Fatalf("\n%s\nNewSBOM(...): unexpected error: %s", tc.reason, err)
+			}
+
+			doc := struct {
+				Packages   []struct{ Name string } `json:"packages"`
+				Components []struct{ Name string } `json:"components"`
+			}{}
+			if err := json.Unmarshal(b, &doc); err != nil {
+				t.Fatalf("\n%s\nNewSBOM(...): invalid JSON: %s", tc.reason, err)
+			}
+			var got []string
+			for _, p := range doc.Packages {
+				got = append(got, p.Name)
+			}
+			for _, c := range doc.Components {
+				got = append(got, c.Name)
+			}
+			if diff := cmp.Diff(tc.want.names, got); diff != "" {
+				t.Errorf("\n%s\nNewSBOM(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	c := Config{
+		Provenance: true,
+		Source: Source{
+			URI:    "file:///ws",
+			Digest: "sha256:abc",
+			Locked: map[string]string{"xpkg.upbound.io/upbound/provider-aws": awsDigest},
+		},
+	}
+	a, err := Generate(c, pkg, time.Now())
+	if err != nil {
+		t.Fatalf("Generate(...): unexpected error: %s", err)
+	}
+	if len(a.SBOM) != 0 {
+		t.Errorf("Generate(...): unexpected SBOM")
+	}
+
+	want := []ResourceDescriptor{
+		{URI: "file:///ws", Name: "source", Digest: map[string]string{"sha256": "abc"}},
+		{URI: "oci://provider-foo-controller:v0.1.0", Name: "controller", Digest: map[string]string{"sha256": controllerDigest[7:]}},
+		{URI: "oci://xpkg.upbound.io/upbound/provider-aws", Digest: map[string]string{"sha256": awsDigest[7:]}},
+	}
+	if diff := cmp.Diff(want, a.Provenance.BuildDefinition.ResolvedDependencies); diff != "" {
+		t.Errorf("Generate(...): -want, +got:\n%s", diff)
+	}
+	if pkg.Dependencies[0].Digest != "" {
+		t.Errorf("Generate(...): modified the supplied package")
+	}
+}
+
+func TestDirDigest(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "/ws/crossplane.yaml", []byte("kind: Provider"), 0o600)
+	_ = afero.WriteFile(fs, "/ws/crds/bucket.yaml", []byte("kind: CustomResourceDefinition"), 0o600)
+
+	d1, err := DirDigest(fs, "/ws")
+	if err != nil {
+		t.Fatalf("DirDigest(...): unexpected error: %s", err)
+	}
+
+	// package files and VCS metadata do not change the digest.
+	_ = afero.WriteFile(fs, "/ws/provider-foo.xpkg", []byte("package"), 0o600)
+	_ = afero.WriteFile(fs, "/ws/.git/HEAD", []byte("ref"), 0o600)
+	d2, _ := DirDigest(fs, "/ws")
+	if diff := cmp.Diff(d1, d2); diff != "" {
+		t.Errorf("DirDigest(...): -want, +got:\n%s", diff)
+	}
+
+	_ = afero.WriteFile(fs, "/ws/crds/bucket.yaml", []byte("kind: CompositeResourceDefinition"), 0o600)
+	d3, _ := DirDigest(fs, "/ws")
+	if d1 == d3 {
+		t.Errorf("DirDigest(...): expected digest to change with file contents")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	amd := NewProvenance(Source{URI: "file:///ws"}, Package{Controller: &Image{Reference: "c:amd64", Digest: "sha256:1"}}, time.Now(), time.Now())
+	arm := NewProvenance(Source{URI: "file:///ws"}, Package{Controller: &Image{Reference: "c:arm64", Digest: "sha256:2"}}, time.Now(), time.Now())
+
+	a := &Attestations{}
+	a.Merge(&Attestations{SBOMFormat: SBOMFormatSPDX, SBOM: []byte(`{}`), Provenance: amd})
+	a.Merge(&Attestations{SBOMFormat: SBOMFormatCycloneDX, SBOM: []byte(`[]`), Provenance: arm})
+
+	if diff := cmp.Diff(SBOMFormatSPDX, a.SBOMFormat); diff != "" {
+		t.Errorf("Merge(...): -want, +got:\n%s", diff)
+	}
+	var got []string
+	for _, d := range a.Provenance.BuildDefinition.ResolvedDependencies {
+		got = append(got, d.URI)
+	}
+	want := []string{"file:///ws", "oci://c:amd64", "oci://c:arm64"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Merge(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestAttach(t *testing.T) {
+	srv := httptest.NewServer(registry.New())
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	tag, _ := name.NewTag(u.Host + "/crossplane/provider-foo:v0.1.0")
+	img, _ := random.Image(128, 1)
+	if err := remote.Write(tag, img); err != nil {
+		t.Fatal(err)
+	}
+	dig, _ := img.Digest()
+	d := tag.Context().Digest(dig.String())
+
+	sbom, _ := NewSBOM(SBOMFormatSPDX, pkg, time.Now())
+	a := &Attestations{
+		SBOMFormat: SBOMFormatSPDX,
+		SBOM:       sbom,
+		Provenance: NewProvenance(Source{URI: "file:///ws"}, pkg, time.Now(), time.Now()),
+	}
+	if err := a.Attach(context.Background(), d); err != nil {
+		t.Fatalf("Attach(...): unexpected error: %s", err)
+	}
+
+	idx, err := remote.Referrers(d)
+	if err != nil {
+		t.Fatalf("Referrers(...): unexpected error: %s", err)
+	}
+	m, _ := idx.IndexManifest()
+	got := make([]string, 0, len(m.Manifests))
+	for _, desc := range m.Manifests {
+		r, err := remote.Image(d.Context().Digest(desc.Digest.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rm, _ := r.Manifest()
+		got = append(got, rm.Layers[0].Annotations[PredicateTypeAnnotation])
+
+		l, _ := r.LayerByDigest(rm.Layers[0].Digest)
+		rc, _ := l.Compressed()
+		s := Statement{}
+		if err := json.NewDecoder(rc).Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+		_ = rc.Close()
+		if diff := cmp.Diff(dig.Hex, s.Subject[0].Digest["sha256"]); diff != "" {
+			t.Errorf("Attach(...): -want subject, +got subject:\n%s", diff)
+		}
+	}
+	sort.Strings(got)
+	want := []string{"https://slsa.dev/provenance/v1", "https://spdx.dev/Document"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Attach(...): -want, +got:\n%s", diff)
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/upbound/up/internal/version"
+)
+
+const (
+	// ProvenancePredicateType is the in-toto predicate type of a build
+	// provenance statement.
+	ProvenancePredicateType = "https://slsa.dev/provenance/v1"
+	// BuildType is the SLSA build type of package builds.
+	BuildType = "https://upbound.io/xpkg/build@v1"
+
+	builderIDFmt = "https://github.com/upbound/up@%s"
+	ociURIFmt    = "oci://%s"
+)
+
+// Config configures the attestations generated for a build.
+type Config struct {
+	// SBOM is the format of the SBOM to generate. No SBOM is generated if
+	// empty.
+	SBOM SBOMFormat
+	// Provenance records the provenance of the build if true.
+	Provenance bool
+	// Source is the source the package is built from.
+	Source Source
+	// Controller is the reference of the controller image the package is
+	// built on, if any.
+	Controller string
+}
+
+// Enabled returns true if any attestation is requested.
+func (c Config) Enabled() bool {
+	return c.SBOM != "" || c.Provenance
+}
+
+// Source is the source a package is built from.
+type Source struct {
+	// URI identifies the source, e.g. the package directory.
+	URI string
+	// Digest is the digest of the source directory as returned by
+	// DirDigest.
+	Digest string
+	// Locked maps dependencies to the digests they are locked to.
+	Locked map[string]string
+}
+
+// Provenance is a SLSA v1 provenance predicate.
+type Provenance struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+// BuildDefinition describes the inputs of a build.
+type BuildDefinition struct {
+	BuildType            string               `json:"buildType"`
+	ExternalParameters   ExternalParameters   `json:"externalParameters"`
+	ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+// ExternalParameters are the parameters of a build.
+type ExternalParameters struct {
+	// Source identifies the source the package was built from.
+	Source string `json:"source"`
+	// Package is the name of the built package.
+	Package string `json:"package"`
+	// Dependencies are the dependencies declared in crossplane.yaml.
+	Dependencies []Dependency `json:"dependencies,omitempty"`
+}
+
+// ResourceDescriptor describes an artifact used by a build.
+type ResourceDescriptor struct {
+	URI    string            `json:"uri"`
+	Name   string            `json:"name,omitempty"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+// RunDetails describes the run of a build.
+type RunDetails struct {
+	Builder  BuilderID     `json:"builder"`
+	Metadata BuildMetadata `json:"metadata"`
+}
+
+// BuilderID identifies the builder.
+type BuilderID struct {
+	ID string `json:"id"`
+}
+
+// BuildMetadata holds the timing of a build.
+type BuildMetadata struct {
+	StartedOn  time.Time `json:"startedOn"`
+	FinishedOn time.Time `json:"finishedOn"`
+}
+
+// NewProvenance returns the provenance of a build of the supplied package
+// from the supplied source.
+func NewProvenance(src Source, p Package, started, finished time.Time) *Provenance {
+	deps := []ResourceDescriptor{{
+		URI:    src.URI,
+		Name:   "source",
+		Digest: digestMap(src.Digest),
+	}}
+	if p.Controller != nil {
+		deps = append(deps, ResourceDescriptor{
+			URI:    fmt.Sprintf(ociURIFmt, p.Controller.Reference),
+			Name:   "controller",
+			Digest: digestMap(p.Controller.Digest),
+		})
+	}
+	for _, d := range p.Dependencies {
+		if d.Digest == "" {
+			continue
+		}
+		deps = append(deps, ResourceDescriptor{
+			URI:    fmt.Sprintf(ociURIFmt, d.Package),
+			Digest: digestMap(d.Digest),
+		})
+	}
+
+	return &Provenance{
+		BuildDefinition: BuildDefinition{
+			BuildType: BuildType,
+			ExternalParameters: ExternalParameters{
+				Source:       src.URI,
+				Package:      p.Name,
+				Dependencies: p.Dependencies,
+			},
+			ResolvedDependencies: deps,
+		},
+		RunDetails: RunDetails{
+			Builder: BuilderID{ID: fmt.Sprintf(builderIDFmt, version.Version())},
+			Metadata: BuildMetadata{
+				StartedOn:  started.UTC(),
+				FinishedOn: finished.UTC(),
+			},
+		},
+	}
+}
+
+// merge adds the resolved dependencies of the supplied provenance that are
+// missing from this provenance and widens the build time range.
+func (p *Provenance) merge(o *Provenance) {
+	seen := make(map[string]bool, len(p.BuildDefinition.ResolvedDependencies))
+	for _, d := range p.BuildDefinition.ResolvedDependencies {
+		seen[d.URI+digestString(d.Digest)] = true
+	}
+	for _, d := range o.BuildDefinition.ResolvedDependencies {
+		if !seen[d.URI+digestString(d.Digest)] {
+			p.BuildDefinition.ResolvedDependencies = append(p.BuildDefinition.ResolvedDependencies, d)
+		}
+	}
+	if o.RunDetails.Metadata.StartedOn.Before(p.RunDetails.Metadata.StartedOn) {
+		p.RunDetails.Metadata.StartedOn = o.RunDetails.Metadata.StartedOn
+	}
+	if o.RunDetails.Metadata.FinishedOn.After(p.RunDetails.Metadata.FinishedOn) {
+		p.RunDetails.Metadata.FinishedOn = o.RunDetails.Metadata.FinishedOn
+	}
+}
+
+// Generate returns the attestations requested by the supplied Config for a
+// build of the supplied package.
+func Generate(c Config, p Package, started time.Time) (*Attestations, error) {
+	now := time.Now()
+	deps := make([]Dependency, len(p.Dependencies))
+	for i, d := range p.Dependencies {
+		if dg, ok := c.Source.Locked[d.Package]; ok {
+			d.Digest = dg
+		}
+		deps[i] = d
+	}
+	p.Dependencies = deps
+
+	a := &Attestations{}
+	if c.SBOM != "" {
+		sbom, err := NewSBOM(c.SBOM, p, now)
+		if err != nil {
+			return nil, err
+		}
+		a.SBOMFormat = c.SBOM
+		a.SBOM = sbom
+	}
+	if c.Provenance {
+		a.Provenance = NewProvenance(c.Source, p, started, now)
+	}
+	return a, nil
+}
+
+// DirDigest returns the digest of the files below the supplied directory. It
+// is the sha256 of the sha256sum style listing of every regular file, sorted
+// by path. Package files (.xpkg) and VCS metadata are skipped, as they do
+// not contribute to the package.
+func DirDigest(fs afero.Fs, root string) (string, error) {
+	files := make([]string, 0)
+	err := afero.Walk(fs, root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || filepath.Ext(path) == ".xpkg" {
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	for _, f := range files {
+		fh, err := fileDigest(fs, f)
+		if err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(root, f)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s  %s\n", fh, filepath.ToSlash(rel))
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func fileDigest(fs afero.Fs, path string) (string, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close() // nolint:errcheck
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// digestMap returns an in-toto digest set for a digest, e.g.
+// {"sha256": "..."}.
+func digestMap(digest string) map[string]string {
+	alg, hex, ok := strings.Cut(digest, ":")
+	if !ok {
+		return nil
+	}
+	return map[string]string{alg: hex}
+}
+
+func digestString(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s:%s;", k, m[k])
+	}
+	return b.String()
+}
@@ -0,0 +1,390 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/google/uuid"
+
+	"github.com/upbound/up/internal/version"
+)
+
+const (
+	spdxVersion      = "SPDX-2.3"
+	spdxNamespaceFmt = "https://spdx.upbound.io/xpkg/%s-%s"
+	spdxNoAssertion  = "NOASSERTION"
+	cycloneDXVersion = "1.5"
+	toolName         = "up"
+
+	errUnknownSBOMFormatFmt = "unknown SBOM format %q"
+	errMarshalSBOM          = "failed to marshal SBOM"
+)
+
+// SBOMFormat is the format of an SBOM.
+type SBOMFormat string
+
+const (
+	// SBOMFormatSPDX is the SPDX 2.3 JSON format.
+	SBOMFormatSPDX SBOMFormat = "spdx"
+	// SBOMFormatCycloneDX is the CycloneDX 1.5 JSON format.
+	SBOMFormatCycloneDX SBOMFormat = "cyclonedx"
+)
+
+// PredicateType returns the in-toto predicate type of an SBOM in the format.
+func (f SBOMFormat) PredicateType() string {
+	switch f {
+	case SBOMFormatCycloneDX:
+		return "https://cyclonedx.org/bom"
+	default:
+		return "https://spdx.dev/Document"
+	}
+}
+
+// Package is the inventory of a built Crossplane package.
+type Package struct {
+	// Name is the name of the package in its meta file.
+	Name string
+	// Kind is the kind of the package, e.g. Provider.
+	Kind string
+	// Objects are the objects packaged, e.g. CRDs, XRDs and Compositions.
+	Objects []Object
+	// Dependencies are the dependencies declared in the meta file.
+	Dependencies []Dependency
+	// Controller is the controller image the package was built on, if any.
+	Controller *Image
+}
+
+// Object is a Kubernetes object in a package.
+type Object struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+}
+
+// Dependency is a package dependency declared in a meta file.
+type Dependency struct {
+	Package     string `json:"package"`
+	Type        string `json:"type,omitempty"`
+	Constraints string `json:"constraints,omitempty"`
+	// Digest is the locked digest of the dependency, if known.
+	Digest string `json:"digest,omitempty"`
+}
+
+// Image is an OCI image.
+type Image struct {
+	Reference string `json:"reference,omitempty"`
+	Digest    string `json:"digest"`
+}
+
+// NewSBOM returns an SBOM of the supplied package in the supplied format.
+func NewSBOM(f SBOMFormat, p Package, created time.Time) (json.RawMessage, error) {
+	var doc any
+	switch f {
+	case SBOMFormatSPDX:
+		doc = spdx(p, created)
+	case SBOMFormatCycloneDX:
+		doc = cycloneDX(p, created)
+	default:
+		return nil, errors.Errorf(errUnknownSBOMFormatFmt, f)
+	}
+	b, err := json.Marshal(doc)
+	return b, errors.Wrap(err, errMarshalSBOM)
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name                  string            `json:"name"`
+	SPDXID                string            `json:"SPDXID"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose,omitempty"`
+	Checksums             []spdxChecksum    `json:"checksums,omitempty"`
+	ExternalRefs          []spdxExternalRef `json:"externalRefs,omitempty"`
+	Comment               string            `json:"comment,omitempty"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+func spdx(p Package, created time.Time) spdxDocument {
+	const root = "SPDXRef-Package"
+	doc := spdxDocument{
+		SPDXVersion:       spdxVersion,
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              p.Name,
+		DocumentNamespace: fmt.Sprintf(spdxNamespaceFmt, p.Name, uuid.NewString()),
+		CreationInfo: spdxCreationInfo{
+			Created:  created.UTC().Format(time.RFC3339),
+			Creators: []string{fmt.Sprintf("Tool: %s-%s", toolName, version.Version())},
+		},
+		Packages: []spdxPackage{{
+			Name:                  p.Name,
+			SPDXID:                root,
+			DownloadLocation:      spdxNoAssertion,
+			PrimaryPackagePurpose: "CONTAINER",
+			Comment:               fmt.Sprintf("Crossplane %s package", p.Kind),
+		}},
+		Relationships: []spdxRelationship{{
+			SPDXElementID:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: root,
+		}},
+	}
+
+	for i, o := range p.Objects {
+		id := fmt.Sprintf("SPDXRef-Object-%d", i)
+		doc.Packages = append(doc.Packages, spdxPackage{
+			Name:             o.Name,
+			SPDXID:           id,
+			VersionInfo:      o.APIVersion,
+			DownloadLocation: spdxNoAssertion,
+			Comment:          o.Kind,
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      root,
+			RelationshipType:   "CONTAINS",
+			RelatedSPDXElement: id,
+		})
+	}
+
+	for i, d := range p.Dependencies {
+		id := fmt.Sprintf("SPDXRef-Dependency-%d", i)
+		doc.Packages = append(doc.Packages, spdxPackage{
+			Name:             d.Package,
+			SPDXID:           id,
+			VersionInfo:      d.Constraints,
+			DownloadLocation: spdxNoAssertion,
+			Checksums:        spdxChecksums(d.Digest),
+			ExternalRefs:     []spdxExternalRef{purlRef(d.Package, d.Digest)},
+			Comment:          d.Type,
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      root,
+			RelationshipType:   "DEPENDS_ON",
+			RelatedSPDXElement: id,
+		})
+	}
+
+	if p.Controller != nil {
+		const id = "SPDXRef-Controller"
+		doc.Packages = append(doc.Packages, spdxPackage{
+			Name:             p.Controller.Reference,
+			SPDXID:           id,
+			DownloadLocation: spdxNoAssertion,
+			Checksums:        spdxChecksums(p.Controller.Digest),
+			ExternalRefs:     []spdxExternalRef{purlRef(p.Controller.Reference, p.Controller.Digest)},
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      root,
+			RelationshipType:   "DESCENDANT_OF",
+			RelatedSPDXElement: id,
+		})
+	}
+	return doc
+}
+
+// spdxChecksums returns the checksum of a digest in SPDX notation, e.g.
+// SHA256.
+func spdxChecksums(digest string) []spdxChecksum {
+	alg, hex, ok := strings.Cut(digest, ":")
+	if !ok {
+		return nil
+	}
+	return []spdxChecksum{{
+		Algorithm:     strings.ToUpper(alg),
+		ChecksumValue: hex,
+	}}
+}
+
+func purlRef(repo, digest string) spdxExternalRef {
+	return spdxExternalRef{
+		ReferenceCategory: "PACKAGE-MANAGER",
+		ReferenceType:     "purl",
+		ReferenceLocator:  purl(repo, digest),
+	}
+}
+
+// purl returns the package URL of an OCI artifact, e.g.
+// pkg:oci/provider-aws@sha256%3A...?repository_url=xpkg.upbound.io/upbound.
+func purl(repo, digest string) string {
+	name := repo
+	url := ""
+	if i := strings.LastIndex(repo, "/"); i >= 0 {
+		name = repo[i+1:]
+		url = repo[:i]
+	}
+	// strip the tag, if any.
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, "@"); i >= 0 {
+		name = name[:i]
+	}
+
+	s := "pkg:oci/" + name
+	if digest != "" {
+		s += "@" + strings.Replace(digest, ":", "%3A", 1)
+	}
+	if url != "" {
+		s += "?repository_url=" + url
+	}
+	return s
+}
+
+type cycloneDXDocument struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDXMetadata     `json:"metadata"`
+	Components   []cycloneDXComponent  `json:"components"`
+	Dependencies []cycloneDXDependency `json:"dependencies"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     []cycloneDXTool    `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type cycloneDXComponent struct {
+	BOMRef      string          `json:"bom-ref"`
+	Type        string          `json:"type"`
+	Name        string          `json:"name"`
+	Version     string          `json:"version,omitempty"`
+	Description string          `json:"description,omitempty"`
+	PURL        string          `json:"purl,omitempty"`
+	Hashes      []cycloneDXHash `json:"hashes,omitempty"`
+}
+
+type cycloneDXHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+func cycloneDX(p Package, created time.Time) cycloneDXDocument {
+	const root = "package"
+	doc := cycloneDXDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  cycloneDXVersion,
+		SerialNumber: "urn:uuid:" + uuid.NewString(),
+		Version:      1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: created.UTC().Format(time.RFC3339),
+			Tools:     []cycloneDXTool{{Name: toolName, Version: version.Version()}},
+			Component: cycloneDXComponent{
+				BOMRef:      root,
+				Type:        "container",
+				Name:        p.Name,
+				Description: fmt.Sprintf("Crossplane %s package", p.Kind),
+			},
+		},
+		Components: make([]cycloneDXComponent, 0, len(p.Objects)+len(p.Dependencies)+1),
+	}
+
+	deps := make([]string, 0, len(p.Dependencies)+1)
+	for i, o := range p.Objects {
+		doc.Components = append(doc.Components, cycloneDXComponent{
+			BOMRef:      fmt.Sprintf("object-%d", i),
+			Type:        "data",
+			Name:        o.Name,
+			Version:     o.APIVersion,
+			Description: o.Kind,
+		})
+	}
+	for i, d := range p.Dependencies {
+		ref := fmt.Sprintf("dependency-%d", i)
+		doc.Components = append(doc.Components, cycloneDXComponent{
+			BOMRef:      ref,
+			Type:        "container",
+			Name:        d.Package,
+			Version:     d.Constraints,
+			Description: d.Type,
+			PURL:        purl(d.Package, d.Digest),
+			Hashes:      cycloneDXHashes(d.Digest),
+		})
+		deps = append(deps, ref)
+	}
+	if p.Controller != nil {
+		const ref = "controller"
+		doc.Components = append(doc.Components, cycloneDXComponent{
+			BOMRef: ref,
+			Type:   "container",
+			Name:   p.Controller.Reference,
+			PURL:   purl(p.Controller.Reference, p.Controller.Digest),
+			Hashes: cycloneDXHashes(p.Controller.Digest),
+		})
+		deps = append(deps, ref)
+	}
+	doc.Dependencies = []cycloneDXDependency{{Ref: root, DependsOn: deps}}
+	return doc
+}
+
+func cycloneDXHashes(digest string) []cycloneDXHash {
+	alg, hex, ok := strings.Cut(digest, ":")
+	if !ok {
+		return nil
+	}
+	if alg == "sha256" {
+		alg = "SHA-256"
+	}
+	return []cycloneDXHash{{Alg: alg, Content: hex}}
+}
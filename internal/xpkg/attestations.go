@@ -0,0 +1,115 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xpkg
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/upbound/up/internal/xpkg/attest"
+)
+
+const (
+	errWriteAttestations = "failed to write attestations to package file"
+	errReadAttestations  = "failed to read attestations from package file"
+)
+
+// WriteImage writes the supplied image to w as a tarball. If tag is not nil
+// it is recorded in the tarball. Non-empty attestations are appended to the
+// tarball, where they are ignored by tools that only read the image.
+func WriteImage(w io.Writer, tag name.Reference, img v1.Image, att *attest.Attestations) error {
+	if att.Empty() {
+		return tarball.Write(tag, img, w)
+	}
+	b, err := json.Marshal(att)
+	if err != nil {
+		return errors.Wrap(err, errWriteAttestations)
+	}
+
+	// tarball.Write closes the tarball it writes, so its entries are copied
+	// to a new tarball that the attestations can be added to.
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(tarball.Write(tag, img, pw))
+	}()
+	defer pr.Close() // nolint:errcheck
+
+	tr := tar.NewReader(pr)
+	tw := tar.NewWriter(w)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil { // nolint:gosec
+			return err
+		}
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     attest.File,
+		Mode:     int64(StreamFileMode),
+		Size:     int64(len(b)),
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return errors.Wrap(err, errWriteAttestations)
+	}
+	if _, err := tw.Write(b); err != nil {
+		return errors.Wrap(err, errWriteAttestations)
+	}
+	return tw.Close()
+}
+
+// ReadAttestations reads the attestations stored in the package file at the
+// supplied path. It returns nil if the package file holds no attestations.
+func ReadAttestations(path string) (*attest.Attestations, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() // nolint:errcheck
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, errReadAttestations)
+		}
+		if filepath.Clean(hdr.Name) != attest.File {
+			continue
+		}
+		a := &attest.Attestations{}
+		if err := json.NewDecoder(tr).Decode(a); err != nil {
+			return nil, errors.Wrap(err, errReadAttestations)
+		}
+		return a, nil
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xpkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/upbound/up/internal/xpkg/attest"
+)
+
+func TestWriteImageAttestations(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		att    *attest.Attestations
+	}{
+		"NoAttestations": {
+			reason: "Should write a plain package file if there are no attestations.",
+		},
+		"Attestations": {
+			reason: "Should store the attestations next to the image in the package file.",
+			att: &attest.Attestations{
+				SBOMFormat: attest.SBOMFormatCycloneDX,
+				SBOM:       []byte(`{"bomFormat":"CycloneDX"}`),
+				Provenance: &attest.Provenance{
+					BuildDefinition: attest.BuildDefinition{
+						BuildType: attest.BuildType,
+					},
+				},
+			},
+		},
+	}
+
+	for n, tc := range cases {
+		t.Run(n, func(t *testing.T) {
+			img := platformImage(t, "linux/amd64")
+			tag, _ := name.NewTag("xpkg.upbound.io/acme/provider-foo:v0.1.0")
+
+			path := filepath.Join(t.TempDir(), "image.xpkg")
+			f, _ := os.Create(path)
+			if err := WriteImage(f, tag, img, tc.att); err != nil {
+				t.Fatalf("\n%s\nWriteImage(...): unexpected error: %s", tc.reason, err)
+			}
+			_ = f.Close()
+
+			got, err := tarball.ImageFromPath(path, nil)
+			if err != nil {
+				t.Fatalf("\n%s\nImageFromPath(...): unexpected error: %s", tc.reason, err)
+			}
+			want, _ := img.Digest()
+			gotDigest, _ := got.Digest()
+			if diff := cmp.Diff(want, gotDigest); diff != "" {
+				t.Errorf("\n%s\nImageFromPath(...): -want digest, +got digest:\n%s", tc.reason, diff)
+			}
+
+			gotAtt, err := ReadAttestations(path)
+			if err != nil {
+				t.Fatalf("\n%s\nReadAttestations(...): unexpected error: %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.att, gotAtt); diff != "" {
+				t.Errorf("\n%s\nReadAttestations(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
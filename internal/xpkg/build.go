@@ -20,6 +20,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
 	pkgmetav1 "github.com/crossplane/crossplane/apis/pkg/meta/v1"
@@ -35,6 +36,7 @@ import (
 
 	"github.com/crossplane/crossplane-runtime/pkg/parser"
 
+	"github.com/upbound/up/internal/xpkg/attest"
 	"github.com/upbound/up/internal/xpkg/parser/examples"
 	"github.com/upbound/up/internal/xpkg/parser/linter"
 	"github.com/upbound/up/internal/xpkg/scheme"
@@ -52,6 +54,7 @@ const (
 	errConfigFile        = "failed to get config file from image"
 	errMutateConfig      = "failed to mutate config for image"
 	errBuildObjectScheme = "failed to build scheme for package encoder"
+	errAttest            = "failed to generate attestations"
 	errParseAuth         = "an auth extension was supplied but could not be parsed"
 	errAuthNotAnnotated  = "an auth extension was supplied but but the " + ProviderConfigKind + " object could not be found"
 	authMetaAnno         = "auth.upbound.io/group"
@@ -120,7 +123,9 @@ func New(pkg, ab, ex parser.Backend, pp parser.Parser, ep *examples.Parser) *Bui
 }
 
 type buildOpts struct {
-	base v1.Image
+	base   v1.Image
+	attest attest.Config
+	out    *attest.Attestations
 }
 
 // A BuildOpt modifies how a package is built.
@@ -134,6 +139,16 @@ func WithController(img v1.Image) BuildOpt {
 	}
 }
 
+// WithAttestations generates the attestations requested by the supplied
+// Config while building the package and stores them in the supplied
+// Attestations.
+func WithAttestations(c attest.Config, out *attest.Attestations) BuildOpt {
+	return func(o *buildOpts) {
+		o.attest = c
+		o.out = out
+	}
+}
+
 type AuthExtension struct {
 	Version      string `yaml:"version"`
 	Discriminant string `yaml:"discriminant"`
@@ -150,6 +165,7 @@ type AuthExtension struct {
 
 // Build compiles a Crossplane package from an on-disk package.
 func (b *Builder) Build(ctx context.Context, opts ...BuildOpt) (v1.Image, runtime.Object, error) { // nolint:gocyclo
+	started := time.Now()
 	bOpts := &buildOpts{
 		base: empty.Image,
 	}
@@ -244,6 +260,14 @@ func (b *Builder) Build(ctx context.Context, opts ...BuildOpt) (v1.Image, runtim
 		return nil, nil, errors.Wrap(err, errConfigFile)
 	}
 
+	if bOpts.out != nil && bOpts.attest.Enabled() {
+		a, err := attest.Generate(bOpts.attest, inventory(meta, pkg, bOpts), started)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, errAttest)
+		}
+		*bOpts.out = *a
+	}
+
 	cfg := cfgFile.Config
 	cfg.Labels = make(map[string]string)
 
@@ -287,6 +311,52 @@ func (b *Builder) Build(ctx context.Context, opts ...BuildOpt) (v1.Image, runtim
 	return bOpts.base, meta, nil
 }
 
+// inventory returns the attestation inventory of the supplied package.
+func inventory(meta runtime.Object, pkg linter.Package, o *buildOpts) attest.Package {
+	p := attest.Package{
+		Kind:    meta.GetObjectKind().GroupVersionKind().Kind,
+		Objects: make([]attest.Object, 0, len(pkg.GetObjects())),
+	}
+	if m, ok := meta.(metav1.Object); ok {
+		p.Name = m.GetName()
+	}
+
+	for _, obj := range pkg.GetObjects() {
+		ao := attest.Object{
+			APIVersion: obj.GetObjectKind().GroupVersionKind().GroupVersion().String(),
+			Kind:       obj.GetObjectKind().GroupVersionKind().Kind,
+		}
+		if m, ok := obj.(metav1.Object); ok {
+			ao.Name = m.GetName()
+		}
+		p.Objects = append(p.Objects, ao)
+	}
+
+	if mp, ok := scheme.TryConvertToPkg(meta, &pkgmetav1.Provider{}, &pkgmetav1.Configuration{}); ok {
+		for _, d := range mp.GetDependencies() {
+			ad := attest.Dependency{Constraints: d.Version}
+			switch {
+			case d.Provider != nil:
+				ad.Package, ad.Type = *d.Provider, string(v1beta1.ProviderPackageType)
+			case d.Configuration != nil:
+				ad.Package, ad.Type = *d.Configuration, string(v1beta1.ConfigurationPackageType)
+			case d.Function != nil:
+				ad.Package, ad.Type = *d.Function, string(v1beta1.FunctionPackageType)
+			default:
+				continue
+			}
+			p.Dependencies = append(p.Dependencies, ad)
+		}
+	}
+
+	if o.attest.Controller != "" {
+		if d, err := o.base.Digest(); err == nil {
+			p.Controller = &attest.Image{Reference: o.attest.Controller, Digest: d.String()}
+		}
+	}
+	return p
+}
+
 // encode encodes a package as a YAML stream.  Does not check meta existence
 // or quantity i.e. it should be linted first to ensure that it is valid.
 func encode(pkg linter.Package) (*bytes.Buffer, error) {
@@ -39,6 +39,7 @@ const (
 
 	errFailedToAddEntry     = "failed to add entry to cache"
 	errFailedToFindEntry    = "failed to find entry"
+	errFailedToParseEntry   = "failed to parse entry"
 	errInvalidValueSupplied = "invalid value supplied"
 	errInvalidVersion       = "invalid version found"
 
@@ -198,6 +199,44 @@ func (c *Local) Versions(k v1beta1.Dependency) ([]string, error) {
 	return vers, nil
 }
 
+// List returns every package in the cache. Entries that cannot be parsed
+// are skipped.
+func (c *Local) List() ([]*xpkg.ParsedPackage, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	pkgs := make([]*xpkg.ParsedPackage, 0)
+	err := afero.Walk(c.fs, c.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() || !strings.Contains(info.Name(), "@") {
+			return nil
+		}
+		p, err := c.pkgres.FromDir(c.fs, path)
+		if err != nil {
+			c.log.Debug(errFailedToParseEntry, "path", path, "error", err)
+			return filepath.SkipDir
+		}
+		pkgs = append(pkgs, p)
+		return filepath.SkipDir
+	})
+	return pkgs, err
+}
+
+// ObjectPath returns the path of the file that the object with the supplied
+// name is stored in within the cache entry for the given package.
+func (c *Local) ObjectPath(k v1beta1.Dependency, obj string) (string, error) {
+	t, err := name.NewTag(image.FullTag(k))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(c.root, calculatePath(&t), fmt.Sprintf(crdNameFmt, obj)), nil
+}
+
 // Watch returns a channel that can be used to subscribe to events
 // from the cache.
 func (c *Local) Watch() <-chan Event {
@@ -213,6 +252,7 @@ func (c *Local) Watch() <-chan Event {
 		// Kick off cache watching in a separate routine so we don't
 		// block upstream operations on an infrequently used operation.
 		go c.watchCache()
+		c.watching = true
 	}
 
 	// use an buffered channel so that we don't block incoming watch events.
@@ -221,6 +261,23 @@ func (c *Local) Watch() <-chan Event {
 	return ch
 }
 
+// Unwatch removes the supplied subscription returned by Watch and closes its
+// channel.
+func (c *Local) Unwatch(ch <-chan Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, sub := range c.subs {
+		if sub == ch {
+			c.subs = append(c.subs[:i], c.subs[i+1:]...)
+			if !c.closed {
+				close(sub)
+			}
+			return
+		}
+	}
+}
+
 // Clean removes all entries from the cache. Returns nil if the directory DNE.
 func (c *Local) Clean() error {
 	c.mu.Lock()
@@ -250,7 +307,12 @@ func (c *Local) publish(e Event) {
 	}
 
 	for _, ch := range c.subs {
-		ch <- e
+		// a subscriber with a pending event has yet to process the cache
+		// change, so there is no need to block on it.
+		select {
+		case ch <- e:
+		default:
+		}
 	}
 }
 
@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	apimetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	ociname "github.com/google/go-containerregistry/pkg/name"
@@ -440,6 +441,85 @@ func TestVersions(t *testing.T) {
 	}
 }
 
+func TestList(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	cache, _ := NewLocal(
+		"/cache",
+		WithFS(fs),
+	)
+
+	cache.add(cache.newEntry(pkg1), "index.docker.io/crossplane/provider-aws@v0.20.1-alpha")
+	cache.add(cache.newEntry(pkg3), "registry.upbound.io/crossplane/provider-gcp@v0.2.0")
+
+	pkgs, err := cache.List()
+	if err != nil {
+		t.Fatalf("List(...): unexpected error: %s", err)
+	}
+	got := make([]string, len(pkgs))
+	for i, p := range pkgs {
+		got[i] = p.Digest()
+	}
+	if diff := cmp.Diff([]string{pkg1.Digest(), pkg3.Digest()}, got); diff != "" {
+		t.Errorf("List(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestObjectPath(t *testing.T) {
+	cache, _ := NewLocal(
+		"/cache",
+		WithFS(afero.NewMemMapFs()),
+	)
+
+	type want struct {
+		path string
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		key    v1beta1.Dependency
+		obj    string
+		want   want
+	}{
+		"Success": {
+			reason: "Should return the path of the object file within the entry of the package.",
+			key: v1beta1.Dependency{
+				Package:     providerAws,
+				Constraints: "v0.20.1-alpha",
+			},
+			obj: "certificates.acm.aws.crossplane.io",
+			want: want{
+				path: "/cache/index.docker.io/crossplane/provider-aws@v0.20.1-alpha/certificates.acm.aws.crossplane.io.yaml",
+			},
+		},
+		"InvalidVersion": {
+			reason: "Should return an error if the package version is not a valid tag.",
+			key: v1beta1.Dependency{
+				Package:     providerAws,
+				Constraints: ">=v0.20.0",
+			},
+			obj: "certificates.acm.aws.crossplane.io",
+			want: want{
+				err: cmpopts.AnyError,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			path, err := cache.ObjectPath(tc.key, tc.obj)
+
+			if diff := cmp.Diff(tc.want.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nObjectPath(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.path, path); diff != "" {
+				t.Errorf("\n%s\nObjectPath(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
 func TestCalculatePath(t *testing.T) {
 	tag1, _ := ociname.NewTag("crossplane/provider-aws:v0.20.1-alpha")
 	tag2, _ := ociname.NewTag("gcr.io/crossplane/provider-gcp:v1.0.0")
@@ -538,6 +618,25 @@ func TestWatch(t *testing.T) {
 	}
 }
 
+func TestUnwatch(t *testing.T) {
+	cache, _ := NewLocal("/tmp/cache")
+
+	ch := cache.Watch()
+	other := cache.Watch()
+	cache.Unwatch(ch)
+	cache.publish("test")
+
+	if _, ok := <-ch; ok {
+		t.Errorf("\nUnwatch(...): expected the channel to be closed")
+	}
+	if diff := cmp.Diff(Event("test"), <-other); diff != "" {
+		t.Errorf("\nUnwatch(...): -want event, +got event:\n%s", diff)
+	}
+	if diff := cmp.Diff(1, len(cache.subs)); diff != "" {
+		t.Errorf("\nUnwatch(...): -want subscriptions, +got subscriptions:\n%s", diff)
+	}
+}
+
 func cacheFileCnt(fs afero.Fs, dir string) int {
 	var cnt int
 	afero.Walk(fs, dir,
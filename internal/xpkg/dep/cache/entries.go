@@ -0,0 +1,230 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/spf13/afero"
+
+	"github.com/upbound/up/internal/xpkg"
+)
+
+const (
+	errReadEntryFmt          = "failed to read cache entry %s"
+	errNoDigestRecorded      = "no digest recorded"
+	errDigestMarkerFmt       = "digest marker for recorded digest %s not found"
+	errDigestMarkerCountFmt  = "found %d digest markers, expected 1"
+	errNoChecksumRecorded    = "no content checksum recorded, remove the entry and resolve it again"
+	errChecksumMismatchFmt   = "content does not match recorded checksum %s"
+	errVersionMismatchFmt    = "recorded version %s does not match cached version %s"
+	errParseEntry            = "failed to parse cached package"
+	errEntryPathOutsideCache = "entry path is outside of the cache"
+)
+
+// EntryInfo describes a package version stored in the cache.
+type EntryInfo struct {
+	// Package is the OCI repository of the package including its registry,
+	// e.g. xpkg.upbound.io/upbound/provider-aws.
+	Package string `json:"package"`
+	// Version is the cached version of the package.
+	Version string `json:"version"`
+	// Digest is the digest of the package image recorded in the entry.
+	Digest string `json:"digest"`
+	// Size is the size of the entry on disk in bytes.
+	Size int64 `json:"size"`
+	// Created is the time the entry was written to the cache.
+	Created time.Time `json:"created"`
+
+	path string
+}
+
+// Entries returns all entries in the cache sorted by package and version.
+func (c *Local) Entries() ([]EntryInfo, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entries := make([]EntryInfo, 0)
+	// an empty cache may not have a root directory.
+	if ok, err := afero.DirExists(c.fs, c.root); err != nil || !ok {
+		return entries, err
+	}
+
+	err := afero.Walk(c.fs, c.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() || !strings.Contains(info.Name(), "@") {
+			return nil
+		}
+
+		e, err := c.entryInfo(path)
+		if err != nil {
+			return errors.Wrapf(err, errReadEntryFmt, path)
+		}
+		entries = append(entries, e)
+		// entries do not contain nested entries.
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Package != entries[j].Package {
+			return entries[i].Package < entries[j].Package
+		}
+		return entries[i].Version < entries[j].Version
+	})
+	return entries, nil
+}
+
+// Remove removes the supplied entry from the cache, along with any parent
+// directories that are left empty.
+func (c *Local) Remove(e EntryInfo) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	loc := filepath.Join(c.root, e.path)
+	if e.path == "" || !strings.HasPrefix(loc, c.root+string(filepath.Separator)) {
+		return errors.New(errEntryPathOutsideCache)
+	}
+	if err := c.fs.RemoveAll(loc); err != nil {
+		return err
+	}
+
+	for dir := filepath.Dir(loc); dir != c.root; dir = filepath.Dir(dir) {
+		files, err := afero.ReadDir(c.fs, dir)
+		if err != nil || len(files) > 0 {
+			break
+		}
+		if err := c.fs.Remove(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Verify checks that the supplied entry can be parsed, that its contents
+// match the digest and version recorded for it and that the package stream
+// file still hashes to the checksum recorded when the entry was written.
+func (c *Local) Verify(e EntryInfo) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	loc := filepath.Join(c.root, e.path)
+	p, err := c.pkgres.FromDir(c.fs, loc)
+	if err != nil {
+		return errors.Wrap(err, errParseEntry)
+	}
+
+	if p.Digest() == "" {
+		return errors.New(errNoDigestRecorded)
+	}
+	if p.Version() != e.Version {
+		return errors.Errorf(errVersionMismatchFmt, p.Version(), e.Version)
+	}
+
+	// the entry carries a file named after the digest of the image it was
+	// extracted from. It contains the sha256 checksum of the package stream
+	// file, which is empty for entries written before it was recorded.
+	markers, err := afero.Glob(c.fs, filepath.Join(loc, "sha256:*"))
+	if err != nil {
+		return err
+	}
+	if len(markers) != 1 {
+		return errors.Errorf(errDigestMarkerCountFmt, len(markers))
+	}
+	if filepath.Base(markers[0]) != p.Digest() {
+		return errors.Errorf(errDigestMarkerFmt, p.Digest())
+	}
+
+	recorded, err := afero.ReadFile(c.fs, markers[0])
+	if err != nil {
+		return err
+	}
+	if len(recorded) == 0 {
+		// entries written before checksums were recorded.
+		return errors.New(errNoChecksumRecorded)
+	}
+	sum, err := contentChecksum(c.fs, loc)
+	if err != nil {
+		return err
+	}
+	if sum != string(recorded) {
+		return errors.Errorf(errChecksumMismatchFmt, string(recorded))
+	}
+	return nil
+}
+
+// entryInfo reads the EntryInfo of the entry at the supplied path.
+func (c *Local) entryInfo(path string) (EntryInfo, error) {
+	rel, err := filepath.Rel(c.root, path)
+	if err != nil {
+		return EntryInfo{}, err
+	}
+	parts := strings.SplitN(filepath.ToSlash(rel), "@", 2)
+
+	e := EntryInfo{
+		Package: parts[0],
+		Version: parts[1],
+		path:    rel,
+	}
+
+	files, err := afero.ReadDir(c.fs, path)
+	if err != nil {
+		return EntryInfo{}, err
+	}
+	for _, f := range files {
+		e.Size += f.Size()
+		if f.Name() == xpkg.JSONStreamFile {
+			e.Created = f.ModTime()
+		}
+	}
+
+	meta, err := c.imageMeta(path)
+	if err != nil && !os.IsNotExist(err) {
+		return EntryInfo{}, err
+	}
+	e.Digest = meta.Digest
+
+	return e, nil
+}
+
+// imageMeta reads the image meta recorded in the first line of the package
+// stream file of the entry at the supplied path.
+func (c *Local) imageMeta(path string) (xpkg.ImageMeta, error) {
+	meta := xpkg.ImageMeta{}
+
+	f, err := c.fs.Open(filepath.Join(path, xpkg.JSONStreamFile))
+	if err != nil {
+		return meta, err
+	}
+	defer f.Close() // nolint:errcheck
+
+	r := bufio.NewReader(f)
+	line, err := r.ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return meta, err
+	}
+	return meta, json.Unmarshal(line, &meta)
+}
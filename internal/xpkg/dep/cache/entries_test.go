@@ -0,0 +1,175 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/spf13/afero"
+)
+
+func TestEntries(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	cache, _ := NewLocal("/cache", WithFS(fs))
+
+	_ = cache.add(cache.newEntry(pkg1), "index.docker.io/crossplane/provider-aws@v0.20.1-alpha")
+	_ = cache.add(cache.newEntry(pkg2), "index.docker.io/crossplane/provider-gcp@v0.18.1")
+
+	want := []EntryInfo{
+		{
+			Package: "index.docker.io/crossplane/provider-aws",
+			Version: "v0.20.1-alpha",
+			Digest:  pkg1.SHA,
+		},
+		{
+			Package: "index.docker.io/crossplane/provider-gcp",
+			Version: "v0.18.1",
+			Digest:  pkg2.SHA,
+		},
+	}
+
+	got, err := cache.Entries()
+	if err != nil {
+		t.Fatalf("Entries(): unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreUnexported(EntryInfo{}), cmpopts.IgnoreFields(EntryInfo{}, "Size", "Created")); diff != "" {
+		t.Errorf("Entries(): -want, +got:\n%s", diff)
+	}
+	for _, e := range got {
+		if e.Size == 0 {
+			t.Errorf("Entries(): expected non-zero size for %s", e.Package)
+		}
+	}
+}
+
+func TestEntriesEmpty(t *testing.T) {
+	cache, _ := NewLocal("/cache", WithFS(afero.NewMemMapFs()))
+
+	got, err := cache.Entries()
+	if err != nil {
+		t.Fatalf("Entries(): unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(0, len(got)); diff != "" {
+		t.Errorf("Entries(): -want, +got:\n%s", diff)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	type args struct {
+		path   string
+		modify func(fs afero.Fs, loc string)
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   error
+	}{
+		"Success": {
+			reason: "Should not return an error for an intact entry.",
+			args: args{
+				path: "index.docker.io/crossplane/provider-aws@v0.20.1-alpha",
+			},
+		},
+		"ErrVersionMismatch": {
+			reason: "Should return an error if the recorded version differs from the cached version.",
+			args: args{
+				path: "index.docker.io/crossplane/provider-aws@v0.21.0",
+			},
+			want: errors.Errorf(errVersionMismatchFmt, "v0.20.1-alpha", "v0.21.0"),
+		},
+		"ErrMissingDigestMarker": {
+			reason: "Should return an error if the digest marker of the entry was removed.",
+			args: args{
+				path: "index.docker.io/crossplane/provider-aws@v0.20.1-alpha",
+				modify: func(fs afero.Fs, loc string) {
+					_ = fs.Remove(filepath.Join(loc, pkg1.SHA))
+				},
+			},
+			want: errors.Errorf(errDigestMarkerCountFmt, 0),
+		},
+		"ErrChecksumMismatch": {
+			reason: "Should return an error if the package stream file does not hash to the recorded checksum.",
+			args: args{
+				path: "index.docker.io/crossplane/provider-aws@v0.20.1-alpha",
+				modify: func(fs afero.Fs, loc string) {
+					_ = afero.WriteFile(fs, filepath.Join(loc, pkg1.SHA), []byte("sha256:recorded"), 0o600)
+				},
+			},
+			want: errors.Errorf(errChecksumMismatchFmt, "sha256:recorded"),
+		},
+		"ErrNoChecksumRecorded": {
+			reason: "Should return an error if the entry was written without a content checksum.",
+			args: args{
+				path: "index.docker.io/crossplane/provider-aws@v0.20.1-alpha",
+				modify: func(fs afero.Fs, loc string) {
+					_ = afero.WriteFile(fs, filepath.Join(loc, pkg1.SHA), nil, 0o600)
+				},
+			},
+			want: errors.New(errNoChecksumRecorded),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			cache, _ := NewLocal("/cache", WithFS(fs))
+			_ = cache.add(cache.newEntry(pkg1), tc.args.path)
+			if tc.args.modify != nil {
+				tc.args.modify(fs, filepath.Join("/cache", tc.args.path))
+			}
+
+			entries, _ := cache.Entries()
+			err := cache.Verify(entries[0])
+
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nVerify(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRemove(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	cache, _ := NewLocal("/cache", WithFS(fs))
+
+	_ = cache.add(cache.newEntry(pkg1), "index.docker.io/crossplane/provider-aws@v0.20.1-alpha")
+	_ = cache.add(cache.newEntry(pkg2), "index.docker.io/crossplane/provider-gcp@v0.18.1")
+	_ = cache.add(cache.newEntry(pkg2), "xpkg.upbound.io/upbound/provider-gcp@v0.18.1")
+
+	entries, _ := cache.Entries()
+	for _, e := range entries[1:] {
+		if err := cache.Remove(e); err != nil {
+			t.Fatalf("Remove(...): unexpected error: %s", err)
+		}
+	}
+
+	got, _ := cache.Entries()
+	if diff := cmp.Diff(entries[:1], got, cmp.AllowUnexported(EntryInfo{})); diff != "" {
+		t.Errorf("Remove(...): -want, +got:\n%s", diff)
+	}
+	// empty parent directories are removed along with the entry.
+	if ok, _ := afero.DirExists(fs, "/cache/xpkg.upbound.io"); ok {
+		t.Errorf("Remove(...): expected empty parent directories to be removed")
+	}
+	if ok, _ := afero.DirExists(fs, "/cache/index.docker.io/crossplane"); !ok {
+		t.Errorf("Remove(...): expected non-empty parent directories to be kept")
+	}
+}
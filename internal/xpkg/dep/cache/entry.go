@@ -15,8 +15,10 @@
 package cache
 
 import (
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -116,15 +118,32 @@ func (e *entry) flush() (*flushstats, error) {
 	}
 	stats.combine(objstats)
 
-	// writing empty digest file
-	_, err = e.fs.Create(filepath.Join(e.location(), e.pkg.Digest()))
+	// the digest marker records the checksum of the package stream file so
+	// that the stored content can be verified later on.
+	sum, err := contentChecksum(e.fs, e.location())
 	if err != nil {
 		return stats, err
 	}
-
+	err = afero.WriteFile(e.fs, filepath.Join(e.location(), e.pkg.Digest()), []byte(sum), 0o600)
 	return stats, err
 }
 
+// contentChecksum returns the sha256 checksum of the package stream file of
+// the entry at the supplied location.
+func contentChecksum(fs afero.Fs, loc string) (string, error) {
+	f, err := fs.Open(filepath.Join(loc, xpkg.JSONStreamFile))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close() // nolint:errcheck
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
+
 func (e *entry) writeImageMeta(registry, repo, version, digest string) (*flushstats, error) {
 	stats := &flushstats{}
 
@@ -0,0 +1,226 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lock implements the lock file that records the resolved transitive
+// dependency graph of a Crossplane package.
+package lock
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane/apis/pkg/v1beta1"
+	"github.com/spf13/afero"
+	"sigs.k8s.io/yaml"
+
+	"github.com/upbound/up/internal/xpkg"
+	"github.com/upbound/up/internal/xpkg/dep/resolver/image"
+)
+
+const (
+	// Version is the current version of the lock file format.
+	Version = "v1alpha1"
+
+	errReadLock           = "failed to read lock file"
+	errParseLock          = "failed to parse lock file"
+	errWriteLock          = "failed to write lock file"
+	errUnsupportedVersion = "unsupported lock file version %q"
+	errNotLockedFmt       = "dependency %s is not present in the lock file"
+	errOutOfDateFmt       = "locked version %s of %s does not satisfy constraint %s"
+)
+
+// Lock is the on-disk representation of a resolved dependency graph.
+type Lock struct {
+	// Version is the version of the lock file format.
+	Version string `json:"version"`
+	// Packages are the resolved packages, sorted by source.
+	Packages []Package `json:"packages"`
+}
+
+// Package is a single resolved package in the Lock.
+type Package struct {
+	// Source is the OCI repository of the package, e.g.
+	// xpkg.upbound.io/upbound/provider-aws.
+	Source string `json:"source"`
+	// Type is the type of the package.
+	Type v1beta1.PackageType `json:"type,omitempty"`
+	// Version is the tag the package constraints resolved to.
+	Version string `json:"version"`
+	// Digest is the digest of the package image at the time it was
+	// resolved.
+	Digest string `json:"digest"`
+	// Dependencies are the dependencies the package declares.
+	Dependencies []Dependency `json:"dependencies,omitempty"`
+}
+
+// Dependency is a dependency declared by a locked Package.
+type Dependency struct {
+	// Source is the OCI repository of the dependency.
+	Source string `json:"source"`
+	// Constraints are the version constraints declared for the dependency.
+	Constraints string `json:"constraints"`
+}
+
+// New returns a new, empty Lock.
+func New() *Lock {
+	return &Lock{
+		Version:  Version,
+		Packages: make([]Package, 0),
+	}
+}
+
+// Path returns the path of the lock file for a package whose meta file lives
+// in the supplied directory.
+func Path(dir string) string {
+	return filepath.Join(dir, xpkg.LockFile)
+}
+
+// Read reads the Lock at the supplied path. If the file does not exist the
+// returned error satisfies os.IsNotExist.
+func Read(fs afero.Fs, path string) (*Lock, error) {
+	b, err := afero.ReadFile(fs, path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, errReadLock)
+	}
+
+	l := &Lock{}
+	if err := yaml.Unmarshal(b, l); err != nil {
+		return nil, errors.Wrap(err, errParseLock)
+	}
+	if l.Version != Version {
+		return nil, errors.Errorf(errUnsupportedVersion, l.Version)
+	}
+	return l, nil
+}
+
+// Write writes the Lock to the supplied path.
+func (l *Lock) Write(fs afero.Fs, path string) error {
+	l.sort()
+	b, err := yaml.Marshal(l)
+	if err != nil {
+		return errors.Wrap(err, errWriteLock)
+	}
+	return errors.Wrap(afero.WriteFile(fs, path, b, xpkg.StreamFileMode), errWriteLock)
+}
+
+// Get returns the locked Package for the supplied source, if one exists.
+func (l *Lock) Get(source string) (Package, bool) {
+	if l == nil {
+		return Package{}, false
+	}
+	for _, p := range l.Packages {
+		if p.Source == source {
+			return p, true
+		}
+	}
+	return Package{}, false
+}
+
+// Upsert adds the supplied Package to the Lock, replacing any existing entry
+// with the same source.
+func (l *Lock) Upsert(p Package) {
+	for i := range l.Packages {
+		if l.Packages[i].Source == p.Source {
+			l.Packages[i] = p
+			return
+		}
+	}
+	l.Packages = append(l.Packages, p)
+	l.sort()
+}
+
+// Remove removes the entry for the supplied source from the Lock, if one
+// exists.
+func (l *Lock) Remove(source string) {
+	for i := range l.Packages {
+		if l.Packages[i].Source == source {
+			l.Packages = append(l.Packages[:i], l.Packages[i+1:]...)
+			return
+		}
+	}
+}
+
+// Merge upserts all packages of the supplied Lock into this Lock.
+func (l *Lock) Merge(o *Lock) {
+	if o == nil {
+		return
+	}
+	for _, p := range o.Packages {
+		l.Upsert(p)
+	}
+}
+
+// Pin returns the supplied dependency with its constraints replaced by the
+// locked version. The second return value is false if the dependency is not
+// locked or if the locked version no longer satisfies its constraints, in
+// which case the dependency is returned unchanged.
+func (l *Lock) Pin(d v1beta1.Dependency) (v1beta1.Dependency, bool) {
+	p, ok := l.Get(d.Package)
+	if !ok || !Satisfies(d.Constraints, p.Version) {
+		return d, false
+	}
+	d.Constraints = p.Version
+	return d, true
+}
+
+// Check returns an error if any of the supplied dependencies is missing from
+// the Lock or if its locked version does not satisfy its constraints.
+func (l *Lock) Check(deps []v1beta1.Dependency) error {
+	for _, d := range deps {
+		p, ok := l.Get(d.Package)
+		if !ok {
+			return errors.Errorf(errNotLockedFmt, d.Package)
+		}
+		if !Satisfies(d.Constraints, p.Version) {
+			return errors.Errorf(errOutOfDateFmt, p.Version, d.Package, d.Constraints)
+		}
+	}
+	return nil
+}
+
+// Satisfies returns true if the supplied version satisfies the supplied
+// constraints. Constraints that are not valid semver constraints are only
+// satisfied by an identical version.
+func Satisfies(constraints, version string) bool {
+	if constraints == "" {
+		constraints = image.DefaultVer
+	}
+	c, err := semver.NewConstraint(constraints)
+	if err != nil {
+		return constraints == version
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+	return c.Check(v)
+}
+
+// String returns a short human readable representation of the Package.
+func (p Package) String() string {
+	return fmt.Sprintf("%s@%s", p.Source, p.Version)
+}
+
+func (l *Lock) sort() {
+	sort.Slice(l.Packages, func(i, j int) bool {
+		return l.Packages[i].Source < l.Packages[j].Source
+	})
+}
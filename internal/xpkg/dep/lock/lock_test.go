@@ -0,0 +1,226 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lock
+
+import (
+	"os"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/crossplane/crossplane/apis/pkg/v1beta1"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+)
+
+var (
+	providerAws = Package{
+		Source:  "xpkg.upbound.io/upbound/provider-aws",
+		Type:    v1beta1.ProviderPackageType,
+		Version: "v0.40.0",
+		Digest:  "sha256:d507e508234732c6dc95d29c8a8c932fa8fa6a229231e309927641f99933892e",
+	}
+	platformRef = Package{
+		Source:  "xpkg.upbound.io/upbound/platform-ref-aws",
+		Type:    v1beta1.ConfigurationPackageType,
+		Version: "v0.9.0",
+		Digest:  "sha256:d507e508234732c6dc95d29c8a8c932fa8fa6a229231e309927077099933707",
+		Dependencies: []Dependency{
+			{
+				Source:      "xpkg.upbound.io/upbound/provider-aws",
+				Constraints: ">=v0.38.0",
+			},
+		},
+	}
+)
+
+func TestReadWrite(t *testing.T) {
+	type want struct {
+		lock *Lock
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		fs     afero.Fs
+		lock   *Lock
+		want   want
+	}{
+		"RoundTrip": {
+			reason: "Should read back a written lock with its packages sorted by source.",
+			fs:     afero.NewMemMapFs(),
+			lock: &Lock{
+				Version:  Version,
+				Packages: []Package{providerAws, platformRef},
+			},
+			want: want{
+				lock: &Lock{
+					Version:  Version,
+					Packages: []Package{platformRef, providerAws},
+				},
+			},
+		},
+		"ErrUnsupportedVersion": {
+			reason: "Should return an error if the lock file format version is unknown.",
+			fs:     afero.NewMemMapFs(),
+			lock: &Lock{
+				Version: "v2",
+			},
+			want: want{
+				err: errors.Errorf(errUnsupportedVersion, "v2"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := Path("/ws")
+			if err := tc.lock.Write(tc.fs, path); err != nil {
+				t.Fatalf("\n%s\nWrite(...): unexpected error: %s", tc.reason, err)
+			}
+			got, err := Read(tc.fs, path)
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nRead(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.lock, got); diff != "" {
+				t.Errorf("\n%s\nRead(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReadNotExist(t *testing.T) {
+	_, err := Read(afero.NewMemMapFs(), Path("/ws"))
+	if !os.IsNotExist(err) {
+		t.Errorf("Read(...): expected not exist error, got: %v", err)
+	}
+}
+
+func TestPin(t *testing.T) {
+	l := &Lock{
+		Version:  Version,
+		Packages: []Package{providerAws},
+	}
+
+	type want struct {
+		dep    v1beta1.Dependency
+		pinned bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		dep    v1beta1.Dependency
+		want   want
+	}{
+		"Pinned": {
+			reason: "Should replace constraints with the locked version if it satisfies them.",
+			dep: v1beta1.Dependency{
+				Package:     providerAws.Source,
+				Constraints: ">=v0.38.0",
+			},
+			want: want{
+				dep: v1beta1.Dependency{
+					Package:     providerAws.Source,
+					Constraints: "v0.40.0",
+				},
+				pinned: true,
+			},
+		},
+		"OutOfDate": {
+			reason: "Should not pin a dependency whose constraints exclude the locked version.",
+			dep: v1beta1.Dependency{
+				Package:     providerAws.Source,
+				Constraints: ">=v0.41.0",
+			},
+			want: want{
+				dep: v1beta1.Dependency{
+					Package:     providerAws.Source,
+					Constraints: ">=v0.41.0",
+				},
+			},
+		},
+		"NotLocked": {
+			reason: "Should not pin a dependency that is missing from the lock.",
+			dep: v1beta1.Dependency{
+				Package:     "xpkg.upbound.io/upbound/provider-gcp",
+				Constraints: ">=v0.38.0",
+			},
+			want: want{
+				dep: v1beta1.Dependency{
+					Package:     "xpkg.upbound.io/upbound/provider-gcp",
+					Constraints: ">=v0.38.0",
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dep, pinned := l.Pin(tc.dep)
+
+			if diff := cmp.Diff(tc.want.dep, dep); diff != "" {
+				t.Errorf("\n%s\nPin(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.pinned, pinned); diff != "" {
+				t.Errorf("\n%s\nPin(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	l := &Lock{
+		Version:  Version,
+		Packages: []Package{providerAws},
+	}
+
+	cases := map[string]struct {
+		reason string
+		deps   []v1beta1.Dependency
+		want   error
+	}{
+		"UpToDate": {
+			reason: "Should not return an error if every dependency is locked.",
+			deps: []v1beta1.Dependency{
+				{Package: providerAws.Source, Constraints: "v0.40.0"},
+			},
+		},
+		"ErrNotLocked": {
+			reason: "Should return an error if a dependency is missing from the lock.",
+			deps: []v1beta1.Dependency{
+				{Package: platformRef.Source, Constraints: "v0.9.0"},
+			},
+			want: errors.Errorf(errNotLockedFmt, platformRef.Source),
+		},
+		"ErrOutOfDate": {
+			reason: "Should return an error if a locked version does not satisfy its constraints.",
+			deps: []v1beta1.Dependency{
+				{Package: providerAws.Source, Constraints: "<v0.40.0"},
+			},
+			want: errors.Errorf(errOutOfDateFmt, "v0.40.0", providerAws.Source, "<v0.40.0"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := l.Check(tc.deps)
+
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nCheck(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
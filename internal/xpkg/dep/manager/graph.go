@@ -0,0 +1,385 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/crossplane/crossplane/apis/pkg/v1beta1"
+
+	ixpkg "github.com/upbound/up/internal/xpkg"
+	xpkg "github.com/upbound/up/internal/xpkg/dep/marshaler/xpkg"
+	"github.com/upbound/up/internal/xpkg/dep/resolver/image"
+)
+
+const (
+	// maxSolveRounds bounds the number of times a single package may be
+	// re-resolved before we give up on finding a stable set of versions.
+	maxSolveRounds = 10
+
+	errConflictFmt       = "conflicting constraints for %s:\n%s"
+	errNotConvergedFmt   = "unable to find a stable version for %s; its constraints keep changing"
+	errMissingFmt        = "dependencies missing from the cache:\n%s"
+	conflictEntryFmt     = "\t%s required by %s\n"
+	missingEntryFmt      = "\t%s:%s required by %s\n"
+	requiredByVersionFmt = "%s@%s"
+)
+
+// Graph is a resolved dependency graph.
+type Graph struct {
+	// Roots are the nodes of the dependencies the graph was resolved for,
+	// in the order they were supplied.
+	Roots []*Node
+
+	nodes map[string]*Node
+}
+
+// Node is a single package in a resolved dependency Graph.
+type Node struct {
+	// Package is the OCI repository of the package.
+	Package string
+	// Type is the type of the package.
+	Type v1beta1.PackageType
+	// Version is the version picked for the package.
+	Version string
+	// Constraints are the constraints placed on the package by the packages
+	// that depend on it.
+	Constraints []Constraint
+	// Dependencies are the packages the package depends on.
+	Dependencies []*Node
+
+	pkg *xpkg.ParsedPackage
+}
+
+// Constraint is a version constraint placed on a package.
+type Constraint struct {
+	// Constraints is the semver constraint or version.
+	Constraints string
+	// RequiredBy identifies the package placing the constraint, e.g.
+	// crossplane/provider-aws@v0.20.0. Constraints placed by the root
+	// dependencies are required by the crossplane.yaml.
+	RequiredBy string
+}
+
+// ParsedPackage returns the parsed package the Node was resolved to.
+func (n *Node) ParsedPackage() *xpkg.ParsedPackage {
+	return n.pkg
+}
+
+// Node returns the Node for the supplied package, if the package is part of
+// the Graph.
+func (g *Graph) Node(pkg string) (*Node, bool) {
+	n, ok := g.nodes[pkg]
+	return n, ok
+}
+
+// Packages returns the parsed packages of all nodes in the Graph in depth
+// first order starting at the roots.
+func (g *Graph) Packages() []*xpkg.ParsedPackage {
+	seen := make(map[string]bool, len(g.nodes))
+	pkgs := make([]*xpkg.ParsedPackage, 0, len(g.nodes))
+
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		if seen[n.Package] {
+			return
+		}
+		seen[n.Package] = true
+		pkgs = append(pkgs, n.pkg)
+		for _, d := range n.Dependencies {
+			walk(d)
+		}
+	}
+	for _, r := range g.Roots {
+		walk(r)
+	}
+	return pkgs
+}
+
+// ResolveGraph resolves the supplied dependencies and all of their
+// transitive dependencies, storing every picked package in the cache. Each
+// package is resolved to the newest version that satisfies the constraints of
+// every package depending on it. If no such version exists an error
+// describing the conflicting constraints is returned.
+//
+// NOTE: versions that have already been picked for the packages placing the
+// constraints are not revisited to find a combination that avoids a
+// conflict.
+func (m *Manager) ResolveGraph(ctx context.Context, deps []v1beta1.Dependency) (*Graph, error) {
+	s := &solver{
+		m:       m,
+		reqs:    make(map[string]map[string]string),
+		types:   make(map[string]v1beta1.PackageType),
+		picked:  make(map[string]*xpkg.ParsedPackage),
+		rounds:  make(map[string]int),
+		dirty:   make(map[string]bool),
+		visited: make(map[string]bool),
+		missing: make(map[string]bool),
+	}
+	for _, d := range deps {
+		s.require(ixpkg.MetaFile, d)
+	}
+
+	if err := s.solve(ctx); err != nil {
+		return nil, err
+	}
+
+	// drop packages from the resolved lock that were picked at some point
+	// but are no longer part of the graph.
+	for p := range s.visited {
+		if _, ok := s.picked[p]; !ok {
+			m.resolved.Remove(p)
+		}
+	}
+
+	return s.graph(deps), nil
+}
+
+// solver iteratively resolves packages until the version picked for every
+// package satisfies all constraints placed on it.
+type solver struct {
+	m *Manager
+
+	// reqs maps a package to the constraints placed on it, keyed by the
+	// package placing the constraint.
+	reqs  map[string]map[string]string
+	types map[string]v1beta1.PackageType
+	// picked maps a package to the version currently picked for it.
+	picked map[string]*xpkg.ParsedPackage
+	rounds map[string]int
+	// dirty holds the packages whose constraints changed since they were
+	// last resolved.
+	dirty   map[string]bool
+	visited map[string]bool
+	// missing holds the packages that could not be found in the cache when
+	// resolving offline.
+	missing map[string]bool
+}
+
+func (s *solver) require(by string, d v1beta1.Dependency) {
+	if s.reqs[d.Package] == nil {
+		s.reqs[d.Package] = make(map[string]string)
+	}
+	s.reqs[d.Package][by] = d.Constraints
+	if d.Type != "" {
+		s.types[d.Package] = d.Type
+	}
+	s.dirty[d.Package] = true
+}
+
+// release removes the constraints placed by the supplied package version.
+func (s *solver) release(src string, p *xpkg.ParsedPackage) {
+	by := fmt.Sprintf(requiredByVersionFmt, src, p.Version())
+	for _, d := range p.Dependencies() {
+		delete(s.reqs[d.Package], by)
+		s.dirty[d.Package] = true
+	}
+}
+
+func (s *solver) solve(ctx context.Context) error { // nolint:gocyclo
+	for len(s.dirty) > 0 {
+		src := s.next()
+		delete(s.dirty, src)
+
+		prev := s.picked[src]
+		if len(s.reqs[src]) == 0 {
+			// nothing depends on the package anymore.
+			if prev != nil {
+				s.release(src, prev)
+				delete(s.picked, src)
+			}
+			delete(s.missing, src)
+			continue
+		}
+
+		s.rounds[src]++
+		if s.rounds[src] > maxSolveRounds {
+			return fmt.Errorf(errNotConvergedFmt, src)
+		}
+
+		c, ok := s.constraints(src)
+		if !ok {
+			return s.conflict(src)
+		}
+
+		s.visited[src] = true
+		p, err := s.m.retrieveAndStorePkg(ctx, v1beta1.Dependency{
+			Package:     src,
+			Type:        s.types[src],
+			Constraints: c,
+		})
+		if errors.Is(err, image.ErrNoMatchingVersion) && len(s.reqs[src]) > 1 {
+			return s.conflict(src)
+		}
+		if s.m.offline && errors.Is(err, os.ErrNotExist) {
+			// keep going so that every missing package is reported at
+			// once.
+			s.missing[src] = true
+			if prev != nil {
+				s.release(src, prev)
+				delete(s.picked, src)
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		delete(s.missing, src)
+
+		if prev != nil {
+			if prev.Version() == p.Version() {
+				continue
+			}
+			s.release(src, prev)
+		}
+		s.picked[src] = p
+		by := fmt.Sprintf(requiredByVersionFmt, src, p.Version())
+		for _, d := range p.Dependencies() {
+			s.require(by, d)
+		}
+	}
+	if len(s.missing) > 0 {
+		return s.missingErr()
+	}
+	return nil
+}
+
+// next returns the dirty package to resolve next. Packages are processed in
+// a stable order so that resolution is reproducible.
+func (s *solver) next() string {
+	srcs := make([]string, 0, len(s.dirty))
+	for src := range s.dirty {
+		srcs = append(srcs, src)
+	}
+	sort.Strings(srcs)
+	return srcs[0]
+}
+
+// constraints combines the constraints placed on the supplied package into a
+// single constraint. It returns false if the constraints cannot be combined,
+// i.e. if different versions that are not semver constraints are required.
+func (s *solver) constraints(src string) (string, bool) {
+	uniq := make(map[string]bool)
+	for _, c := range s.reqs[src] {
+		if c == "" {
+			c = image.DefaultVer
+		}
+		uniq[c] = true
+	}
+	cs := make([]string, 0, len(uniq))
+	for c := range uniq {
+		cs = append(cs, c)
+	}
+	sort.Strings(cs)
+
+	if len(cs) == 1 {
+		return cs[0], true
+	}
+	for _, c := range cs {
+		if _, err := semver.NewConstraint(c); err != nil {
+			return "", false
+		}
+	}
+	return strings.Join(cs, ", "), true
+}
+
+// conflict returns an error that lists the constraints placed on the
+// supplied package.
+func (s *solver) conflict(src string) error {
+	bys := make([]string, 0, len(s.reqs[src]))
+	for by := range s.reqs[src] {
+		bys = append(bys, by)
+	}
+	sort.Strings(bys)
+
+	var b strings.Builder
+	for _, by := range bys {
+		fmt.Fprintf(&b, conflictEntryFmt, s.reqs[src][by], by)
+	}
+	return fmt.Errorf(errConflictFmt, src, strings.TrimSuffix(b.String(), "\n"))
+}
+
+// missingErr returns an error that lists the packages missing from the cache
+// along with the constraints placed on them.
+func (s *solver) missingErr() error {
+	srcs := make([]string, 0, len(s.missing))
+	for src := range s.missing {
+		srcs = append(srcs, src)
+	}
+	sort.Strings(srcs)
+
+	var b strings.Builder
+	for _, src := range srcs {
+		bys := make([]string, 0, len(s.reqs[src]))
+		for by := range s.reqs[src] {
+			bys = append(bys, by)
+		}
+		sort.Strings(bys)
+		for _, by := range bys {
+			fmt.Fprintf(&b, missingEntryFmt, src, s.reqs[src][by], by)
+		}
+	}
+	return fmt.Errorf(errMissingFmt, strings.TrimSuffix(b.String(), "\n"))
+}
+
+// graph builds the Graph from the picked packages.
+func (s *solver) graph(roots []v1beta1.Dependency) *Graph {
+	g := &Graph{
+		Roots: make([]*Node, 0, len(roots)),
+		nodes: make(map[string]*Node, len(s.picked)),
+	}
+
+	for src, p := range s.picked {
+		n := &Node{
+			Package: src,
+			Type:    p.Type(),
+			Version: p.Version(),
+			pkg:     p,
+		}
+		bys := make([]string, 0, len(s.reqs[src]))
+		for by := range s.reqs[src] {
+			bys = append(bys, by)
+		}
+		sort.Strings(bys)
+		for _, by := range bys {
+			n.Constraints = append(n.Constraints, Constraint{
+				Constraints: s.reqs[src][by],
+				RequiredBy:  by,
+			})
+		}
+		g.nodes[src] = n
+	}
+
+	for _, n := range g.nodes {
+		for _, d := range n.pkg.Dependencies() {
+			if dn, ok := g.nodes[d.Package]; ok {
+				n.Dependencies = append(n.Dependencies, dn)
+			}
+		}
+	}
+
+	for _, r := range roots {
+		if n, ok := g.nodes[r.Package]; ok {
+			g.Roots = append(g.Roots, n)
+		}
+	}
+	return g
+}
@@ -33,6 +33,7 @@ import (
 
 	ixpkg "github.com/upbound/up/internal/xpkg"
 	"github.com/upbound/up/internal/xpkg/dep/cache"
+	"github.com/upbound/up/internal/xpkg/dep/lock"
 	xpkg "github.com/upbound/up/internal/xpkg/dep/marshaler/xpkg"
 	"github.com/upbound/up/internal/xpkg/dep/resolver/image"
 )
@@ -45,6 +46,10 @@ const (
 	defaultWatchInterval = "100ms"
 
 	errInvalidSemVerConstraintFmt = "invalid semver constraint %v: %w"
+	errLockedDigestMismatchFmt    = "digest of %s:%s does not match the lock file (locked %s, found %s); re-run with --update to accept the new digest"
+	errNotCachedFmt               = "%s:%s is not in the cache: %w"
+	errVerifyFmt                  = "failed to verify %s:%s: %w"
+	errDigestChangedFmt           = "digest of %s:%s changed after verification (verified %s, pulled %s)"
 )
 
 // Manager defines a dependency Manager
@@ -53,9 +58,17 @@ type Manager struct {
 	i             ImageResolver
 	x             XpkgMarshaler
 	log           logging.Logger
-	cacheRoot     string
 	watchInterval *time.Duration
 
+	// lock holds the versions pinned by a pre-existing lock file, if any.
+	lock *lock.Lock
+	// resolved records every package resolved by the Manager.
+	resolved *lock.Lock
+	// offline resolves dependencies against the cache only.
+	offline bool
+	// v verifies the signature of every package before it is used, if set.
+	v Verifier
+
 	acc []*xpkg.ParsedPackage
 }
 
@@ -64,7 +77,10 @@ type Cache interface {
 	Get(v1beta1.Dependency) (*xpkg.ParsedPackage, error)
 	Store(v1beta1.Dependency, *xpkg.ParsedPackage) error
 	Versions(v1beta1.Dependency) ([]string, error)
+	ObjectPath(v1beta1.Dependency, string) (string, error)
+	List() ([]*xpkg.ParsedPackage, error)
 	Watch() <-chan cache.Event
+	Unwatch(<-chan cache.Event)
 }
 
 // ImageResolver defines the API contract for working with an
@@ -75,6 +91,12 @@ type ImageResolver interface {
 	ResolveTag(context.Context, v1beta1.Dependency) (string, error)
 }
 
+// Verifier defines the API contract for verifying the signature of a package
+// at a given digest.
+type Verifier interface {
+	Verify(context.Context, name.Digest) error
+}
+
 // XpkgMarshaler defines the API contract for working with an
 // xpkg.ParsedPackage marshaler.
 type XpkgMarshaler interface {
@@ -91,21 +113,10 @@ func New(opts ...Option) (*Manager, error) {
 
 	m := &Manager{
 		log:           logging.NewNopLogger(),
-		cacheRoot:     defaultCacheRoot,
 		watchInterval: &interval,
 	}
 
-	// TODO(@tnthornton) move this resolution to the config.
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return nil, err
-	}
-
-	c, err := cache.NewLocal(
-		filepath.Join(
-			filepath.Clean(home),
-			m.cacheRoot,
-		),
+	c, err := NewLocalCache(
 		cache.WithLogger(m.log),
 		cache.WithWatchInterval(m.watchInterval),
 	)
@@ -122,6 +133,7 @@ func New(opts ...Option) (*Manager, error) {
 	m.c = c
 	m.x = x
 	m.acc = make([]*xpkg.ParsedPackage, 0)
+	m.resolved = lock.New()
 
 	for _, o := range opts {
 		o(m)
@@ -130,6 +142,18 @@ func New(opts ...Option) (*Manager, error) {
 	return m, nil
 }
 
+// NewLocalCache returns the cache.Local at the default cache root, which is
+// used by a Manager unless it is supplied a cache. It lets several Managers
+// share one cache.
+func NewLocalCache(opts ...cache.Option) (*cache.Local, error) {
+	// TODO(@tnthornton) move this resolution to the config.
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return cache.NewLocal(filepath.Join(filepath.Clean(home), defaultCacheRoot), opts...)
+}
+
 // Option modifies the Manager.
 type Option func(*Manager)
 
@@ -140,6 +164,31 @@ func WithCache(c Cache) Option {
 	}
 }
 
+// WithLock pins dependency resolution to the versions recorded in the
+// supplied lock.Lock. Locked versions that no longer satisfy the constraints
+// of a dependency are ignored and re-resolved.
+func WithLock(l *lock.Lock) Option {
+	return func(m *Manager) {
+		m.lock = l
+	}
+}
+
+// WithOffline configures the Manager to resolve dependencies against the
+// versions present in the cache only, without contacting any registry.
+func WithOffline() Option {
+	return func(m *Manager) {
+		m.offline = true
+	}
+}
+
+// WithVerifier sets the Verifier used to require a valid signature for every
+// package the Manager resolves from a registry.
+func WithVerifier(v Verifier) Option {
+	return func(m *Manager) {
+		m.v = v
+	}
+}
+
 // WithLogger overrides the default logger with the supplied logger.
 func WithLogger(l logging.Logger) Option {
 	return func(m *Manager) {
@@ -190,11 +239,34 @@ func (m *Manager) Versions(ctx context.Context, d v1beta1.Dependency) ([]string,
 	return m.c.Versions(d)
 }
 
+// Cached returns every package that currently exists in the cache,
+// regardless of whether it is a dependency.
+func (m *Manager) Cached() ([]*xpkg.ParsedPackage, error) {
+	return m.c.List()
+}
+
+// ObjectPath returns the path of the file the object with the supplied name
+// is stored in within the cache entry of the supplied resolved dependency.
+func (m *Manager) ObjectPath(d v1beta1.Dependency, name string) (string, error) {
+	return m.c.ObjectPath(d, name)
+}
+
+// Lock returns a lock.Lock recording every package (both defined and
+// transitive) resolved by the Manager so far.
+func (m *Manager) Lock() *lock.Lock {
+	return m.resolved
+}
+
 // Watch provides a hook for watching changes coming from the cache.
 func (m *Manager) Watch() <-chan cache.Event {
 	return m.c.Watch()
 }
 
+// Unwatch stops the supplied watch returned by Watch.
+func (m *Manager) Unwatch(ch <-chan cache.Event) {
+	m.c.Unwatch(ch)
+}
+
 // Resolve resolves the given package as well as it's transitive dependencies. If dependencies
 // are not included in the current cache, an error is returned.
 func (m *Manager) Resolve(ctx context.Context, d v1beta1.Dependency) (v1beta1.Dependency, []*xpkg.ParsedPackage, error) {
@@ -223,21 +295,16 @@ func (m *Manager) Resolve(ctx context.Context, d v1beta1.Dependency) (v1beta1.De
 func (m *Manager) AddAll(ctx context.Context, d v1beta1.Dependency) (v1beta1.Dependency, []*xpkg.ParsedPackage, error) {
 	ud := v1beta1.Dependency{}
 
-	e, err := m.retrieveAndStorePkg(ctx, d)
+	g, err := m.ResolveGraph(ctx, []v1beta1.Dependency{d})
 	if err != nil {
 		return ud, m.acc, err
 	}
-	m.acc = append(m.acc, e)
-
-	// recursively resolve all transitive dependencies
-	// currently assumes we have something from
-	if err := m.addAllDeps(ctx, e); err != nil {
-		return ud, m.acc, err
-	}
+	m.acc = append(m.acc, g.Packages()...)
 
-	ud.Type = e.Type()
+	e := g.Roots[0]
+	ud.Type = e.Type
 	ud.Package = d.Package
-	ud.Constraints = e.Version()
+	ud.Constraints = e.Version
 
 	return ud, m.acc, nil
 }
@@ -263,42 +330,51 @@ func (m *Manager) retrieveAllDeps(ctx context.Context, p *xpkg.ParsedPackage) er
 	return nil
 }
 
-// addAllDeps recursively resolves the transitive dependencies for a
-// given xpkg.ParsedPackage.
-func (m *Manager) addAllDeps(ctx context.Context, p *xpkg.ParsedPackage) error {
-
-	if len(p.Dependencies()) == 0 {
-		// no remaining dependencies to resolve
-		return nil
-	}
-
-	for _, d := range p.Dependencies() {
-		e, err := m.retrieveAndStorePkg(ctx, d)
-		if err != nil {
-			return err
-		}
-		m.acc = append(m.acc, e)
+// Offline returns true if the Manager resolves dependencies against the cache
+// only.
+func (m *Manager) Offline() bool {
+	return m.offline
+}
 
-		if err := m.addAllDeps(ctx, e); err != nil {
-			return err
-		}
+// AddImage stores the supplied package image in the cache under the supplied
+// tag, e.g. to seed the cache from a package file.
+func (m *Manager) AddImage(tag name.Tag, i v1.Image) (*xpkg.ParsedPackage, error) {
+	d := v1beta1.Dependency{
+		Package:     tag.Repository.Name(),
+		Constraints: tag.TagStr(),
 	}
-
-	return nil
+	return m.storeImage(d, tag, tag.TagStr(), i)
 }
 
-func (m *Manager) addPkg(ctx context.Context, d v1beta1.Dependency) (*xpkg.ParsedPackage, error) {
+// addPkg pulls the package satisfying the supplied dependency and stores it
+// in the cache. If want is not empty the pulled image must have that digest,
+// e.g. the digest whose signature was verified; otherwise nothing is stored.
+func (m *Manager) addPkg(ctx context.Context, d v1beta1.Dependency, want string) (*xpkg.ParsedPackage, error) {
 	// this is expensive
 	t, i, err := m.i.ResolveImage(ctx, d)
 	if err != nil {
 		return nil, err
 	}
 
+	if want != "" {
+		got, err := i.Digest()
+		if err != nil {
+			return nil, err
+		}
+		if got.String() != want {
+			return nil, fmt.Errorf(errDigestChangedFmt, d.Package, d.Constraints, want, got.String())
+		}
+	}
+
 	tag, err := name.NewTag(d.Package)
 	if err != nil {
 		return nil, err
 	}
 
+	return m.storeImage(d, tag, t, i)
+}
+
+func (m *Manager) storeImage(d v1beta1.Dependency, tag name.Tag, t string, i v1.Image) (*xpkg.ParsedPackage, error) {
 	digest, err := i.Digest()
 	if err != nil {
 		return nil, err
@@ -339,10 +415,20 @@ func (m *Manager) retrievePkg(ctx context.Context, d v1beta1.Dependency) (*xpkg.
 		return nil, err
 	}
 
-	return m.c.Get(d)
+	p, err := m.c.Get(d)
+	if err != nil {
+		return nil, err
+	}
+
+	m.record(d, p)
+	return p, nil
 }
 
 func (m *Manager) retrieveAndStorePkg(ctx context.Context, d v1beta1.Dependency) (*xpkg.ParsedPackage, error) {
+	if m.offline {
+		return m.retrieveCachedPkg(d)
+	}
+
 	// resolve version prior to Get
 	if err := m.finalizeExtDepVersion(ctx, &d); err != nil {
 		return nil, fmt.Errorf("failed to resolve %s:%s: %w", d.Package, d.Constraints, err)
@@ -354,32 +440,143 @@ func (m *Manager) retrieveAndStorePkg(ctx context.Context, d v1beta1.Dependency)
 	}
 
 	if os.IsNotExist(err) {
+		// verified is the digest whose signature was checked; the pulled
+		// image must match it.
+		var verified string
+		if m.v != nil {
+			digest, err := m.i.ResolveDigest(ctx, d)
+			if err != nil {
+				return nil, err
+			}
+			if err := m.verify(ctx, d, digest); err != nil {
+				return nil, err
+			}
+			verified = digest
+		}
 		// root dependency does not yet exist in cache, store it
-		p, err = m.addPkg(ctx, d)
+		p, err = m.addPkg(ctx, d, verified)
 		if err != nil {
 			return nil, err
 		}
+		if err := m.checkLockedDigest(d, p.Digest()); err != nil {
+			return nil, err
+		}
 	} else {
 		// check if digest is different from what we have locally
 		digest, err := m.i.ResolveDigest(ctx, d)
 		if err != nil {
 			return nil, err
 		}
+		if err := m.checkLockedDigest(d, digest); err != nil {
+			return nil, err
+		}
+		if err := m.verify(ctx, d, digest); err != nil {
+			return nil, err
+		}
 
 		if p.Digest() != digest {
 			// digest is different, update what we have
-			p, err = m.addPkg(ctx, d)
+			verified := ""
+			if m.v != nil {
+				verified = digest
+			}
+			p, err = m.addPkg(ctx, d, verified)
 			if err != nil {
 				return nil, err
 			}
 		}
 	}
 
+	m.record(d, p)
+	return p, nil
+}
+
+// verify verifies the signature of the supplied dependency at the supplied
+// digest if the Manager has a Verifier.
+func (m *Manager) verify(ctx context.Context, d v1beta1.Dependency, digest string) error {
+	if m.v == nil {
+		return nil
+	}
+	ref, err := name.NewDigest(fmt.Sprintf("%s@%s", d.Package, digest))
+	if err != nil {
+		return fmt.Errorf(errVerifyFmt, d.Package, d.Constraints, err)
+	}
+	if err := m.v.Verify(ctx, ref); err != nil {
+		return fmt.Errorf(errVerifyFmt, d.Package, d.Constraints, err)
+	}
+	return nil
+}
+
+// retrieveCachedPkg retrieves the package satisfying the supplied dependency
+// from the cache only. If no cached version satisfies the dependency the
+// returned error wraps os.ErrNotExist.
+func (m *Manager) retrieveCachedPkg(d v1beta1.Dependency) (*xpkg.ParsedPackage, error) {
+	if d.Constraints == "" {
+		d.Constraints = image.DefaultVer
+	}
+	constraints := d.Constraints
+
+	// constraints that are not semver constraints, e.g. digests, can only be
+	// looked up as is.
+	if _, err := semver.NewConstraint(d.Constraints); err == nil {
+		if err := m.finalizeLocalDepVersion(context.Background(), &d); err != nil {
+			return nil, fmt.Errorf(errNotCachedFmt, d.Package, constraints, err)
+		}
+	}
+
+	p, err := m.c.Get(d)
+	if err != nil {
+		return nil, fmt.Errorf(errNotCachedFmt, d.Package, constraints, err)
+	}
+	if err := m.checkLockedDigest(d, p.Digest()); err != nil {
+		return nil, err
+	}
+
+	m.record(d, p)
 	return p, nil
 }
 
+// record adds the supplied resolved dependency and its package to the
+// Manager's resolved lock.
+func (m *Manager) record(d v1beta1.Dependency, p *xpkg.ParsedPackage) {
+	deps := make([]lock.Dependency, len(p.Dependencies()))
+	for i, pd := range p.Dependencies() {
+		deps[i] = lock.Dependency{
+			Source:      pd.Package,
+			Constraints: pd.Constraints,
+		}
+	}
+	m.resolved.Upsert(lock.Package{
+		Source:       d.Package,
+		Type:         p.Type(),
+		Version:      d.Constraints,
+		Digest:       p.Digest(),
+		Dependencies: deps,
+	})
+}
+
+// checkLockedDigest returns an error if the supplied dependency is pinned by
+// the lock to a different digest than the supplied one, i.e. the locked tag
+// has been moved in the registry.
+func (m *Manager) checkLockedDigest(d v1beta1.Dependency, digest string) error {
+	p, ok := m.lock.Get(d.Package)
+	if !ok || p.Version != d.Constraints || p.Digest == "" {
+		return nil
+	}
+	if p.Digest != digest {
+		return fmt.Errorf(errLockedDigestMismatchFmt, d.Package, d.Constraints, p.Digest, digest)
+	}
+	return nil
+}
+
 // finalizeExtDepVersion sets the resolved tag version on the supplied v1beta1.Dependency.
 func (m *Manager) finalizeExtDepVersion(ctx context.Context, d *v1beta1.Dependency) error {
+	// prefer the version pinned by the lock if it still satisfies the
+	// constraints
+	if pd, ok := m.lock.Pin(*d); ok {
+		*d = pd
+	}
+
 	// determine the version (using resolver) to use based on the supplied constraints
 	v, err := m.i.ResolveTag(ctx, *d)
 	if err != nil {
@@ -393,6 +590,13 @@ func (m *Manager) finalizeExtDepVersion(ctx context.Context, d *v1beta1.Dependen
 // finalizeLocalDepVersion sets the resolve tag version on the supplied v1beta1.Dependency
 // based on versions currently located in the cache.
 func (m *Manager) finalizeLocalDepVersion(_ context.Context, d *v1beta1.Dependency) error {
+	// prefer the version pinned by the lock if it still satisfies the
+	// constraints
+	if pd, ok := m.lock.Pin(*d); ok {
+		*d = pd
+		return nil
+	}
+
 	// check up front if we already have a semver constraint
 	c, err := semver.NewConstraint(d.Constraints)
 	if err != nil {
@@ -18,6 +18,7 @@ import (
 	"archive/tar"
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"testing"
 
@@ -40,6 +41,7 @@ import (
 
 	"github.com/upbound/up/internal/xpkg"
 	"github.com/upbound/up/internal/xpkg/dep/cache"
+	"github.com/upbound/up/internal/xpkg/dep/lock"
 	"github.com/upbound/up/internal/xpkg/dep/resolver/image"
 )
 
@@ -425,7 +427,7 @@ func TestSnapshot(t *testing.T) {
 			)
 
 			// add the pkg to the cache
-			m.addPkg(ctx, tc.args.dep)
+			m.addPkg(ctx, tc.args.dep, "")
 
 			got, err := m.View(ctx, []v1beta1.Dependency{tc.args.dep})
 
@@ -444,9 +446,381 @@ func TestSnapshot(t *testing.T) {
 	}
 }
 
+func TestAddAllWithLock(t *testing.T) {
+	ctx := context.Background()
+
+	meta := &metav1.Provider{
+		TypeMeta: apimetav1.TypeMeta{
+			APIVersion: "meta.pkg.crossplane.io/v1alpha1",
+			Kind:       "Provider",
+		},
+	}
+	digest, _ := newPackageImage(meta).Digest()
+
+	dep := v1beta1.Dependency{
+		Package:     "crossplane/provider-aws",
+		Constraints: ">=v0.1.0",
+	}
+
+	type want struct {
+		version string
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason string
+		lock   *lock.Lock
+		want   want
+	}{
+		"NoLock": {
+			reason: "Should resolve the newest version matching the constraints.",
+			want: want{
+				version: "v0.2.0",
+			},
+		},
+		"Pinned": {
+			reason: "Should resolve the version pinned by the lock.",
+			lock: &lock.Lock{
+				Version: lock.Version,
+				Packages: []lock.Package{
+					{Source: dep.Package, Version: "v0.1.0", Digest: digest.String()},
+				},
+			},
+			want: want{
+				version: "v0.1.0",
+			},
+		},
+		"ErrDigestMismatch": {
+			reason: "Should return an error if the locked tag now points to a different digest.",
+			lock: &lock.Lock{
+				Version: lock.Version,
+				Packages: []lock.Package{
+					{Source: dep.Package, Version: "v0.1.0", Digest: "sha256:0000"},
+				},
+			},
+			want: want{
+				err: fmt.Errorf(errLockedDigestMismatchFmt, dep.Package, "v0.1.0", "sha256:0000", digest.String()),
+			},
+		},
+	}
+
+	for n, tc := range cases {
+		t.Run(n, func(t *testing.T) {
+			c, _ := cache.NewLocal("/tmp/cache", cache.WithFS(afero.NewMemMapFs()))
+
+			ref1, _ := name.ParseReference("crossplane/provider-aws:v0.1.0")
+			ref2, _ := name.ParseReference("crossplane/provider-aws:v0.2.0")
+			f := NewMockFetcher(
+				WithPackageObjects(ref1, meta),
+				WithPackageObjects(ref2, meta),
+			)
+			f.tags = []string{"v0.1.0", "v0.2.0"}
+
+			m, _ := New(
+				WithCache(c),
+				WithLock(tc.lock),
+				WithResolver(image.NewResolver(image.WithFetcher(f))),
+			)
+
+			ud, _, err := m.AddAll(ctx, dep)
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nAddAll(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want.version, ud.Constraints); diff != "" {
+				t.Errorf("\n%s\nAddAll(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			got, _ := m.Lock().Get(dep.Package)
+			if diff := cmp.Diff(tc.want.version, got.Version); diff != "" {
+				t.Errorf("\n%s\nLock(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestResolveGraph(t *testing.T) {
+	ctx := context.Background()
+
+	config := &metav1.Configuration{
+		TypeMeta: apimetav1.TypeMeta{
+			APIVersion: "meta.pkg.crossplane.io/v1",
+			Kind:       "Configuration",
+		},
+		Spec: metav1.ConfigurationSpec{
+			MetaSpec: metav1.MetaSpec{
+				DependsOn: []metav1.Dependency{
+					{
+						Provider: ptr.To("crossplane/provider-aws"),
+						Version:  "<v0.2.0",
+					},
+				},
+			},
+		},
+	}
+	provider := &metav1.Provider{
+		TypeMeta: apimetav1.TypeMeta{
+			APIVersion: "meta.pkg.crossplane.io/v1",
+			Kind:       "Provider",
+		},
+	}
+
+	type want struct {
+		versions map[string]string
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason string
+		deps   []v1beta1.Dependency
+		want   want
+	}{
+		"SatisfyAllConstraints": {
+			reason: "Should pick the newest version that satisfies the constraints of every dependant.",
+			deps: []v1beta1.Dependency{
+				{Package: "crossplane/configuration-a", Constraints: "v0.1.0"},
+				{Package: "crossplane/provider-aws", Constraints: ">=v0.1.0"},
+			},
+			want: want{
+				versions: map[string]string{
+					"crossplane/configuration-a": "v0.1.0",
+					"crossplane/provider-aws":    "v0.1.0",
+				},
+			},
+		},
+		"ErrConflict": {
+			reason: "Should return an error listing the conflicting constraints.",
+			deps: []v1beta1.Dependency{
+				{Package: "crossplane/configuration-a", Constraints: "v0.1.0"},
+				{Package: "crossplane/provider-aws", Constraints: ">=v0.2.0"},
+			},
+			want: want{
+				err: errors.New("conflicting constraints for crossplane/provider-aws:\n" +
+					"\t>=v0.2.0 required by crossplane.yaml\n" +
+					"\t<v0.2.0 required by crossplane/configuration-a@v0.1.0"),
+			},
+		},
+	}
+
+	for n, tc := range cases {
+		t.Run(n, func(t *testing.T) {
+			c, _ := cache.NewLocal("/tmp/cache", cache.WithFS(afero.NewMemMapFs()))
+
+			cref, _ := name.ParseReference("crossplane/configuration-a:v0.1.0")
+			pref1, _ := name.ParseReference("crossplane/provider-aws:v0.1.0")
+			pref2, _ := name.ParseReference("crossplane/provider-aws:v0.2.0")
+			f := NewMockFetcher(
+				WithPackageObjects(cref, config),
+				WithPackageObjects(pref1, provider),
+				WithPackageObjects(pref2, provider),
+			)
+			f.tags = []string{"v0.1.0", "v0.2.0"}
+
+			m, _ := New(
+				WithCache(c),
+				WithResolver(image.NewResolver(image.WithFetcher(f))),
+			)
+
+			g, err := m.ResolveGraph(ctx, tc.deps)
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nResolveGraph(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			for pkg, ver := range tc.want.versions {
+				n, ok := g.Node(pkg)
+				if !ok {
+					t.Errorf("\n%s\nResolveGraph(...): missing node for %s", tc.reason, pkg)
+					continue
+				}
+				if diff := cmp.Diff(ver, n.Version); diff != "" {
+					t.Errorf("\n%s\nResolveGraph(...): -want, +got:\n%s", tc.reason, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveGraphOffline(t *testing.T) {
+	ctx := context.Background()
+
+	provider := &metav1.Provider{
+		TypeMeta: apimetav1.TypeMeta{
+			APIVersion: "meta.pkg.crossplane.io/v1",
+			Kind:       "Provider",
+		},
+	}
+
+	type want struct {
+		version string
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason string
+		deps   []v1beta1.Dependency
+		want   want
+	}{
+		"Cached": {
+			reason: "Should resolve the newest cached version that satisfies the constraints.",
+			deps: []v1beta1.Dependency{
+				{Package: "crossplane/provider-aws", Constraints: ">=v0.1.0"},
+			},
+			want: want{
+				version: "v0.2.0",
+			},
+		},
+		"ErrMissing": {
+			reason: "Should return an error listing every dependency that is not cached.",
+			deps: []v1beta1.Dependency{
+				{Package: "crossplane/provider-aws", Constraints: ">=v0.3.0"},
+				{Package: "crossplane/provider-gcp", Constraints: ">=v0.1.0"},
+			},
+			want: want{
+				err: errors.New("dependencies missing from the cache:\n" +
+					"\tcrossplane/provider-aws:>=v0.3.0 required by crossplane.yaml\n" +
+					"\tcrossplane/provider-gcp:>=v0.1.0 required by crossplane.yaml"),
+			},
+		},
+	}
+
+	for n, tc := range cases {
+		t.Run(n, func(t *testing.T) {
+			c, _ := cache.NewLocal("/tmp/cache", cache.WithFS(afero.NewMemMapFs()))
+
+			// the fetcher fails every request to ensure that no registry is
+			// contacted.
+			f := NewMockFetcher()
+			f.err = errors.New("offline")
+
+			m, _ := New(
+				WithCache(c),
+				WithOffline(),
+				WithResolver(image.NewResolver(image.WithFetcher(f))),
+			)
+
+			for _, v := range []string{"v0.1.0", "v0.2.0"} {
+				tag, _ := name.NewTag("crossplane/provider-aws:" + v)
+				if _, err := m.AddImage(tag, newPackageImage(provider)); err != nil {
+					t.Fatalf("\n%s\nAddImage(...): unexpected error: %s", tc.reason, err)
+				}
+			}
+
+			g, err := m.ResolveGraph(ctx, tc.deps)
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nResolveGraph(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			n, _ := g.Node("crossplane/provider-aws")
+			if diff := cmp.Diff(tc.want.version, n.Version); diff != "" {
+				t.Errorf("\n%s\nResolveGraph(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestResolveGraphVerify(t *testing.T) {
+	ctx := context.Background()
+
+	provider := &metav1.Provider{
+		TypeMeta: apimetav1.TypeMeta{
+			APIVersion: "meta.pkg.crossplane.io/v1",
+			Kind:       "Provider",
+		},
+	}
+	h, _ := newPackageImage(provider).Digest()
+	digest := h.String()
+	other := "sha256:d507e508234732c6dc95d29c8a8c932fa8fa6a229231e309927641f99933892e"
+	errBoom := errors.New("boom")
+
+	type want struct {
+		verified []string
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason string
+		digest string
+		err    error
+		want   want
+	}{
+		"Verified": {
+			reason: "Should verify the resolved digest of every package.",
+			want: want{
+				verified: []string{"crossplane/provider-aws@" + digest},
+			},
+		},
+		"ErrVerify": {
+			reason: "Should return an error if a package cannot be verified.",
+			err:    errBoom,
+			want: want{
+				verified: []string{"crossplane/provider-aws@" + digest},
+				err:      fmt.Errorf(errVerifyFmt, "crossplane/provider-aws", "v0.1.0", errBoom),
+			},
+		},
+		"ErrDigestChanged": {
+			reason: "Should return an error if the pulled package does not have the verified digest.",
+			digest: other,
+			want: want{
+				verified: []string{"crossplane/provider-aws@" + other},
+				err:      fmt.Errorf(errDigestChangedFmt, "crossplane/provider-aws", "v0.1.0", other, digest),
+			},
+		},
+	}
+
+	for n, tc := range cases {
+		t.Run(n, func(t *testing.T) {
+			c, _ := cache.NewLocal("/tmp/cache", cache.WithFS(afero.NewMemMapFs()))
+
+			pref, _ := name.ParseReference("crossplane/provider-aws:v0.1.0")
+			f := NewMockFetcher(WithPackageObjects(pref, provider))
+			f.digest = digest
+			if tc.digest != "" {
+				f.digest = tc.digest
+			}
+			v := &MockVerifier{err: tc.err}
+
+			m, _ := New(
+				WithCache(c),
+				WithVerifier(v),
+				WithResolver(image.NewResolver(image.WithFetcher(f))),
+			)
+
+			_, err := m.ResolveGraph(ctx, []v1beta1.Dependency{
+				{Package: "crossplane/provider-aws", Constraints: "v0.1.0"},
+			})
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nResolveGraph(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.verified, v.verified); diff != "" {
+				t.Errorf("\n%s\nResolveGraph(...): -want verified, +got verified:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+type MockVerifier struct {
+	verified []string
+	err      error
+}
+
+func (v *MockVerifier) Verify(_ context.Context, d name.Digest) error {
+	v.verified = append(v.verified, d.String())
+	return v.err
+}
+
 type MockFetcher struct {
 	pkgMeta map[name.Reference][]runtime.Object
 	tags    []string
+	digest  string
 	err     error
 }
 
@@ -481,6 +855,9 @@ func (m *MockFetcher) Fetch(ctx context.Context, ref name.Reference, secrets ...
 }
 func (m *MockFetcher) Head(ctx context.Context, ref name.Reference, secrets ...string) (*v1.Descriptor, error) {
 	h, _ := v1.NewHash("test")
+	if m.digest != "" {
+		h, _ = v1.NewHash(m.digest)
+	}
 
 	return &v1.Descriptor{
 		Digest: h,
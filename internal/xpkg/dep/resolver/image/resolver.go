@@ -41,6 +41,10 @@ const (
 	errTagDoesNotExist    = "supplied tag does not exist in the registry"
 )
 
+// ErrNoMatchingVersion is returned when no tag in the registry satisfies the
+// constraints of a dependency.
+var ErrNoMatchingVersion = errors.New(errNoMatchingVersion)
+
 // Resolver --
 type Resolver struct {
 	f Fetcher
@@ -159,7 +163,7 @@ func (r *Resolver) ResolveTag(ctx context.Context, dep v1beta1.Dependency) (stri
 	}
 
 	if ver == "" {
-		return "", errors.New(errNoMatchingVersion)
+		return "", ErrNoMatchingVersion
 	}
 
 	return ver, nil
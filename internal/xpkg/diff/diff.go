@@ -0,0 +1,347 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package diff compares the APIs defined by the CRDs and XRDs of two
+// packages.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	xpextv1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	extv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	crdKind = "CustomResourceDefinition"
+
+	errConvertCRDFmt  = "failed to convert CustomResourceDefinition %s"
+	errParseSchemaFmt = "failed to parse schema of %s version %s"
+)
+
+// ChangeType is the type of an API Change.
+type ChangeType string
+
+// Types of API changes.
+const (
+	KindAdded             ChangeType = "KindAdded"
+	KindRemoved           ChangeType = "KindRemoved"
+	VersionAdded          ChangeType = "VersionAdded"
+	VersionRemoved        ChangeType = "VersionRemoved"
+	VersionServed         ChangeType = "VersionServed"
+	VersionNotServed      ChangeType = "VersionNotServed"
+	StorageVersionChanged ChangeType = "StorageVersionChanged"
+	FieldAdded            ChangeType = "FieldAdded"
+	FieldRemoved          ChangeType = "FieldRemoved"
+	FieldRequired         ChangeType = "FieldRequired"
+)
+
+// Change is a single difference between the APIs of two packages.
+type Change struct {
+	// Type is the type of the change.
+	Type ChangeType `json:"type"`
+	// Definition is the kind of the object defining the API, i.e.
+	// CustomResourceDefinition or CompositeResourceDefinition.
+	Definition string `json:"definition"`
+	// Kind identifies the changed API in the form kind.group.
+	Kind string `json:"kind"`
+	// Version is the changed version of the API, if any.
+	Version string `json:"version,omitempty"`
+	// Field is the path of the changed field, if any.
+	Field string `json:"field,omitempty"`
+	// Breaking is true if clients of the API may break because of the
+	// change.
+	Breaking bool `json:"breaking"`
+	// Message describes the change.
+	Message string `json:"message"`
+}
+
+// Compare compares the APIs defined by the CRDs and XRDs in the supplied
+// objects of two packages. Changes are returned ordered by kind, version and
+// field.
+func Compare(from, to []runtime.Object) ([]Change, error) {
+	fa, err := apis(from)
+	if err != nil {
+		return nil, err
+	}
+	ta, err := apis(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]Change, 0)
+	for _, k := range kinds(fa, ta) {
+		f, fok := fa[k]
+		t, tok := ta[k]
+		switch {
+		case !tok:
+			changes = append(changes, f.change(KindRemoved, "", "", true, "kind was removed"))
+		case !fok:
+			changes = append(changes, t.change(KindAdded, "", "", false, "kind was added"))
+		default:
+			changes = append(changes, compareAPIs(f, t)...)
+		}
+	}
+	return changes, nil
+}
+
+// Breaking returns the number of breaking changes.
+func Breaking(changes []Change) int {
+	n := 0
+	for _, c := range changes {
+		if c.Breaking {
+			n++
+		}
+	}
+	return n
+}
+
+// api is a kind defined by a CRD or XRD.
+type api struct {
+	definition string
+	kind       string
+	storage    string
+	versions   map[string]version
+}
+
+type version struct {
+	served bool
+	schema *extv1.JSONSchemaProps
+}
+
+func (a api) change(t ChangeType, version, field string, breaking bool, msg string) Change {
+	return Change{
+		Type:       t,
+		Definition: a.definition,
+		Kind:       a.kind,
+		Version:    version,
+		Field:      field,
+		Breaking:   breaking,
+		Message:    msg,
+	}
+}
+
+func compareAPIs(f, t api) []Change {
+	changes := make([]Change, 0)
+	if f.storage != t.storage && f.storage != "" && t.storage != "" {
+		changes = append(changes, t.change(StorageVersionChanged, t.storage, "", false, fmt.Sprintf("storage version changed from %s to %s", f.storage, t.storage)))
+	}
+
+	for _, v := range versions(f, t) {
+		fv, fok := f.versions[v]
+		tv, tok := t.versions[v]
+		switch {
+		case !tok:
+			changes = append(changes, f.change(VersionRemoved, v, "", true, "version was removed"))
+			continue
+		case !fok:
+			changes = append(changes, t.change(VersionAdded, v, "", false, "version was added"))
+			continue
+		case fv.served && !tv.served:
+			changes = append(changes, t.change(VersionNotServed, v, "", true, "version is no longer served"))
+		case !fv.served && tv.served:
+			changes = append(changes, t.change(VersionServed, v, "", false, "version is now served"))
+		}
+		changes = append(changes, compareSchemas(t, v, "", fv.schema, tv.schema)...)
+	}
+	return changes
+}
+
+// compareSchemas recursively compares the properties of the supplied
+// schemas. Fields that were removed or became required are breaking.
+func compareSchemas(a api, v, path string, from, to *extv1.JSONSchemaProps) []Change { //nolint:gocyclo
+	changes := make([]Change, 0)
+	if from == nil || to == nil {
+		return changes
+	}
+
+	freq := set(from.Required)
+	treq := set(to.Required)
+	names := make([]string, 0, len(from.Properties)+len(to.Properties))
+	for n := range from.Properties {
+		names = append(names, n)
+	}
+	for n := range to.Properties {
+		if _, ok := from.Properties[n]; !ok {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		field := join(path, n)
+		fp, fok := from.Properties[n]
+		tp, tok := to.Properties[n]
+		switch {
+		case !tok:
+			changes = append(changes, a.change(FieldRemoved, v, field, true, "field was removed"))
+			continue
+		case !fok && treq[n]:
+			changes = append(changes, a.change(FieldRequired, v, field, true, "required field was added"))
+			continue
+		case !fok:
+			changes = append(changes, a.change(FieldAdded, v, field, false, "field was added"))
+			continue
+		case !freq[n] && treq[n]:
+			changes = append(changes, a.change(FieldRequired, v, field, true, "field became required"))
+		}
+		changes = append(changes, compareSchemas(a, v, field, &fp, &tp)...)
+	}
+
+	if from.Items != nil && to.Items != nil {
+		changes = append(changes, compareSchemas(a, v, path+"[*]", from.Items.Schema, to.Items.Schema)...)
+	}
+	return changes
+}
+
+// apis returns the kinds defined by the CRDs and XRDs in the supplied
+// objects. Claims offered by XRDs are returned as separate kinds.
+func apis(objs []runtime.Object) (map[string]api, error) { //nolint:gocyclo
+	apis := make(map[string]api)
+	for _, o := range objs {
+		switch d := o.(type) {
+		case *extv1beta1.CustomResourceDefinition:
+			crd, err := convertV1Beta1CRD(d)
+			if err != nil {
+				return nil, err
+			}
+			a := crdAPI(crd)
+			apis[a.kind] = a
+		case *extv1.CustomResourceDefinition:
+			a := crdAPI(d)
+			apis[a.kind] = a
+		case *xpextv1.CompositeResourceDefinition:
+			a, err := xrdAPI(d, d.Spec.Names)
+			if err != nil {
+				return nil, err
+			}
+			apis[a.kind] = a
+			if d.Spec.ClaimNames == nil {
+				continue
+			}
+			c, err := xrdAPI(d, *d.Spec.ClaimNames)
+			if err != nil {
+				return nil, err
+			}
+			apis[c.kind] = c
+		}
+	}
+	return apis, nil
+}
+
+func crdAPI(crd *extv1.CustomResourceDefinition) api {
+	a := api{
+		definition: crdKind,
+		kind:       kindName(crd.Spec.Names.Kind, crd.Spec.Group),
+		versions:   make(map[string]version, len(crd.Spec.Versions)),
+	}
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			a.storage = v.Name
+		}
+		var s *extv1.JSONSchemaProps
+		if v.Schema != nil {
+			s = v.Schema.OpenAPIV3Schema
+		}
+		a.versions[v.Name] = version{served: v.Served, schema: s}
+	}
+	return a
+}
+
+func xrdAPI(xrd *xpextv1.CompositeResourceDefinition, names extv1.CustomResourceDefinitionNames) (api, error) {
+	a := api{
+		definition: xpextv1.CompositeResourceDefinitionKind,
+		kind:       kindName(names.Kind, xrd.Spec.Group),
+		versions:   make(map[string]version, len(xrd.Spec.Versions)),
+	}
+	for _, v := range xrd.Spec.Versions {
+		if v.Referenceable {
+			a.storage = v.Name
+		}
+		var s *extv1.JSONSchemaProps
+		if v.Schema != nil && len(v.Schema.OpenAPIV3Schema.Raw) > 0 {
+			s = &extv1.JSONSchemaProps{}
+			if err := json.Unmarshal(v.Schema.OpenAPIV3Schema.Raw, s); err != nil {
+				return api{}, errors.Wrapf(err, errParseSchemaFmt, xrd.GetName(), v.Name)
+			}
+		}
+		a.versions[v.Name] = version{served: v.Served, schema: s}
+	}
+	return a, nil
+}
+
+func convertV1Beta1CRD(in *extv1beta1.CustomResourceDefinition) (*extv1.CustomResourceDefinition, error) {
+	internal := &apiextensions.CustomResourceDefinition{}
+	if err := extv1beta1.Convert_v1beta1_CustomResourceDefinition_To_apiextensions_CustomResourceDefinition(in, internal, nil); err != nil {
+		return nil, errors.Wrapf(err, errConvertCRDFmt, in.GetName())
+	}
+	out := &extv1.CustomResourceDefinition{}
+	if err := extv1.Convert_apiextensions_CustomResourceDefinition_To_v1_CustomResourceDefinition(internal, out, nil); err != nil {
+		return nil, errors.Wrapf(err, errConvertCRDFmt, in.GetName())
+	}
+	return out, nil
+}
+
+func kinds(a, b map[string]api) []string {
+	ks := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		ks = append(ks, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			ks = append(ks, k)
+		}
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+func versions(a, b api) []string {
+	vs := make([]string, 0, len(a.versions)+len(b.versions))
+	for v := range a.versions {
+		vs = append(vs, v)
+	}
+	for v := range b.versions {
+		if _, ok := a.versions[v]; !ok {
+			vs = append(vs, v)
+		}
+	}
+	sort.Strings(vs)
+	return vs
+}
+
+func kindName(kind, group string) string {
+	return kind + "." + group
+}
+
+func join(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func set(s []string) map[string]bool {
+	m := make(map[string]bool, len(s))
+	for _, e := range s {
+		m[e] = true
+	}
+	return m
+}
@@ -0,0 +1,206 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	xpextv1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/google/go-cmp/cmp"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func crd(versions ...extv1.CustomResourceDefinitionVersion) *extv1.CustomResourceDefinition {
+	return &extv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "buckets.s3.aws.upbound.io"},
+		Spec: extv1.CustomResourceDefinitionSpec{
+			Group:    "s3.aws.upbound.io",
+			Names:    extv1.CustomResourceDefinitionNames{Kind: "Bucket"},
+			Versions: versions,
+		},
+	}
+}
+
+func crdVersion(name string, served, storage bool, s *extv1.JSONSchemaProps) extv1.CustomResourceDefinitionVersion {
+	return extv1.CustomResourceDefinitionVersion{
+		Name:    name,
+		Served:  served,
+		Storage: storage,
+		Schema:  &extv1.CustomResourceValidation{OpenAPIV3Schema: s},
+	}
+}
+
+func object(required []string, props map[string]extv1.JSONSchemaProps) *extv1.JSONSchemaProps {
+	return &extv1.JSONSchemaProps{
+		Type:       "object",
+		Required:   required,
+		Properties: props,
+	}
+}
+
+func spec(required []string, props map[string]extv1.JSONSchemaProps) *extv1.JSONSchemaProps {
+	return object(nil, map[string]extv1.JSONSchemaProps{
+		"spec": *object(required, props),
+	})
+}
+
+var str = extv1.JSONSchemaProps{Type: "string"}
+
+func TestCompare(t *testing.T) {
+	type args struct {
+		from []runtime.Object
+		to   []runtime.Object
+	}
+	type want struct {
+		changes []Change
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoChanges": {
+			reason: "Should not return changes for identical APIs.",
+			args: args{
+				from: []runtime.Object{crd(crdVersion("v1beta1", true, true, spec(nil, map[string]extv1.JSONSchemaProps{"region": str})))},
+				to:   []runtime.Object{crd(crdVersion("v1beta1", true, true, spec(nil, map[string]extv1.JSONSchemaProps{"region": str})))},
+			},
+			want: want{
+				changes: []Change{},
+			},
+		},
+		"KindAddedAndRemoved": {
+			reason: "Should report removed kinds as breaking and added kinds as non-breaking.",
+			args: args{
+				from: []runtime.Object{crd(crdVersion("v1beta1", true, true, nil))},
+				to: []runtime.Object{&xpextv1.CompositeResourceDefinition{
+					Spec: xpextv1.CompositeResourceDefinitionSpec{
+						Group: "acme.io",
+						Names: extv1.CustomResourceDefinitionNames{Kind: "XBucket"},
+						ClaimNames: &extv1.CustomResourceDefinitionNames{
+							Kind: "Bucket",
+						},
+					},
+				}},
+			},
+			want: want{
+				changes: []Change{
+					{Type: KindAdded, Definition: xpextv1.CompositeResourceDefinitionKind, Kind: "Bucket.acme.io", Message: "kind was added"},
+					{Type: KindRemoved, Definition: crdKind, Kind: "Bucket.s3.aws.upbound.io", Breaking: true, Message: "kind was removed"},
+					{Type: KindAdded, Definition: xpextv1.CompositeResourceDefinitionKind, Kind: "XBucket.acme.io", Message: "kind was added"},
+				},
+			},
+		},
+		"VersionChanges": {
+			reason: "Should report removed and no longer served versions as breaking.",
+			args: args{
+				from: []runtime.Object{crd(
+					crdVersion("v1alpha1", true, false, nil),
+					crdVersion("v1beta1", true, true, nil),
+				)},
+				to: []runtime.Object{crd(
+					crdVersion("v1beta1", false, false, nil),
+					crdVersion("v1beta2", true, true, nil),
+				)},
+			},
+			want: want{
+				changes: []Change{
+					{Type: StorageVersionChanged, Definition: crdKind, Kind: "Bucket.s3.aws.upbound.io", Version: "v1beta2", Message: "storage version changed from v1beta1 to v1beta2"},
+					{Type: VersionRemoved, Definition: crdKind, Kind: "Bucket.s3.aws.upbound.io", Version: "v1alpha1", Breaking: true, Message: "version was removed"},
+					{Type: VersionNotServed, Definition: crdKind, Kind: "Bucket.s3.aws.upbound.io", Version: "v1beta1", Breaking: true, Message: "version is no longer served"},
+					{Type: VersionAdded, Definition: crdKind, Kind: "Bucket.s3.aws.upbound.io", Version: "v1beta2", Message: "version was added"},
+				},
+			},
+		},
+		"FieldChanges": {
+			reason: "Should report removed and newly required fields as breaking and optional new fields as non-breaking.",
+			args: args{
+				from: []runtime.Object{crd(crdVersion("v1beta1", true, true, spec(nil, map[string]extv1.JSONSchemaProps{
+					"region": str,
+					"acl":    str,
+					"tags": {
+						Type:  "array",
+						Items: &extv1.JSONSchemaPropsOrArray{Schema: object(nil, map[string]extv1.JSONSchemaProps{"key": str})},
+					},
+				})))},
+				to: []runtime.Object{crd(crdVersion("v1beta1", true, true, spec([]string{"region", "policy"}, map[string]extv1.JSONSchemaProps{
+					"region": str,
+					"policy": str,
+					"owner":  str,
+					"tags": {
+						Type:  "array",
+						Items: &extv1.JSONSchemaPropsOrArray{Schema: object(nil, map[string]extv1.JSONSchemaProps{})},
+					},
+				})))},
+			},
+			want: want{
+				changes: []Change{
+					{Type: FieldRemoved, Definition: crdKind, Kind: "Bucket.s3.aws.upbound.io", Version: "v1beta1", Field: "spec.acl", Breaking: true, Message: "field was removed"},
+					{Type: FieldAdded, Definition: crdKind, Kind: "Bucket.s3.aws.upbound.io", Version: "v1beta1", Field: "spec.owner", Message: "field was added"},
+					{Type: FieldRequired, Definition: crdKind, Kind: "Bucket.s3.aws.upbound.io", Version: "v1beta1", Field: "spec.policy", Breaking: true, Message: "required field was added"},
+					{Type: FieldRequired, Definition: crdKind, Kind: "Bucket.s3.aws.upbound.io", Version: "v1beta1", Field: "spec.region", Breaking: true, Message: "field became required"},
+					{Type: FieldRemoved, Definition: crdKind, Kind: "Bucket.s3.aws.upbound.io", Version: "v1beta1", Field: "spec.tags[*].key", Breaking: true, Message: "field was removed"},
+				},
+			},
+		},
+		"XRDSchema": {
+			reason: "Should compare the raw schemas of XRD versions.",
+			args: args{
+				from: []runtime.Object{xrd(`{"type":"object","properties":{"spec":{"type":"object","properties":{"size":{"type":"integer"}}}}}`)},
+				to:   []runtime.Object{xrd(`{"type":"object","properties":{"spec":{"type":"object","properties":{}}}}`)},
+			},
+			want: want{
+				changes: []Change{
+					{Type: FieldRemoved, Definition: xpextv1.CompositeResourceDefinitionKind, Kind: "XDatabase.acme.io", Version: "v1", Field: "spec.size", Breaking: true, Message: "field was removed"},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			changes, err := Compare(tc.args.from, tc.args.to)
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nCompare(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.changes, changes); diff != "" {
+				t.Errorf("\n%s\nCompare(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func xrd(schema string) *xpextv1.CompositeResourceDefinition {
+	return &xpextv1.CompositeResourceDefinition{
+		Spec: xpextv1.CompositeResourceDefinitionSpec{
+			Group: "acme.io",
+			Names: extv1.CustomResourceDefinitionNames{Kind: "XDatabase"},
+			Versions: []xpextv1.CompositeResourceDefinitionVersion{{
+				Name:          "v1",
+				Served:        true,
+				Referenceable: true,
+				Schema: &xpextv1.CompositeResourceValidation{
+					OpenAPIV3Schema: runtime.RawExtension{Raw: []byte(schema)},
+				},
+			}},
+		},
+	}
+}
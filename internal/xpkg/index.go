@@ -0,0 +1,289 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xpkg
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/upbound/up/internal/xpkg/attest"
+)
+
+const (
+	// ociLayoutFile is the file marking the root of an OCI image layout.
+	ociLayoutFile = "oci-layout"
+	// refNameAnnotation is the OCI annotation recording the reference of an
+	// image index in an OCI image layout.
+	refNameAnnotation = "org.opencontainers.image.ref.name"
+
+	errNoPlatformImages   = "at least one image is required to build an index"
+	errPlatformFmt        = "failed to determine the platform of image %d"
+	errDuplicatePlatform  = "more than one image supplied for platform %s"
+	errWriteLayout        = "failed to write OCI image layout"
+	errReadLayout         = "failed to read OCI image layout"
+	errNotExactlyOneIndex = "package file must contain exactly one image index"
+	errInvalidLayoutPath  = "invalid path %q in package file"
+)
+
+// BuildIndex builds an OCI image index from the supplied package images,
+// recording the platform of each image as found in its config file.
+func BuildIndex(imgs ...v1.Image) (v1.ImageIndex, error) {
+	if len(imgs) == 0 {
+		return nil, errors.New(errNoPlatformImages)
+	}
+
+	seen := make(map[string]bool, len(imgs))
+	adds := make([]mutate.IndexAddendum, len(imgs))
+	for i, img := range imgs {
+		cfg, err := img.ConfigFile()
+		if err != nil {
+			return nil, errors.Wrapf(err, errPlatformFmt, i)
+		}
+		p := cfg.Platform()
+		if p == nil {
+			return nil, errors.Errorf(errPlatformFmt, i)
+		}
+		if seen[p.String()] {
+			return nil, errors.Errorf(errDuplicatePlatform, p.String())
+		}
+		seen[p.String()] = true
+
+		mt, err := img.MediaType()
+		if err != nil {
+			return nil, err
+		}
+		adds[i] = mutate.IndexAddendum{
+			Add: img,
+			Descriptor: v1.Descriptor{
+				MediaType: mt,
+				Platform:  p,
+			},
+		}
+	}
+	return mutate.IndexMediaType(mutate.AppendManifests(empty.Index, adds...), types.OCIImageIndex), nil
+}
+
+// IndexImages returns the images referenced by the supplied image index.
+func IndexImages(idx v1.ImageIndex) ([]v1.Image, error) {
+	m, err := idx.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	imgs := make([]v1.Image, 0, len(m.Manifests))
+	for _, d := range m.Manifests {
+		img, err := idx.Image(d.Digest)
+		if err != nil {
+			return nil, err
+		}
+		imgs = append(imgs, img)
+	}
+	return imgs, nil
+}
+
+// WriteIndex writes the supplied image index to w as a tarball of an OCI
+// image layout. If tag is not nil it is recorded in the layout. Non-empty
+// attestations are stored next to the layout.
+func WriteIndex(w io.Writer, tag name.Reference, idx v1.ImageIndex, att *attest.Attestations) error {
+	dir, err := os.MkdirTemp("", "xpkg-index-")
+	if err != nil {
+		return errors.Wrap(err, errWriteLayout)
+	}
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	p, err := layout.Write(dir, empty.Index)
+	if err != nil {
+		return errors.Wrap(err, errWriteLayout)
+	}
+	var opts []layout.Option
+	if tag != nil {
+		opts = append(opts, layout.WithAnnotations(map[string]string{refNameAnnotation: tag.String()}))
+	}
+	if err := p.AppendIndex(idx, opts...); err != nil {
+		return errors.Wrap(err, errWriteLayout)
+	}
+	if !att.Empty() {
+		b, err := json.Marshal(att)
+		if err != nil {
+			return errors.Wrap(err, errWriteAttestations)
+		}
+		if err := os.WriteFile(filepath.Join(dir, attest.File), b, 0o600); err != nil {
+			return errors.Wrap(err, errWriteAttestations)
+		}
+	}
+	return errors.Wrap(tarDir(w, dir), errWriteLayout)
+}
+
+// IndexFile is an image index read from a package file.
+type IndexFile struct {
+	// Index is the image index stored in the package file.
+	Index v1.ImageIndex
+	// Tag is the tag recorded for the index, if any.
+	Tag string
+
+	dir string
+}
+
+// Close removes the temporary files backing the IndexFile.
+func (f *IndexFile) Close() error {
+	return os.RemoveAll(f.dir)
+}
+
+// IsIndexFile returns true if the package file at the supplied path contains
+// an image index rather than a single image.
+func IsIndexFile(path string) (bool, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return false, err
+	}
+	defer f.Close() // nolint:errcheck
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if filepath.Clean(hdr.Name) == ociLayoutFile {
+			return true, nil
+		}
+	}
+}
+
+// ReadIndexFile reads the image index stored in the package file at the
+// supplied path. The returned IndexFile must be closed once it is no longer
+// used.
+func ReadIndexFile(path string) (*IndexFile, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() // nolint:errcheck
+
+	dir, err := os.MkdirTemp("", "xpkg-index-")
+	if err != nil {
+		return nil, errors.Wrap(err, errReadLayout)
+	}
+	ixf := &IndexFile{dir: dir}
+
+	if err := untarDir(f, dir); err != nil {
+		_ = ixf.Close()
+		return nil, errors.Wrap(err, errReadLayout)
+	}
+
+	root, err := layout.ImageIndexFromPath(dir)
+	if err != nil {
+		_ = ixf.Close()
+		return nil, errors.Wrap(err, errReadLayout)
+	}
+	m, err := root.IndexManifest()
+	if err != nil {
+		_ = ixf.Close()
+		return nil, errors.Wrap(err, errReadLayout)
+	}
+	if len(m.Manifests) != 1 || !m.Manifests[0].MediaType.IsIndex() {
+		_ = ixf.Close()
+		return nil, errors.New(errNotExactlyOneIndex)
+	}
+	ixf.Index, err = root.ImageIndex(m.Manifests[0].Digest)
+	if err != nil {
+		_ = ixf.Close()
+		return nil, errors.Wrap(err, errReadLayout)
+	}
+	ixf.Tag = m.Manifests[0].Annotations[refNameAnnotation]
+	return ixf, nil
+}
+
+// tarDir writes the regular files in dir to w as a tarball.
+func tarDir(w io.Writer, dir string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		f, err := os.Open(filepath.Clean(path))
+		if err != nil {
+			return err
+		}
+		defer f.Close() // nolint:errcheck
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// untarDir extracts the regular files of the tarball read from r into dir.
+func untarDir(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		path := filepath.Join(dir, filepath.Clean(hdr.Name))
+		if !strings.HasPrefix(path, filepath.Clean(dir)+string(filepath.Separator)) {
+			return errors.Errorf(errInvalidLayoutPath, hdr.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, tr); err != nil { // nolint:gosec
+			_ = f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xpkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/upbound/up/internal/xpkg/attest"
+)
+
+func platformImage(t *testing.T, platform string) v1.Image {
+	t.Helper()
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := v1.ParsePlatform(platform)
+	cfg, _ := img.ConfigFile()
+	cfg.OS = p.OS
+	cfg.Architecture = p.Architecture
+	img, err = mutate.ConfigFile(img, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func TestBuildIndex(t *testing.T) {
+	type want struct {
+		platforms []string
+		err       error
+	}
+
+	cases := map[string]struct {
+		reason    string
+		platforms []string
+		want      want
+	}{
+		"MultiPlatform": {
+			reason:    "Should build an index referencing every image with its platform.",
+			platforms: []string{"linux/amd64", "linux/arm64"},
+			want: want{
+				platforms: []string{"linux/amd64", "linux/arm64"},
+			},
+		},
+		"ErrDuplicatePlatform": {
+			reason:    "Should return an error if two images are built for the same platform.",
+			platforms: []string{"linux/amd64", "linux/amd64"},
+			want: want{
+				err: errors.Errorf(errDuplicatePlatform, "linux/amd64"),
+			},
+		},
+		"ErrNoImages": {
+			reason: "Should return an error if no images are supplied.",
+			want: want{
+				err: errors.New(errNoPlatformImages),
+			},
+		},
+	}
+
+	for n, tc := range cases {
+		t.Run(n, func(t *testing.T) {
+			imgs := make([]v1.Image, len(tc.platforms))
+			for i, p := range tc.platforms {
+				imgs[i] = platformImage(t, p)
+			}
+
+			idx, err := BuildIndex(imgs...)
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nBuildIndex(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			m, _ := idx.IndexManifest()
+			got := make([]string, len(m.Manifests))
+			for i, d := range m.Manifests {
+				got[i] = d.Platform.String()
+			}
+			if diff := cmp.Diff(tc.want.platforms, got); diff != "" {
+				t.Errorf("\n%s\nBuildIndex(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestIndexFileRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	idx, err := BuildIndex(platformImage(t, "linux/amd64"), platformImage(t, "linux/arm64"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, _ := name.NewTag("xpkg.upbound.io/acme/provider-foo:v0.1.0")
+
+	path := filepath.Join(dir, "index.xpkg")
+	f, _ := os.Create(path)
+	att := &attest.Attestations{SBOMFormat: attest.SBOMFormatSPDX, SBOM: []byte(`{"spdxVersion":"SPDX-2.3"}`)}
+	if err := WriteIndex(f, tag, idx, att); err != nil {
+		t.Fatalf("WriteIndex(...): unexpected error: %s", err)
+	}
+	_ = f.Close()
+
+	isIndex, err := IsIndexFile(path)
+	if err != nil || !isIndex {
+		t.Fatalf("IsIndexFile(...): expected index file, got %t, %v", isIndex, err)
+	}
+
+	ixf, err := ReadIndexFile(path)
+	if err != nil {
+		t.Fatalf("ReadIndexFile(...): unexpected error: %s", err)
+	}
+	defer ixf.Close() // nolint:errcheck
+
+	want, _ := idx.Digest()
+	got, _ := ixf.Index.Digest()
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadIndexFile(...): -want digest, +got digest:\n%s", diff)
+	}
+	if diff := cmp.Diff(tag.String(), ixf.Tag); diff != "" {
+		t.Errorf("ReadIndexFile(...): -want tag, +got tag:\n%s", diff)
+	}
+	imgs, err := IndexImages(ixf.Index)
+	if err != nil || len(imgs) != 2 {
+		t.Errorf("IndexImages(...): expected 2 images, got %d, %v", len(imgs), err)
+	}
+
+	gotAtt, err := ReadAttestations(path)
+	if err != nil {
+		t.Fatalf("ReadAttestations(...): unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(att, gotAtt); diff != "" {
+		t.Errorf("ReadAttestations(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestIsIndexFileImage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "image.xpkg")
+	if err := tarball.WriteToFile(path, nil, platformImage(t, "linux/amd64")); err != nil {
+		t.Fatal(err)
+	}
+
+	isIndex, err := IsIndexFile(path)
+	if err != nil || isIndex {
+		t.Errorf("IsIndexFile(...): expected image file, got %t, %v", isIndex, err)
+	}
+}
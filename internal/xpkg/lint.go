@@ -66,6 +66,21 @@ func NewFunctionLinter() linter.Linter {
 	return linter.NewPackageLinter(linter.PackageLinterFns(OneMeta), linter.ObjectLinterFns(IsFunction), linter.ObjectLinterFns())
 }
 
+// LinterFor returns the package linter for the kind of the supplied meta
+// object. It returns false if the kind is not a known package kind.
+func LinterFor(meta runtime.Object) (linter.Linter, bool) {
+	switch meta.GetObjectKind().GroupVersionKind().Kind {
+	case pkgmetav1.ConfigurationKind:
+		return NewConfigurationLinter(), true
+	case pkgmetav1.ProviderKind:
+		return NewProviderLinter(), true
+	case pkgmetav1beta1.FunctionKind:
+		return NewFunctionLinter(), true
+	default:
+		return nil, false
+	}
+}
+
 // OneMeta checks that there is only one meta object in the package.
 func OneMeta(pkg linter.Package) error {
 	if len(pkg.GetMeta()) != 1 {
@@ -0,0 +1,432 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sign signs and verifies Crossplane packages in OCI registries using
+// cosign compatible key-pair signatures.
+package sign
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/spf13/afero"
+)
+
+const (
+	// SignatureAnnotation is the layer annotation holding the base64
+	// encoded signature of the layer's payload.
+	SignatureAnnotation = "dev.cosignproject.cosign/signature"
+	// PayloadMediaType is the media type of a signature payload layer.
+	PayloadMediaType types.MediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+	// ArtifactType is the artifact type of signatures stored as OCI
+	// referrers.
+	ArtifactType = "application/vnd.dev.cosign.artifact.sig.v1+json"
+
+	payloadType  = "cosign container image signature"
+	sigTagSuffix = "sig"
+
+	errReadKey            = "failed to read key"
+	errNoPEMBlock         = "key is not PEM encoded"
+	errUnsupportedKeyFmt  = "unsupported key type %q; supply an unencrypted ECDSA key"
+	errNotECDSA           = "key is not an ECDSA key"
+	errResolveDigest      = "failed to resolve digest"
+	errSign               = "failed to sign payload"
+	errWriteSignature     = "failed to write signature"
+	errReadSignatures     = "failed to read signatures"
+	errNoValidSignatureFm = "no valid signature found for %s"
+	errUnknownStorageFmt  = "unknown signature storage %q"
+)
+
+// ErrNoValidSignature is returned if no signature of a package verifies
+// against the supplied key.
+var ErrNoValidSignature = errors.New("no valid signature")
+
+// Storage is where signatures are stored in the registry.
+type Storage string
+
+const (
+	// StorageTag stores signatures in an image tagged after the digest of
+	// the signed package, e.g. sha256-<hex>.sig.
+	StorageTag Storage = "tag"
+	// StorageReferrers stores signatures as OCI referrers of the signed
+	// package.
+	StorageReferrers Storage = "referrers"
+)
+
+// Payload is the simple signing payload that is signed.
+type Payload struct {
+	Critical Critical          `json:"critical"`
+	Optional map[string]string `json:"optional"`
+}
+
+// Critical holds the signed identity of a package.
+type Critical struct {
+	Identity Identity `json:"identity"`
+	Image    Image    `json:"image"`
+	Type     string   `json:"type"`
+}
+
+// Identity is the repository of the signed package.
+type Identity struct {
+	DockerReference string `json:"docker-reference"`
+}
+
+// Image is the digest of the signed package.
+type Image struct {
+	DockerManifestDigest string `json:"docker-manifest-digest"`
+}
+
+// Signature is a verified signature of a package.
+type Signature struct {
+	// Storage is where the signature was found.
+	Storage Storage `json:"storage"`
+	// Payload is the signed payload.
+	Payload Payload `json:"payload"`
+}
+
+type options struct {
+	storage Storage
+	remote  []remote.Option
+}
+
+// Option modifies how packages are signed and verified.
+type Option func(*options)
+
+// WithStorage sets where signatures are stored. Signatures are stored with
+// StorageTag by default. Verification always considers both storages.
+func WithStorage(s Storage) Option {
+	return func(o *options) {
+		o.storage = s
+	}
+}
+
+// WithRemoteOptions sets the options used to access the registry, e.g. for
+// authentication.
+func WithRemoteOptions(opts ...remote.Option) Option {
+	return func(o *options) {
+		o.remote = append(o.remote, opts...)
+	}
+}
+
+func newOptions(ctx context.Context, opts []Option) *options {
+	o := &options{
+		storage: StorageTag,
+		remote:  []remote.Option{remote.WithContext(ctx)},
+	}
+	for _, fn := range opts {
+		fn(o)
+	}
+	return o
+}
+
+// LoadPrivateKey reads a PEM encoded ECDSA private key.
+func LoadPrivateKey(fs afero.Fs, path string) (*ecdsa.PrivateKey, error) {
+	b, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, errors.Wrap(err, errReadKey)
+	}
+	p, _ := pem.Decode(b)
+	if p == nil {
+		return nil, errors.New(errNoPEMBlock)
+	}
+
+	var key any
+	switch p.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(p.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(p.Bytes)
+	default:
+		return nil, errors.Errorf(errUnsupportedKeyFmt, p.Type)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, errReadKey)
+	}
+	k, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New(errNotECDSA)
+	}
+	return k, nil
+}
+
+// LoadPublicKey reads a PEM encoded ECDSA public key.
+func LoadPublicKey(fs afero.Fs, path string) (*ecdsa.PublicKey, error) {
+	b, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, errors.Wrap(err, errReadKey)
+	}
+	p, _ := pem.Decode(b)
+	if p == nil {
+		return nil, errors.New(errNoPEMBlock)
+	}
+	if p.Type != "PUBLIC KEY" {
+		return nil, errors.Errorf(errUnsupportedKeyFmt, p.Type)
+	}
+	key, err := x509.ParsePKIXPublicKey(p.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, errReadKey)
+	}
+	k, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New(errNotECDSA)
+	}
+	return k, nil
+}
+
+// ResolveDigest resolves the supplied reference to the digest of the image
+// or index it points to.
+func ResolveDigest(ctx context.Context, ref name.Reference, opts ...Option) (name.Digest, error) {
+	if d, ok := ref.(name.Digest); ok {
+		return d, nil
+	}
+	o := newOptions(ctx, opts)
+	desc, err := remote.Head(ref, o.remote...)
+	if err != nil {
+		return name.Digest{}, errors.Wrap(err, errResolveDigest)
+	}
+	return ref.Context().Digest(desc.Digest.String()), nil
+}
+
+// Sign signs the package at the supplied digest with the supplied key and
+// stores the signature in the registry of the package.
+func Sign(ctx context.Context, d name.Digest, key *ecdsa.PrivateKey, opts ...Option) error {
+	o := newOptions(ctx, opts)
+
+	payload, err := json.Marshal(Payload{
+		Critical: Critical{
+			Identity: Identity{DockerReference: d.Context().Name()},
+			Image:    Image{DockerManifestDigest: d.DigestStr()},
+			Type:     payloadType,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, errSign)
+	}
+	h := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, h[:])
+	if err != nil {
+		return errors.Wrap(err, errSign)
+	}
+
+	add := mutate.Addendum{
+		Layer: static.NewLayer(payload, PayloadMediaType),
+		Annotations: map[string]string{
+			SignatureAnnotation: base64.StdEncoding.EncodeToString(sig),
+		},
+	}
+
+	switch o.storage {
+	case StorageTag:
+		return errors.Wrap(signTag(d, add, o), errWriteSignature)
+	case StorageReferrers:
+		return errors.Wrap(signReferrer(d, add, o), errWriteSignature)
+	default:
+		return errors.Errorf(errUnknownStorageFmt, o.storage)
+	}
+}
+
+// signTag appends the signature to the signature image tagged after the
+// digest of the package.
+func signTag(d name.Digest, add mutate.Addendum, o *options) error {
+	t := SignatureTag(d)
+	base, err := remote.Image(t, o.remote...)
+	if isNotFound(err) {
+		base = mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	img, err := mutate.Append(base, add)
+	if err != nil {
+		return err
+	}
+	return remote.Write(t, img, o.remote...)
+}
+
+// signReferrer pushes the signature as an artifact referring to the package.
+func signReferrer(d name.Digest, add mutate.Addendum, o *options) error {
+	desc, err := remote.Head(d, o.remote...)
+	if err != nil {
+		return err
+	}
+	img := mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+	img = mutate.ConfigMediaType(img, ArtifactType)
+	img, err = mutate.Append(img, add)
+	if err != nil {
+		return err
+	}
+	img = mutate.Subject(img, *desc).(v1.Image)
+
+	dig, err := img.Digest()
+	if err != nil {
+		return err
+	}
+	return remote.Write(d.Context().Digest(dig.String()), img, o.remote...)
+}
+
+// Verify returns the signatures of the package at the supplied digest that
+// verify against the supplied key. Signatures stored both by tag and as
+// referrers are considered. If no signature verifies, an error satisfying
+// errors.Is(err, ErrNoValidSignature) is returned.
+func Verify(ctx context.Context, d name.Digest, key *ecdsa.PublicKey, opts ...Option) ([]Signature, error) {
+	o := newOptions(ctx, opts)
+
+	sigs := make([]Signature, 0)
+
+	img, err := remote.Image(SignatureTag(d), o.remote...)
+	if err != nil && !isNotFound(err) {
+		return nil, errors.Wrap(err, errReadSignatures)
+	}
+	if err == nil {
+		s, err := verifyImage(img, d, key, StorageTag)
+		if err != nil {
+			return nil, errors.Wrap(err, errReadSignatures)
+		}
+		sigs = append(sigs, s...)
+	}
+
+	idx, err := remote.Referrers(d, append(o.remote, remote.WithFilter("artifactType", ArtifactType))...)
+	if err != nil && !isNotFound(err) {
+		return nil, errors.Wrap(err, errReadSignatures)
+	}
+	if err == nil {
+		m, err := idx.IndexManifest()
+		if err != nil {
+			return nil, errors.Wrap(err, errReadSignatures)
+		}
+		for _, desc := range m.Manifests {
+			if desc.ArtifactType != ArtifactType {
+				continue
+			}
+			img, err := remote.Image(d.Context().Digest(desc.Digest.String()), o.remote...)
+			if err != nil {
+				return nil, errors.Wrap(err, errReadSignatures)
+			}
+			s, err := verifyImage(img, d, key, StorageReferrers)
+			if err != nil {
+				return nil, errors.Wrap(err, errReadSignatures)
+			}
+			sigs = append(sigs, s...)
+		}
+	}
+
+	if len(sigs) == 0 {
+		return nil, errors.Wrap(ErrNoValidSignature, fmt.Sprintf(errNoValidSignatureFm, d.String()))
+	}
+	return sigs, nil
+}
+
+// verifyImage returns the signatures in the supplied signature image that
+// verify against the supplied key and sign the supplied digest of the
+// supplied repository. Invalid signatures are skipped.
+func verifyImage(img v1.Image, d name.Digest, key *ecdsa.PublicKey, s Storage) ([]Signature, error) {
+	m, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	sigs := make([]Signature, 0)
+	for _, l := range m.Layers {
+		enc, ok := l.Annotations[SignatureAnnotation]
+		if !ok || l.MediaType != PayloadMediaType {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(enc)
+		if err != nil {
+			continue
+		}
+		layer, err := img.LayerByDigest(l.Digest)
+		if err != nil {
+			return nil, err
+		}
+		rc, err := layer.Compressed()
+		if err != nil {
+			return nil, err
+		}
+		payload, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		h := sha256.Sum256(payload)
+		if !ecdsa.VerifyASN1(key, h[:], sig) {
+			continue
+		}
+		p := Payload{}
+		if err := json.Unmarshal(payload, &p); err != nil {
+			continue
+		}
+		// the signature must be for this exact package, not the same image
+		// pushed to another repository.
+		if p.Critical.Image.DockerManifestDigest != d.DigestStr() || p.Critical.Identity.DockerReference != d.Context().Name() {
+			continue
+		}
+		sigs = append(sigs, Signature{Storage: s, Payload: p})
+	}
+	return sigs, nil
+}
+
+// SignatureTag returns the tag of the image holding the signatures of the
+// package at the supplied digest.
+func SignatureTag(d name.Digest) name.Tag {
+	return d.Context().Tag(fmt.Sprintf("%s.%s", strings.Replace(d.DigestStr(), ":", "-", 1), sigTagSuffix))
+}
+
+// Verifier verifies that packages carry a valid signature.
+type Verifier struct {
+	key  *ecdsa.PublicKey
+	opts []Option
+}
+
+// NewVerifier returns a Verifier that verifies signatures against the
+// supplied key.
+func NewVerifier(key *ecdsa.PublicKey, opts ...Option) *Verifier {
+	return &Verifier{key: key, opts: opts}
+}
+
+// Verify returns an error if the package at the supplied digest does not
+// carry a signature that verifies against the key of the Verifier.
+func (v *Verifier) Verify(ctx context.Context, d name.Digest) error {
+	_, err := Verify(ctx, d, v.key, v.opts...)
+	return err
+}
+
+func isNotFound(err error) bool {
+	var terr *transport.Error
+	if !errors.As(err, &terr) {
+		return false
+	}
+	return terr.StatusCode == http.StatusNotFound
+}
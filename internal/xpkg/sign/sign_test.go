@@ -0,0 +1,264 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sign
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/spf13/afero"
+)
+
+// pushRandom pushes a random image to an in-process registry and returns its
+// digest.
+func pushRandom(t *testing.T) name.Digest {
+	t.Helper()
+
+	srv := httptest.NewServer(registry.New())
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag, err := name.NewTag(u.Host + "/crossplane/provider-aws:v0.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := random.Image(128, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(tag, img); err != nil {
+		t.Fatal(err)
+	}
+	dig, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tag.Context().Digest(dig.String())
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestSignVerify(t *testing.T) {
+	type args struct {
+		storage []Storage
+		signer  bool
+	}
+	type want struct {
+		storage []Storage
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Tag": {
+			reason: "Should verify a signature stored by tag.",
+			args: args{
+				storage: []Storage{StorageTag},
+				signer:  true,
+			},
+			want: want{
+				storage: []Storage{StorageTag},
+			},
+		},
+		"Referrers": {
+			reason: "Should verify a signature stored as a referrer.",
+			args: args{
+				storage: []Storage{StorageReferrers},
+				signer:  true,
+			},
+			want: want{
+				storage: []Storage{StorageReferrers},
+			},
+		},
+		"Both": {
+			reason: "Should verify signatures from every storage, including multiple signatures by tag.",
+			args: args{
+				storage: []Storage{StorageTag, StorageReferrers, StorageTag},
+				signer:  true,
+			},
+			want: want{
+				storage: []Storage{StorageTag, StorageTag, StorageReferrers},
+			},
+		},
+		"Unsigned": {
+			reason: "Should return an error if the package is not signed.",
+			want: want{
+				err: ErrNoValidSignature,
+			},
+		},
+		"WrongKey": {
+			reason: "Should return an error if the package is signed by another key.",
+			args: args{
+				storage: []Storage{StorageTag, StorageReferrers},
+			},
+			want: want{
+				err: ErrNoValidSignature,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			d := pushRandom(t)
+			key := newKey(t)
+
+			signer := newKey(t)
+			if tc.args.signer {
+				signer = key
+			}
+			for _, s := range tc.args.storage {
+				if err := Sign(ctx, d, signer, WithStorage(s)); err != nil {
+					t.Fatalf("\n%s\nSign(...): unexpected error: %s", tc.reason, err)
+				}
+			}
+
+			sigs, err := Verify(ctx, d, &key.PublicKey)
+			if !errors.Is(err, tc.want.err) {
+				t.Errorf("\n%s\nVerify(...): want error %v, got %v", tc.reason, tc.want.err, err)
+			}
+
+			var got []Storage
+			for _, s := range sigs {
+				got = append(got, s.Storage)
+				if s.Payload.Critical.Image.DockerManifestDigest != d.DigestStr() {
+					t.Errorf("\n%s\nVerify(...): signature for digest %s, want %s", tc.reason, s.Payload.Critical.Image.DockerManifestDigest, d.DigestStr())
+				}
+			}
+			if diff := cmp.Diff(tc.want.storage, got); diff != "" {
+				t.Errorf("\n%s\nVerify(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestVerifyOtherDigest(t *testing.T) {
+	ctx := context.Background()
+	d := pushRandom(t)
+	key := newKey(t)
+
+	if err := Sign(ctx, d, key); err != nil {
+		t.Fatal(err)
+	}
+
+	// copy the signature of the package to the tag of another digest.
+	other := d.Context().Digest("sha256:0000000000000000000000000000000000000000000000000000000000000000")
+	img, err := remote.Image(SignatureTag(d))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(SignatureTag(other), img); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Verify(ctx, other, &key.PublicKey); !errors.Is(err, ErrNoValidSignature) {
+		t.Errorf("Verify(...): want error %v, got %v", ErrNoValidSignature, err)
+	}
+}
+
+func TestVerifyOtherRepository(t *testing.T) {
+	ctx := context.Background()
+	d := pushRandom(t)
+	key := newKey(t)
+
+	if err := Sign(ctx, d, key); err != nil {
+		t.Fatal(err)
+	}
+
+	// copy the signature of the package to the same digest in another
+	// repository.
+	repo, err := name.NewRepository(d.Context().RegistryStr() + "/crossplane/provider-gcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := repo.Digest(d.DigestStr())
+	img, err := remote.Image(SignatureTag(d))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(SignatureTag(other), img); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Verify(ctx, other, &key.PublicKey); !errors.Is(err, ErrNoValidSignature) {
+		t.Errorf("Verify(...): want error %v, got %v", ErrNoValidSignature, err)
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	key := newKey(t)
+	fs := afero.NewMemMapFs()
+
+	priv, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = afero.WriteFile(fs, "/key.pem", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: priv}), 0o600)
+	_ = afero.WriteFile(fs, "/key.pub", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}), 0o600)
+	_ = afero.WriteFile(fs, "/cosign.key", pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED COSIGN PRIVATE KEY", Bytes: priv}), 0o600)
+
+	gotPriv, err := LoadPrivateKey(fs, "/key.pem")
+	if err != nil {
+		t.Fatalf("LoadPrivateKey(...): unexpected error: %s", err)
+	}
+	if !gotPriv.Equal(key) {
+		t.Errorf("LoadPrivateKey(...): loaded key does not match")
+	}
+
+	gotPub, err := LoadPublicKey(fs, "/key.pub")
+	if err != nil {
+		t.Fatalf("LoadPublicKey(...): unexpected error: %s", err)
+	}
+	if !gotPub.Equal(&key.PublicKey) {
+		t.Errorf("LoadPublicKey(...): loaded key does not match")
+	}
+
+	if _, err := LoadPrivateKey(fs, "/cosign.key"); err == nil {
+		t.Errorf("LoadPrivateKey(...): expected error for encrypted key")
+	}
+	if _, err := LoadPublicKey(fs, "/key.pem"); err == nil {
+		t.Errorf("LoadPublicKey(...): expected error for private key")
+	}
+}
@@ -0,0 +1,515 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane/apis/pkg/v1beta1"
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/golang/tools/lsp/protocol"
+	"github.com/golang/tools/span"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/upbound/up/internal/xpkg"
+	"github.com/upbound/up/internal/xpkg/dep/lock"
+	mxpkg "github.com/upbound/up/internal/xpkg/dep/marshaler/xpkg"
+)
+
+const (
+	// FetchDependencyCommand is the command that fetches a dependency along
+	// with its transitive dependencies into the cache, like `up xpkg dep`
+	// does. Its only argument is the v1beta1.Dependency to fetch.
+	FetchDependencyCommand = "xpls.fetchDependency"
+
+	actionAddDependencyFmt = "Add dependency on %s %s"
+	actionChangeVersionFmt = "Change version to %s"
+	actionFetchFmt         = "Fetch %s %s"
+
+	// minVersionFmt is the constraint used for dependencies that are
+	// added or bumped by a code action.
+	minVersionFmt = ">=%s"
+
+	specField      = "spec"
+	dependsOnField = "dependsOn"
+)
+
+// CodeActions returns the quick fixes for the supplied diagnostics of the
+// file with the supplied URI. For dependencies of the meta file that are
+// missing from the cache, fixes either change the version to a cached one or
+// fetch the dependency. For objects whose kind is unknown, fixes add a cached
+// package defining the kind to the dependencies of the meta file.
+func (s *Snapshot) CodeActions(ctx context.Context, uri span.URI, diags []protocol.Diagnostic) ([]protocol.CodeAction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	details, ok := s.wsview.FileDetails()[uri]
+	if !ok {
+		return nil, errors.New(errInvalidFileURI)
+	}
+
+	actions := make([]protocol.CodeAction, 0)
+	for _, d := range diags {
+		if uri == s.metaURI() {
+			actions = append(actions, s.dependencyFixes(ctx, uri, details.Body, d)...)
+			continue
+		}
+		actions = append(actions, s.definitionFixes(details.Body, d)...)
+	}
+	return actions, nil
+}
+
+// metaURI returns the URI of the meta file, if there is one.
+func (s *Snapshot) metaURI() span.URI {
+	if s.wsview.MetaLocation() == "" {
+		return ""
+	}
+	return span.URIFromPath(filepath.Join(s.wsview.MetaLocation(), xpkg.MetaFile))
+}
+
+// dependencyFixes returns the fixes for a diagnostic of a dependency of the
+// meta file.
+func (s *Snapshot) dependencyFixes(ctx context.Context, uri span.URI, body []byte, diag protocol.Diagnostic) []protocol.CodeAction {
+	if s.wsview.Meta() == nil {
+		return nil
+	}
+	deps, err := s.wsview.Meta().DependsOn()
+	if err != nil {
+		return nil
+	}
+	i, ok := dependencyAt(body, diag.Range.Start.Line, len(deps))
+	if !ok {
+		return nil
+	}
+	d := deps[i]
+
+	vers, err := s.dm.Versions(ctx, d)
+	if err != nil {
+		return nil
+	}
+	if len(vers) == 0 {
+		return []protocol.CodeAction{fetchAction(d, diag)}
+	}
+	if p, ok := s.lock.Get(d.Package); ok && lock.Satisfies(d.Constraints, p.Version) && !contains(vers, p.Version) {
+		d.Constraints = p.Version
+		return []protocol.CodeAction{fetchAction(d, diag)}
+	}
+	if versionMatch(d.Constraints, vers) {
+		return nil
+	}
+
+	fetch := fetchAction(d, diag)
+	latest := latestVersion(vers)
+	rng, ok := versionRange(body, diag.Range.Start.Line)
+	if latest == "" || !ok {
+		return []protocol.CodeAction{fetch}
+	}
+	c := bumpConstraint(d.Constraints, latest)
+	return []protocol.CodeAction{
+		{
+			Title:       fmt.Sprintf(actionChangeVersionFmt, c),
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diag},
+			IsPreferred: true,
+			Edit: protocol.WorkspaceEdit{
+				Changes: map[string][]protocol.TextEdit{
+					string(protocol.URIFromSpanURI(uri)): {{Range: rng, NewText: fmt.Sprintf("%q", c)}},
+				},
+			},
+		},
+		fetch,
+	}
+}
+
+// definitionFixes returns the fixes for a diagnostic of an object whose kind
+// has no definition in the workspace or its dependencies.
+func (s *Snapshot) definitionFixes(body []byte, diag protocol.Diagnostic) []protocol.CodeAction {
+	c, _, ok := lineCursor(body, diag.Range.Start)
+	if !ok {
+		return nil
+	}
+	_, gvk, ok := c.object()
+	if !ok {
+		return nil
+	}
+	if _, ok := s.validators[gvk]; ok || s.wsview.Meta() == nil {
+		return nil
+	}
+
+	candidates := s.definingPackages(gvk)
+	actions := make([]protocol.CodeAction, 0, len(candidates))
+	for _, p := range candidates {
+		d := v1beta1.Dependency{
+			Package:     p.Name(),
+			Type:        p.Type(),
+			Constraints: bumpConstraint("", p.Version()),
+		}
+		edit, err := s.addDependencyEdit(d)
+		if err != nil {
+			s.log.Debug(errAddDependency, "error", err)
+			continue
+		}
+		actions = append(actions, protocol.CodeAction{
+			Title:       fmt.Sprintf(actionAddDependencyFmt, d.Package, d.Constraints),
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diag},
+			IsPreferred: len(candidates) == 1,
+			Edit:        edit,
+		})
+	}
+	return actions
+}
+
+// definingPackages returns the newest version of every cached package that
+// defines the supplied GVK and is not a dependency of the meta file yet,
+// sorted by name.
+func (s *Snapshot) definingPackages(gvk schema.GroupVersionKind) []*mxpkg.ParsedPackage {
+	cached, err := s.dm.Cached()
+	if err != nil {
+		s.log.Debug(errListCache, "error", err)
+		return nil
+	}
+	declared := make(map[string]bool)
+	if deps, err := s.wsview.Meta().DependsOn(); err == nil {
+		for _, d := range deps {
+			declared[d.Package] = true
+		}
+	}
+
+	newest := make(map[string]*mxpkg.ParsedPackage)
+	for _, p := range cached {
+		if declared[p.Name()] {
+			continue
+		}
+		if _, _, ok := definition(p.Objects(), gvk); !ok {
+			continue
+		}
+		if curr, ok := newest[p.Name()]; ok && latestVersion([]string{curr.Version(), p.Version()}) == curr.Version() {
+			continue
+		}
+		newest[p.Name()] = p
+	}
+
+	pkgs := make([]*mxpkg.ParsedPackage, 0, len(newest))
+	for _, p := range newest {
+		pkgs = append(pkgs, p)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Name() < pkgs[j].Name() })
+	return pkgs
+}
+
+// addDependencyEdit returns the edit that adds the supplied dependency to
+// the meta file. The dependency is inserted under spec.dependsOn as text so
+// that the comments and formatting of the meta file are kept.
+func (s *Snapshot) addDependencyEdit(d v1beta1.Dependency) (protocol.WorkspaceEdit, error) {
+	uri := s.metaURI()
+	var body []byte
+	if details, ok := s.wsview.FileDetails()[uri]; ok {
+		body = details.Body
+	} else {
+		b, err := os.ReadFile(uri.Filename())
+		if err != nil {
+			return protocol.WorkspaceEdit{}, err
+		}
+		body = b
+	}
+
+	edit, ok := insertDependency(body, d)
+	if !ok {
+		return protocol.WorkspaceEdit{}, errors.New(errInsertDependency)
+	}
+	return protocol.WorkspaceEdit{
+		Changes: map[string][]protocol.TextEdit{
+			string(protocol.URIFromSpanURI(uri)): {edit},
+		},
+	}, nil
+}
+
+// insertDependency returns the edit that inserts the supplied dependency
+// after the last dependency of the supplied meta file, creating spec and
+// spec.dependsOn if they are missing. It returns false if spec or
+// spec.dependsOn are written in flow style other than an empty sequence.
+func insertDependency(body []byte, d v1beta1.Dependency) (protocol.TextEdit, bool) {
+	lines := strings.Split(string(body), "\n")
+	end := documentEnd(lines)
+	last := blockEnd(lines, -1, end, -1)
+	if last < 0 {
+		return protocol.TextEdit{}, false
+	}
+
+	spec := childKey(lines, 0, end, 0, specField)
+	if spec < 0 {
+		return insertAfter(lines, last, specField+":\n"+indented(2, dependsOnField+":")+"\n"+dependencyItem(2, d)), true
+	}
+	l, _ := parseLine(lines[spec])
+	if l.value != "" {
+		return protocol.TextEdit{}, false
+	}
+	specEnd := blockEnd(lines, spec, end, 0)
+	col := childIndent(lines, spec, specEnd, 0)
+	if col < 0 {
+		col = 2
+	}
+
+	deps := childKey(lines, spec+1, specEnd+1, col, dependsOnField)
+	if deps < 0 {
+		return insertAfter(lines, specEnd, indented(col, dependsOnField+":")+"\n"+dependencyItem(col, d)), true
+	}
+	l, _ = parseLine(lines[deps])
+	switch l.value {
+	case "":
+	case "[]":
+		// replace the empty flow sequence with a block sequence.
+		line := lines[deps]
+		from := l.valueCol
+		for from > 0 && line[from-1] == ' ' {
+			from--
+		}
+		to := l.valueCol + len("[]")
+		return protocol.TextEdit{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: uint32(deps), Character: utf16Len(line[:from])},
+				End:   protocol.Position{Line: uint32(deps), Character: utf16Len(line[:to])},
+			},
+			NewText: "\n" + dependencyItem(col, d),
+		}, true
+	default:
+		return protocol.TextEdit{}, false
+	}
+
+	depsEnd := deps
+	itemCol := col
+	for i := deps + 1; i < end; i++ {
+		il, ok := parseLine(lines[i])
+		if !ok {
+			continue
+		}
+		if il.indent <= col && !(il.dash && il.indent == col) {
+			break
+		}
+		if il.dash && depsEnd == deps {
+			itemCol = il.indent
+		}
+		depsEnd = i
+	}
+	return insertAfter(lines, depsEnd, dependencyItem(itemCol, d)), true
+}
+
+// dependencyItem returns the sequence item of the supplied dependency with
+// its dash at the supplied column.
+func dependencyItem(col int, d v1beta1.Dependency) string {
+	return indented(col, fmt.Sprintf("- %s: %s\n  %s: %q", strings.ToLower(string(d.Type)), d.Package, versionField, d.Constraints))
+}
+
+// indented returns the supplied lines indented by the supplied number of
+// spaces.
+func indented(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+// insertAfter returns the edit that inserts the supplied text as new lines
+// after the supplied line.
+func insertAfter(lines []string, line int, text string) protocol.TextEdit {
+	if line+1 < len(lines) {
+		pos := protocol.Position{Line: uint32(line + 1)}
+		return protocol.TextEdit{Range: protocol.Range{Start: pos, End: pos}, NewText: text + "\n"}
+	}
+	// the line is the last one and has no trailing newline.
+	pos := protocol.Position{Line: uint32(line), Character: utf16Len(lines[line])}
+	return protocol.TextEdit{Range: protocol.Range{Start: pos, End: pos}, NewText: "\n" + text}
+}
+
+// documentEnd returns the line at which the first document of the supplied
+// lines ends.
+func documentEnd(lines []string) int {
+	content := false
+	for i, line := range lines {
+		if strings.HasPrefix(line, docSeparator) {
+			if content {
+				return i
+			}
+			continue
+		}
+		if _, ok := parseLine(line); ok {
+			content = true
+		}
+	}
+	return len(lines)
+}
+
+// childKey returns the line before the supplied end holding the supplied key
+// at the supplied column, or -1 if there is none.
+func childKey(lines []string, start, end, col int, key string) int {
+	for i := start; i < end; i++ {
+		l, ok := parseLine(lines[i])
+		if ok && !l.dash && l.indent == col && l.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// childIndent returns the column of the first line of the block of the
+// supplied key, or -1 if the block is empty.
+func childIndent(lines []string, key, last, col int) int {
+	for i := key + 1; i <= last; i++ {
+		if l, ok := parseLine(lines[i]); ok && l.indent > col {
+			return l.indent
+		}
+	}
+	return -1
+}
+
+// blockEnd returns the last line of the block of the key on the supplied
+// line, i.e. the last line before the supplied end that is indented deeper
+// than the supplied column. It returns the key line if the block is empty.
+func blockEnd(lines []string, key, end, col int) int {
+	last := key
+	for i := key + 1; i < end; i++ {
+		l, ok := parseLine(lines[i])
+		if !ok || strings.HasPrefix(lines[i], docSeparator) {
+			continue
+		}
+		if l.indent <= col {
+			break
+		}
+		last = i
+	}
+	return last
+}
+
+// utf16Len returns the length of the supplied string in UTF-16 code units,
+// which is how LSP positions count characters.
+func utf16Len(s string) uint32 {
+	return uint32(len(utf16.Encode([]rune(s))))
+}
+
+// fetchAction returns the action that fetches the supplied dependency.
+func fetchAction(d v1beta1.Dependency, diag protocol.Diagnostic) protocol.CodeAction {
+	// a dependency always marshals.
+	arg, _ := json.Marshal(d)
+	title := fmt.Sprintf(actionFetchFmt, d.Package, d.Constraints)
+	return protocol.CodeAction{
+		Title:       title,
+		Kind:        protocol.QuickFix,
+		Diagnostics: []protocol.Diagnostic{diag},
+		Command: &protocol.Command{
+			Title:     title,
+			Command:   FetchDependencyCommand,
+			Arguments: []json.RawMessage{arg},
+		},
+	}
+}
+
+// dependencyAt returns the index of the dependency of the meta file that has
+// a field on the supplied line.
+func dependencyAt(body []byte, line uint32, n int) (int, bool) {
+	f, err := parser.ParseBytes(body, 0)
+	if err != nil || len(f.Docs) == 0 {
+		return 0, false
+	}
+	fields := []string{
+		versionField,
+		strings.ToLower(string(v1beta1.ProviderPackageType)),
+		strings.ToLower(string(v1beta1.ConfigurationPackageType)),
+		strings.ToLower(string(v1beta1.FunctionPackageType)),
+	}
+	for i := 0; i < n; i++ {
+		for _, field := range fields {
+			p, err := yaml.PathString("$." + fmt.Sprintf(dependsOnPathFmt, i, field))
+			if err != nil {
+				continue
+			}
+			node, err := p.FilterNode(f.Docs[0].Body)
+			if err != nil || node == nil || node.GetToken() == nil {
+				continue
+			}
+			if uint32(node.GetToken().Position.Line-1) == line {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// versionRange returns the range of the version value on the supplied line.
+func versionRange(body []byte, line uint32) (protocol.Range, bool) {
+	lines := strings.Split(string(body), "\n")
+	if int(line) >= len(lines) {
+		return protocol.Range{}, false
+	}
+	l, ok := parseLine(lines[line])
+	if !ok || l.key != versionField || l.value == "" {
+		return protocol.Range{}, false
+	}
+	v := lines[line][l.valueCol:]
+	if i := strings.Index(v, " #"); i >= 0 {
+		v = v[:i]
+	}
+	v = strings.TrimRight(v, " ")
+	return protocol.Range{
+		Start: protocol.Position{Line: line, Character: utf16Len(lines[line][:l.valueCol])},
+		End:   protocol.Position{Line: line, Character: utf16Len(lines[line][:l.valueCol+len(v)])},
+	}, true
+}
+
+// latestVersion returns the newest of the supplied semantic versions.
+func latestVersion(vers []string) string {
+	var latest *semver.Version
+	orig := ""
+	for _, v := range vers {
+		sv, err := semver.NewVersion(v)
+		if err != nil {
+			continue
+		}
+		if latest == nil || sv.GreaterThan(latest) {
+			latest, orig = sv, v
+		}
+	}
+	return orig
+}
+
+// bumpConstraint returns the constraint replacing the supplied constraint so
+// that the supplied version satisfies it. Exact versions are replaced by the
+// version, while ranges are replaced by a minimum version.
+func bumpConstraint(c, v string) string {
+	if _, err := semver.NewVersion(c); err == nil {
+		return v
+	}
+	if _, err := semver.NewVersion(v); err != nil {
+		return v
+	}
+	return fmt.Sprintf(minVersionFmt, v)
+}
+
+func contains(vers []string, v string) bool {
+	for _, s := range vers {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,360 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/crossplane/crossplane/apis/pkg/v1beta1"
+	"github.com/golang/tools/lsp/protocol"
+	"github.com/golang/tools/span"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	mxpkg "github.com/upbound/up/internal/xpkg/dep/marshaler/xpkg"
+	"github.com/upbound/up/internal/xpkg/workspace"
+)
+
+var testMeta = []byte(`apiVersion: meta.pkg.crossplane.io/v1
+kind: Configuration
+metadata:
+  name: acme
+spec:
+  dependsOn:
+  - provider: xpkg.upbound.io/crossplane-contrib/provider-aws
+    version: ">=v0.50.0"
+`)
+
+func TestCodeActions(t *testing.T) {
+	widgets := &mxpkg.ParsedPackage{
+		DepName: "xpkg.upbound.io/acme/provider-widgets",
+		PType:   v1beta1.ProviderPackageType,
+		Ver:     "v1.2.0",
+		Objs: []runtime.Object{
+			&extv1.CustomResourceDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: "widgets.acme.io"},
+				Spec: extv1.CustomResourceDefinitionSpec{
+					Group: "acme.io",
+					Names: extv1.CustomResourceDefinitionNames{Kind: "Widget"},
+				},
+			},
+		},
+	}
+	older := &mxpkg.ParsedPackage{
+		DepName: widgets.DepName,
+		PType:   widgets.PType,
+		Ver:     "v1.1.0",
+		Objs:    widgets.Objs,
+	}
+
+	type want struct {
+		titles []string
+		edit   string
+		meta   string
+	}
+
+	cases := map[string]struct {
+		reason   string
+		meta     []byte
+		file     string
+		doc      []byte
+		line     uint32
+		versions []string
+		cached   []*mxpkg.ParsedPackage
+		want     want
+	}{
+		"ChangeVersion": {
+			reason:   "Should offer to change the version of a dependency to the newest cached version, or to fetch it.",
+			file:     "/ws/crossplane.yaml",
+			doc:      testMeta,
+			line:     7,
+			versions: []string{"v0.40.0", "v0.45.0"},
+			want: want{
+				titles: []string{
+					"Change version to >=v0.45.0",
+					"Fetch xpkg.upbound.io/crossplane-contrib/provider-aws >=v0.50.0",
+				},
+				edit: `">=v0.45.0"`,
+			},
+		},
+		"FetchMissing": {
+			reason: "Should offer to fetch a dependency that is not cached at all.",
+			file:   "/ws/crossplane.yaml",
+			doc:    testMeta,
+			line:   6,
+			want: want{
+				titles: []string{
+					"Fetch xpkg.upbound.io/crossplane-contrib/provider-aws >=v0.50.0",
+				},
+			},
+		},
+		"AddDependency": {
+			reason: "Should offer to add the newest cached package defining an unknown kind to the dependencies.",
+			file:   "/ws/examples/widget.yaml",
+			doc: []byte(`apiVersion: acme.io/v1
+kind: Widget
+metadata:
+  name: widget
+`),
+			line:     0,
+			versions: []string{"v0.50.0"},
+			cached:   []*mxpkg.ParsedPackage{older, widgets},
+			want: want{
+				titles: []string{
+					"Add dependency on xpkg.upbound.io/acme/provider-widgets >=v1.2.0",
+				},
+				meta: `apiVersion: meta.pkg.crossplane.io/v1
+kind: Configuration
+metadata:
+  name: acme
+spec:
+  dependsOn:
+  - provider: xpkg.upbound.io/crossplane-contrib/provider-aws
+    version: ">=v0.50.0"
+  - provider: xpkg.upbound.io/acme/provider-widgets
+    version: ">=v1.2.0"
+`,
+			},
+		},
+		"AddDependencyKeepFormatting": {
+			reason: "Should insert the dependency after the last one, keeping comments, key order and indentation.",
+			meta: []byte(`# acme configuration
+apiVersion: meta.pkg.crossplane.io/v1
+kind: Configuration
+metadata:
+  name: acme
+spec:
+    dependsOn:
+        # AWS provider
+        - provider: xpkg.upbound.io/crossplane-contrib/provider-aws
+          version: ">=v0.50.0" # pinned
+    crossplane:
+        version: ">=v1.14.0"
+`),
+			file: "/ws/examples/widget.yaml",
+			doc: []byte(`apiVersion: acme.io/v1
+kind: Widget
+`),
+			versions: []string{"v0.50.0"},
+			cached:   []*mxpkg.ParsedPackage{widgets},
+			want: want{
+				titles: []string{
+					"Add dependency on xpkg.upbound.io/acme/provider-widgets >=v1.2.0",
+				},
+				meta: `# acme configuration
+apiVersion: meta.pkg.crossplane.io/v1
+kind: Configuration
+metadata:
+  name: acme
+spec:
+    dependsOn:
+        # AWS provider
+        - provider: xpkg.upbound.io/crossplane-contrib/provider-aws
+          version: ">=v0.50.0" # pinned
+        - provider: xpkg.upbound.io/acme/provider-widgets
+          version: ">=v1.2.0"
+    crossplane:
+        version: ">=v1.14.0"
+`,
+			},
+		},
+		"AddDependencyCreateDependsOn": {
+			reason: "Should create spec.dependsOn if the meta file has no dependencies.",
+			meta: []byte(`apiVersion: meta.pkg.crossplane.io/v1
+kind: Configuration
+metadata:
+  name: acme
+spec:
+  crossplane:
+    version: ">=v1.14.0"
+`),
+			file: "/ws/examples/widget.yaml",
+			doc: []byte(`apiVersion: acme.io/v1
+kind: Widget
+`),
+			versions: []string{"v0.50.0"},
+			cached:   []*mxpkg.ParsedPackage{widgets},
+			want: want{
+				titles: []string{
+					"Add dependency on xpkg.upbound.io/acme/provider-widgets >=v1.2.0",
+				},
+				meta: `apiVersion: meta.pkg.crossplane.io/v1
+kind: Configuration
+metadata:
+  name: acme
+spec:
+  crossplane:
+    version: ">=v1.14.0"
+  dependsOn:
+  - provider: xpkg.upbound.io/acme/provider-widgets
+    version: ">=v1.2.0"
+`,
+			},
+		},
+		"AddDependencyEmptyFlowSequence": {
+			reason: "Should replace an empty flow style spec.dependsOn with the dependency.",
+			meta: []byte(`apiVersion: meta.pkg.crossplane.io/v1
+kind: Configuration
+metadata:
+  name: acme
+spec:
+  dependsOn: []
+`),
+			file: "/ws/examples/widget.yaml",
+			doc: []byte(`apiVersion: acme.io/v1
+kind: Widget
+`),
+			versions: []string{"v0.50.0"},
+			cached:   []*mxpkg.ParsedPackage{widgets},
+			want: want{
+				titles: []string{
+					"Add dependency on xpkg.upbound.io/acme/provider-widgets >=v1.2.0",
+				},
+				meta: `apiVersion: meta.pkg.crossplane.io/v1
+kind: Configuration
+metadata:
+  name: acme
+spec:
+  dependsOn:
+  - provider: xpkg.upbound.io/acme/provider-widgets
+    version: ">=v1.2.0"
+`,
+			},
+		},
+		"AddDependencyCreateSpec": {
+			reason: "Should create spec if the meta file has none, counting positions in UTF-16 code units.",
+			meta: []byte(`apiVersion: meta.pkg.crossplane.io/v1
+kind: Configuration
+metadata:
+  name: acme
+  annotations:
+    meta.crossplane.io/description: Widgets 🚀 für alle`),
+			file: "/ws/examples/widget.yaml",
+			doc: []byte(`apiVersion: acme.io/v1
+kind: Widget
+`),
+			versions: []string{"v0.50.0"},
+			cached:   []*mxpkg.ParsedPackage{widgets},
+			want: want{
+				titles: []string{
+					"Add dependency on xpkg.upbound.io/acme/provider-widgets >=v1.2.0",
+				},
+				meta: `apiVersion: meta.pkg.crossplane.io/v1
+kind: Configuration
+metadata:
+  name: acme
+  annotations:
+    meta.crossplane.io/description: Widgets 🚀 für alle
+spec:
+  dependsOn:
+  - provider: xpkg.upbound.io/acme/provider-widgets
+    version: ">=v1.2.0"`,
+			},
+		},
+		"NoDefiningPackage": {
+			reason: "Should not offer fixes for an unknown kind that no cached package defines.",
+			file:   "/ws/examples/widget.yaml",
+			doc: []byte(`apiVersion: acme.io/v1
+kind: Gadget
+`),
+			line:     0,
+			versions: []string{"v0.50.0"},
+			cached:   []*mxpkg.ParsedPackage{widgets},
+			want: want{
+				titles: []string{},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			meta := testMeta
+			if tc.meta != nil {
+				meta = tc.meta
+			}
+			fs := afero.NewMemMapFs()
+			_ = afero.WriteFile(fs, "/ws/crossplane.yaml", meta, os.ModePerm)
+			_ = afero.WriteFile(fs, tc.file, tc.doc, os.ModePerm)
+			ws, _ := workspace.New("/ws", workspace.WithFS(fs), workspace.WithPermissiveParser())
+
+			factory, _ := NewFactory("/ws", WithDepManager(&MockDepManager{versions: tc.versions, cached: tc.cached}))
+			snap, err := factory.New(context.Background(), WithWorkspace(ws))
+			if err != nil {
+				t.Fatalf("\n%s\nNew(...): unexpected error: %s", tc.reason, err)
+			}
+
+			diag := protocol.Diagnostic{
+				Range: protocol.Range{
+					Start: protocol.Position{Line: tc.line, Character: 4},
+					End:   protocol.Position{Line: tc.line, Character: 8},
+				},
+			}
+			actions, err := snap.CodeActions(context.Background(), span.URIFromPath(tc.file), []protocol.Diagnostic{diag})
+			if err != nil {
+				t.Fatalf("\n%s\nCodeActions(...): unexpected error: %s", tc.reason, err)
+			}
+
+			titles := make([]string, len(actions))
+			for i, a := range actions {
+				titles[i] = a.Title
+			}
+			if diff := cmp.Diff(tc.want.titles, titles); diff != "" {
+				t.Errorf("\n%s\nCodeActions(...): -want titles, +got titles:\n%s", tc.reason, diff)
+			}
+			if tc.want.edit != "" {
+				edit := ""
+				for _, edits := range actions[0].Edit.Changes {
+					edit = edits[0].NewText
+				}
+				if diff := cmp.Diff(tc.want.edit, edit); diff != "" {
+					t.Errorf("\n%s\nCodeActions(...): -want edit, +got edit:\n%s", tc.reason, diff)
+				}
+			}
+			if tc.want.meta != "" {
+				got := string(meta)
+				for _, edits := range actions[0].Edit.Changes {
+					got = applyEdit(got, edits[0])
+				}
+				if diff := cmp.Diff(tc.want.meta, got); diff != "" {
+					t.Errorf("\n%s\nCodeActions(...): -want meta, +got meta:\n%s", tc.reason, diff)
+				}
+			}
+		})
+	}
+}
+
+// applyEdit returns the supplied body with the supplied edit applied.
+func applyEdit(body string, e protocol.TextEdit) string {
+	lines := strings.SplitAfter(body, "\n")
+	offset := func(p protocol.Position) int {
+		o := 0
+		for i := 0; i < int(p.Line) && i < len(lines); i++ {
+			o += len(lines[i])
+		}
+		if int(p.Line) >= len(lines) {
+			return o
+		}
+		u := utf16.Encode([]rune(lines[p.Line]))
+		return o + len(string(utf16.Decode(u[:p.Character])))
+	}
+	return body[:offset(e.Range.Start)] + e.NewText + body[offset(e.Range.End):]
+}
@@ -0,0 +1,352 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	xpextv1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/golang/tools/lsp/protocol"
+	"github.com/golang/tools/span"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+const (
+	keyAPIVersion       = "apiVersion"
+	keyKind             = "kind"
+	keyBase             = "base"
+	keyType             = "type"
+	keyFromFieldPath    = "fromFieldPath"
+	keyToFieldPath      = "toFieldPath"
+	keySpec             = "spec"
+	keyCompositeTypeRef = "compositeTypeRef"
+	keyResources        = "resources"
+	keyPatches          = "patches"
+	keyCombine          = "combine"
+	keyVariables        = "variables"
+
+	// sort required fields before optional ones.
+	sortRequired = "0"
+	sortOptional = "1"
+)
+
+// Completion returns the completion items at the supplied position of the
+// file with the supplied URI. Completions are derived from the schemas of the
+// workspace and its dependencies and include field names and enum values of
+// objects, including resources embedded in Compositions, apiVersion and kind
+// values, and the field paths of Composition patches.
+func (s *Snapshot) Completion(_ context.Context, uri span.URI, pos protocol.Position) ([]protocol.CompletionItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	details, ok := s.wsview.FileDetails()[uri]
+	if !ok {
+		return nil, errors.New(errInvalidFileURI)
+	}
+	c, ok := newCursor(details.Body, pos)
+	if !ok {
+		return []protocol.CompletionItem{}, nil
+	}
+
+	inner := len(c.frames) - 1
+	if c.value && (c.key == keyAPIVersion || c.key == keyKind) {
+		return s.gvkCompletions(c, c.fields(c.frames[inner])), nil
+	}
+	if c.value && (c.key == keyFromFieldPath || c.key == keyToFieldPath) {
+		if sch, ok := s.patchSchema(c); ok {
+			return fieldPathCompletions(c, sch), nil
+		}
+	}
+
+	root, gvk, ok := c.object()
+	if !ok {
+		return []protocol.CompletionItem{}, nil
+	}
+	path := c.path(root, inner)
+	if c.value {
+		path = append(path, c.key)
+	}
+	sch := schemaAt(s.Schema(gvk), path)
+	if sch == nil {
+		return []protocol.CompletionItem{}, nil
+	}
+	if c.value {
+		return enumCompletions(c, sch), nil
+	}
+	return fieldCompletions(c, sch, c.fields(c.frames[inner])), nil
+}
+
+// object returns the index of the innermost mapping enclosing the cursor that
+// is a Kubernetes object, along with its GVK.
+func (c *cursor) object() (int, schema.GroupVersionKind, bool) {
+	for i := len(c.frames) - 1; i >= 0; i-- {
+		if gvk, ok := c.gvk(c.frames[i]); ok {
+			return i, gvk, true
+		}
+	}
+	return 0, schema.GroupVersionKind{}, false
+}
+
+// gvk returns the GVK of the supplied mapping if it is a Kubernetes object.
+func (c *cursor) gvk(f frame) (schema.GroupVersionKind, bool) {
+	fields := c.fields(f)
+	av, kind := fields[keyAPIVersion].value, fields[keyKind].value
+	if av == "" || kind == "" {
+		return schema.GroupVersionKind{}, false
+	}
+	return schema.FromAPIVersionAndKind(av, kind), true
+}
+
+// gvkCompletions returns the known apiVersion or kind values, limited to the
+// ones matching the kind or apiVersion already set.
+func (s *Snapshot) gvkCompletions(c *cursor, fields map[string]entry) []protocol.CompletionItem {
+	seen := make(map[string]bool)
+	items := make([]protocol.CompletionItem, 0)
+	for gvk := range s.validators {
+		av, kind := gvk.GroupVersion().String(), gvk.Kind
+		label, detail := av, kind
+		if c.key == keyKind {
+			if v := fields[keyAPIVersion].value; v != "" && v != av {
+				continue
+			}
+			label, detail = kind, av
+		} else if v := fields[keyKind].value; v != "" && v != kind {
+			continue
+		}
+		if seen[label] {
+			continue
+		}
+		seen[label] = true
+		items = append(items, c.item(label, detail, "", protocol.ValueCompletion, label))
+	}
+	return sortItems(items)
+}
+
+// patchSchema returns the schema of the object a Composition patch field path
+// at the cursor refers to.
+func (s *Snapshot) patchSchema(c *cursor) (*spec.Schema, bool) { //nolint:gocyclo
+	// the patches may be part of the input of a function, which is an
+	// object itself.
+	root := -1
+	for i := range c.frames {
+		if gvk, ok := c.gvk(c.frames[i]); ok && gvk.Group == xpextv1.Group && gvk.Kind == xpextv1.CompositionKind {
+			root = i
+			break
+		}
+	}
+	if root < 0 {
+		return nil, false
+	}
+
+	// the cursor is either in a patch, or in a variable of a combine patch.
+	inner := len(c.frames) - 1
+	variable := false
+	if suffix(c.frames[inner].segs, keyVariables, seqSegment) && inner >= 2 && suffix(c.frames[inner-1].segs, keyCombine) {
+		variable = true
+		inner -= 2
+	}
+	if inner <= root || !suffix(c.frames[inner].segs, keyPatches, seqSegment) {
+		return nil, false
+	}
+	if variable && c.key != keyFromFieldPath {
+		return nil, false
+	}
+
+	t := xpextv1.PatchType(c.fields(c.frames[inner])[keyType].value)
+	if t == "" {
+		t = xpextv1.PatchTypeFromCompositeFieldPath
+	}
+
+	// fromFieldPath of composite patches and toFieldPath of patches to the
+	// composite refer to the composite resource, all others to the base
+	// of the composed resource.
+	composite := false
+	switch t { //nolint:exhaustive
+	case xpextv1.PatchTypeFromCompositeFieldPath, xpextv1.PatchTypeCombineFromComposite:
+		composite = c.key == keyFromFieldPath
+	case xpextv1.PatchTypeToCompositeFieldPath, xpextv1.PatchTypeCombineToComposite:
+		composite = c.key == keyToFieldPath
+	default:
+		return nil, false
+	}
+
+	if composite {
+		xr := schema.FromAPIVersionAndKind(
+			c.lookup(c.frames[root], keySpec, keyCompositeTypeRef, keyAPIVersion),
+			c.lookup(c.frames[root], keySpec, keyCompositeTypeRef, keyKind),
+		)
+		sch := s.Schema(xr)
+		return sch, sch != nil
+	}
+
+	// patches of patch sets have no base.
+	res := inner - 1
+	if res <= root || !suffix(c.frames[res].segs, keyResources, seqSegment) {
+		return nil, false
+	}
+	base := schema.FromAPIVersionAndKind(
+		c.lookup(c.frames[res], keyBase, keyAPIVersion),
+		c.lookup(c.frames[res], keyBase, keyKind),
+	)
+	sch := s.Schema(base)
+	return sch, sch != nil
+}
+
+// fieldCompletions returns the properties of the supplied schema that are
+// not yet set.
+func fieldCompletions(c *cursor, sch *spec.Schema, set map[string]entry) []protocol.CompletionItem {
+	required := make(map[string]bool, len(sch.Required))
+	for _, r := range sch.Required {
+		required[r] = true
+	}
+	items := make([]protocol.CompletionItem, 0, len(sch.Properties))
+	for name, p := range sch.Properties {
+		if _, ok := set[name]; ok {
+			continue
+		}
+		it := c.item(name, schemaType(&p), p.Description, protocol.FieldCompletion, name+": ")
+		it.SortText = sortOptional + name
+		if required[name] {
+			it.SortText = sortRequired + name
+		}
+		items = append(items, it)
+	}
+	return sortItems(items)
+}
+
+// enumCompletions returns the enum values of the supplied schema.
+func enumCompletions(c *cursor, sch *spec.Schema) []protocol.CompletionItem {
+	items := make([]protocol.CompletionItem, 0, len(sch.Enum))
+	for _, e := range sch.Enum {
+		v := fmt.Sprint(e)
+		items = append(items, c.item(v, schemaType(sch), sch.Description, protocol.EnumMemberCompletion, v))
+	}
+	return sortItems(items)
+}
+
+// fieldPathCompletions returns the field paths below the part of the field
+// path that has been typed so far.
+func fieldPathCompletions(c *cursor, sch *spec.Schema) []protocol.CompletionItem {
+	parent := ""
+	if i := strings.LastIndex(c.prefix, "."); i >= 0 {
+		parent = c.prefix[:i]
+	}
+	segs, err := fieldpath.Parse(parent)
+	if err != nil {
+		return []protocol.CompletionItem{}
+	}
+	path := make([]string, 0, len(segs))
+	for _, s := range segs {
+		if s.Type == fieldpath.SegmentIndex {
+			path = append(path, seqSegment)
+			continue
+		}
+		path = append(path, s.Field)
+	}
+	sch = schemaAt(sch, path)
+	if sch == nil {
+		return []protocol.CompletionItem{}
+	}
+
+	items := make([]protocol.CompletionItem, 0, len(sch.Properties))
+	for name, p := range sch.Properties {
+		fp := name
+		if parent != "" {
+			fp = parent + "." + name
+		}
+		items = append(items, c.item(fp, schemaType(&p), p.Description, protocol.FieldCompletion, fp))
+	}
+	return sortItems(items)
+}
+
+// item returns a completion item that replaces the text typed before the
+// cursor with the supplied text.
+func (c *cursor) item(label, detail, doc string, kind protocol.CompletionItemKind, text string) protocol.CompletionItem {
+	return protocol.CompletionItem{
+		Label:         label,
+		Kind:          kind,
+		Detail:        detail,
+		Documentation: doc,
+		FilterText:    label,
+		TextEdit: &protocol.TextEdit{
+			Range:   c.prefixRange(),
+			NewText: text,
+		},
+	}
+}
+
+// schemaAt returns the schema at the supplied path below the supplied schema.
+func schemaAt(sch *spec.Schema, path []string) *spec.Schema {
+	for _, p := range path {
+		if sch == nil {
+			return nil
+		}
+		if p == seqSegment {
+			if sch.Items == nil {
+				return nil
+			}
+			sch = sch.Items.Schema
+			continue
+		}
+		if ps, ok := sch.Properties[p]; ok {
+			sch = &ps
+			continue
+		}
+		if sch.AdditionalProperties != nil && sch.AdditionalProperties.Schema != nil {
+			sch = sch.AdditionalProperties.Schema
+			continue
+		}
+		return nil
+	}
+	return sch
+}
+
+func schemaType(sch *spec.Schema) string {
+	if len(sch.Type) == 0 {
+		return ""
+	}
+	if sch.Type[0] == "array" && sch.Items != nil && sch.Items.Schema != nil && len(sch.Items.Schema.Type) > 0 {
+		return "[]" + sch.Items.Schema.Type[0]
+	}
+	return sch.Type[0]
+}
+
+func suffix(segs []string, s ...string) bool {
+	if len(segs) < len(s) {
+		return false
+	}
+	for i := range s {
+		if segs[len(segs)-len(s)+i] != s[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sortItems(items []protocol.CompletionItem) []protocol.CompletionItem {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].SortText != items[j].SortText {
+			return items[i].SortText < items[j].SortText
+		}
+		return items[i].Label < items[j].Label
+	})
+	return items
+}
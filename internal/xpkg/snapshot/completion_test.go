@@ -0,0 +1,280 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/golang/tools/lsp/protocol"
+	"github.com/golang/tools/span"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/upbound/up/internal/xpkg/workspace"
+)
+
+var testXRD = []byte(`apiVersion: apiextensions.crossplane.io/v1
+kind: CompositeResourceDefinition
+metadata:
+  name: xbuckets.acme.io
+spec:
+  group: acme.io
+  names:
+    kind: XBucket
+    plural: xbuckets
+  claimNames:
+    kind: Bucket
+    plural: buckets
+  versions:
+  - name: v1
+    served: true
+    referenceable: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              parameters:
+                type: object
+                properties:
+                  region:
+                    type: string
+                  size:
+                    type: integer
+`)
+
+// cursorMarker marks the position of the cursor in test documents.
+const cursorMarker = "|"
+
+// withCursor returns the supplied document without the cursor marker, along
+// with the position of the marker.
+func withCursor(doc string) ([]byte, protocol.Position) {
+	for i, l := range strings.Split(doc, "\n") {
+		if c := strings.Index(l, cursorMarker); c >= 0 {
+			return []byte(strings.Replace(doc, cursorMarker, "", 1)), protocol.Position{Line: uint32(i), Character: uint32(c)}
+		}
+	}
+	return []byte(doc), protocol.Position{}
+}
+
+func TestCompletion(t *testing.T) {
+	forProvider := []string{
+		"region",
+		"tags",
+		"certificateAuthorityARN",
+		"certificateAuthorityARNRef",
+		"certificateAuthorityARNSelector",
+		"certificateTransparencyLoggingPreference",
+		"domainValidationOptions",
+		"renewCertificate",
+		"subjectAlternativeNames",
+		"validationMethod",
+	}
+	prefixed := func(p string, names ...string) []string {
+		out := make([]string, len(names))
+		for i, n := range names {
+			out[i] = p + n
+		}
+		return out
+	}
+
+	cases := map[string]struct {
+		reason string
+		doc    string
+		want   []string
+	}{
+		"ManagedResourceFields": {
+			reason: "Should complete the fields of a managed resource that are not set yet, required fields first.",
+			doc: `apiVersion: acm.aws.crossplane.io/v1alpha1
+kind: Certificate
+metadata:
+  name: cert
+spec:
+  forProvider:
+    domainName: example.com
+    re|`,
+			want: forProvider,
+		},
+		"EnumValues": {
+			reason: "Should complete the enum values of a field.",
+			doc: `apiVersion: acm.aws.crossplane.io/v1alpha1
+kind: Certificate
+spec:
+  forProvider:
+    validationMethod: |`,
+			want: []string{"DNS", "EMAIL"},
+		},
+		"Kinds": {
+			reason: "Should complete the kinds of the apiVersion that is set.",
+			doc: `apiVersion: acme.io/v1
+kind: |`,
+			want: []string{"Bucket", "XBucket"},
+		},
+		"APIVersions": {
+			reason: "Should complete the apiVersions of the kind that is set.",
+			doc: `apiVersion: |
+kind: Certificate`,
+			want: []string{"acm.aws.crossplane.io/v1alpha1"},
+		},
+		"CompositionBase": {
+			reason: "Should complete the fields of a resource embedded in a Composition.",
+			doc: `apiVersion: apiextensions.crossplane.io/v1
+kind: Composition
+metadata:
+  name: buckets
+spec:
+  compositeTypeRef:
+    apiVersion: acme.io/v1
+    kind: XBucket
+  resources:
+  - name: cert
+    base:
+      apiVersion: acm.aws.crossplane.io/v1alpha1
+      kind: Certificate
+      spec:
+        forProvider:
+          domainName: example.com
+          |
+    patches:
+    - fromFieldPath: spec.parameters.region
+      toFieldPath: spec.forProvider.region`,
+			want: forProvider,
+		},
+		"PatchFromCompositeFieldPath": {
+			reason: "Should complete the fromFieldPath of a patch with fields of the composite resource.",
+			doc: `apiVersion: apiextensions.crossplane.io/v1
+kind: Composition
+spec:
+  compositeTypeRef:
+    apiVersion: acme.io/v1
+    kind: XBucket
+  resources:
+  - name: cert
+    base:
+      apiVersion: acm.aws.crossplane.io/v1alpha1
+      kind: Certificate
+    patches:
+    - type: FromCompositeFieldPath
+      fromFieldPath: spec.parameters.|`,
+			want: []string{"spec.parameters.region", "spec.parameters.size"},
+		},
+		"PatchToFieldPath": {
+			reason: "Should complete the toFieldPath of a patch with fields of the base resource.",
+			doc: `apiVersion: apiextensions.crossplane.io/v1
+kind: Composition
+spec:
+  compositeTypeRef:
+    apiVersion: acme.io/v1
+    kind: XBucket
+  resources:
+  - name: cert
+    base:
+      apiVersion: acm.aws.crossplane.io/v1alpha1
+      kind: Certificate
+    patches:
+    - fromFieldPath: spec.parameters.region
+      toFieldPath: spec.forProvider.tags[0].|`,
+			want: []string{"spec.forProvider.tags[0].key", "spec.forProvider.tags[0].value"},
+		},
+		"CombineVariable": {
+			reason: "Should complete the variables of a combine patch with fields of the composite resource.",
+			doc: `apiVersion: apiextensions.crossplane.io/v1
+kind: Composition
+spec:
+  compositeTypeRef:
+    apiVersion: acme.io/v1
+    kind: XBucket
+  resources:
+  - name: cert
+    base:
+      apiVersion: acm.aws.crossplane.io/v1alpha1
+      kind: Certificate
+    patches:
+    - type: CombineFromComposite
+      combine:
+        variables:
+        - fromFieldPath: spec.parameters.|`,
+			want: []string{"spec.parameters.region", "spec.parameters.size"},
+		},
+		"PipelinePatch": {
+			reason: "Should complete the field paths of patches in the input of a pipeline step.",
+			doc: `apiVersion: apiextensions.crossplane.io/v1
+kind: Composition
+spec:
+  compositeTypeRef:
+    apiVersion: acme.io/v1
+    kind: XBucket
+  mode: Pipeline
+  pipeline:
+  - step: patch-and-transform
+    functionRef:
+      name: function-patch-and-transform
+    input:
+      apiVersion: pt.fn.crossplane.io/v1beta1
+      kind: Resources
+      resources:
+      - name: cert
+        base:
+          apiVersion: acm.aws.crossplane.io/v1alpha1
+          kind: Certificate
+        patches:
+        - type: ToCompositeFieldPath
+          fromFieldPath: spec.|`,
+			want: prefixed("spec.", "deletionPolicy", "forProvider", "providerConfigRef", "providerRef", "writeConnectionSecretToRef"),
+		},
+		"UnknownKind": {
+			reason: "Should not return completions for objects without a known schema.",
+			doc: `apiVersion: acme.io/v1
+kind: Unknown
+spec:
+  |`,
+			want: []string{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			body, pos := withCursor(tc.doc)
+			fs := afero.NewMemMapFs()
+			_ = afero.WriteFile(fs, "/ws/crd.yaml", testSingleVersionCRD, os.ModePerm)
+			_ = afero.WriteFile(fs, "/ws/xrd.yaml", testXRD, os.ModePerm)
+			_ = afero.WriteFile(fs, "/ws/test.yaml", body, os.ModePerm)
+			ws, _ := workspace.New("/ws", workspace.WithFS(fs), workspace.WithPermissiveParser())
+
+			factory, _ := NewFactory("/ws", WithDepManager(NewMockDepManager()))
+			snap, err := factory.New(context.Background(), WithWorkspace(ws))
+			if err != nil {
+				t.Fatalf("\n%s\nNew(...): unexpected error: %s", tc.reason, err)
+			}
+
+			items, err := snap.Completion(context.Background(), span.URIFromPath("/ws/test.yaml"), pos)
+			if err != nil {
+				t.Fatalf("\n%s\nCompletion(...): unexpected error: %s", tc.reason, err)
+			}
+			got := make([]string, len(items))
+			for i, it := range items {
+				got[i] = it.Label
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nCompletion(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -19,7 +19,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"path"
+	"strings"
 
+	"github.com/google/go-containerregistry/pkg/name"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	verrors "k8s.io/kube-openapi/pkg/validation/errors"
@@ -27,10 +30,12 @@ import (
 
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
 	xpextv1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/crossplane/crossplane/apis/pkg/v1beta1"
 
 	icomposite "github.com/crossplane/crossplane/controller/apiextensions/composite"
 	icompositions "github.com/crossplane/crossplane/controller/apiextensions/compositions"
 
+	"github.com/upbound/up/internal/xpkg"
 	"github.com/upbound/up/internal/xpkg/snapshot/validator"
 )
 
@@ -40,6 +45,10 @@ const (
 	errFmt                  = "%s (%s)"
 	errInvalidValidationFmt = "invalid validation result returned for %s"
 	resourceBaseFmt         = "spec.resources[%d].base.%s"
+	stepInputFmt            = "spec.pipeline[%d].input.%s"
+	stepFunctionRefFmt      = "spec.pipeline[%d].functionRef.name"
+
+	errFunctionNotDependencyFmt = "function %s is not declared as a dependency in %s"
 
 	errIncorrectErrType = "incorrect validaton error type seen"
 	errInvalidType      = "invalid type passed in, expected Unstructured"
@@ -49,6 +58,7 @@ const (
 type CompositionValidator struct {
 	s          *Snapshot
 	validators []compositionValidator
+	steps      []stepValidator
 }
 
 // DefaultCompositionValidators returns a new Composition validator.
@@ -58,6 +68,10 @@ func DefaultCompositionValidators(s *Snapshot) (validator.Validator, error) {
 		validators: []compositionValidator{
 			NewPatchesValidator(s),
 		},
+		steps: []stepValidator{
+			NewFunctionRefValidator(s),
+			NewStepInputValidator(s),
+		},
 	}, nil
 }
 
@@ -71,6 +85,12 @@ func (c *CompositionValidator) Validate(ctx context.Context, data any) *validate
 		return validator.Nop
 	}
 
+	if comp.Spec.Mode != nil && *comp.Spec.Mode == xpextv1.CompositionModePipeline {
+		return &validate.Result{
+			Errors: c.validatePipeline(ctx, comp),
+		}
+	}
+
 	compRefGVK := schema.FromAPIVersionAndKind(
 		comp.Spec.CompositeTypeRef.APIVersion,
 		comp.Spec.CompositeTypeRef.Kind,
@@ -102,6 +122,30 @@ func (c *CompositionValidator) Validate(ctx context.Context, data any) *validate
 	}
 }
 
+// validatePipeline validates the steps of a Composition in Pipeline mode.
+// Pipeline Compositions are not rendered, their steps are validated instead.
+func (c *CompositionValidator) validatePipeline(ctx context.Context, comp *xpextv1.Composition) []error {
+	errs := []error{}
+
+	// surface the errors of the upstream Composition validation, e.g.
+	// duplicate step names, at the offending field.
+	_, ferrs := comp.Validate()
+	for _, fe := range ferrs {
+		errs = append(errs, &validator.Validation{
+			TypeCode: validator.ErrorTypeCode,
+			Message:  fe.Error(),
+			Name:     fe.Field,
+		})
+	}
+
+	for i, st := range comp.Spec.Pipeline {
+		for _, v := range c.steps {
+			errs = append(errs, v.validate(ctx, i, st)...)
+		}
+	}
+	return errs
+}
+
 func (c *CompositionValidator) marshal(data any) (*xpextv1.Composition, error) {
 	u, ok := data.(*unstructured.Unstructured)
 	if !ok {
@@ -173,3 +217,117 @@ func (p *PatchesValidator) validate(ctx context.Context, idx int, cd resource.Co
 
 	return []error{fmt.Errorf(errInvalidValidationFmt, cdgvk)}
 }
+
+type stepValidator interface {
+	validate(context.Context, int, xpextv1.PipelineStep) []error
+}
+
+// FunctionRefValidator validates that the Functions referenced by the steps of
+// a Composition pipeline are declared as dependencies in the meta file.
+type FunctionRefValidator struct {
+	s *Snapshot
+}
+
+// NewFunctionRefValidator returns a new FunctionRefValidator.
+func NewFunctionRefValidator(s *Snapshot) *FunctionRefValidator {
+	return &FunctionRefValidator{
+		s: s,
+	}
+}
+
+// validate validates that the step's functionRef resolves to a Function
+// dependency.
+func (f *FunctionRefValidator) validate(_ context.Context, idx int, st xpextv1.PipelineStep) []error {
+	fns, ok := f.functions()
+	if !ok || st.FunctionRef.Name == "" || fns[st.FunctionRef.Name] {
+		return nil
+	}
+	return []error{
+		&validator.Validation{
+			TypeCode: validator.ErrorTypeCode,
+			Message:  fmt.Sprintf(errFunctionNotDependencyFmt, st.FunctionRef.Name, xpkg.MetaFile),
+			Name:     fmt.Sprintf(stepFunctionRefFmt, idx),
+		},
+	}
+}
+
+// functions returns the names the Function dependencies declared in the meta
+// file can be referenced by. A Function installed as a dependency is named
+// after its repository, e.g. crossplane-contrib-function-auto-ready, while
+// Functions installed by hand are commonly named after the last path segment
+// of their repository. The second return value is false if the workspace does
+// not have a meta file to resolve Functions against.
+func (f *FunctionRefValidator) functions() (map[string]bool, bool) {
+	if f.s.wsview == nil || f.s.wsview.Meta() == nil {
+		return nil, false
+	}
+	deps, err := f.s.wsview.Meta().DependsOn()
+	if err != nil {
+		return nil, false
+	}
+
+	fns := make(map[string]bool)
+	for _, d := range deps {
+		if d.Type != v1beta1.FunctionPackageType {
+			continue
+		}
+		repo := d.Package
+		if r, err := name.NewRepository(d.Package); err == nil {
+			repo = r.RepositoryStr()
+		}
+		fns[xpkg.ToDNSLabel(repo)] = true
+		fns[xpkg.ToDNSLabel(path.Base(repo))] = true
+	}
+	return fns, true
+}
+
+// StepInputValidator validates the input of the steps of a Composition
+// pipeline against the input CRD shipped by the Function.
+type StepInputValidator struct {
+	s *Snapshot
+}
+
+// NewStepInputValidator returns a new StepInputValidator.
+func NewStepInputValidator(s *Snapshot) *StepInputValidator {
+	return &StepInputValidator{
+		s: s,
+	}
+}
+
+// validate validates that the step's input is valid per the schema of its
+// kind.
+func (i *StepInputValidator) validate(ctx context.Context, idx int, st xpextv1.PipelineStep) []error {
+	if st.Input == nil || len(st.Input.Raw) == 0 {
+		return nil
+	}
+	in := &unstructured.Unstructured{}
+	if err := in.UnmarshalJSON(st.Input.Raw); err != nil {
+		// inputs without a kind can't be resolved to a schema.
+		return nil // nolint:nilerr
+	}
+
+	ingvk := in.GroupVersionKind()
+	v, ok := i.s.validators[ingvk]
+	if !ok {
+		return gvkDNEWarning(ingvk, fmt.Sprintf(stepInputFmt, idx, "apiVersion"))
+	}
+
+	result := v.Validate(ctx, in)
+	if result == nil {
+		return []error{fmt.Errorf(errInvalidValidationFmt, ingvk)}
+	}
+	errs := []error{}
+	for _, e := range result.Errors {
+		var ve *verrors.Validation
+		if !errors.As(e, &ve) {
+			return []error{errors.New(errIncorrectErrType)}
+		}
+		// errors on top-level input fields are named .field
+		errs = append(errs, &validator.Validation{
+			TypeCode: ve.Code(),
+			Message:  fmt.Sprintf(errFmt, ve.Error(), ingvk),
+			Name:     fmt.Sprintf(stepInputFmt, idx, strings.TrimPrefix(ve.Name, ".")),
+		})
+	}
+	return errs
+}
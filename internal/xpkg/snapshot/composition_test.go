@@ -17,9 +17,11 @@ package snapshot
 import (
 	"context"
 	"encoding/json"
+	"os"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
 
 	apimetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -34,6 +36,7 @@ import (
 
 	"github.com/upbound/up/internal/xpkg/scheme"
 	"github.com/upbound/up/internal/xpkg/snapshot/validator"
+	"github.com/upbound/up/internal/xpkg/workspace"
 )
 
 func TestCompositionValidation(t *testing.T) {
@@ -398,3 +401,189 @@ func TestCompositionValidation(t *testing.T) {
 		})
 	}
 }
+
+var (
+	testPipelineMeta = []byte(`apiVersion: meta.pkg.crossplane.io/v1
+kind: Configuration
+metadata:
+  name: acme
+spec:
+  dependsOn:
+  - function: xpkg.upbound.io/crossplane-contrib/function-patch-and-transform
+    version: ">=v0.2.0"
+`)
+	testInputCRD = []byte(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: resources.pt.fn.crossplane.io
+spec:
+  group: pt.fn.crossplane.io
+  names:
+    kind: Resources
+    plural: resources
+  scope: Namespaced
+  versions:
+  - name: v1beta1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        required:
+        - resources
+        properties:
+          apiVersion:
+            type: string
+          kind:
+            type: string
+          resources:
+            type: array
+            items:
+              type: object
+`)
+)
+
+func TestPipelineValidation(t *testing.T) {
+	ctx := context.Background()
+
+	input := func(raw string) *runtime.RawExtension {
+		return &runtime.RawExtension{Raw: []byte(raw)}
+	}
+	pipeline := func(steps ...v1.PipelineStep) *v1.Composition {
+		return &v1.Composition{
+			TypeMeta: apimetav1.TypeMeta{
+				Kind:       v1.CompositionKind,
+				APIVersion: v1.SchemeGroupVersion.String(),
+			},
+			Spec: v1.CompositionSpec{
+				Mode:     ptr.To(v1.CompositionModePipeline),
+				Pipeline: steps,
+			},
+		}
+	}
+
+	cases := map[string]struct {
+		reason string
+		data   runtime.Object
+		want   *validate.Result
+	}{
+		"Valid": {
+			reason: "Should not return errors for steps referencing dependencies with valid inputs.",
+			data: pipeline(
+				v1.PipelineStep{
+					Step:        "patch-and-transform",
+					FunctionRef: v1.FunctionReference{Name: "crossplane-contrib-function-patch-and-transform"},
+					Input:       input(`{"apiVersion": "pt.fn.crossplane.io/v1beta1", "kind": "Resources", "resources": []}`),
+				},
+				v1.PipelineStep{
+					Step:        "short-name",
+					FunctionRef: v1.FunctionReference{Name: "function-patch-and-transform"},
+				},
+			),
+			want: &validate.Result{Errors: []error{}},
+		},
+		"FunctionNotDependency": {
+			reason: "Should return an error for a step referencing a Function that is not a dependency.",
+			data: pipeline(
+				v1.PipelineStep{
+					Step:        "auto-ready",
+					FunctionRef: v1.FunctionReference{Name: "function-auto-ready"},
+				},
+			),
+			want: &validate.Result{
+				Errors: []error{
+					&validator.Validation{
+						TypeCode: validator.ErrorTypeCode,
+						Message:  "function function-auto-ready is not declared as a dependency in crossplane.yaml",
+						Name:     "spec.pipeline[0].functionRef.name",
+					},
+				},
+			},
+		},
+		"DuplicateStep": {
+			reason: "Should return an error for steps that share a name.",
+			data: pipeline(
+				v1.PipelineStep{
+					Step:        "pt",
+					FunctionRef: v1.FunctionReference{Name: "function-patch-and-transform"},
+				},
+				v1.PipelineStep{
+					Step:        "pt",
+					FunctionRef: v1.FunctionReference{Name: "function-patch-and-transform"},
+				},
+			),
+			want: &validate.Result{
+				Errors: []error{
+					&validator.Validation{
+						TypeCode: validator.ErrorTypeCode,
+						Message:  `spec.pipeline[1].step: Duplicate value: "pt"`,
+						Name:     "spec.pipeline[1].step",
+					},
+				},
+			},
+		},
+		"InputMissingRequiredField": {
+			reason: "Should return an error for an input that is invalid per the Function's input CRD.",
+			data: pipeline(
+				v1.PipelineStep{
+					Step:        "pt",
+					FunctionRef: v1.FunctionReference{Name: "function-patch-and-transform"},
+					Input:       input(`{"apiVersion": "pt.fn.crossplane.io/v1beta1", "kind": "Resources"}`),
+				},
+			),
+			want: &validate.Result{
+				Errors: []error{
+					&validator.Validation{
+						TypeCode: 602,
+						Message:  ".resources in body is required (pt.fn.crossplane.io/v1beta1, Kind=Resources)",
+						Name:     "spec.pipeline[0].input.resources",
+					},
+				},
+			},
+		},
+		"InputMissingValidator": {
+			reason: "Should return a warning for an input whose kind has no definition.",
+			data: pipeline(
+				v1.PipelineStep{
+					Step:        "pt",
+					FunctionRef: v1.FunctionReference{Name: "function-patch-and-transform"},
+					Input:       input(`{"apiVersion": "pt.fn.crossplane.io/v1alpha1", "kind": "Resources"}`),
+				},
+			),
+			want: &validate.Result{
+				Errors: []error{
+					&validator.Validation{
+						TypeCode: validator.WarningTypeCode,
+						Message:  "no definition found for resource (pt.fn.crossplane.io/v1alpha1, Kind=Resources)",
+						Name:     "spec.pipeline[0].input.apiVersion",
+					},
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			_ = afero.WriteFile(fs, "/ws/crossplane.yaml", testPipelineMeta, os.ModePerm)
+			_ = afero.WriteFile(fs, "/ws/input.yaml", testInputCRD, os.ModePerm)
+			ws, _ := workspace.New("/ws", workspace.WithFS(fs), workspace.WithPermissiveParser())
+
+			factory, _ := NewFactory("/ws", WithDepManager(NewMockDepManager()))
+			s, err := factory.New(ctx, WithWorkspace(ws))
+			if err != nil {
+				t.Fatalf("\n%s\nNew(...): unexpected error: %s", tc.reason, err)
+			}
+
+			b, _ := json.Marshal(tc.data)
+			var u unstructured.Unstructured
+			_ = json.Unmarshal(b, &u)
+
+			v, _ := DefaultCompositionValidators(s)
+			result := v.Validate(ctx, &u)
+
+			if diff := cmp.Diff(tc.want, result); diff != "" {
+				t.Errorf("\n%s\nPipelineValidation(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
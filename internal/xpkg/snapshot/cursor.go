@@ -0,0 +1,356 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"strings"
+
+	"github.com/golang/tools/lsp/protocol"
+)
+
+const (
+	// seqSegment is the path segment of a sequence item.
+	seqSegment = "[]"
+
+	docSeparator = "---"
+)
+
+// yamlLine is a single non-empty line of a YAML document.
+type yamlLine struct {
+	// indent is the column of the first character of the line.
+	indent int
+	// dash is true if the line starts a sequence item.
+	dash bool
+	// col is the column of the content following the dash of a sequence
+	// item, or the indent otherwise.
+	col int
+	// key is the mapping key on the line, if any.
+	key string
+	// value is the inline value following the key, if any.
+	value string
+	// valueCol is the column of the inline value.
+	valueCol int
+}
+
+// parseLine parses the supplied line. It returns false if the line is blank
+// or a comment.
+func parseLine(s string) (yamlLine, bool) {
+	trimmed := strings.TrimLeft(s, " ")
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return yamlLine{}, false
+	}
+	l := yamlLine{indent: len(s) - len(trimmed)}
+	l.col = l.indent
+	rest := trimmed
+	if trimmed == "-" || strings.HasPrefix(trimmed, "- ") {
+		l.dash = true
+		rest = strings.TrimLeft(trimmed[1:], " ")
+		l.col = l.indent + len(trimmed) - len(rest)
+		if rest == "" {
+			l.col = l.indent + 2
+		}
+	}
+
+	idx := strings.Index(rest, ": ")
+	if idx < 0 && strings.HasSuffix(rest, ":") {
+		idx = len(rest) - 1
+	}
+	if idx < 0 {
+		return l, true
+	}
+	l.key = unquote(strings.TrimSpace(rest[:idx]))
+	v := rest[idx+1:]
+	l.valueCol = l.col + idx + 1 + len(v) - len(strings.TrimLeft(v, " "))
+	v = strings.TrimSpace(v)
+	if i := strings.Index(v, " #"); i >= 0 {
+		v = strings.TrimSpace(v[:i])
+	}
+	l.value = unquote(v)
+	return l, true
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"' || s[0] == '\'' && s[len(s)-1] == '\'') {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// frame is a mapping enclosing the cursor.
+type frame struct {
+	// segs are the path segments leading from the parent mapping to the
+	// mapping.
+	segs []string
+	// line is a line of the mapping.
+	line int
+	// col is the column of the keys of the mapping.
+	col int
+}
+
+// entry is a key of a mapping.
+type entry struct {
+	value string
+	line  int
+}
+
+// cursor is a position in a YAML document, along with the mappings that
+// enclose it. It works on the raw text of the document so that it can be
+// used while the document is being edited and does not parse.
+type cursor struct {
+	lines []string
+	// start and end are the lines the document holding the cursor spans.
+	start, end int
+
+	pos protocol.Position
+	// frames are the mappings enclosing the cursor, starting with the
+	// document root.
+	frames []frame
+
+	// key is the key at the cursor. It is empty if the cursor is at a
+	// key that is being typed.
+	key string
+	// value is true if the cursor is at the value of key.
+	value bool
+	// prefix is the text of the key or value before the cursor.
+	prefix string
+	// prefixCol is the column at which prefix starts.
+	prefixCol int
+}
+
+// newCursor returns the cursor at the supplied position of the supplied
+// file body.
+func newCursor(body []byte, pos protocol.Position) (*cursor, bool) {
+	c := &cursor{
+		lines: strings.Split(string(body), "\n"),
+		pos:   pos,
+	}
+	line := int(pos.Line)
+	if line >= len(c.lines) {
+		return nil, false
+	}
+	c.start, c.end = 0, len(c.lines)
+	for i := line; i >= 0; i-- {
+		if strings.HasPrefix(c.lines[i], docSeparator) {
+			if i == line {
+				return nil, false
+			}
+			c.start = i + 1
+			break
+		}
+	}
+	for i := line + 1; i < len(c.lines); i++ {
+		if strings.HasPrefix(c.lines[i], docSeparator) {
+			c.end = i
+			break
+		}
+	}
+
+	text := c.lines[line]
+	ch := int(pos.Character)
+	if ch > len(text) {
+		ch = len(text)
+	}
+	pre := text[:ch]
+
+	l, ok := parseLine(pre)
+	switch {
+	case !ok:
+		// blank line, the cursor column determines the mapping.
+		l = yamlLine{indent: len(pre), col: len(pre)}
+		c.prefixCol = len(pre)
+	case l.key != "":
+		c.key = l.key
+		c.value = true
+		c.prefix = strings.TrimLeft(pre[l.valueCol:], "\"'")
+		c.prefixCol = len(pre) - len(c.prefix)
+	default:
+		c.prefix = pre[l.col:]
+		c.prefixCol = l.col
+	}
+	c.frames = c.enclosing(line, l)
+	return c, true
+}
+
+// lineCursor returns the cursor at the end of the line of the supplied
+// position, so that the key on the line is known regardless of where on the
+// line the position is. It returns false if the line holds no key.
+func lineCursor(body []byte, pos protocol.Position) (*cursor, protocol.Range, bool) {
+	lines := strings.Split(string(body), "\n")
+	if int(pos.Line) >= len(lines) {
+		return nil, protocol.Range{}, false
+	}
+	text := lines[pos.Line]
+	l, ok := parseLine(text)
+	if !ok || l.key == "" || int(pos.Character) < l.col {
+		return nil, protocol.Range{}, false
+	}
+	c, ok := newCursor(body, protocol.Position{Line: pos.Line, Character: uint32(len(text))})
+	if !ok {
+		return nil, protocol.Range{}, false
+	}
+	return c, protocol.Range{
+		Start: protocol.Position{Line: pos.Line, Character: uint32(l.col)},
+		End:   protocol.Position{Line: pos.Line, Character: uint32(l.col + len(l.key))},
+	}, true
+}
+
+// enclosing returns the mappings enclosing the supplied line, which is a key
+// of the innermost mapping.
+func (c *cursor) enclosing(line int, l yamlLine) []frame { //nolint:gocyclo
+	inner := frame{line: line, col: l.col}
+	frames := []frame{}
+	seqCol := -1
+	if l.dash {
+		inner.segs = []string{seqSegment}
+		seqCol = l.indent
+	}
+
+	for i := line - 1; i >= c.start; i-- {
+		p, ok := parseLine(c.lines[i])
+		if !ok {
+			continue
+		}
+		if seqCol >= 0 {
+			// we are in a sequence item, look for the key owning the
+			// sequence, skipping preceding items.
+			if p.indent > seqCol || p.dash && p.indent == seqCol {
+				continue
+			}
+			if p.key == "" {
+				break
+			}
+			seqCol = -1
+		} else {
+			if p.col >= inner.col {
+				if p.dash && p.col == inner.col {
+					// start of the sequence item holding the mapping.
+					inner.segs = append([]string{seqSegment}, inner.segs...)
+					seqCol = p.indent
+				}
+				continue
+			}
+			if p.key == "" {
+				break
+			}
+		}
+
+		inner.segs = append([]string{p.key}, inner.segs...)
+		frames = append(frames, inner)
+		inner = frame{line: i, col: p.col}
+		if p.dash {
+			inner.segs = []string{seqSegment}
+			seqCol = p.indent
+		}
+	}
+	frames = append(frames, inner)
+
+	// reverse so that the document root comes first.
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+	return frames
+}
+
+// path returns the path from the mapping at index from to the mapping at
+// index to.
+func (c *cursor) path(from, to int) []string {
+	p := []string{}
+	for i := from + 1; i <= to && i < len(c.frames); i++ {
+		p = append(p, c.frames[i].segs...)
+	}
+	return p
+}
+
+// fields returns the keys of the supplied mapping, excluding the key being
+// typed at the cursor.
+func (c *cursor) fields(f frame) map[string]entry {
+	fields := make(map[string]entry)
+	add := func(i int, l yamlLine) {
+		if l.key == "" || i == int(c.pos.Line) && !c.value {
+			return
+		}
+		fields[l.key] = entry{value: l.value, line: i}
+	}
+
+	for i := f.line; i >= c.start; i-- {
+		l, ok := parseLine(c.lines[i])
+		if !ok {
+			continue
+		}
+		if l.col < f.col {
+			break
+		}
+		if l.col == f.col {
+			add(i, l)
+			if l.dash {
+				break
+			}
+		}
+	}
+	for i := f.line + 1; i < c.end; i++ {
+		l, ok := parseLine(c.lines[i])
+		if !ok {
+			continue
+		}
+		if l.col < f.col || l.col == f.col && l.dash {
+			break
+		}
+		if l.col == f.col {
+			add(i, l)
+		}
+	}
+	return fields
+}
+
+// child returns the mapping that is the value of the supplied key of the
+// supplied mapping.
+func (c *cursor) child(f frame, key string) (frame, bool) {
+	k, ok := c.fields(f)[key]
+	if !ok {
+		return frame{}, false
+	}
+	for i := k.line + 1; i < c.end; i++ {
+		l, ok := parseLine(c.lines[i])
+		if !ok {
+			continue
+		}
+		if l.col <= f.col || l.dash {
+			return frame{}, false
+		}
+		return frame{segs: []string{key}, line: i, col: l.col}, true
+	}
+	return frame{}, false
+}
+
+// lookup returns the value at the supplied path of keys below the supplied
+// mapping.
+func (c *cursor) lookup(f frame, path ...string) string {
+	for _, k := range path[:len(path)-1] {
+		var ok bool
+		if f, ok = c.child(f, k); !ok {
+			return ""
+		}
+	}
+	return c.fields(f)[path[len(path)-1]].value
+}
+
+// prefixRange returns the range of the text before the cursor that a
+// completion replaces.
+func (c *cursor) prefixRange() protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: c.pos.Line, Character: uint32(c.prefixCol)},
+		End:   c.pos,
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"os"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	xpextv1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/crossplane/crossplane/apis/pkg/v1beta1"
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/golang/tools/lsp/protocol"
+	"github.com/golang/tools/span"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	extv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// pathNamesKind and pathClaimNamesKind are the paths of the kinds
+	// defined by CRDs and XRDs.
+	pathNamesKind      = "$.spec.names.kind"
+	pathClaimNamesKind = "$.spec.claimNames.kind"
+)
+
+// Definition returns the location of the XRD or CRD defining the type
+// referred to by the apiVersion or kind at the supplied position of the file
+// with the supplied URI. This includes the type of an object itself, as well
+// as references such as a Composition's compositeTypeRef. Definitions in the
+// workspace take precedence over the ones of dependencies, which are located
+// in the package cache.
+func (s *Snapshot) Definition(_ context.Context, uri span.URI, pos protocol.Position) ([]protocol.Location, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	details, ok := s.wsview.FileDetails()[uri]
+	if !ok {
+		return nil, errors.New(errInvalidFileURI)
+	}
+	c, _, ok := lineCursor(details.Body, pos)
+	if !ok || c.key != keyAPIVersion && c.key != keyKind {
+		return []protocol.Location{}, nil
+	}
+	gvk, ok := c.gvk(c.frames[len(c.frames)-1])
+	if !ok {
+		return []protocol.Location{}, nil
+	}
+
+	if loc, ok := s.wsDefinition(gvk); ok {
+		return []protocol.Location{loc}, nil
+	}
+	if loc, ok := s.depDefinition(gvk); ok {
+		return []protocol.Location{loc}, nil
+	}
+	return []protocol.Location{}, nil
+}
+
+// wsDefinition returns the location of the workspace XRD defining the
+// supplied GVK, either as a composite resource or as a claim.
+func (s *Snapshot) wsDefinition(gvk schema.GroupVersionKind) (protocol.Location, bool) {
+	for _, n := range s.wsview.Nodes() {
+		if n.GetGVK().Kind != xpextv1.CompositeResourceDefinitionKind {
+			continue
+		}
+		u, ok := n.GetObject().(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		path, ok := defines(u.Object, gvk)
+		if !ok {
+			continue
+		}
+		return location(n.GetFileName(), n.GetAST(), path), true
+	}
+	return protocol.Location{}, false
+}
+
+// depDefinition returns the location of the cached file holding the XRD or
+// CRD of a dependency defining the supplied GVK.
+func (s *Snapshot) depDefinition(gvk schema.GroupVersionKind) (protocol.Location, bool) {
+	for name, p := range s.packages {
+		u, path, ok := definition(p.Objects(), gvk)
+		if !ok {
+			continue
+		}
+		file, err := s.dm.ObjectPath(v1beta1.Dependency{
+			Package:     name,
+			Constraints: p.Version(),
+		}, u.GetName())
+		if err != nil {
+			continue
+		}
+		return fileLocation(file, path), true
+	}
+	return protocol.Location{}, false
+}
+
+// definition returns the XRD or CRD among the supplied objects that defines
+// the supplied GVK, along with the path of its kind field.
+func definition(objs []runtime.Object, gvk schema.GroupVersionKind) (*unstructured.Unstructured, string, bool) {
+	for _, o := range objs {
+		switch o.(type) {
+		case *xpextv1.CompositeResourceDefinition, *extv1.CustomResourceDefinition, *extv1beta1.CustomResourceDefinition:
+		default:
+			continue
+		}
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+		if err != nil {
+			continue
+		}
+		if path, ok := defines(u, gvk); ok {
+			return &unstructured.Unstructured{Object: u}, path, true
+		}
+	}
+	return nil, "", false
+}
+
+// defines returns the path of the kind field of the supplied XRD or CRD if
+// it defines the supplied GVK, either as its kind or as the kind of its
+// claim.
+func defines(o map[string]any, gvk schema.GroupVersionKind) (string, bool) {
+	if group, _, _ := unstructured.NestedString(o, "spec", "group"); group != gvk.Group {
+		return "", false
+	}
+	if kind, _, _ := unstructured.NestedString(o, "spec", "names", "kind"); kind == gvk.Kind {
+		return pathNamesKind, true
+	}
+	if kind, _, _ := unstructured.NestedString(o, "spec", "claimNames", "kind"); kind != "" && kind == gvk.Kind {
+		return pathClaimNamesKind, true
+	}
+	return "", false
+}
+
+// fileLocation returns the location of the field at the supplied path of the
+// first document of the supplied file. The start of the file is returned if
+// the field cannot be found.
+func fileLocation(file, path string) protocol.Location {
+	b, err := os.ReadFile(file) //nolint:gosec // the file is in the package cache.
+	if err != nil {
+		return location(file, nil, path)
+	}
+	f, err := parser.ParseBytes(b, 0)
+	if err != nil || len(f.Docs) == 0 {
+		return location(file, nil, path)
+	}
+	return location(file, f.Docs[0].Body, path)
+}
+
+// location returns the location of the field at the supplied path of the
+// supplied node of the supplied file.
+func location(file string, n ast.Node, path string) protocol.Location {
+	loc := protocol.Location{
+		URI: protocol.URIFromSpanURI(span.URIFromPath(file)),
+	}
+	if rng, ok := fieldRange(n, path); ok {
+		loc.Range = rng
+	}
+	return loc
+}
+
+// fieldRange returns the range of the value of the field at the supplied path
+// of the supplied node.
+func fieldRange(n ast.Node, path string) (protocol.Range, bool) {
+	if n == nil {
+		return protocol.Range{}, false
+	}
+	p, err := yaml.PathString(path)
+	if err != nil {
+		return protocol.Range{}, false
+	}
+	field, err := p.FilterNode(n)
+	if err != nil || field == nil || field.GetToken() == nil {
+		return protocol.Range{}, false
+	}
+	tok := field.GetToken()
+	start := protocol.Position{
+		Line:      uint32(tok.Position.Line - 1),
+		Character: uint32(tok.Position.Column - 1),
+	}
+	return protocol.Range{
+		Start: start,
+		End:   protocol.Position{Line: start.Line, Character: start.Character + uint32(len(tok.Value))},
+	}, true
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/golang/tools/lsp/protocol"
+	"github.com/golang/tools/span"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/upbound/up/internal/xpkg/workspace"
+)
+
+func TestDefinition(t *testing.T) {
+	xrdURI := protocol.URIFromSpanURI(span.URIFromPath("/ws/xrd.yaml"))
+	compositeKind := protocol.Location{
+		URI: xrdURI,
+		Range: protocol.Range{
+			Start: protocol.Position{Line: 7, Character: 10},
+			End:   protocol.Position{Line: 7, Character: 17},
+		},
+	}
+	claimKind := protocol.Location{
+		URI: xrdURI,
+		Range: protocol.Range{
+			Start: protocol.Position{Line: 10, Character: 10},
+			End:   protocol.Position{Line: 10, Character: 16},
+		},
+	}
+
+	cases := map[string]struct {
+		reason string
+		doc    string
+		want   []protocol.Location
+	}{
+		"ClaimKind": {
+			reason: "Should return the claim kind of the XRD defining the kind of a claim.",
+			doc: `apiVersion: acme.io/v1
+kind: Buc|ket
+metadata:
+  name: bucket`,
+			want: []protocol.Location{claimKind},
+		},
+		"ClaimAPIVersion": {
+			reason: "Should return the definition when the cursor is on the apiVersion.",
+			doc: `apiVersion: acme|.io/v1
+kind: Bucket`,
+			want: []protocol.Location{claimKind},
+		},
+		"CompositeTypeRef": {
+			reason: "Should return the composite kind of the XRD referred to by a Composition.",
+			doc: `apiVersion: apiextensions.crossplane.io/v1
+kind: Composition
+spec:
+  compositeTypeRef:
+    apiVersion: acme.io/v1
+    kind: XBu|cket`,
+			want: []protocol.Location{compositeKind},
+		},
+		"NotDefined": {
+			reason: "Should not return a location for a kind that is not defined by an XRD.",
+			doc: `apiVersion: apiextensions.crossplane.io/v1
+kind: Compo|sition`,
+			want: []protocol.Location{},
+		},
+		"NotAKind": {
+			reason: "Should not return a location for fields other than apiVersion and kind.",
+			doc: `apiVersion: acme.io/v1
+kind: Bucket
+metadata:
+  name: buc|ket`,
+			want: []protocol.Location{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			body, pos := withCursor(tc.doc)
+			fs := afero.NewMemMapFs()
+			_ = afero.WriteFile(fs, "/ws/xrd.yaml", testXRD, os.ModePerm)
+			_ = afero.WriteFile(fs, "/ws/test.yaml", body, os.ModePerm)
+			ws, _ := workspace.New("/ws", workspace.WithFS(fs), workspace.WithPermissiveParser())
+
+			factory, _ := NewFactory("/ws", WithDepManager(NewMockDepManager()))
+			snap, err := factory.New(context.Background(), WithWorkspace(ws))
+			if err != nil {
+				t.Fatalf("\n%s\nNew(...): unexpected error: %s", tc.reason, err)
+			}
+
+			got, err := snap.Definition(context.Background(), span.URIFromPath("/ws/test.yaml"), pos)
+			if err != nil {
+				t.Fatalf("\n%s\nDefinition(...): unexpected error: %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nDefinition(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
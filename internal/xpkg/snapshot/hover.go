@@ -0,0 +1,165 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/golang/tools/lsp/protocol"
+	"github.com/golang/tools/span"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// Hover returns the documentation of the field at the supplied position of
+// the file with the supplied URI, as described by the schema of the object
+// holding the field. For the field paths of Composition patches the
+// documentation of the field the path refers to is returned. Nil is returned
+// if there is no documentation for the position.
+func (s *Snapshot) Hover(_ context.Context, uri span.URI, pos protocol.Position) (*protocol.Hover, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	details, ok := s.wsview.FileDetails()[uri]
+	if !ok {
+		return nil, errors.New(errInvalidFileURI)
+	}
+	c, rng, ok := lineCursor(details.Body, pos)
+	if !ok {
+		return nil, nil
+	}
+
+	if c.key == keyFromFieldPath || c.key == keyToFieldPath {
+		if sch, ok := s.patchSchema(c); ok {
+			return fieldPathHover(c.prefix, sch), nil
+		}
+	}
+
+	root, gvk, ok := c.object()
+	if !ok {
+		return nil, nil
+	}
+	parent := schemaAt(s.Schema(gvk), c.path(root, len(c.frames)-1))
+	sch := schemaAt(parent, []string{c.key})
+	if sch == nil {
+		return nil, nil
+	}
+	return &protocol.Hover{
+		Contents: protocol.MarkupContent{
+			Kind:  protocol.Markdown,
+			Value: schemaDoc(c.key, sch, required(parent, c.key)),
+		},
+		Range: rng,
+	}, nil
+}
+
+// fieldPathHover returns the documentation of the field the supplied field
+// path refers to.
+func fieldPathHover(fp string, sch *spec.Schema) *protocol.Hover {
+	segs, err := fieldpath.Parse(fp)
+	if err != nil || len(segs) == 0 {
+		return nil
+	}
+	path := make([]string, 0, len(segs))
+	for _, s := range segs {
+		if s.Type == fieldpath.SegmentIndex {
+			path = append(path, seqSegment)
+			continue
+		}
+		path = append(path, s.Field)
+	}
+	parent := schemaAt(sch, path[:len(path)-1])
+	field := schemaAt(parent, path[len(path)-1:])
+	if field == nil {
+		return nil
+	}
+	return &protocol.Hover{
+		Contents: protocol.MarkupContent{
+			Kind:  protocol.Markdown,
+			Value: schemaDoc(fp, field, required(parent, path[len(path)-1])),
+		},
+	}
+}
+
+func required(sch *spec.Schema, name string) bool {
+	if sch == nil {
+		return false
+	}
+	for _, r := range sch.Required {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaDoc renders the description, type and constraints of the supplied
+// schema as markdown.
+func schemaDoc(name string, sch *spec.Schema, required bool) string { //nolint:gocyclo
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "**%s**", name)
+	if t := schemaType(sch); t != "" {
+		fmt.Fprintf(b, " `%s`", t)
+	}
+	if required {
+		b.WriteString(" (required)")
+	}
+	if sch.Description != "" {
+		fmt.Fprintf(b, "\n\n%s", sch.Description)
+	}
+
+	constraints := make([]string, 0)
+	if len(sch.Enum) > 0 {
+		vals := make([]string, len(sch.Enum))
+		for i, e := range sch.Enum {
+			vals[i] = fmt.Sprintf("`%v`", e)
+		}
+		constraints = append(constraints, "Enum: "+strings.Join(vals, ", "))
+	}
+	if sch.Default != nil {
+		constraints = append(constraints, fmt.Sprintf("Default: `%v`", sch.Default))
+	}
+	if sch.Format != "" {
+		constraints = append(constraints, fmt.Sprintf("Format: `%s`", sch.Format))
+	}
+	if sch.Pattern != "" {
+		constraints = append(constraints, fmt.Sprintf("Pattern: `%s`", sch.Pattern))
+	}
+	if sch.Minimum != nil {
+		constraints = append(constraints, fmt.Sprintf("Minimum: %v", *sch.Minimum))
+	}
+	if sch.Maximum != nil {
+		constraints = append(constraints, fmt.Sprintf("Maximum: %v", *sch.Maximum))
+	}
+	if sch.MinLength != nil {
+		constraints = append(constraints, fmt.Sprintf("Minimum length: %d", *sch.MinLength))
+	}
+	if sch.MaxLength != nil {
+		constraints = append(constraints, fmt.Sprintf("Maximum length: %d", *sch.MaxLength))
+	}
+	if sch.MinItems != nil {
+		constraints = append(constraints, fmt.Sprintf("Minimum items: %d", *sch.MinItems))
+	}
+	if sch.MaxItems != nil {
+		constraints = append(constraints, fmt.Sprintf("Maximum items: %d", *sch.MaxItems))
+	}
+	if len(constraints) > 0 {
+		fmt.Fprintf(b, "\n\n- %s", strings.Join(constraints, "\n- "))
+	}
+	return b.String()
+}
@@ -0,0 +1,137 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/golang/tools/lsp/protocol"
+	"github.com/golang/tools/span"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/upbound/up/internal/xpkg/workspace"
+)
+
+func TestHover(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		doc    string
+		want   *protocol.Hover
+	}{
+		"RequiredField": {
+			reason: "Should return the description and type of a required field.",
+			doc: `apiVersion: acm.aws.crossplane.io/v1alpha1
+kind: Certificate
+spec:
+  forProvider:
+    re|gion: us-east-1`,
+			want: &protocol.Hover{
+				Contents: protocol.MarkupContent{
+					Kind:  protocol.Markdown,
+					Value: "**region** `string` (required)\n\nRegion is the region you'd like your Certificate to be created in.",
+				},
+				Range: protocol.Range{
+					Start: protocol.Position{Line: 4, Character: 4},
+					End:   protocol.Position{Line: 4, Character: 10},
+				},
+			},
+		},
+		"EnumField": {
+			reason: "Should return the enum values of a field when hovering its value.",
+			doc: `apiVersion: acm.aws.crossplane.io/v1alpha1
+kind: Certificate
+spec:
+  forProvider:
+    validationMethod: D|NS`,
+			want: &protocol.Hover{
+				Contents: protocol.MarkupContent{
+					Kind:  protocol.Markdown,
+					Value: "**validationMethod** `string`\n\nMethod to validate certificate.\n\n- Enum: `DNS`, `EMAIL`",
+				},
+				Range: protocol.Range{
+					Start: protocol.Position{Line: 4, Character: 4},
+					End:   protocol.Position{Line: 4, Character: 20},
+				},
+			},
+		},
+		"PatchFieldPath": {
+			reason: "Should return the documentation of the field a patch field path refers to.",
+			doc: `apiVersion: apiextensions.crossplane.io/v1
+kind: Composition
+spec:
+  compositeTypeRef:
+    apiVersion: acme.io/v1
+    kind: XBucket
+  resources:
+  - base:
+      apiVersion: acm.aws.crossplane.io/v1alpha1
+      kind: Certificate
+    patches:
+    - fromFieldPath: spec.parameters.size
+      toFieldPath: spec.forProvider.re|gion`,
+			want: &protocol.Hover{
+				Contents: protocol.MarkupContent{
+					Kind:  protocol.Markdown,
+					Value: "**spec.forProvider.region** `string` (required)\n\nRegion is the region you'd like your Certificate to be created in.",
+				},
+			},
+		},
+		"UnknownField": {
+			reason: "Should not return anything for a field that is not part of the schema.",
+			doc: `apiVersion: acm.aws.crossplane.io/v1alpha1
+kind: Certificate
+spec:
+  forProvider:
+    unknown|: value`,
+		},
+		"NoKey": {
+			reason: "Should not return anything for a line without a key.",
+			doc: `apiVersion: acm.aws.crossplane.io/v1alpha1
+kind: Certificate
+spec:
+  forProvider:
+    subjectAlternativeNames:
+    - exa|mple.com`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			body, pos := withCursor(tc.doc)
+			fs := afero.NewMemMapFs()
+			_ = afero.WriteFile(fs, "/ws/crd.yaml", testSingleVersionCRD, os.ModePerm)
+			_ = afero.WriteFile(fs, "/ws/xrd.yaml", testXRD, os.ModePerm)
+			_ = afero.WriteFile(fs, "/ws/test.yaml", body, os.ModePerm)
+			ws, _ := workspace.New("/ws", workspace.WithFS(fs), workspace.WithPermissiveParser())
+
+			factory, _ := NewFactory("/ws", WithDepManager(NewMockDepManager()))
+			snap, err := factory.New(context.Background(), WithWorkspace(ws))
+			if err != nil {
+				t.Fatalf("\n%s\nNew(...): unexpected error: %s", tc.reason, err)
+			}
+
+			got, err := snap.Hover(context.Background(), span.URIFromPath("/ws/test.yaml"), pos)
+			if err != nil {
+				t.Fatalf("\n%s\nHover(...): unexpected error: %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nHover(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
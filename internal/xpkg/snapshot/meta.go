@@ -31,6 +31,7 @@ import (
 	"k8s.io/kube-openapi/pkg/validation/validate"
 	"sigs.k8s.io/yaml"
 
+	"github.com/upbound/up/internal/xpkg/dep/lock"
 	"github.com/upbound/up/internal/xpkg/dep/manager"
 	pyaml "github.com/upbound/up/internal/xpkg/parser/yaml"
 	"github.com/upbound/up/internal/xpkg/scheme"
@@ -45,6 +46,8 @@ const (
 	errAPIVersionDeprecatedFmt = "%s is deprecated in favor of %s"
 	errFailedConvertToPkg      = "unable to convert to package"
 	errPackageDNEFmt           = "Package %s does not exist locally. Please run `up xpkg dep` to fix."
+	errLockedVersionDNEFmt     = "Locked version %s does not exist locally. Please run `up xpkg dep` to fix."
+	errLockOutOfDateFmt        = "Locked version %s does not match %s. Please run `up xpkg dep` to fix."
 	errVersionDENFmt           = "Version matching %s does not exist locally. Please run `up xpkg dep` to fix."
 	errWrongPkgTypeFmt         = "Incorrect package type. '%s' does not match type for %s of '%s'"
 )
@@ -67,7 +70,7 @@ func DefaultMetaValidators(s *Snapshot) (*MetaValidator, error) {
 
 	validators := []metaValidator{
 		NewTypeValidator(s),
-		NewVersionValidator(s.dm),
+		NewVersionValidator(s.dm, s.lock),
 	}
 
 	return &MetaValidator{
@@ -197,15 +200,18 @@ func (v *TypeValidator) validate(_ context.Context, i int, d v1beta1.Dependency)
 // VersionValidator is used to validate the dependency versions in a meta file.
 type VersionValidator struct {
 	manager DepManager
+	lock    *lock.Lock
 }
 
-// NewVersionValidator returns a new VersionValidator.
+// NewVersionValidator returns a new VersionValidator. If a lock is supplied,
+// dependencies pinned by it are validated against their locked versions.
 // NOTE(@tnthornton) NewVersionValidator needs snapshot's manager due to the
 // use case where someone adds a dependency to the crossplane.yaml and we need
 // to validate its existence.
-func NewVersionValidator(manager DepManager) *VersionValidator {
+func NewVersionValidator(manager DepManager, l *lock.Lock) *VersionValidator {
 	return &VersionValidator{
 		manager: manager,
+		lock:    l,
 	}
 }
 
@@ -223,6 +229,9 @@ func (v *VersionValidator) validate(ctx context.Context, i int, d v1beta1.Depend
 			Message: fmt.Sprintf(errPackageDNEFmt, d.Package),
 		}
 	}
+	if p, ok := v.lock.Get(d.Package); ok {
+		return validateLocked(i, d, p, vers)
+	}
 	if !versionMatch(d.Constraints, vers) {
 		return &validator.Validation{
 			Name:    fmt.Sprintf(dependsOnPathFmt, i, versionField),
@@ -232,6 +241,26 @@ func (v *VersionValidator) validate(ctx context.Context, i int, d v1beta1.Depend
 	return nil
 }
 
+// validateLocked validates a dependency that is pinned by the lock file.
+func validateLocked(i int, d v1beta1.Dependency, p lock.Package, vers []string) error {
+	if !lock.Satisfies(d.Constraints, p.Version) {
+		return &validator.Validation{
+			Name:     fmt.Sprintf(dependsOnPathFmt, i, versionField),
+			Message:  fmt.Sprintf(errLockOutOfDateFmt, p.Version, d.Constraints),
+			TypeCode: validator.WarningTypeCode,
+		}
+	}
+	for _, v := range vers {
+		if v == p.Version {
+			return nil
+		}
+	}
+	return &validator.Validation{
+		Name:    fmt.Sprintf(dependsOnPathFmt, i, versionField),
+		Message: fmt.Sprintf(errLockedVersionDNEFmt, p.Version),
+	}
+}
+
 // versionMatch returns true if the supplied constraint matches a pre-existing
 // version in the supplied versions slice.
 func versionMatch(constraint string, vers []string) bool {
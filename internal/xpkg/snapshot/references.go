@@ -0,0 +1,172 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"sort"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	xpextv1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/golang/tools/lsp/protocol"
+	"github.com/golang/tools/span"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/upbound/up/internal/xpkg/workspace"
+)
+
+const (
+	pathCompositeTypeRefKind = "$.spec.compositeTypeRef.kind"
+)
+
+// References returns the locations of the workspace objects using the type
+// at the supplied position of the file with the supplied URI. The type is
+// either the one referred to by an apiVersion or kind, or the composite and
+// claim types defined by the XRD at the position. Objects use a type if they
+// are of that type, including the resources embedded in Compositions, or if
+// they are Compositions referring to the type in their compositeTypeRef.
+// Versions are ignored when matching types. The locations of the workspace
+// XRD defining the type are included if includeDecl is true.
+func (s *Snapshot) References(_ context.Context, uri span.URI, pos protocol.Position, includeDecl bool) ([]protocol.Location, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	details, ok := s.wsview.FileDetails()[uri]
+	if !ok {
+		return nil, errors.New(errInvalidFileURI)
+	}
+
+	gks := s.referencedKinds(details, pos)
+	if len(gks) == 0 {
+		return []protocol.Location{}, nil
+	}
+
+	locs := []protocol.Location{}
+	if includeDecl {
+		for _, gk := range gks {
+			if loc, ok := s.wsDefinition(gk.WithVersion("")); ok {
+				locs = append(locs, loc)
+			}
+		}
+	}
+	locs = append(locs, s.uses(gks)...)
+	return dedupe(locs), nil
+}
+
+// referencedKinds returns the kinds referred to at the supplied position of
+// the file with the supplied details.
+func (s *Snapshot) referencedKinds(details *workspace.Details, pos protocol.Position) []schema.GroupKind {
+	c, _, ok := lineCursor(details.Body, pos)
+	if ok && (c.key == keyAPIVersion || c.key == keyKind) {
+		gvk, ok := c.gvk(c.frames[len(c.frames)-1])
+		if ok && gvk.Kind != xpextv1.CompositeResourceDefinitionKind {
+			return []schema.GroupKind{gvk.GroupKind()}
+		}
+	}
+
+	// otherwise look for the kinds defined by an XRD at the position.
+	for id := range details.NodeIDs {
+		n, ok := s.wsview.Nodes()[id]
+		if !ok || n.GetGVK().Kind != xpextv1.CompositeResourceDefinitionKind {
+			continue
+		}
+		rng := nodeRange(n.GetAST())
+		if pos.Line < rng.Start.Line || pos.Line > rng.End.Line {
+			continue
+		}
+		u, ok := n.GetObject().(*unstructured.Unstructured)
+		if !ok {
+			return nil
+		}
+		return definedKinds(u)
+	}
+	return nil
+}
+
+// uses returns the locations of the workspace objects using the supplied
+// kinds, sorted by file and position.
+func (s *Snapshot) uses(gks []schema.GroupKind) []protocol.Location {
+	match := make(map[schema.GroupKind]bool, len(gks))
+	for _, gk := range gks {
+		match[gk] = true
+	}
+
+	locs := []protocol.Location{}
+	for _, n := range s.wsview.Nodes() {
+		if match[n.GetGVK().GroupKind()] {
+			locs = append(locs, location(n.GetFileName(), n.GetAST(), pathKind))
+		}
+		if n.GetGVK().Kind != xpextv1.CompositionKind {
+			continue
+		}
+		u, ok := n.GetObject().(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		apiVersion, _, _ := unstructured.NestedString(u.Object, "spec", "compositeTypeRef", "apiVersion")
+		kind, _, _ := unstructured.NestedString(u.Object, "spec", "compositeTypeRef", "kind")
+		if match[schema.FromAPIVersionAndKind(apiVersion, kind).GroupKind()] {
+			locs = append(locs, location(n.GetFileName(), n.GetAST(), pathCompositeTypeRefKind))
+		}
+	}
+	// nodes of examples that share a name and kind overwrite each other, so
+	// examples are collected separately.
+	for gvk, nodes := range s.wsview.Examples() {
+		if !match[gvk.GroupKind()] {
+			continue
+		}
+		for _, n := range nodes {
+			locs = append(locs, location(n.GetFileName(), n.GetAST(), pathKind))
+		}
+	}
+
+	sort.SliceStable(locs, func(i, j int) bool {
+		if locs[i].URI != locs[j].URI {
+			return locs[i].URI < locs[j].URI
+		}
+		return before(locs[i].Range.Start, locs[j].Range.Start)
+	})
+	return locs
+}
+
+// definedKinds returns the composite and claim kinds defined by the supplied
+// XRD.
+func definedKinds(xrd *unstructured.Unstructured) []schema.GroupKind {
+	group, _, _ := unstructured.NestedString(xrd.Object, "spec", "group")
+	gks := []schema.GroupKind{}
+	if kind, _, _ := unstructured.NestedString(xrd.Object, "spec", "names", "kind"); kind != "" {
+		gks = append(gks, schema.GroupKind{Group: group, Kind: kind})
+	}
+	if kind, _, _ := unstructured.NestedString(xrd.Object, "spec", "claimNames", "kind"); kind != "" {
+		gks = append(gks, schema.GroupKind{Group: group, Kind: kind})
+	}
+	return gks
+}
+
+// dedupe removes duplicate locations while preserving the order of the
+// supplied locations.
+func dedupe(locs []protocol.Location) []protocol.Location {
+	seen := make(map[protocol.Location]bool, len(locs))
+	out := make([]protocol.Location, 0, len(locs))
+	for _, l := range locs {
+		if seen[l] {
+			continue
+		}
+		seen[l] = true
+		out = append(out, l)
+	}
+	return out
+}
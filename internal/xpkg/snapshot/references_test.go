@@ -0,0 +1,94 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/tools/lsp/protocol"
+	"github.com/golang/tools/span"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReferences(t *testing.T) {
+	loc := func(file string, r protocol.Range) protocol.Location {
+		return protocol.Location{URI: protocol.URIFromSpanURI(span.URIFromPath(file)), Range: r}
+	}
+	compositeDecl := loc("/ws/xrd.yaml", rng(7, 10, 7, 17))
+	claimDecl := loc("/ws/xrd.yaml", rng(10, 10, 10, 16))
+	compositeTypeRef := loc("/ws/composition.yaml", rng(7, 10, 7, 17))
+	example := loc("/ws/examples/bucket.yaml", rng(1, 6, 1, 12))
+
+	type args struct {
+		file        string
+		pos         protocol.Position
+		includeDecl bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   []protocol.Location
+	}{
+		"XRD": {
+			reason: "Should return the Compositions and examples using the kinds defined by the XRD at the position.",
+			args: args{
+				file:        "/ws/xrd.yaml",
+				pos:         protocol.Position{Line: 3, Character: 10},
+				includeDecl: true,
+			},
+			want: []protocol.Location{compositeDecl, claimDecl, compositeTypeRef, example},
+		},
+		"ClaimKind": {
+			reason: "Should return the objects of the kind at the position.",
+			args: args{
+				file: "/ws/examples/bucket.yaml",
+				pos:  protocol.Position{Line: 1, Character: 8},
+			},
+			want: []protocol.Location{example},
+		},
+		"CompositeTypeRef": {
+			reason: "Should return the Compositions referring to the composite kind at the position.",
+			args: args{
+				file:        "/ws/composition.yaml",
+				pos:         protocol.Position{Line: 7, Character: 12},
+				includeDecl: true,
+			},
+			want: []protocol.Location{compositeDecl, compositeTypeRef},
+		},
+		"NotAKind": {
+			reason: "Should not return locations for fields that do not refer to a kind.",
+			args: args{
+				file: "/ws/composition.yaml",
+				pos:  protocol.Position{Line: 3, Character: 10},
+			},
+			want: []protocol.Location{},
+		},
+	}
+
+	snap := symbolSnapshot(t)
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := snap.References(context.Background(), span.URIFromPath(tc.args.file), tc.args.pos, tc.args.includeDecl)
+			if err != nil {
+				t.Fatalf("\n%s\nReferences(...): unexpected error: %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nReferences(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/kube-openapi/pkg/validation/spec"
 	"k8s.io/kube-openapi/pkg/validation/validate"
 
 	apimachyaml "k8s.io/apimachinery/pkg/util/yaml"
@@ -45,6 +46,7 @@ import (
 
 	"github.com/upbound/up/internal/xpkg"
 	"github.com/upbound/up/internal/xpkg/dep/cache"
+	"github.com/upbound/up/internal/xpkg/dep/lock"
 	"github.com/upbound/up/internal/xpkg/dep/manager"
 	mxpkg "github.com/upbound/up/internal/xpkg/dep/marshaler/xpkg"
 	"github.com/upbound/up/internal/xpkg/scheme"
@@ -60,13 +62,20 @@ const (
 	errInvalidNodeID     = "invalid node id supplied"
 	errInvalidRange      = "invalid range supplied"
 	errNoChangesSupplied = "no content changes provided"
+	errReadLock          = "failed to read lock file"
+	errAddDependency     = "failed to add dependency to meta file"
+	errInsertDependency  = "cannot insert dependency into flow style spec.dependsOn of meta file"
+	errListCache         = "failed to list cached packages"
 )
 
 // DepManager defines the API necessary for working with the dependency manager.
 type DepManager interface {
 	View(context.Context, []v1beta1.Dependency) (*manager.View, error)
 	Versions(context.Context, v1beta1.Dependency) ([]string, error)
+	ObjectPath(v1beta1.Dependency, string) (string, error)
+	Cached() ([]*mxpkg.ParsedPackage, error)
 	Watch() <-chan cache.Event
+	Unwatch(<-chan cache.Event)
 }
 
 // Snapshot provides a unified point in time snapshot of the details needed to
@@ -82,6 +91,9 @@ type Snapshot struct {
 	w   *workspace.Workspace
 	log logging.Logger
 
+	// lock is the lock file found next to the meta file, if any.
+	lock *lock.Lock
+
 	objScheme  *runtime.Scheme
 	metaScheme *runtime.Scheme
 	// packages includes the parsed packages from the defined package
@@ -180,6 +192,11 @@ func (f *Factory) WatchExt() <-chan cache.Event {
 	return f.m.Watch()
 }
 
+// UnwatchExt stops the supplied subscription returned by WatchExt.
+func (f *Factory) UnwatchExt(ch <-chan cache.Event) {
+	f.m.Unwatch(ch)
+}
+
 // init initializes the snapshot with needed details from the workspace
 // and dep manager.
 func (s *Snapshot) init(ctx context.Context) error {
@@ -189,12 +206,27 @@ func (s *Snapshot) init(ctx context.Context) error {
 
 	s.wsview = s.w.View()
 
+	l, err := s.w.Lock()
+	if err != nil && !os.IsNotExist(err) {
+		// an unreadable lock file shouldn't prevent validation of the rest
+		// of the workspace.
+		s.log.Debug(errReadLock, "error", err)
+	}
+	s.lock = l
+
 	meta := s.wsview.Meta()
 	if meta != nil {
 		deps, err := meta.DependsOn()
 		if err != nil {
 			return err
 		}
+		// resolve the versions pinned by the lock file rather than the
+		// newest cached versions.
+		for i, d := range deps {
+			if pd, ok := s.lock.Pin(d); ok {
+				deps[i] = pd
+			}
+		}
 		extView, err := s.dm.View(ctx, deps)
 		if err != nil {
 			return err
@@ -260,6 +292,15 @@ func (s *Snapshot) Validator(gvk schema.GroupVersionKind) validator.Validator {
 	return s.validators[gvk]
 }
 
+// Schema returns the OpenAPI schema of the provided GVK within the Snapshot,
+// if its Validator validates against one. Nil otherwise.
+func (s *Snapshot) Schema(gvk schema.GroupVersionKind) *spec.Schema {
+	if sp, ok := s.validators[gvk].(validator.SchemaProvider); ok {
+		return sp.Schema()
+	}
+	return nil
+}
+
 // Package returns the ParsedPackage corresponding to the supplied package name
 // as defined in the crossplane.yaml, if one exists. Nil otherwise.
 func (s *Snapshot) Package(name string) *mxpkg.ParsedPackage {
@@ -276,19 +317,20 @@ func (s *Snapshot) ReParseFile(ctx context.Context, path string) error {
 }
 
 // UpdateContent updates the current in-memory content representation for the
-// provided file uri.
+// provided file uri. Changes without a range replace the whole content.
 func (s *Snapshot) UpdateContent(ctx context.Context, uri span.URI, changes []protocol.TextDocumentContentChangeEvent) error {
 	if len(changes) == 0 {
 		return errors.New(errNoChangesSupplied)
 	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	body, err := s.updateChanges(ctx, uri, changes)
 	if err != nil {
 		return err
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
 	details := s.wsview.FileDetails()[uri]
 	details.Body = body
 
@@ -319,7 +361,8 @@ func (s *Snapshot) updateChanges(_ context.Context, uri span.URI, changes []prot
 		}
 
 		if c.Range == nil {
-			return nil, errors.New(errInvalidRange)
+			content = []byte(c.Text)
+			continue
 		}
 
 		spn, err := m.RangeSpan(*c.Range)
@@ -415,6 +458,34 @@ func (s *Snapshot) ValidateAllFiles(ctx context.Context) (map[span.URI][]protoco
 	return results, nil
 }
 
+// ValidateExamples validates the examples in the workspace against the
+// schemas of the workspace and its dependencies. Diagnostics are returned for
+// every file containing an example.
+func (s *Snapshot) ValidateExamples(ctx context.Context) (map[span.URI][]protocol.Diagnostic, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make(map[span.URI][]protocol.Diagnostic)
+	for gvk, nodes := range s.wsview.Examples() {
+		v, ok := s.validators[gvk]
+		for _, n := range nodes {
+			uri := span.URIFromPath(n.GetFileName())
+			if _, ok := results[uri]; !ok {
+				results[uri] = []protocol.Diagnostic{}
+			}
+
+			res := &validate.Result{
+				Errors: gvkDNEWarning(gvk, "apiVersion"),
+			}
+			if ok {
+				res = v.Validate(ctx, n.GetObject())
+			}
+			results[uri] = append(results[uri], validationDiagnostics(res, n.GetAST(), gvk)...)
+		}
+	}
+	return results, nil
+}
+
 // ValidateMeta performs validations specifically on the meta file. This is
 // specifically helpful when performing background validations and not
 // responding to validation requests synchronously.
@@ -442,6 +513,11 @@ func (s *Snapshot) Validate(ctx context.Context, uri span.URI) ([]protocol.Diagn
 	}
 
 	for id := range details.NodeIDs {
+		// stop early if the diagnostics are no longer wanted, e.g. because
+		// the file changed again.
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		n, ok := s.wsview.Nodes()[id]
 		if !ok {
 			return nil, errors.New(errInvalidNodeID)
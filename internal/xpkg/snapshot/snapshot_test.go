@@ -16,9 +16,12 @@ package snapshot
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
 
+	"github.com/golang/tools/lsp/protocol"
+	"github.com/golang/tools/span"
 	"github.com/google/go-cmp/cmp"
 	"github.com/spf13/afero"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -28,6 +31,7 @@ import (
 
 	"github.com/upbound/up/internal/xpkg/dep/cache"
 	"github.com/upbound/up/internal/xpkg/dep/manager"
+	mxpkg "github.com/upbound/up/internal/xpkg/dep/marshaler/xpkg"
 	"github.com/upbound/up/internal/xpkg/workspace"
 )
 
@@ -112,17 +116,226 @@ func TestWSLoadValidators(t *testing.T) {
 	}
 }
 
-type MockDepManager struct{}
+type MockDepManager struct {
+	versions []string
+	cached   []*mxpkg.ParsedPackage
+}
 
 func NewMockDepManager() *MockDepManager { return &MockDepManager{} }
 
 func (m *MockDepManager) View(context.Context, []v1beta1.Dependency) (*manager.View, error) {
-	return nil, nil
+	return &manager.View{}, nil
 }
 func (m *MockDepManager) Versions(context.Context, v1beta1.Dependency) ([]string, error) {
-	return nil, nil
+	return m.versions, nil
+}
+
+func (m *MockDepManager) ObjectPath(v1beta1.Dependency, string) (string, error) {
+	return "", nil
+}
+
+func (m *MockDepManager) Cached() ([]*mxpkg.ParsedPackage, error) {
+	return m.cached, nil
 }
 
 func (m *MockDepManager) Watch() <-chan cache.Event {
 	return make(<-chan cache.Event)
 }
+
+func (m *MockDepManager) Unwatch(<-chan cache.Event) {}
+
+func TestValidateExamples(t *testing.T) {
+	validExample := []byte(`apiVersion: acm.aws.crossplane.io/v1alpha1
+kind: Certificate
+metadata:
+  name: valid
+spec:
+  forProvider:
+    domainName: example.com
+    region: us-east-1
+    tags:
+    - key: team
+      value: platform
+`)
+	invalidExample := []byte(`apiVersion: acm.aws.crossplane.io/v1alpha1
+kind: Certificate
+metadata:
+  name: invalid
+spec:
+  forProvider:
+    domainName: 5
+    region: us-east-1
+    tags:
+    - key: team
+      value: platform
+`)
+	unknownExample := []byte(`apiVersion: example.org/v1alpha1
+kind: Unknown
+metadata:
+  name: unknown
+`)
+
+	type diag struct {
+		Line     uint32
+		Severity protocol.DiagnosticSeverity
+	}
+
+	cases := map[string]struct {
+		reason string
+		files  map[string][]byte
+		want   map[span.URI][]diag
+	}{
+		"ValidExample": {
+			reason: "Should not return diagnostics for an example that matches its schema.",
+			files: map[string][]byte{
+				"/ws/crd.yaml":            testSingleVersionCRD,
+				"/ws/examples/valid.yaml": validExample,
+			},
+			want: map[span.URI][]diag{
+				span.URIFromPath("/ws/examples/valid.yaml"): {},
+			},
+		},
+		"InvalidExample": {
+			reason: "Should return an error at the location of a field that does not match its schema.",
+			files: map[string][]byte{
+				"/ws/crd.yaml":              testSingleVersionCRD,
+				"/ws/examples/invalid.yaml": invalidExample,
+			},
+			want: map[span.URI][]diag{
+				span.URIFromPath("/ws/examples/invalid.yaml"): {
+					{Line: 6, Severity: protocol.SeverityError},
+				},
+			},
+		},
+		"UnknownSchema": {
+			reason: "Should return a warning for an example without a known schema.",
+			files: map[string][]byte{
+				"/ws/examples/unknown.yaml": unknownExample,
+			},
+			want: map[span.URI][]diag{
+				span.URIFromPath("/ws/examples/unknown.yaml"): {
+					{Line: 0, Severity: protocol.SeverityWarning},
+				},
+			},
+		},
+		"NotAnExample": {
+			reason: "Should not validate objects outside of example directories.",
+			files: map[string][]byte{
+				"/ws/crd.yaml":     testSingleVersionCRD,
+				"/ws/invalid.yaml": invalidExample,
+			},
+			want: map[span.URI][]diag{},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for p, b := range tc.files {
+				_ = afero.WriteFile(fs, p, b, os.ModePerm)
+			}
+			ws, _ := workspace.New("/ws", workspace.WithFS(fs), workspace.WithPermissiveParser())
+
+			factory, _ := NewFactory("/ws",
+				WithDepManager(NewMockDepManager()),
+			)
+			snap, err := factory.New(context.Background(), WithWorkspace(ws))
+			if err != nil {
+				t.Fatalf("\n%s\nNew(...): unexpected error: %s", tc.reason, err)
+			}
+
+			res, err := snap.ValidateExamples(context.Background())
+			if err != nil {
+				t.Fatalf("\n%s\nValidateExamples(...): unexpected error: %s", tc.reason, err)
+			}
+
+			got := make(map[span.URI][]diag, len(res))
+			for uri, ds := range res {
+				got[uri] = make([]diag, 0, len(ds))
+				for _, d := range ds {
+					got[uri] = append(got[uri], diag{Line: d.Range.Start.Line, Severity: d.Severity})
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nValidateExamples(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestUpdateContent(t *testing.T) {
+	doc := []byte("apiVersion: v1\nkind: ConfigMap\n")
+	uri := span.URIFromPath("/ws/cm.yaml")
+
+	change := func(sl, sc, el, ec uint32, text string) protocol.TextDocumentContentChangeEvent {
+		r := rng(sl, sc, el, ec)
+		return protocol.TextDocumentContentChangeEvent{Range: &r, Text: text}
+	}
+
+	type want struct {
+		body string
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason  string
+		changes []protocol.TextDocumentContentChangeEvent
+		want    want
+	}{
+		"Incremental": {
+			reason:  "Should replace the range of an incremental change.",
+			changes: []protocol.TextDocumentContentChangeEvent{change(1, 6, 1, 15, "Secret")},
+			want: want{
+				body: "apiVersion: v1\nkind: Secret\n",
+			},
+		},
+		"InOrder": {
+			reason: "Should apply changes in order, each against the result of the previous one.",
+			changes: []protocol.TextDocumentContentChangeEvent{
+				change(1, 6, 1, 15, "Secret"),
+				change(1, 12, 1, 12, "List"),
+			},
+			want: want{
+				body: "apiVersion: v1\nkind: SecretList\n",
+			},
+		},
+		"Full": {
+			reason: "Should replace the whole content for a change without a range.",
+			changes: []protocol.TextDocumentContentChangeEvent{
+				change(1, 6, 1, 15, "Secret"),
+				{Text: "apiVersion: v2\n"},
+			},
+			want: want{
+				body: "apiVersion: v2\n",
+			},
+		},
+		"ErrNoChanges": {
+			reason: "Should return an error if no changes are supplied.",
+			want: want{
+				body: string(doc),
+				err:  errors.New(errNoChangesSupplied),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			_ = afero.WriteFile(fs, uri.Filename(), doc, os.ModePerm)
+			ws, _ := workspace.New("/ws", workspace.WithFS(fs), workspace.WithPermissiveParser())
+
+			factory, _ := NewFactory("/ws", WithDepManager(NewMockDepManager()))
+			snap, err := factory.New(context.Background(), WithWorkspace(ws))
+			if err != nil {
+				t.Fatalf("\n%s\nNew(...): unexpected error: %s", tc.reason, err)
+			}
+
+			err = snap.UpdateContent(context.Background(), uri, tc.changes)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nUpdateContent(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.body, string(snap.wsview.FileDetails()[uri].Body)); diff != "" {
+				t.Errorf("\n%s\nUpdateContent(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
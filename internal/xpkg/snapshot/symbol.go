@@ -0,0 +1,194 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	xpextv1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	pkgmetav1 "github.com/crossplane/crossplane/apis/pkg/meta/v1"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/golang/tools/lsp/protocol"
+	"github.com/golang/tools/span"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/upbound/up/internal/xpkg/workspace"
+)
+
+const (
+	pathName = "$.metadata.name"
+	pathKind = "$.kind"
+
+	kindCRD = "CustomResourceDefinition"
+)
+
+// DocumentSymbols returns a symbol for every object in the file with the
+// supplied URI. The resources and step inputs embedded in a Composition are
+// returned as children of the Composition's symbol.
+func (s *Snapshot) DocumentSymbols(_ context.Context, uri span.URI) ([]protocol.DocumentSymbol, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	details, ok := s.wsview.FileDetails()[uri]
+	if !ok {
+		return nil, errors.New(errInvalidFileURI)
+	}
+
+	nodes := s.wsview.Nodes()
+	syms := []protocol.DocumentSymbol{}
+	for id := range details.NodeIDs {
+		n, ok := nodes[id]
+		if !ok {
+			continue
+		}
+		sym := documentSymbol(n)
+		for _, did := range n.GetDependants() {
+			if dn, ok := nodes[did]; ok {
+				sym.Children = append(sym.Children, documentSymbol(dn))
+			}
+		}
+		syms = append(syms, sym)
+	}
+	sort.Slice(syms, func(i, j int) bool {
+		return before(syms[i].Range.Start, syms[j].Range.Start)
+	})
+	return syms, nil
+}
+
+// WorkspaceSymbols returns a symbol for every top-level object in the
+// workspace whose name contains the supplied query, ignoring case. All
+// symbols are returned for an empty query.
+func (s *Snapshot) WorkspaceSymbols(_ context.Context, query string) ([]protocol.SymbolInformation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query = strings.ToLower(query)
+	nodes := s.wsview.Nodes()
+	syms := []protocol.SymbolInformation{}
+	for _, details := range s.wsview.FileDetails() {
+		for id := range details.NodeIDs {
+			n, ok := nodes[id]
+			if !ok {
+				continue
+			}
+			name := symbolName(n)
+			if !strings.Contains(strings.ToLower(name), query) {
+				continue
+			}
+			syms = append(syms, protocol.SymbolInformation{
+				Name:     name,
+				Kind:     symbolKind(n.GetGVK()),
+				Location: location(n.GetFileName(), n.GetAST(), pathName),
+			})
+		}
+	}
+	sort.Slice(syms, func(i, j int) bool {
+		if syms[i].Name != syms[j].Name {
+			return syms[i].Name < syms[j].Name
+		}
+		return syms[i].Location.URI < syms[j].Location.URI
+	})
+	return syms, nil
+}
+
+// documentSymbol returns the symbol of the supplied node. Its selection range
+// is the node's name, or its kind for nodes without a name.
+func documentSymbol(n workspace.Node) protocol.DocumentSymbol {
+	rng := nodeRange(n.GetAST())
+	sel, ok := fieldRange(n.GetAST(), pathName)
+	if !ok {
+		sel, _ = fieldRange(n.GetAST(), pathKind)
+	}
+	return protocol.DocumentSymbol{
+		Name:           symbolName(n),
+		Detail:         n.GetGVK().String(),
+		Kind:           symbolKind(n.GetGVK()),
+		Range:          rng,
+		SelectionRange: sel,
+	}
+}
+
+// symbolName returns the name of the supplied node's object, or its kind if
+// the object does not have a name.
+func symbolName(n workspace.Node) string {
+	if u, ok := n.GetObject().(*unstructured.Unstructured); ok && u.GetName() != "" {
+		return u.GetName()
+	}
+	return n.GetGVK().Kind
+}
+
+// symbolKind returns the kind of symbol used for objects of the supplied GVK.
+// Definitions are represented as interfaces and Compositions, which implement
+// them, as classes.
+func symbolKind(gvk schema.GroupVersionKind) protocol.SymbolKind {
+	switch gvk.Kind {
+	case xpextv1.CompositeResourceDefinitionKind, kindCRD:
+		return protocol.Interface
+	case xpextv1.CompositionKind:
+		return protocol.Class
+	case pkgmetav1.ConfigurationKind, pkgmetav1.ProviderKind:
+		return protocol.Package
+	default:
+		return protocol.Object
+	}
+}
+
+// nodeRange returns the range spanning all tokens of the supplied node.
+func nodeRange(n ast.Node) protocol.Range {
+	e := &extent{}
+	ast.Walk(e, n)
+	return e.rng
+}
+
+// extent is an ast.Visitor that accumulates the range of the visited tokens.
+type extent struct {
+	rng  protocol.Range
+	seen bool
+}
+
+func (e *extent) Visit(n ast.Node) ast.Visitor {
+	tok := n.GetToken()
+	if tok == nil || tok.Position == nil {
+		return e
+	}
+	start := protocol.Position{
+		Line:      uint32(tok.Position.Line - 1),
+		Character: uint32(tok.Position.Column - 1),
+	}
+	end := protocol.Position{
+		Line:      start.Line,
+		Character: start.Character + uint32(len(tok.Value)),
+	}
+	if !e.seen || before(start, e.rng.Start) {
+		e.rng.Start = start
+	}
+	if !e.seen || before(e.rng.End, end) {
+		e.rng.End = end
+	}
+	e.seen = true
+	return e
+}
+
+// before returns true if position a is before position b.
+func before(a, b protocol.Position) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Character < b.Character
+}
@@ -0,0 +1,172 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/golang/tools/lsp/protocol"
+	"github.com/golang/tools/span"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/upbound/up/internal/xpkg/workspace"
+)
+
+var (
+	testSymbolComposition = []byte(`apiVersion: apiextensions.crossplane.io/v1
+kind: Composition
+metadata:
+  name: xbuckets.acme.io
+spec:
+  compositeTypeRef:
+    apiVersion: acme.io/v1
+    kind: XBucket
+  resources:
+  - name: bucket
+    base:
+      apiVersion: s3.aws.upbound.io/v1beta1
+      kind: Bucket
+`)
+	testSymbolExample = []byte(`apiVersion: acme.io/v1
+kind: Bucket
+metadata:
+  name: my-bucket
+`)
+)
+
+// symbolSnapshot returns a snapshot of a workspace holding an XRD, a
+// Composition and an example.
+func symbolSnapshot(t *testing.T) *Snapshot {
+	t.Helper()
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "/ws/xrd.yaml", testXRD, os.ModePerm)
+	_ = afero.WriteFile(fs, "/ws/composition.yaml", testSymbolComposition, os.ModePerm)
+	_ = afero.WriteFile(fs, "/ws/examples/bucket.yaml", testSymbolExample, os.ModePerm)
+	ws, _ := workspace.New("/ws", workspace.WithFS(fs), workspace.WithPermissiveParser())
+
+	factory, _ := NewFactory("/ws", WithDepManager(NewMockDepManager()))
+	snap, err := factory.New(context.Background(), WithWorkspace(ws))
+	if err != nil {
+		t.Fatalf("New(...): unexpected error: %s", err)
+	}
+	return snap
+}
+
+func rng(sl, sc, el, ec uint32) protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: sl, Character: sc},
+		End:   protocol.Position{Line: el, Character: ec},
+	}
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		file   string
+		want   []protocol.DocumentSymbol
+	}{
+		"Composition": {
+			reason: "Should return the Composition with its embedded resources as children.",
+			file:   "/ws/composition.yaml",
+			want: []protocol.DocumentSymbol{
+				{
+					Name:           "xbuckets.acme.io",
+					Detail:         "apiextensions.crossplane.io/v1, Kind=Composition",
+					Kind:           protocol.Class,
+					Range:          rng(0, 0, 12, 18),
+					SelectionRange: rng(3, 8, 3, 24),
+					Children: []protocol.DocumentSymbol{
+						{
+							Name:           "Bucket",
+							Detail:         "s3.aws.upbound.io/v1beta1, Kind=Bucket",
+							Kind:           protocol.Object,
+							Range:          rng(11, 6, 12, 18),
+							SelectionRange: rng(12, 12, 12, 18),
+						},
+					},
+				},
+			},
+		},
+		"XRD": {
+			reason: "Should return XRDs as interfaces.",
+			file:   "/ws/xrd.yaml",
+			want: []protocol.DocumentSymbol{
+				{
+					Name:           "xbuckets.acme.io",
+					Detail:         "apiextensions.crossplane.io/v1, Kind=CompositeResourceDefinition",
+					Kind:           protocol.Interface,
+					Range:          rng(0, 0, 29, 33),
+					SelectionRange: rng(3, 8, 3, 24),
+				},
+			},
+		},
+	}
+
+	snap := symbolSnapshot(t)
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := snap.DocumentSymbols(context.Background(), span.URIFromPath(tc.file))
+			if err != nil {
+				t.Fatalf("\n%s\nDocumentSymbols(...): unexpected error: %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nDocumentSymbols(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWorkspaceSymbols(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		query  string
+		want   []string
+	}{
+		"All": {
+			reason: "Should return every top-level object for an empty query.",
+			want:   []string{"my-bucket", "xbuckets.acme.io", "xbuckets.acme.io"},
+		},
+		"Query": {
+			reason: "Should return the objects whose name contains the query, ignoring case.",
+			query:  "MY-",
+			want:   []string{"my-bucket"},
+		},
+		"NoMatch": {
+			reason: "Should not return objects whose name does not contain the query.",
+			query:  "widget",
+			want:   []string{},
+		},
+	}
+
+	snap := symbolSnapshot(t)
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			syms, err := snap.WorkspaceSymbols(context.Background(), tc.query)
+			if err != nil {
+				t.Fatalf("\n%s\nWorkspaceSymbols(...): unexpected error: %s", tc.reason, err)
+			}
+			got := make([]string, len(syms))
+			for i, s := range syms {
+				got[i] = s.Name
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nWorkspaceSymbols(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
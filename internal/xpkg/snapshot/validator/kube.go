@@ -17,6 +17,7 @@ package validator
 import (
 	"context"
 
+	"k8s.io/kube-openapi/pkg/validation/spec"
 	"k8s.io/kube-openapi/pkg/validation/validate"
 )
 
@@ -42,3 +43,12 @@ type UsingContext struct {
 func (uc *UsingContext) Validate(_ context.Context, data any) *validate.Result {
 	return uc.k.Validate(data)
 }
+
+// Schema returns the OpenAPI schema of the underlying kubeValidator, if it
+// validates against one.
+func (uc *UsingContext) Schema() *spec.Schema {
+	if sv, ok := uc.k.(*validate.SchemaValidator); ok {
+		return sv.Schema
+	}
+	return nil
+}
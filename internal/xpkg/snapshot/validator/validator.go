@@ -17,6 +17,7 @@ package validator
 import (
 	"context"
 
+	"k8s.io/kube-openapi/pkg/validation/spec"
 	"k8s.io/kube-openapi/pkg/validation/validate"
 )
 
@@ -93,3 +94,22 @@ func (o *ObjectValidator) Validate(ctx context.Context, data any) *validate.Resu
 func (o *ObjectValidator) AddToChain(validators ...Validator) {
 	o.chain = append(o.chain, validators...)
 }
+
+// SchemaProvider is implemented by validators that validate against an
+// OpenAPI schema.
+type SchemaProvider interface {
+	Schema() *spec.Schema
+}
+
+// Schema returns the OpenAPI schema of the first validator in the chain that
+// validates against one, or nil if there is none.
+func (o *ObjectValidator) Schema() *spec.Schema {
+	for _, v := range o.chain {
+		if sp, ok := v.(SchemaProvider); ok {
+			if s := sp.Schema(); s != nil {
+				return s
+			}
+		}
+	}
+	return nil
+}
@@ -77,6 +77,12 @@ func (m *Meta) Upsert(d v1beta1.Dependency) error {
 	return upsertDeps(d, m.obj)
 }
 
+// DeepCopy returns a copy of the Meta that can be modified without affecting
+// the original.
+func (m *Meta) DeepCopy() *Meta {
+	return New(m.obj.DeepCopyObject())
+}
+
 // Bytes returns the cleaned up byte representation of the meta file obj.
 func (m *Meta) Bytes() ([]byte, error) {
 	data, err := sigsyaml.Marshal(m.obj)
@@ -17,6 +17,7 @@ package workspace
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"io/fs"
 	"os"
@@ -44,6 +45,7 @@ import (
 	pkgmetav1 "github.com/crossplane/crossplane/apis/pkg/meta/v1"
 
 	"github.com/upbound/up/internal/xpkg"
+	"github.com/upbound/up/internal/xpkg/dep/lock"
 	pyaml "github.com/upbound/up/internal/xpkg/parser/yaml"
 	"github.com/upbound/up/internal/xpkg/workspace/meta"
 )
@@ -54,11 +56,17 @@ var (
 	compResources *yaml.Path
 	compBase      *yaml.Path
 	compPipeline  *yaml.Path
+	compInput     *yaml.Path
 )
 
 const (
 	yamlExt = ".yaml"
 
+	// embeddedResourceFmt and embeddedInputFmt are the formats of the names
+	// given to the resources and step inputs embedded in a Composition.
+	embeddedResourceFmt = "%s.spec.resources[%d].base"
+	embeddedInputFmt    = "%s.spec.pipeline[%d].input"
+
 	errCompositionResources = "resources in Composition are malformed"
 	errCompositionPipeline  = "pipeline in Composition is malformed"
 	errCompositionMode      = "unknown Composition mode"
@@ -81,6 +89,10 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+	compInput, err = yaml.PathString("$.input")
+	if err != nil {
+		panic(err)
+	}
 }
 
 // Workspace provides APIs for interacting with the current project workspace.
@@ -177,6 +189,17 @@ func (w *Workspace) Write(m *meta.Meta) error {
 	return afero.WriteFile(w.fs, filepath.Join(w.view.metaLocation, xpkg.MetaFile), b, os.ModePerm)
 }
 
+// Lock reads the lock file that lives next to the meta file. If no lock file
+// exists the returned error satisfies os.IsNotExist.
+func (w *Workspace) Lock() (*lock.Lock, error) {
+	return lock.Read(w.fs, lock.Path(w.view.metaLocation))
+}
+
+// WriteLock writes the supplied lock file next to the meta file.
+func (w *Workspace) WriteLock(l *lock.Lock) error {
+	return l.Write(w.fs, lock.Path(w.view.metaLocation))
+}
+
 // Parse parses the full workspace in order to hydrate the workspace's View.
 func (w *Workspace) Parse(ctx context.Context) error {
 	w.mu.Lock()
@@ -284,6 +307,9 @@ type parseContext struct {
 	path     string
 	doc      int
 	rootNode bool
+	// name is the name of embedded objects, which usually don't have a name
+	// of their own.
+	name string
 }
 
 // parseDoc recursively parses a YAML document into PackageNodes. Embedded nodes
@@ -320,15 +346,18 @@ func (v *View) parseDoc(ctx context.Context, pCtx parseContext) (NodeIdentifier,
 		pCtx.node = doc.Body
 	}
 
+	var dependants []NodeIdentifier
 	switch obj.GetKind() {
 	case xpextv1.CompositeResourceDefinitionKind:
 		if err := v.parseXRD(pCtx); err != nil {
 			return NodeIdentifier{}, err
 		}
 	case xpextv1.CompositionKind:
-		if err := v.parseComposition(ctx, pCtx); err != nil {
+		deps, err := v.parseComposition(ctx, pCtx)
+		if err != nil {
 			return NodeIdentifier{}, err
 		}
+		dependants = deps
 	case pkgmetav1.ConfigurationKind:
 		if err := v.parseMeta(ctx, pCtx); err != nil {
 			return NodeIdentifier{}, err
@@ -340,15 +369,20 @@ func (v *View) parseDoc(ctx context.Context, pCtx parseContext) (NodeIdentifier,
 	default:
 		v.parseExample(pCtx)
 	}
-	// TODO(hasheddan): if this is an embedded resource we don't have a name so
-	// we should form a deterministic name based on its parent Composition.
-	id := nodeID(obj.GetName(), obj.GroupVersionKind())
+	name := obj.GetName()
+	if name == "" {
+		// embedded resources don't have a name, use the deterministic name
+		// based on their parent Composition instead.
+		name = pCtx.name
+	}
+	id := nodeID(name, obj.GroupVersionKind())
 
 	v.nodes[id] = &PackageNode{
-		ast:      pCtx.node,
-		fileName: pCtx.path,
-		gvk:      obj.GroupVersionKind(),
-		obj:      &obj,
+		ast:        pCtx.node,
+		fileName:   pCtx.path,
+		gvk:        obj.GroupVersionKind(),
+		obj:        &obj,
+		dependants: dependants,
 	}
 
 	if pCtx.rootNode {
@@ -358,11 +392,11 @@ func (v *View) parseDoc(ctx context.Context, pCtx parseContext) (NodeIdentifier,
 	return id, nil
 }
 
-func (v *View) parseComposition(ctx context.Context, pCtx parseContext) error {
+func (v *View) parseComposition(ctx context.Context, pCtx parseContext) ([]NodeIdentifier, error) {
 	var cp xpextv1.Composition
 	if err := k8syaml.Unmarshal(pCtx.docBytes, &cp); err != nil {
 		// we have a composition but failed to unmarshal it, skip for now.
-		return nil // nolint:nilerr
+		return nil, nil // nolint:nilerr
 	}
 
 	mode := xpextv1.CompositionModeResources
@@ -373,7 +407,7 @@ func (v *View) parseComposition(ctx context.Context, pCtx parseContext) error {
 	case xpextv1.CompositionModeResources:
 		resNode, err := compResources.FilterNode(pCtx.node)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		pCtx.node = resNode
 		pCtx.rootNode = false
@@ -383,7 +417,7 @@ func (v *View) parseComposition(ctx context.Context, pCtx parseContext) error {
 	case xpextv1.CompositionModePipeline:
 		pipeNode, err := compPipeline.FilterNode(pCtx.node)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		pCtx.node = pipeNode
@@ -392,31 +426,34 @@ func (v *View) parseComposition(ctx context.Context, pCtx parseContext) error {
 		return v.parseCompositionPipeline(ctx, pCtx)
 
 	default:
-		return errors.New(errCompositionMode)
+		return nil, errors.New(errCompositionMode)
 	}
 }
 
-func (v *View) parseCompositionResources(ctx context.Context, pCtx parseContext) error {
+// parseCompositionResources parses the base of every composed resource of a
+// Composition and returns their node identifiers.
+func (v *View) parseCompositionResources(ctx context.Context, pCtx parseContext) ([]NodeIdentifier, error) {
 	seq, ok := pCtx.node.(*ast.SequenceNode)
 	if !ok {
 		// NOTE(hasheddan): if the Composition's resources field is not a
 		// sequence node, we skip parsing embedded resources because the
 		// Composition itself is malformed.
-		return errors.New(errCompositionResources)
+		return nil, errors.New(errCompositionResources)
 	}
 
-	dependants := map[NodeIdentifier]struct{}{}
+	dependants := []NodeIdentifier{}
+	parent := pCtx.obj.GetName()
 
-	for _, s := range seq.Values {
+	for i, s := range seq.Values {
 		// process ComposedTemplate
 		b, err := s.MarshalYAML()
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		var ct xpextv1.ComposedTemplate
 		if err := k8syaml.Unmarshal(b, &ct); err != nil {
-			return err
+			return nil, err
 		}
 
 		// recurse into resource[i].base
@@ -427,27 +464,51 @@ func (v *View) parseCompositionResources(ctx context.Context, pCtx parseContext)
 		}
 		pCtx.node = sNode
 		pCtx.rootNode = false
+		pCtx.name = fmt.Sprintf(embeddedResourceFmt, parent, i)
 
 		id, err := v.parseDoc(ctx, pCtx)
 		if err != nil {
 			// TODO(hasheddan): surface this error as a diagnostic.
 			continue
 		}
-		dependants[id] = struct{}{}
+		dependants = append(dependants, id)
 	}
 
-	return nil
+	return dependants, nil
 }
 
-func (v *View) parseCompositionPipeline(_ context.Context, pCtx parseContext) error {
-	_, ok := pCtx.node.(*ast.SequenceNode)
+// parseCompositionPipeline parses the input of every step of a Composition
+// pipeline and returns their node identifiers.
+func (v *View) parseCompositionPipeline(ctx context.Context, pCtx parseContext) ([]NodeIdentifier, error) {
+	seq, ok := pCtx.node.(*ast.SequenceNode)
 	if !ok {
-		return errors.New(errCompositionPipeline)
+		// the Composition itself is malformed, skip parsing step inputs.
+		return nil, errors.New(errCompositionPipeline)
 	}
 
-	// TODO(adamwg): Parse the pipeline more thoroughly to expose any issues.
+	dependants := []NodeIdentifier{}
+	parent := pCtx.obj.GetName()
 
-	return nil
+	for i, s := range seq.Values {
+		// recurse into pipeline[i].input, steps without an input have
+		// nothing further to parse.
+		iNode, err := compInput.FilterNode(s)
+		if err != nil || iNode == nil {
+			continue
+		}
+		pCtx.node = iNode
+		pCtx.rootNode = false
+		pCtx.name = fmt.Sprintf(embeddedInputFmt, parent, i)
+
+		id, err := v.parseDoc(ctx, pCtx)
+		if err != nil {
+			// an unparseable input is reported when validating the step.
+			continue
+		}
+		dependants = append(dependants, id)
+	}
+
+	return dependants, nil
 }
 
 func (v *View) parseExample(ctx parseContext) {
@@ -613,14 +674,26 @@ type NodeIdentifier struct {
 	gvk  schema.GroupVersionKind
 }
 
+// Name returns the name of the identified node. Nodes embedded in a
+// Composition are named after their location in the Composition.
+func (n NodeIdentifier) Name() string {
+	return n.name
+}
+
+// GVK returns the GroupVersionKind of the identified node.
+func (n NodeIdentifier) GVK() schema.GroupVersionKind {
+	return n.gvk
+}
+
 // A PackageNode represents a concrete node in an xpkg.
 // TODO(hasheddan): PackageNode should be refactored into separate
 // implementations for each node type (e.g. XRD, Composition, CRD, etc.).
 type PackageNode struct {
-	ast      ast.Node
-	fileName string
-	gvk      schema.GroupVersionKind
-	obj      runtime.Object
+	ast        ast.Node
+	fileName   string
+	gvk        schema.GroupVersionKind
+	obj        runtime.Object
+	dependants []NodeIdentifier
 }
 
 // GetAST gets the YAML AST node for this package node.
@@ -633,11 +706,10 @@ func (p *PackageNode) GetFileName() string {
 	return p.fileName
 }
 
-// GetDependants gets the set of nodes dependant on this node.
-// TODO(hasheddan): this method signature may change depending on how we want to
-// construct the node graph for a workspace.
+// GetDependants gets the set of nodes dependant on this node, i.e. the
+// resources and step inputs embedded in a Composition.
 func (p *PackageNode) GetDependants() []NodeIdentifier {
-	return nil
+	return p.dependants
 }
 
 // GetGVK returns the GroupVersionKind of this node.
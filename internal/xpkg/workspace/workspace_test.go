@@ -42,6 +42,7 @@ import (
 
 var (
 	testComposition      []byte
+	testPipeline         []byte
 	testInvalidXRD       []byte
 	testMultipleObject   []byte
 	testMultiVersionCRD  []byte
@@ -50,6 +51,7 @@ var (
 
 func init() {
 	testComposition, _ = afero.ReadFile(afero.NewOsFs(), "testdata/composition.yaml")
+	testPipeline, _ = afero.ReadFile(afero.NewOsFs(), "testdata/pipeline-composition.yaml")
 	testInvalidXRD, _ = afero.ReadFile(afero.NewOsFs(), "testdata/invalid-xrd.yaml")
 	testMultipleObject, _ = afero.ReadFile(afero.NewOsFs(), "testdata/multiple-object.yaml")
 	testMultiVersionCRD, _ = afero.ReadFile(afero.NewOsFs(), "testdata/multiple-version-crd.yaml")
@@ -127,9 +129,21 @@ func TestParse(t *testing.T) {
 				return fs
 			}())},
 			nodes: map[NodeIdentifier]struct{}{
-				nodeID("", schema.FromAPIVersionAndKind("ec2.aws.crossplane.io/v1beta1", "VPC")):               {},
-				nodeID("", schema.FromAPIVersionAndKind("ec2.aws.crossplane.io/v1beta1", "Subnet")):            {},
-				nodeID("vpcpostgresqlinstances.aws.database.example.org", xpextv1.CompositionGroupVersionKind): {},
+				nodeID("vpcpostgresqlinstances.aws.database.example.org.spec.resources[0].base", schema.FromAPIVersionAndKind("ec2.aws.crossplane.io/v1beta1", "VPC")):    {},
+				nodeID("vpcpostgresqlinstances.aws.database.example.org.spec.resources[1].base", schema.FromAPIVersionAndKind("ec2.aws.crossplane.io/v1beta1", "Subnet")): {},
+				nodeID("vpcpostgresqlinstances.aws.database.example.org", xpextv1.CompositionGroupVersionKind):                                                            {},
+			},
+		},
+		"SuccessfulParsePipelineComposition": {
+			reason: "Should add a package node for Composition and the input of every pipeline step.",
+			opts: []Option{WithFS(func() afero.Fs {
+				fs := afero.NewMemMapFs()
+				_ = afero.WriteFile(fs, "/ws/composition.yaml", testPipeline, os.ModePerm)
+				return fs
+			}())},
+			nodes: map[NodeIdentifier]struct{}{
+				nodeID("xbuckets.aws.example.org.spec.pipeline[0].input", schema.FromAPIVersionAndKind("pt.fn.crossplane.io/v1beta1", "Resources")): {},
+				nodeID("xbuckets.aws.example.org", xpextv1.CompositionGroupVersionKind):                                                             {},
 			},
 		},
 		"SuccessfulParseMultipleSameFile": {
@@ -173,6 +187,51 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestGetDependants(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		body   []byte
+		id     NodeIdentifier
+		want   []NodeIdentifier
+	}{
+		"Resources": {
+			reason: "Should return the embedded resources of a Composition in order.",
+			body:   testComposition,
+			id:     nodeID("vpcpostgresqlinstances.aws.database.example.org", xpextv1.CompositionGroupVersionKind),
+			want: []NodeIdentifier{
+				nodeID("vpcpostgresqlinstances.aws.database.example.org.spec.resources[0].base", schema.FromAPIVersionAndKind("ec2.aws.crossplane.io/v1beta1", "VPC")),
+				nodeID("vpcpostgresqlinstances.aws.database.example.org.spec.resources[1].base", schema.FromAPIVersionAndKind("ec2.aws.crossplane.io/v1beta1", "Subnet")),
+			},
+		},
+		"Pipeline": {
+			reason: "Should return the step inputs of a Composition, skipping steps without an input.",
+			body:   testPipeline,
+			id:     nodeID("xbuckets.aws.example.org", xpextv1.CompositionGroupVersionKind),
+			want: []NodeIdentifier{
+				nodeID("xbuckets.aws.example.org.spec.pipeline[0].input", schema.FromAPIVersionAndKind("pt.fn.crossplane.io/v1beta1", "Resources")),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			_ = afero.WriteFile(fs, "/ws/composition.yaml", tc.body, os.ModePerm)
+			ws, _ := New("/ws", WithFS(fs))
+			if err := ws.Parse(context.Background()); err != nil {
+				t.Fatalf("\n%s\nParse(...): unexpected error: %s", tc.reason, err)
+			}
+
+			n, ok := ws.View().Nodes()[tc.id]
+			if !ok {
+				t.Fatalf("\n%s\nNodes(...): missing node:\n%v", tc.reason, tc.id)
+			}
+			if diff := cmp.Diff(tc.want, n.GetDependants(), cmp.AllowUnexported(NodeIdentifier{})); diff != "" {
+				t.Errorf("\n%s\nGetDependants(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
 func TestRWMetaFile(t *testing.T) {
 
 	cfgMetaFile := &metav1.Configuration{
@@ -17,7 +17,10 @@ package dispatcher
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"sync"
 
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	"github.com/golang/tools/lsp/protocol"
 	"github.com/sourcegraph/jsonrpc2"
@@ -26,6 +29,27 @@ import (
 const (
 	errParseSaveParameters   = "failed to parse document save parameters"
 	errParseChangeParameters = "failed to parse document change parameters"
+	errParseParameters       = "failed to parse request parameters"
+	errReply                 = "failed to reply to request"
+	errClose                 = "failed to close connection"
+	errMissingParameters     = "request parameters are missing"
+	errNotInitialized        = "server is not initialized"
+	errAlreadyInitialized    = "server is already initialized"
+	errShuttingDown          = "server is shutting down"
+	errMethodNotFoundFmt     = "method %s is not supported"
+)
+
+// codeServerNotInitialized is the LSP error code returned for requests that
+// are received before the initialize request.
+const codeServerNotInitialized int64 = -32002
+
+// state is the lifecycle state of a connection.
+type state int
+
+const (
+	stateNew state = iota
+	stateInitialized
+	stateShutdown
 )
 
 // Server defines the set of LSP methods we currently support.
@@ -34,13 +58,26 @@ type Server interface {
 	DidOpen(context.Context, *protocol.DidOpenTextDocumentParams)
 	DidSave(context.Context, *protocol.DidSaveTextDocumentParams)
 	DidChangeWatchedFiles(context.Context, *protocol.DidChangeWatchedFilesParams)
-	Initialize(context.Context, *jsonrpc2.Conn, jsonrpc2.ID, *protocol.InitializeParams)
+	Initialize(context.Context, *jsonrpc2.Conn, jsonrpc2.ID, *protocol.InitializeParams) error
+	Shutdown(context.Context, jsonrpc2.ID)
+	Completion(context.Context, jsonrpc2.ID, *protocol.CompletionParams)
+	Hover(context.Context, jsonrpc2.ID, *protocol.HoverParams)
+	Definition(context.Context, jsonrpc2.ID, *protocol.DefinitionParams)
+	CodeAction(context.Context, jsonrpc2.ID, *protocol.CodeActionParams)
+	DocumentSymbol(context.Context, jsonrpc2.ID, *protocol.DocumentSymbolParams)
+	WorkspaceSymbol(context.Context, jsonrpc2.ID, *protocol.WorkspaceSymbolParams)
+	References(context.Context, jsonrpc2.ID, *protocol.ReferenceParams)
+	ExecuteCommand(context.Context, jsonrpc2.ID, *protocol.ExecuteCommandParams)
 }
 
 // Dispatcher is responsible for routing JSONPPC request events to the
-// appropriate place.
+// appropriate place. A Dispatcher tracks the lifecycle of a single connection
+// and must not be shared between connections.
 type Dispatcher struct {
 	log logging.Logger
+
+	mu    sync.Mutex
+	state state
 }
 
 // New returns a new Dispatcher.
@@ -67,24 +104,48 @@ func WithLogger(l logging.Logger) Option {
 	}
 }
 
+// ShutdownRequested returns true if the client requested a shutdown before
+// the connection was closed.
+func (d *Dispatcher) ShutdownRequested() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.state == stateShutdown
+}
+
 // Dispatch dispatches the given JSONRPC request to the appropriate server function.
 func (d *Dispatcher) Dispatch(ctx context.Context, server Server, conn *jsonrpc2.Conn, r *jsonrpc2.Request) { // nolint:gocyclo
+	if !d.admit(ctx, conn, r) {
+		return
+	}
+
 	switch r.Method {
 	case "initialize":
 		var params protocol.InitializeParams
-		if err := json.Unmarshal(*r.Params, &params); err != nil {
-			// If we can't understand the initialization parmaters panic because
-			// future operations will not work.
-			panic(err)
+		if err := unmarshal(r, &params); err != nil {
+			d.invalidParams(ctx, conn, r, err)
+			return
+		}
+		if err := server.Initialize(ctx, conn, r.ID, &params); err != nil {
+			d.log.Debug(errNotInitialized, "error", err)
+			return
+		}
+		d.setState(stateInitialized)
+		return
+	case "shutdown":
+		d.setState(stateShutdown)
+		server.Shutdown(ctx, r.ID)
+		return
+	case "exit":
+		if err := conn.Close(); err != nil {
+			d.log.Debug(errClose, "error", err)
 		}
-		server.Initialize(ctx, conn, r.ID, &params)
 		return
 	case "initialized":
 		// NOTE(hasheddan): no need to respond when the client reports initialized.
 		return
 	case "textDocument/didChange":
 		var params protocol.DidChangeTextDocumentParams
-		if err := json.Unmarshal(*r.Params, &params); err != nil {
+		if err := unmarshal(r, &params); err != nil {
 			d.log.Debug(errParseChangeParameters)
 			break
 		}
@@ -93,7 +154,7 @@ func (d *Dispatcher) Dispatch(ctx context.Context, server Server, conn *jsonrpc2
 		return
 	case "textDocument/didOpen":
 		var params protocol.DidOpenTextDocumentParams
-		if err := json.Unmarshal(*r.Params, &params); err != nil {
+		if err := unmarshal(r, &params); err != nil {
 			d.log.Debug(errParseSaveParameters)
 			break
 		}
@@ -101,7 +162,7 @@ func (d *Dispatcher) Dispatch(ctx context.Context, server Server, conn *jsonrpc2
 		return
 	case "textDocument/didSave":
 		var params protocol.DidSaveTextDocumentParams
-		if err := json.Unmarshal(*r.Params, &params); err != nil {
+		if err := unmarshal(r, &params); err != nil {
 			// If we can't parse the save parameters, log the error and skip
 			// parsing.
 			// TODO(hasheddan): surface this in diagnostics.
@@ -112,12 +173,145 @@ func (d *Dispatcher) Dispatch(ctx context.Context, server Server, conn *jsonrpc2
 		return
 	case "workspace/didChangeWatchedFiles":
 		var params protocol.DidChangeWatchedFilesParams
-		if err := json.Unmarshal(*r.Params, &params); err != nil {
+		if err := unmarshal(r, &params); err != nil {
 			d.log.Debug(errParseChangeParameters)
 			break
 		}
 
 		server.DidChangeWatchedFiles(ctx, &params)
 		return
+	case "textDocument/completion":
+		var params protocol.CompletionParams
+		if err := unmarshal(r, &params); err != nil {
+			d.invalidParams(ctx, conn, r, err)
+			return
+		}
+		server.Completion(ctx, r.ID, &params)
+		return
+	case "textDocument/hover":
+		var params protocol.HoverParams
+		if err := unmarshal(r, &params); err != nil {
+			d.invalidParams(ctx, conn, r, err)
+			return
+		}
+		server.Hover(ctx, r.ID, &params)
+		return
+	case "textDocument/definition":
+		var params protocol.DefinitionParams
+		if err := unmarshal(r, &params); err != nil {
+			d.invalidParams(ctx, conn, r, err)
+			return
+		}
+		server.Definition(ctx, r.ID, &params)
+		return
+	case "textDocument/codeAction":
+		var params protocol.CodeActionParams
+		if err := unmarshal(r, &params); err != nil {
+			d.invalidParams(ctx, conn, r, err)
+			return
+		}
+		server.CodeAction(ctx, r.ID, &params)
+		return
+	case "textDocument/documentSymbol":
+		var params protocol.DocumentSymbolParams
+		if err := unmarshal(r, &params); err != nil {
+			d.invalidParams(ctx, conn, r, err)
+			return
+		}
+		server.DocumentSymbol(ctx, r.ID, &params)
+		return
+	case "workspace/symbol":
+		var params protocol.WorkspaceSymbolParams
+		if err := unmarshal(r, &params); err != nil {
+			d.invalidParams(ctx, conn, r, err)
+			return
+		}
+		server.WorkspaceSymbol(ctx, r.ID, &params)
+		return
+	case "textDocument/references":
+		var params protocol.ReferenceParams
+		if err := unmarshal(r, &params); err != nil {
+			d.invalidParams(ctx, conn, r, err)
+			return
+		}
+		server.References(ctx, r.ID, &params)
+		return
+	case "workspace/executeCommand":
+		var params protocol.ExecuteCommandParams
+		if err := unmarshal(r, &params); err != nil {
+			d.invalidParams(ctx, conn, r, err)
+			return
+		}
+		server.ExecuteCommand(ctx, r.ID, &params)
+		return
+	}
+
+	// NOTE: notifications we don't support, such as $/cancelRequest, are
+	// ignored.
+	if !r.Notif {
+		d.replyWithError(ctx, conn, r, jsonrpc2.CodeMethodNotFound, fmt.Sprintf(errMethodNotFoundFmt, r.Method))
+	}
+}
+
+// admit returns true if the supplied request may be dispatched in the current
+// lifecycle state of the connection. Requests that are not admitted are
+// replied to with an error, notifications are dropped.
+func (d *Dispatcher) admit(ctx context.Context, conn *jsonrpc2.Conn, r *jsonrpc2.Request) bool {
+	if r.Method == "exit" {
+		return true
+	}
+
+	d.mu.Lock()
+	s := d.state
+	d.mu.Unlock()
+
+	var (
+		code int64
+		msg  string
+	)
+	switch {
+	case s == stateShutdown:
+		code, msg = jsonrpc2.CodeInvalidRequest, errShuttingDown
+	case s == stateInitialized && r.Method == "initialize":
+		code, msg = jsonrpc2.CodeInvalidRequest, errAlreadyInitialized
+	case s == stateNew && r.Method != "initialize":
+		code, msg = codeServerNotInitialized, errNotInitialized
+	default:
+		return true
 	}
+
+	if !r.Notif {
+		d.replyWithError(ctx, conn, r, code, msg)
+	}
+	return false
+}
+
+// unmarshal unmarshals the parameters of the supplied request.
+func unmarshal(r *jsonrpc2.Request, v any) error {
+	if r.Params == nil {
+		return errors.New(errMissingParameters)
+	}
+	return json.Unmarshal(*r.Params, v)
+}
+
+func (d *Dispatcher) setState(s state) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.state = s
+}
+
+func (d *Dispatcher) replyWithError(ctx context.Context, conn *jsonrpc2.Conn, r *jsonrpc2.Request, code int64, msg string) {
+	if err := conn.ReplyWithError(ctx, r.ID, &jsonrpc2.Error{
+		Code:    code,
+		Message: msg,
+	}); err != nil {
+		d.log.Debug(errReply, "error", err)
+	}
+}
+
+// invalidParams replies to the supplied request with an error stating that
+// its parameters could not be parsed.
+func (d *Dispatcher) invalidParams(ctx context.Context, conn *jsonrpc2.Conn, r *jsonrpc2.Request, err error) {
+	d.log.Debug(errParseParameters, "method", r.Method, "error", err)
+	d.replyWithError(ctx, conn, r, jsonrpc2.CodeInvalidParams, err.Error())
 }
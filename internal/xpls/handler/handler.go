@@ -30,6 +30,7 @@ type Handler struct {
 	log        logging.Logger
 	dispatcher *dispatcher.Dispatcher
 	server     *server.Server
+	serverOpts []server.Option
 }
 
 // New constructs a new LSP handler,
@@ -38,7 +39,11 @@ func New(opts ...Option) (*Handler, error) {
 		log: logging.NewNopLogger(),
 	}
 
-	server, err := server.New(server.WithLogger(h.log))
+	for _, o := range opts {
+		o(h)
+	}
+
+	server, err := server.New(append([]server.Option{server.WithLogger(h.log)}, h.serverOpts...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -47,10 +52,6 @@ func New(opts ...Option) (*Handler, error) {
 
 	h.dispatcher = dispatcher.New(dispatcher.WithLogger(h.log))
 
-	for _, o := range opts {
-		o(h)
-	}
-
 	return h, nil
 }
 
@@ -64,7 +65,35 @@ func WithLogger(l logging.Logger) Option {
 	}
 }
 
-// Handle handles LSP requests. It panics if we cannot initialize the workspace.
+// WithOffline configures the server to resolve dependencies against the cache
+// only and to not check for updates.
+func WithOffline() Option {
+	return func(h *Handler) {
+		h.serverOpts = append(h.serverOpts, server.WithOffline())
+	}
+}
+
+// WithShared configures the server to use the supplied state shared with the
+// handlers of other connections.
+func WithShared(sh *server.Shared) Option {
+	return func(h *Handler) {
+		h.serverOpts = append(h.serverOpts, server.WithShared(sh))
+	}
+}
+
+// Handle handles LSP requests.
 func (h *Handler) Handle(ctx context.Context, conn *jsonrpc2.Conn, r *jsonrpc2.Request) { // nolint:gocyclo
 	h.dispatcher.Dispatch(ctx, h.server, conn, r)
 }
+
+// Close cancels all background work of the server. It must be called once
+// the connection is closed.
+func (h *Handler) Close() {
+	h.server.Close()
+}
+
+// ShutdownRequested returns true if the client requested a shutdown before
+// exiting.
+func (h *Handler) ShutdownRequested() bool {
+	return h.dispatcher.ShutdownRequested()
+}
@@ -0,0 +1,350 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/tools/lsp/protocol"
+	"github.com/golang/tools/span"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+const (
+	testMeta = `apiVersion: meta.pkg.crossplane.io/v1
+kind: Configuration
+metadata:
+  name: getting-started
+`
+	testXRD = `apiVersion: apiextensions.crossplane.io/v1
+kind: CompositeResourceDefinition
+metadata:
+  name: xbars.acme.io
+spec:
+  group: acme.io
+  names:
+    kind: XBar
+    plural: xbars
+  versions:
+  - name: v1
+    served: true
+    referenceable: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              size:
+                type: integer
+`
+	testExample = `apiVersion: acme.io/v1
+kind: XBar
+metadata:
+  name: example
+spec:
+  size: 1
+`
+
+	timeout = 10 * time.Second
+)
+
+// client is a language client connected to a Handler through an in-memory
+// pipe.
+type client struct {
+	conn  *jsonrpc2.Conn
+	srv   *jsonrpc2.Conn
+	h     *Handler
+	root  string
+	diags chan *protocol.PublishDiagnosticsParams
+}
+
+func newClient(t *testing.T) *client {
+	t.Helper()
+
+	// the dependency cache lives in the home directory.
+	t.Setenv("HOME", t.TempDir())
+
+	root := t.TempDir()
+	for name, body := range map[string]string{
+		"crossplane.yaml":    testMeta,
+		"xbar.yaml":          testXRD,
+		"examples/xbar.yaml": testExample,
+	} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h, err := New(WithOffline())
+	if err != nil {
+		t.Fatalf("New(...): unexpected error: %s", err)
+	}
+
+	c := &client{
+		h:     h,
+		root:  root,
+		diags: make(chan *protocol.PublishDiagnosticsParams, 16),
+	}
+
+	ctx := context.Background()
+	sp, cp := net.Pipe()
+	c.srv = jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(sp, jsonrpc2.VSCodeObjectCodec{}), h)
+	// the pipe is unbuffered, so the client must keep reading while it
+	// replies to requests from the server.
+	c.conn = jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(cp, jsonrpc2.VSCodeObjectCodec{}), jsonrpc2.AsyncHandler(jsonrpc2.HandlerWithError(c.handle)))
+	t.Cleanup(func() {
+		// closing the client lets the server observe the disconnect rather
+		// than closing it while it may still be reading responses.
+		_ = c.conn.Close()
+		<-c.srv.DisconnectNotify()
+	})
+	return c
+}
+
+// handle handles requests sent by the server to the client.
+func (c *client) handle(_ context.Context, _ *jsonrpc2.Conn, r *jsonrpc2.Request) (any, error) {
+	if r.Method == "textDocument/publishDiagnostics" {
+		p := &protocol.PublishDiagnosticsParams{}
+		if err := json.Unmarshal(*r.Params, p); err != nil {
+			return nil, err
+		}
+		c.diags <- p
+	}
+	return nil, nil
+}
+
+func (c *client) uri(name string) protocol.DocumentURI {
+	return protocol.URIFromSpanURI(span.URIFromPath(filepath.Join(c.root, name)))
+}
+
+// call calls the supplied method and returns the code of the error returned
+// by the server, or 0 if the call succeeded.
+func (c *client) call(t *testing.T, method string, params any) int64 {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if params == nil {
+		params = struct{}{}
+	}
+	err := c.conn.Call(ctx, method, params, nil)
+	if err == nil {
+		return 0
+	}
+	var rerr *jsonrpc2.Error
+	if !errors.As(err, &rerr) {
+		t.Fatalf("Call(%s): unexpected error: %s", method, err)
+	}
+	return rerr.Code
+}
+
+func (c *client) notify(t *testing.T, method string, params any) {
+	t.Helper()
+	if err := c.conn.Notify(context.Background(), method, params); err != nil {
+		t.Fatalf("Notify(%s): unexpected error: %s", method, err)
+	}
+}
+
+func (c *client) initialize(t *testing.T) int64 {
+	t.Helper()
+	return c.call(t, "initialize", &protocol.InitializeParams{
+		RootURI: protocol.URIFromSpanURI(span.URIFromPath(c.root)),
+	})
+}
+
+// waitDiagnostics waits until the supplied number of diagnostics is published
+// for the supplied URI.
+func (c *client) waitDiagnostics(t *testing.T, uri protocol.DocumentURI, n int) {
+	t.Helper()
+	deadline := time.After(timeout)
+	for {
+		select {
+		case p := <-c.diags:
+			if p.URI == uri && len(p.Diagnostics) == n {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("timed out waiting for %d diagnostics of %s", n, uri)
+		}
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	type call struct {
+		method string
+		params any
+	}
+	hover := call{
+		method: "textDocument/hover",
+		params: &protocol.HoverParams{},
+	}
+
+	cases := map[string]struct {
+		reason string
+		calls  []call
+		want   []int64
+	}{
+		"NotInitialized": {
+			reason: "Should reply with an error to requests received before initialize.",
+			calls:  []call{hover},
+			want:   []int64{-32002},
+		},
+		"InvalidInitializeParams": {
+			reason: "Should reply with an error rather than panic if the initialize parameters are invalid.",
+			calls: []call{
+				{method: "initialize", params: "root"},
+				hover,
+			},
+			want: []int64{jsonrpc2.CodeInvalidParams, -32002},
+		},
+		"Initialized": {
+			reason: "Should serve requests once initialized.",
+			calls: []call{
+				{method: "initialize", params: &protocol.InitializeParams{}},
+				hover,
+			},
+			want: []int64{0, 0},
+		},
+		"AlreadyInitialized": {
+			reason: "Should reply with an error to a second initialize request.",
+			calls: []call{
+				{method: "initialize", params: &protocol.InitializeParams{}},
+				{method: "initialize", params: &protocol.InitializeParams{}},
+			},
+			want: []int64{0, jsonrpc2.CodeInvalidRequest},
+		},
+		"MethodNotFound": {
+			reason: "Should reply with an error to requests for unsupported methods.",
+			calls: []call{
+				{method: "initialize", params: &protocol.InitializeParams{}},
+				{method: "textDocument/rename"},
+			},
+			want: []int64{0, jsonrpc2.CodeMethodNotFound},
+		},
+		"ShutDown": {
+			reason: "Should reply with an error to requests received after shutdown.",
+			calls: []call{
+				{method: "initialize", params: &protocol.InitializeParams{}},
+				{method: "shutdown"},
+				hover,
+			},
+			want: []int64{0, 0, jsonrpc2.CodeInvalidRequest},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := newClient(t)
+
+			got := make([]int64, len(tc.calls))
+			for i, cl := range tc.calls {
+				if p, ok := cl.params.(*protocol.InitializeParams); ok {
+					p.RootURI = protocol.URIFromSpanURI(span.URIFromPath(c.root))
+				}
+				got[i] = c.call(t, cl.method, cl.params)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nCall(...): -want codes, +got codes:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestExit(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		shutdown bool
+	}{
+		"AfterShutdown": {
+			reason:   "Should close the connection on exit and report the requested shutdown.",
+			shutdown: true,
+		},
+		"WithoutShutdown": {
+			reason: "Should close the connection on exit and report that no shutdown was requested.",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := newClient(t)
+			if code := c.initialize(t); code != 0 {
+				t.Fatalf("\n%s\ninitialize: unexpected error code %d", tc.reason, code)
+			}
+			if tc.shutdown {
+				if code := c.call(t, "shutdown", nil); code != 0 {
+					t.Fatalf("\n%s\nshutdown: unexpected error code %d", tc.reason, code)
+				}
+			}
+			c.notify(t, "exit", nil)
+
+			select {
+			case <-c.srv.DisconnectNotify():
+			case <-time.After(timeout):
+				t.Fatalf("\n%s\nexit: connection was not closed", tc.reason)
+			}
+			if diff := cmp.Diff(tc.shutdown, c.h.ShutdownRequested()); diff != "" {
+				t.Errorf("\n%s\nShutdownRequested(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDidChange(t *testing.T) {
+	c := newClient(t)
+	if code := c.initialize(t); code != 0 {
+		t.Fatalf("initialize: unexpected error code %d", code)
+	}
+	c.notify(t, "initialized", &protocol.InitializedParams{})
+
+	uri := c.uri("examples/xbar.yaml")
+	c.notify(t, "textDocument/didOpen", &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri, Version: 1, Text: testExample},
+	})
+	c.waitDiagnostics(t, uri, 0)
+
+	// replace the size with a string, which the XRD doesn't allow.
+	c.notify(t, "textDocument/didChange", &protocol.DidChangeTextDocumentParams{
+		TextDocument: protocol.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri},
+			Version:                2,
+		},
+		ContentChanges: []protocol.TextDocumentContentChangeEvent{
+			{
+				Range: &protocol.Range{
+					Start: protocol.Position{Line: 5, Character: 8},
+					End:   protocol.Position{Line: 5, Character: 9},
+				},
+				Text: "big",
+			},
+		},
+	})
+	c.waitDiagnostics(t, uri, 1)
+}
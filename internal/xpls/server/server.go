@@ -16,6 +16,7 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"sync"
@@ -26,16 +27,26 @@ import (
 	"github.com/sourcegraph/go-lsp"
 	"github.com/sourcegraph/jsonrpc2"
 
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane/apis/pkg/v1beta1"
 
 	"github.com/upbound/up/internal/version"
+	"github.com/upbound/up/internal/xpkg/dep/cache"
 	"github.com/upbound/up/internal/xpkg/dep/manager"
+	mxpkg "github.com/upbound/up/internal/xpkg/dep/marshaler/xpkg"
 	"github.com/upbound/up/internal/xpkg/snapshot"
 )
 
 var (
-	// kind describes how text synchronization works.
-	kind = lsp.TDSKIncremental
+	// textSync describes how text synchronization works. Changes are sent
+	// incrementally and saves are only used as a signal to re-read the
+	// workspace.
+	textSync = &lsp.TextDocumentSyncOptions{
+		OpenClose: true,
+		Change:    lsp.TDSKIncremental,
+		Save:      &lsp.SaveOptions{},
+	}
 )
 
 const (
@@ -49,18 +60,119 @@ const (
 	errPublishDiagnostics = "failed to publish diagnostics"
 	errRegisteringWatches = "failed to register workspace watchers"
 	errValidateMeta       = "failed to validate crossplane.yaml file in workspace"
+	errValidateExamples   = "failed to validate examples in workspace"
 	errShowMessage        = "failed to show message"
 	errValidateNodes      = "failed to validate nodes in workspace"
+	errCompletion         = "failed to compute completions"
+	errHover              = "failed to compute hover"
+	errDefinition         = "failed to find definition"
+	errCodeActions        = "failed to compute code actions"
+	errSymbols            = "failed to compute symbols"
+	errReferences         = "failed to find references"
+	errUnknownCommandFmt  = "unknown command %s"
+	errCommandArguments   = "invalid command arguments"
+	errFetchOffline       = "cannot fetch dependencies while offline"
+	errFetchDependencyFmt = "failed to fetch %s: %s"
+	errReply              = "failed to reply to request"
+	errInitialize         = "failed to initialize workspace"
+	errUpdateContent      = "failed to update document content"
+)
+
+var (
+	// completionTriggers are the characters that trigger completion in
+	// addition to identifier characters.
+	completionTriggers = []string{".", " "}
 )
 
+// Shared is the state that is shared by the Servers of all connections to a
+// single language server process, so that editors connecting later benefit
+// from dependencies that are already cached. Only the cache is shared: a
+// manager.Manager accumulates the dependencies of a single workspace, so
+// every Server has its own.
+type Shared struct {
+	c        *cache.Local
+	log      logging.Logger
+	offline  bool
+	interval time.Duration
+}
+
+// NewShared returns the Shared state for Servers using the supplied logger.
+// If offline is true dependencies are resolved against the cache only.
+func NewShared(log logging.Logger, offline bool) (*Shared, error) {
+	interval, err := time.ParseDuration(defaultWatchInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	// TODO(@tnthornton) supply cache root from Config here.
+	c, err := manager.NewLocalCache(
+		cache.WithLogger(log),
+		cache.WithWatchInterval(&interval),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Shared{c: c, log: log, offline: offline, interval: interval}, nil
+}
+
+// newManager returns a manager.Manager backed by the shared cache.
+func (sh *Shared) newManager() (*manager.Manager, error) {
+	mopts := []manager.Option{
+		manager.WithLogger(sh.log),
+		manager.WithWatchInterval(&sh.interval),
+		manager.WithCache(sh.c),
+	}
+	if sh.offline {
+		mopts = append(mopts, manager.WithOffline())
+	}
+	return manager.New(mopts...)
+}
+
+// depManager serializes access to the manager.Manager of a Server, which is
+// used by snapshots and by dependency fetches at the same time but is not
+// safe for concurrent use.
+type depManager struct {
+	mu sync.Mutex
+	*manager.Manager
+}
+
+// View calls View of the underlying manager.Manager.
+func (d *depManager) View(ctx context.Context, deps []v1beta1.Dependency) (*manager.View, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.Manager.View(ctx, deps)
+}
+
+// AddAll calls AddAll of the underlying manager.Manager.
+func (d *depManager) AddAll(ctx context.Context, dep v1beta1.Dependency) (v1beta1.Dependency, []*mxpkg.ParsedPackage, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.Manager.AddAll(ctx, dep)
+}
+
 // Server services incoming LSP requests.
 type Server struct {
 	conn *jsonrpc2.Conn
 
-	i   *version.Informer
-	log logging.Logger
-	m   *manager.Manager
-	mu  sync.RWMutex
+	i      *version.Informer
+	log    logging.Logger
+	shared *Shared
+	dm     *depManager
+	mu     sync.RWMutex
+
+	// ctx is cancelled when the client requests a shutdown or disconnects.
+	// It bounds the lifetime of all work started in the background.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// validating holds the cancel functions of in-flight validations keyed
+	// by the URI being validated.
+	vmu        sync.Mutex
+	validating map[span.URI]context.CancelFunc
+
+	// offline disables all registry access.
+	offline bool
 
 	root span.URI
 
@@ -71,24 +183,27 @@ type Server struct {
 // New returns a new Server.
 func New(opts ...Option) (*Server, error) {
 	s := &Server{
-		log: logging.NewNopLogger(),
+		log:        logging.NewNopLogger(),
+		validating: make(map[span.URI]context.CancelFunc),
 	}
 
-	interval, err := time.ParseDuration(defaultWatchInterval)
-	if err != nil {
-		return nil, err
+	for _, o := range opts {
+		o(s)
 	}
 
-	// TODO(@tnthornton) supply cache root from Config here.
-	m, err := manager.New(
-		manager.WithLogger(s.log),
-		manager.WithWatchInterval(&interval),
-	)
+	if s.shared == nil {
+		sh, err := NewShared(s.log, s.offline)
+		if err != nil {
+			return nil, err
+		}
+		s.shared = sh
+	}
+
+	m, err := s.shared.newManager()
 	if err != nil {
 		return nil, err
 	}
-
-	s.m = m
+	s.dm = &depManager{Manager: m}
 
 	s.i = version.NewInformer(version.WithLogger(s.log))
 
@@ -106,8 +221,26 @@ func WithLogger(l logging.Logger) Option {
 	}
 }
 
-// Initialize handles calls to Initialize.
-func (s *Server) Initialize(ctx context.Context, conn *jsonrpc2.Conn, id jsonrpc2.ID, params *protocol.InitializeParams) {
+// WithOffline configures the Server to resolve dependencies against the cache
+// only and to not check for updates.
+func WithOffline() Option {
+	return func(s *Server) {
+		s.offline = true
+	}
+}
+
+// WithShared configures the Server to use the supplied Shared state rather
+// than creating its own.
+func WithShared(sh *Shared) Option {
+	return func(s *Server) {
+		s.shared = sh
+	}
+}
+
+// Initialize handles calls to Initialize. An error is returned if the
+// workspace could not be initialized, in which case the error has already
+// been replied to the client.
+func (s *Server) Initialize(ctx context.Context, conn *jsonrpc2.Conn, id jsonrpc2.ID, params *protocol.InitializeParams) error {
 
 	// TODO(@tnthornton) this is the only place that the passed in conn is used.
 	// Given that the conn is the same at the time it is established, we should
@@ -120,86 +253,173 @@ func (s *Server) Initialize(ctx context.Context, conn *jsonrpc2.Conn, id jsonrpc
 	factory, err := snapshot.NewFactory(
 		s.root.Filename(),
 		snapshot.WithLogger(s.log),
-		snapshot.WithDepManager(s.m),
+		snapshot.WithDepManager(s.dm),
 	)
 	if err != nil {
-		panic(err)
+		s.replyWithError(ctx, id, jsonrpc2.CodeInternalError, errors.Wrap(err, errInitialize).Error())
+		return err
 	}
 
 	s.snapFactory = factory
 
 	snap, err := s.snapFactory.New(ctx)
 	if err != nil {
-		panic(err)
+		s.replyWithError(ctx, id, jsonrpc2.CodeInternalError, errors.Wrap(err, errInitialize).Error())
+		return err
 	}
 
 	s.snap = snap
 
-	s.watchSnapshot(context.Background()) //nolint:contextcheck  // TODO(epk) thread through top level context
+	// NOTE: background work must outlive the initialize request, so it is
+	// bound to the connection rather than to the request.
+	s.vmu.Lock()
+	s.ctx, s.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	s.vmu.Unlock()
+
+	s.watchSnapshot(s.ctx)
 
 	// TODO (@tnthornton) move to using protocol.InitializeResult
 	reply := &lsp.InitializeResult{
 		Capabilities: lsp.ServerCapabilities{
 			TextDocumentSync: &lsp.TextDocumentSyncOptionsOrKind{
-				Kind: &kind,
+				Options: textSync,
+			},
+			CompletionProvider: &lsp.CompletionOptions{
+				TriggerCharacters: completionTriggers,
+			},
+			HoverProvider:           true,
+			DefinitionProvider:      true,
+			CodeActionProvider:      true,
+			DocumentSymbolProvider:  true,
+			WorkspaceSymbolProvider: true,
+			ReferencesProvider:      true,
+			ExecuteCommandProvider: &lsp.ExecuteCommandOptions{
+				Commands: []string{snapshot.FetchDependencyCommand},
 			},
 		},
 	}
 
 	if err := s.conn.Reply(ctx, id, reply); err != nil {
-		// If we fail to initialize the workspace we won't receive future
-		// messages so we panic and try again on restart.
-		panic(err)
+		s.cancel()
+		return err
 	}
 
-	s.registerWatchFilesCapability(context.Background()) //nolint:contextcheck // TODO(epk) thread through top level context
-	s.checkMetaFile(context.Background())                //nolint:contextcheck // TODO(epk) thread through top level context
-	s.checkForUpdates(context.Background())              //nolint:contextcheck // TODO(epk) thread through top level context
+	s.registerWatchFilesCapability(s.ctx)
+	s.checkMetaFile(s.ctx)
+	s.checkExamples(s.ctx)
+	s.checkForUpdates(s.ctx)
+	return nil
+}
+
+// Shutdown handles calls to Shutdown. All background work, including
+// in-flight validations, is cancelled.
+func (s *Server) Shutdown(ctx context.Context, id jsonrpc2.ID) {
+	s.Close()
+	s.reply(ctx, id, nil)
 }
 
-// DidChange handles calls to DidChange.
+// Close cancels all background work, including in-flight validations. It is
+// called when the client disconnects, whether or not it requested a
+// shutdown.
+func (s *Server) Close() {
+	s.vmu.Lock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	for uri, cancel := range s.validating {
+		cancel()
+		delete(s.validating, uri)
+	}
+	s.vmu.Unlock()
+}
+
+// DidChange handles calls to DidChange. Validation of the previous version
+// of the document is cancelled if it is still in-flight.
 func (s *Server) DidChange(ctx context.Context, params *protocol.DidChangeTextDocumentParams) {
-	uri := params.TextDocument.URI.SpanURI()
-	filename := uri.Filename()
+	s.update(ctx, params.TextDocument.URI.SpanURI(), params.ContentChanges)
+}
+
+// DidOpen handles calls to DidOpen. The content of the opened document takes
+// precedence over the content on disk.
+func (s *Server) DidOpen(ctx context.Context, params *protocol.DidOpenTextDocumentParams) {
+	s.update(ctx, params.TextDocument.URI.SpanURI(), []protocol.TextDocumentContentChangeEvent{
+		{Text: params.TextDocument.Text},
+	})
+}
+
+// update applies the supplied changes to the document at the supplied URI and
+// validates it in the background.
+func (s *Server) update(ctx context.Context, uri span.URI, changes []protocol.TextDocumentContentChangeEvent) {
+	s.cancelValidation(uri)
+
+	s.mu.RLock()
+	snap := s.snap
+	s.mu.RUnlock()
 
 	// update snapshot for changes seen
-	err := s.snap.UpdateContent(ctx, uri, params.ContentChanges)
-	if err != nil {
-		s.log.Debug(err.Error())
+	if err := snap.UpdateContent(ctx, uri, changes); err != nil {
+		s.log.Debug(errUpdateContent, "error", err)
 		return
 	}
 
-	if err := s.snap.ReParseFile(ctx, filename); err != nil {
+	if err := snap.ReParseFile(ctx, uri.Filename()); err != nil {
 		s.log.Debug(err.Error())
 		return
 	}
 
 	// TODO(hasheddan): diagnostics should be cached and validation should
 	// be performed selectively.
-	diags, err := s.snap.Validate(ctx, params.TextDocument.URI.SpanURI())
-	if err != nil {
-		s.log.Debug(errValidateNodes, "error", err)
-		return
-	}
-	reply := &protocol.PublishDiagnosticsParams{
-		URI:         params.TextDocument.URI,
-		Diagnostics: diags,
-	}
-	s.publishDiagnostics(ctx, reply)
+	s.validate(snap, uri)
 }
 
-// DidOpen handles calls to DidOpen.
-func (s *Server) DidOpen(ctx context.Context, params *protocol.DidOpenTextDocumentParams) {
-	diags, err := s.snap.Validate(ctx, params.TextDocument.URI.SpanURI())
-	if err != nil {
-		s.log.Debug(errValidateNodes, "error", err)
-		return
+// validate validates the document at the supplied URI in the background and
+// publishes the resulting diagnostics unless the validation was cancelled in
+// the meantime.
+func (s *Server) validate(snap *snapshot.Snapshot, uri span.URI) {
+	ctx, cancel := context.WithCancel(s.ctx)
+
+	s.vmu.Lock()
+	s.validating[uri] = cancel
+	s.vmu.Unlock()
+
+	go func() {
+		defer s.doneValidation(ctx, uri, cancel)
+
+		diags, err := snap.Validate(ctx, uri)
+		if err != nil {
+			s.log.Debug(errValidateNodes, "error", err)
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		s.publishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
+			URI:         protocol.URIFromSpanURI(uri),
+			Diagnostics: diags,
+		})
+	}()
+}
+
+// cancelValidation cancels the in-flight validation of the supplied URI, if
+// there is one.
+func (s *Server) cancelValidation(uri span.URI) {
+	s.vmu.Lock()
+	defer s.vmu.Unlock()
+	if cancel, ok := s.validating[uri]; ok {
+		cancel()
+		delete(s.validating, uri)
 	}
-	reply := &protocol.PublishDiagnosticsParams{
-		URI:         params.TextDocument.URI,
-		Diagnostics: diags,
+}
+
+// doneValidation releases the supplied validation. The entry for the URI is
+// only removed if it has not been replaced by a newer validation.
+func (s *Server) doneValidation(ctx context.Context, uri span.URI, cancel context.CancelFunc) {
+	s.vmu.Lock()
+	defer s.vmu.Unlock()
+	if ctx.Err() == nil {
+		delete(s.validating, uri)
 	}
-	s.publishDiagnostics(ctx, reply)
+	cancel()
 }
 
 // DidSave handles calls to DidSave.
@@ -260,6 +480,141 @@ func (s *Server) DidChangeWatchedFiles(ctx context.Context, params *protocol.Did
 	}
 }
 
+// Completion handles calls to Completion.
+func (s *Server) Completion(ctx context.Context, id jsonrpc2.ID, params *protocol.CompletionParams) {
+	s.mu.RLock()
+	snap := s.snap
+	s.mu.RUnlock()
+
+	items, err := snap.Completion(ctx, params.TextDocument.URI.SpanURI(), params.Position)
+	if err != nil {
+		s.log.Debug(errCompletion, "error", err)
+		items = []protocol.CompletionItem{}
+	}
+	s.reply(ctx, id, &protocol.CompletionList{Items: items})
+}
+
+// Hover handles calls to Hover.
+func (s *Server) Hover(ctx context.Context, id jsonrpc2.ID, params *protocol.HoverParams) {
+	s.mu.RLock()
+	snap := s.snap
+	s.mu.RUnlock()
+
+	hover, err := snap.Hover(ctx, params.TextDocument.URI.SpanURI(), params.Position)
+	if err != nil {
+		s.log.Debug(errHover, "error", err)
+	}
+	s.reply(ctx, id, hover)
+}
+
+// Definition handles calls to Definition.
+func (s *Server) Definition(ctx context.Context, id jsonrpc2.ID, params *protocol.DefinitionParams) {
+	s.mu.RLock()
+	snap := s.snap
+	s.mu.RUnlock()
+
+	locs, err := snap.Definition(ctx, params.TextDocument.URI.SpanURI(), params.Position)
+	if err != nil {
+		s.log.Debug(errDefinition, "error", err)
+		locs = []protocol.Location{}
+	}
+	s.reply(ctx, id, locs)
+}
+
+// CodeAction handles calls to CodeAction.
+func (s *Server) CodeAction(ctx context.Context, id jsonrpc2.ID, params *protocol.CodeActionParams) {
+	s.mu.RLock()
+	snap := s.snap
+	s.mu.RUnlock()
+
+	actions, err := snap.CodeActions(ctx, params.TextDocument.URI.SpanURI(), params.Context.Diagnostics)
+	if err != nil {
+		s.log.Debug(errCodeActions, "error", err)
+		actions = []protocol.CodeAction{}
+	}
+	s.reply(ctx, id, actions)
+}
+
+// DocumentSymbol handles calls to DocumentSymbol.
+func (s *Server) DocumentSymbol(ctx context.Context, id jsonrpc2.ID, params *protocol.DocumentSymbolParams) {
+	s.mu.RLock()
+	snap := s.snap
+	s.mu.RUnlock()
+
+	syms, err := snap.DocumentSymbols(ctx, params.TextDocument.URI.SpanURI())
+	if err != nil {
+		s.log.Debug(errSymbols, "error", err)
+		syms = []protocol.DocumentSymbol{}
+	}
+	s.reply(ctx, id, syms)
+}
+
+// WorkspaceSymbol handles calls to WorkspaceSymbol.
+func (s *Server) WorkspaceSymbol(ctx context.Context, id jsonrpc2.ID, params *protocol.WorkspaceSymbolParams) {
+	s.mu.RLock()
+	snap := s.snap
+	s.mu.RUnlock()
+
+	syms, err := snap.WorkspaceSymbols(ctx, params.Query)
+	if err != nil {
+		s.log.Debug(errSymbols, "error", err)
+		syms = []protocol.SymbolInformation{}
+	}
+	s.reply(ctx, id, syms)
+}
+
+// References handles calls to References.
+func (s *Server) References(ctx context.Context, id jsonrpc2.ID, params *protocol.ReferenceParams) {
+	s.mu.RLock()
+	snap := s.snap
+	s.mu.RUnlock()
+
+	locs, err := snap.References(ctx, params.TextDocument.URI.SpanURI(), params.Position, params.Context.IncludeDeclaration)
+	if err != nil {
+		s.log.Debug(errReferences, "error", err)
+		locs = []protocol.Location{}
+	}
+	s.reply(ctx, id, locs)
+}
+
+// ExecuteCommand handles calls to ExecuteCommand. Dependencies are fetched in
+// the background; the resulting cache change refreshes the snapshot.
+func (s *Server) ExecuteCommand(ctx context.Context, id jsonrpc2.ID, params *protocol.ExecuteCommandParams) {
+	if params.Command != snapshot.FetchDependencyCommand {
+		s.replyWithError(ctx, id, jsonrpc2.CodeInvalidParams, fmt.Sprintf(errUnknownCommandFmt, params.Command))
+		return
+	}
+	var d v1beta1.Dependency
+	if len(params.Arguments) != 1 || json.Unmarshal(params.Arguments[0], &d) != nil || d.Package == "" {
+		s.replyWithError(ctx, id, jsonrpc2.CodeInvalidParams, errCommandArguments)
+		return
+	}
+	if s.offline {
+		s.replyWithError(ctx, id, jsonrpc2.CodeInvalidRequest, errFetchOffline)
+		return
+	}
+
+	go func() {
+		if _, _, err := s.dm.AddAll(ctx, d); err != nil {
+			s.replyWithError(ctx, id, jsonrpc2.CodeInternalError, fmt.Sprintf(errFetchDependencyFmt, d.Package, err))
+			return
+		}
+		s.reply(ctx, id, nil)
+	}()
+}
+
+func (s *Server) reply(ctx context.Context, id jsonrpc2.ID, result any) {
+	if err := s.conn.Reply(ctx, id, result); err != nil {
+		s.log.Debug(errReply, "error", err)
+	}
+}
+
+func (s *Server) replyWithError(ctx context.Context, id jsonrpc2.ID, code int64, msg string) {
+	if err := s.conn.ReplyWithError(ctx, id, &jsonrpc2.Error{Code: code, Message: msg}); err != nil {
+		s.log.Debug(errReply, "error", err)
+	}
+}
+
 func (s *Server) publishDiagnostics(ctx context.Context, params *protocol.PublishDiagnosticsParams) {
 	if err := s.conn.Notify(ctx, "textDocument/publishDiagnostics", params); err != nil {
 		s.log.Debug(errPublishDiagnostics, "error", err)
@@ -296,6 +651,9 @@ func (s *Server) registerWatchFilesCapability(ctx context.Context) {
 }
 
 func (s *Server) checkForUpdates(ctx context.Context) {
+	if s.offline {
+		return
+	}
 	go func() {
 		local, remote, ok := s.i.CanUpgrade(ctx)
 		if !ok {
@@ -324,14 +682,39 @@ func (s *Server) checkMetaFile(ctx context.Context) {
 	}()
 }
 
+// checkExamples validates the examples in the workspace so that broken
+// examples are surfaced without having to open them.
+func (s *Server) checkExamples(ctx context.Context) {
+	go func() {
+		validations, err := s.snap.ValidateExamples(ctx)
+		if err != nil {
+			s.log.Debug(errValidateExamples, "error", err)
+			return
+		}
+		for uri, diags := range validations {
+			s.publishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
+				URI:         protocol.URIFromSpanURI(uri),
+				Diagnostics: diags,
+			})
+		}
+	}()
+}
+
 // // watchSnapshot watches the cache for changes.
 func (s *Server) watchSnapshot(ctx context.Context) { // nolint:gocyclo
 	watch := s.snapFactory.WatchExt()
 
 	go func() {
+		defer s.snapFactory.UnwatchExt(watch)
 		for {
-			// TODO(@tnthornton) handle error/close case from cache
-			<-watch
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-watch:
+				if !ok {
+					return
+				}
+			}
 			s.log.Debug("change seen at cache, processing...")
 			go func() {
 				s.mu.Lock()
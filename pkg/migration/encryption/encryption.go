@@ -0,0 +1,138 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package encryption encrypts and decrypts migration archives for OpenPGP
+// recipients.
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+)
+
+const (
+	errNoIdentity      = "archive is encrypted, an identity is required to decrypt it"
+	errNoPassphrase    = "private key is protected by a passphrase"
+	errWrongPassphrase = "cannot decrypt private key with the supplied passphrase"
+	errFmtReadKeys     = "cannot read OpenPGP keys from %q"
+	errFmtNoKeys       = "%q does not contain any OpenPGP keys"
+	errDecrypt         = "cannot decrypt archive"
+	errEncrypt         = "cannot encrypt archive"
+)
+
+// gzipMagic are the first bytes of every unencrypted archive.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// PassphraseFunc returns the passphrase of the private key with the supplied
+// ID.
+type PassphraseFunc func(keyID string) ([]byte, error)
+
+// ReadKeys reads the armored or binary OpenPGP keys in the supplied files.
+func ReadKeys(paths []string) (openpgp.EntityList, error) {
+	var keys openpgp.EntityList
+	for _, p := range paths {
+		b, err := os.ReadFile(p) // nolint:gosec // Reading user supplied key files is the point.
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtReadKeys, p)
+		}
+		el, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(b))
+		if err != nil {
+			el, err = openpgp.ReadKeyRing(bytes.NewReader(b))
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtReadKeys, p)
+		}
+		if len(el) == 0 {
+			return nil, errors.Errorf(errFmtNoKeys, p)
+		}
+		keys = append(keys, el...)
+	}
+	return keys, nil
+}
+
+// Encrypt returns a writer that encrypts everything written to it for the
+// supplied recipients and writes it to w. The returned writer must be closed
+// to complete the encrypted message, it does not close w.
+func Encrypt(w io.Writer, recipients openpgp.EntityList) (io.WriteCloser, error) {
+	ew, err := openpgp.Encrypt(w, recipients, nil, &openpgp.FileHints{IsBinary: true}, nil)
+	return ew, errors.Wrap(err, errEncrypt)
+}
+
+// Decrypt returns a reader of the decrypted content of r. Unencrypted
+// archives are returned as is, so that decryption is transparent. The
+// passphrase function is called for identities protected by a passphrase and
+// may be nil if there are none.
+//
+// The integrity of an encrypted archive is only verified once the returned
+// reader was read to the end.
+func Decrypt(r io.Reader, identities openpgp.EntityList, passphrase PassphraseFunc) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		return br, nil
+	}
+	if len(identities) == 0 {
+		return nil, errors.New(errNoIdentity)
+	}
+
+	prompted := false
+	md, err := openpgp.ReadMessage(br, identities, func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+		// We are called again if none of the keys could be decrypted, so
+		// only try once to not end up in a loop.
+		if symmetric || prompted || passphrase == nil {
+			return nil, errors.New(errNoPassphrase)
+		}
+		prompted = true
+		for _, k := range keys {
+			if k.PrivateKey == nil || !k.PrivateKey.Encrypted {
+				continue
+			}
+			p, err := passphrase(k.PublicKey.KeyIdString())
+			if err != nil {
+				return nil, err
+			}
+			if err := k.PrivateKey.Decrypt(p); err == nil {
+				return nil, nil
+			}
+		}
+		return nil, errors.New(errWrongPassphrase)
+	}, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, errDecrypt)
+	}
+	return &stickyReader{r: md.UnverifiedBody}, nil
+}
+
+// stickyReader returns the first error of the wrapped reader, including
+// io.EOF, for all subsequent reads. The integrity of an OpenPGP message is
+// verified again on every read after its end, which fails after the first
+// time.
+type stickyReader struct {
+	r   io.Reader
+	err error
+}
+
+func (s *stickyReader) Read(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	n, err := s.r.Read(p)
+	s.err = err
+	return n, err
+}
@@ -0,0 +1,241 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encryption
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+)
+
+func newEntity(t *testing.T) *openpgp.Entity {
+	t.Helper()
+	return newEntityWithConfig(t, &packet.Config{RSABits: 1024, DefaultHash: crypto.SHA256})
+}
+
+func newEntityWithConfig(t *testing.T, c *packet.Config) *openpgp.Entity {
+	t.Helper()
+	e, err := openpgp.NewEntity("migration", "", "migration@example.com", c)
+	if err != nil {
+		t.Fatalf("NewEntity(...): %s", err)
+	}
+	return e
+}
+
+func archive(t *testing.T, content string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	gw := gzip.NewWriter(&b)
+	if _, err := gw.Write([]byte(content)); err != nil {
+		t.Fatalf("Write(...): %s", err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatalf("Close(): %s", err)
+	}
+	return b.Bytes()
+}
+
+func encrypt(t *testing.T, b []byte, recipients openpgp.EntityList) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	w, err := Encrypt(&out, recipients)
+	if err != nil {
+		t.Fatalf("Encrypt(...): %s", err)
+	}
+	if _, err := w.Write(b); err != nil {
+		t.Fatalf("Write(...): %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close(): %s", err)
+	}
+	return out.Bytes()
+}
+
+func TestDecrypt(t *testing.T) {
+	alice := newEntity(t)
+	bob := newEntity(t)
+	// GnuPG creates EdDSA keys with a Curve25519 ECDH encryption subkey by
+	// default.
+	carol := newEntityWithConfig(t, &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	plain := archive(t, "export.yaml")
+
+	type args struct {
+		in         []byte
+		identities openpgp.EntityList
+	}
+	type want struct {
+		out []byte
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Unencrypted": {
+			reason: "Should return unencrypted archives as is.",
+			args: args{
+				in: plain,
+			},
+			want: want{
+				out: plain,
+			},
+		},
+		"Encrypted": {
+			reason: "Should decrypt archives encrypted for one of the identities.",
+			args: args{
+				in:         encrypt(t, plain, openpgp.EntityList{alice, bob}),
+				identities: openpgp.EntityList{bob},
+			},
+			want: want{
+				out: plain,
+			},
+		},
+		"EncryptedCurve25519": {
+			reason: "Should decrypt archives encrypted for a Curve25519 key.",
+			args: args{
+				in:         encrypt(t, plain, openpgp.EntityList{carol}),
+				identities: openpgp.EntityList{carol},
+			},
+			want: want{
+				out: plain,
+			},
+		},
+		"ErrNoIdentity": {
+			reason: "Should return an error if an encrypted archive is read without identities.",
+			args: args{
+				in: encrypt(t, plain, openpgp.EntityList{alice}),
+			},
+			want: want{
+				err: errors.New(errNoIdentity),
+			},
+		},
+		"ErrWrongIdentity": {
+			reason: "Should return an error if the archive was not encrypted for any of the identities.",
+			args: args{
+				in:         encrypt(t, plain, openpgp.EntityList{alice}),
+				identities: openpgp.EntityList{bob},
+			},
+			want: want{
+				err: errors.Wrap(errors.New("openpgp: incorrect key"), errDecrypt),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r, err := Decrypt(bytes.NewReader(tc.args.in), tc.args.identities, nil)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nDecrypt(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			out, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("\n%s\nReadAll(...): unexpected error: %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.out, out); diff != "" {
+				t.Errorf("\n%s\nDecrypt(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReadKeys(t *testing.T) {
+	e := newEntity(t)
+	dir := t.TempDir()
+
+	var binary bytes.Buffer
+	if err := e.Serialize(&binary); err != nil {
+		t.Fatalf("Serialize(...): %s", err)
+	}
+	var armored bytes.Buffer
+	aw, err := armor.Encode(&armored, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("Encode(...): %s", err)
+	}
+	if err := e.Serialize(aw); err != nil {
+		t.Fatalf("Serialize(...): %s", err)
+	}
+	if err := aw.Close(); err != nil {
+		t.Fatalf("Close(): %s", err)
+	}
+
+	files := map[string][]byte{
+		"key.gpg": binary.Bytes(),
+		"key.asc": armored.Bytes(),
+		"empty":   nil,
+	}
+	for name, b := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0600); err != nil {
+			t.Fatalf("WriteFile(...): %s", err)
+		}
+	}
+
+	type want struct {
+		keys []uint64
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		paths  []string
+		want   want
+	}{
+		"BinaryAndArmored": {
+			reason: "Should read both binary and armored keys.",
+			paths:  []string{filepath.Join(dir, "key.gpg"), filepath.Join(dir, "key.asc")},
+			want: want{
+				keys: []uint64{e.PrimaryKey.KeyId, e.PrimaryKey.KeyId},
+			},
+		},
+		"ErrNoKeys": {
+			reason: "Should return an error if a file does not contain any keys.",
+			paths:  []string{filepath.Join(dir, "empty")},
+			want: want{
+				err: errors.Errorf(errFmtNoKeys, filepath.Join(dir, "empty")),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			keys, err := ReadKeys(tc.paths)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nReadKeys(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+			var ids []uint64
+			for _, k := range keys {
+				ids = append(ids, k.PrimaryKey.KeyId)
+			}
+			if diff := cmp.Diff(tc.want.keys, ids); diff != "" {
+				t.Errorf("\n%s\nReadKeys(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+
+	"github.com/upbound/up/pkg/migration/meta/v1alpha1"
+)
+
+const (
+	// checkpointFile is the file in the export directory that records the
+	// progress of an export. It is not part of the archive.
+	checkpointFile = "checkpoint.yaml"
+
+	errFmtCheckpointMismatch = "checkpoint in %q was created with different export options, remove the directory to start over"
+	errFmtCheckpointNotEmpty = "checkpoint directory %q is not empty and does not contain a checkpoint"
+)
+
+// checkpoint records the group resources that were completely exported to
+// the export directory, so that an interrupted export can be resumed.
+type checkpoint struct {
+	// Options are the options of the export that created the checkpoint.
+	Options v1alpha1.ExportOptions `yaml:"options"`
+	// Since is the path of the base archive of an incremental export.
+	Since string `yaml:"since,omitempty"`
+
+	// NativeResources and CustomResources are the number of resources
+	// exported per completed type.
+	NativeResources map[string]int `yaml:"nativeResources,omitempty"`
+	CustomResources map[string]int `yaml:"customResources,omitempty"`
+
+	// Versions and Deleted are recorded per completed group resource, see
+	// v1alpha1.ExportMeta.
+	Versions map[string]map[string]v1alpha1.ObjectVersion `yaml:"versions,omitempty"`
+	Deleted  map[string][]string                          `yaml:"deleted,omitempty"`
+}
+
+func newCheckpoint(opts Options) *checkpoint {
+	return &checkpoint{
+		Options:         exportOptions(opts),
+		Since:           opts.Since,
+		NativeResources: make(map[string]int),
+		CustomResources: make(map[string]int),
+		Versions:        make(map[string]map[string]v1alpha1.ObjectVersion),
+		Deleted:         make(map[string][]string),
+	}
+}
+
+// loadCheckpoint loads the checkpoint from the supplied export directory. A
+// new checkpoint is returned if the directory is empty. An error is returned
+// if the directory contains anything but a checkpoint, as it is removed once
+// the export succeeds, or if the existing checkpoint was created with
+// different options.
+func loadCheckpoint(fs afero.Afero, dir string, opts Options) (*checkpoint, error) {
+	cp := newCheckpoint(opts)
+	b, err := fs.ReadFile(filepath.Join(dir, checkpointFile))
+	if os.IsNotExist(err) {
+		fis, err := fs.ReadDir(dir)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read checkpoint directory")
+		}
+		if len(fis) > 0 {
+			return nil, errors.Errorf(errFmtCheckpointNotEmpty, dir)
+		}
+		return cp, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read checkpoint")
+	}
+
+	stored := &checkpoint{}
+	if err := yaml.Unmarshal(b, stored); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal checkpoint")
+	}
+	if !bytes.Equal(cp.origin(), stored.origin()) {
+		return nil, errors.Errorf(errFmtCheckpointMismatch, dir)
+	}
+	for gr, n := range stored.NativeResources {
+		cp.NativeResources[gr] = n
+	}
+	for gr, n := range stored.CustomResources {
+		cp.CustomResources[gr] = n
+	}
+	for gr, v := range stored.Versions {
+		cp.Versions[gr] = v
+	}
+	for gr, d := range stored.Deleted {
+		cp.Deleted[gr] = d
+	}
+	return cp, nil
+}
+
+// origin returns the serialized options the checkpoint was created with.
+func (c *checkpoint) origin() []byte {
+	// Marshalling a struct of strings and string slices cannot fail.
+	b, _ := yaml.Marshal(&checkpoint{Options: c.Options, Since: c.Since})
+	return b
+}
+
+// save writes the checkpoint to the supplied export directory. The file is
+// replaced atomically so that an interruption never leaves a partially
+// written checkpoint behind.
+func (c *checkpoint) save(fs afero.Afero, dir string) error {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal checkpoint")
+	}
+	tmp := filepath.Join(dir, checkpointFile+".tmp")
+	if err := fs.WriteFile(tmp, b, 0600); err != nil {
+		return errors.Wrap(err, "cannot write checkpoint")
+	}
+	return errors.Wrap(fs.Rename(tmp, filepath.Join(dir, checkpointFile)), "cannot write checkpoint")
+}
+
+// done returns true if the supplied type was completely exported.
+func (c *checkpoint) done(r string) bool {
+	_, native := c.NativeResources[r]
+	_, custom := c.CustomResources[r]
+	return native || custom
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/upbound/up/pkg/migration/meta/v1alpha1"
+)
+
+func TestLoadCheckpoint(t *testing.T) {
+	opts := Options{
+		ExcludeNamespaces:     []string{"kube-system"},
+		IncludeExtraResources: []string{"secrets"},
+	}
+	saved := newCheckpoint(opts)
+	saved.NativeResources["secrets"] = 1
+	saved.Versions["secrets"] = map[string]v1alpha1.ObjectVersion{"a/foo": {ResourceVersion: "1"}}
+
+	type want struct {
+		cp  *checkpoint
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		saved  *checkpoint
+		files  []string
+		opts   Options
+		want   want
+	}{
+		"New": {
+			reason: "Should return an empty checkpoint if none was saved yet.",
+			opts:   opts,
+			want: want{
+				cp: newCheckpoint(opts),
+			},
+		},
+		"ErrNotEmpty": {
+			reason: "Should return an error if the directory contains anything but a checkpoint.",
+			files:  []string{"/export/important.txt"},
+			opts:   opts,
+			want: want{
+				err: errors.Errorf(errFmtCheckpointNotEmpty, "/export"),
+			},
+		},
+		"Resume": {
+			reason: "Should return the saved checkpoint if it was created with the same options.",
+			saved:  saved,
+			opts:   opts,
+			want: want{
+				cp: saved,
+			},
+		},
+		"ErrMismatch": {
+			reason: "Should return an error if the saved checkpoint was created with different options.",
+			saved:  saved,
+			opts: Options{
+				IncludeExtraResources: []string{"secrets"},
+			},
+			want: want{
+				err: errors.Errorf(errFmtCheckpointMismatch, "/export"),
+			},
+		},
+		"ErrMismatchSince": {
+			reason: "Should return an error if the saved checkpoint was created for a different base export.",
+			saved:  saved,
+			opts: Options{
+				ExcludeNamespaces:     []string{"kube-system"},
+				IncludeExtraResources: []string{"secrets"},
+				Since:                 "xp-state.tar.gz",
+			},
+			want: want{
+				err: errors.Errorf(errFmtCheckpointMismatch, "/export"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.Afero{Fs: afero.NewMemMapFs()}
+			_ = fs.MkdirAll("/export", 0700)
+			for _, f := range tc.files {
+				_ = fs.WriteFile(f, nil, 0600)
+			}
+			if tc.saved != nil {
+				if err := tc.saved.save(fs, "/export"); err != nil {
+					t.Fatalf("\n%s\nsave(...): unexpected error: %s", tc.reason, err)
+				}
+			}
+
+			cp, err := loadCheckpoint(fs, "/export", tc.opts)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nloadCheckpoint(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.cp, cp); diff != "" {
+				t.Errorf("\n%s\nloadCheckpoint(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -24,9 +24,11 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
 	xpmeta "github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
@@ -42,11 +44,14 @@ import (
 
 	"github.com/upbound/up/pkg/migration"
 	"github.com/upbound/up/pkg/migration/category"
+	"github.com/upbound/up/pkg/migration/encryption"
 	"github.com/upbound/up/pkg/migration/meta/v1alpha1"
 )
 
 const (
 	stepFailed = "Failed!"
+
+	errEncryptWithCheckpoint = "cannot encrypt the archive when staging the export in a checkpoint directory, the directory would keep unencrypted secrets if the export fails"
 )
 
 // Options for the exporter.
@@ -66,6 +71,25 @@ type Options struct {
 
 	// PauseBeforeExport pauses all managed resources before starting the export process.
 	PauseBeforeExport bool // default: false
+
+	// CheckpointDir is the directory the exported state is staged in before
+	// archiving it. If set, the directory is kept if the export fails and
+	// types that were already exported are skipped when the export is run
+	// again with the same directory.
+	CheckpointDir string // default: none, a temporary directory is used
+	// Since is the path to the archive of a previous export. If set, only
+	// resources that changed since that export are exported.
+	Since string // default: none
+
+	// EncryptTo are paths to OpenPGP public keys to encrypt the archive for.
+	// It cannot be combined with CheckpointDir, whose staged state is not
+	// encrypted.
+	EncryptTo []string // default: none, the archive is not encrypted
+	// Identities are paths to OpenPGP private keys to decrypt the archive
+	// of the previous export with.
+	Identities []string // default: none
+	// Passphrase returns the passphrase of a private key in Identities.
+	Passphrase encryption.PassphraseFunc // default: none
 }
 
 // ControlPlaneStateExporter exports the state of a Crossplane control plane.
@@ -93,21 +117,58 @@ func NewControlPlaneStateExporter(crdClient apiextensionsclientset.Interface, dy
 }
 
 // Export exports the state of the control plane.
-func (e *ControlPlaneStateExporter) Export(ctx context.Context) error { // nolint:gocyclo // This is the high level export command, so it's expected to be a bit complex.
+func (e *ControlPlaneStateExporter) Export(ctx context.Context) (err error) { // nolint:gocyclo // This is the high level export command, so it's expected to be a bit complex.
+	if len(e.options.EncryptTo) > 0 && e.options.CheckpointDir != "" {
+		return errors.New(errEncryptWithCheckpoint)
+	}
 
 	// TODO(turkenh): Check if we can use `afero.NewMemMapFs()` just like import and avoid the need for a temporary directory.
 	fs := afero.Afero{Fs: afero.NewOsFs()}
 	// We are using a temporary directory to store the exported state before
 	// archiving it. This temporary directory will be deleted after the archive
-	// is created.
-	tmpDir, err := fs.TempDir("", "up")
-	if err != nil {
-		return errors.Wrap(err, "cannot create temporary directory")
+	// is created. A checkpoint directory is only deleted if the export
+	// succeeds, so that a failed export can be resumed.
+	tmpDir := e.options.CheckpointDir
+	if tmpDir == "" {
+		if tmpDir, err = fs.TempDir("", "up"); err != nil {
+			return errors.Wrap(err, "cannot create temporary directory")
+		}
+	} else if err = fs.MkdirAll(tmpDir, 0700); err != nil {
+		return errors.Wrapf(err, "cannot create checkpoint directory %q", tmpDir)
 	}
 	defer func() {
+		if err != nil && e.options.CheckpointDir != "" {
+			return
+		}
 		_ = fs.RemoveAll(tmpDir)
 	}()
 
+	var recipients openpgp.EntityList
+	if len(e.options.EncryptTo) > 0 {
+		if recipients, err = encryption.ReadKeys(e.options.EncryptTo); err != nil {
+			return errors.Wrap(err, "cannot read encryption recipients")
+		}
+	}
+
+	cp, err := loadCheckpoint(fs, tmpDir, e.options)
+	if err != nil {
+		return err
+	}
+	// Save the checkpoint right away to mark the directory as ours.
+	if err = cp.save(fs, tmpDir); err != nil {
+		return errors.Wrap(err, "cannot save export checkpoint")
+	}
+
+	var base *v1alpha1.ExportMeta
+	if e.options.Since != "" {
+		if base, err = e.readArchiveMeta(fs, e.options.Since); err != nil {
+			return errors.Wrapf(err, "cannot read base export %q", e.options.Since)
+		}
+		if base.Versions == nil {
+			return errors.Errorf("base export %q does not record resource versions, it cannot be used for an incremental export", e.options.Since)
+		}
+	}
+
 	if e.options.PauseBeforeExport {
 		pauseMsg := "Pausing all managed resources before export... "
 		s, _ := migration.DefaultSpinner.Start(pauseMsg)
@@ -175,12 +236,15 @@ func (e *ControlPlaneStateExporter) Export(ctx context.Context) error { // nolin
 	exportCRsMsg := fmt.Sprintf("Exporting %d Crossplane resources...", len(exportList))
 	s, _ = migration.DefaultSpinner.Start(exportCRsMsg)
 
-	crCounts := make(map[string]int, len(exportList))
-
 	for i, crd := range exportList {
 		inExportCRsMsg := fmt.Sprintf("( %d / %d ) Exporting Crossplane resource %s...", i+1, len(exportList), crd.GetName())
 		s.UpdateText(inExportCRsMsg)
 
+		if cp.done(crd.GetName()) {
+			// Exported by a previous, interrupted run.
+			continue
+		}
+
 		var inCount int
 		var gvr schema.GroupVersionResource
 
@@ -188,7 +252,7 @@ func (e *ControlPlaneStateExporter) Export(ctx context.Context) error { // nolin
 			// Retry on connection refused errors, these could be transient.
 			return net.IsConnectionRefused(err)
 		}, func() (exportErr error) {
-			gvr, inCount, exportErr = e.exportCrossplaneResources(ctx, crd, fs, tmpDir)
+			gvr, inCount, exportErr = e.exportCrossplaneResources(ctx, crd, fs, tmpDir, cp, base)
 			return exportErr
 		})
 
@@ -204,11 +268,15 @@ func (e *ControlPlaneStateExporter) Export(ctx context.Context) error { // nolin
 			return errors.Wrapf(err, "cannot export Crossplane resource %q", crd.GetName())
 		}
 
-		crCounts[gvr.GroupResource().String()] = inCount
+		cp.CustomResources[gvr.GroupResource().String()] = inCount
+		if err = cp.save(fs, tmpDir); err != nil {
+			s.Fail(inExportCRsMsg + stepFailed)
+			return errors.Wrap(err, "cannot save export checkpoint")
+		}
 	}
 
 	total := 0
-	for _, count := range crCounts {
+	for _, count := range cp.CustomResources {
 		total += count
 	}
 
@@ -219,22 +287,25 @@ func (e *ControlPlaneStateExporter) Export(ctx context.Context) error { // nolin
 	exportNativeMsg := fmt.Sprintf("Exporting %d native resources...", len(e.options.IncludeExtraResources))
 	s, _ = migration.DefaultSpinner.Start(exportNativeMsg)
 
-	nativeCounts := make(map[string]int, len(e.options.IncludeExtraResources))
-
 	// In addition to the Crossplane resources, we also need to export some native resources. These are
 	// defaulted as "namespaces", "configmaps" and "secrets". However, the user can also specify additional
 	// resources to include or exclude the default ones.
 	for r := range e.extraResources() {
-		inExportNativeMsg := fmt.Sprintf("( %d / %d ) Exporting native resource %s...", len(nativeCounts)+1, len(e.options.IncludeExtraResources), r)
+		inExportNativeMsg := fmt.Sprintf("( %d / %d ) Exporting native resource %s...", len(cp.NativeResources)+1, len(e.options.IncludeExtraResources), r)
 		s.UpdateText(inExportNativeMsg)
 
+		if cp.done(r) {
+			// Exported by a previous, interrupted run.
+			continue
+		}
+
 		var inCount int
 
 		err := retry.OnError(retry.DefaultRetry, func(err error) bool {
 			// Retry on connection refused errors, these could be transient.
 			return net.IsConnectionRefused(err)
 		}, func() (exportErr error) {
-			inCount, exportErr = e.exportNativeResource(ctx, r, fs, tmpDir)
+			inCount, exportErr = e.exportNativeResource(ctx, r, fs, tmpDir, cp, base)
 			return exportErr
 		})
 
@@ -250,11 +321,15 @@ func (e *ControlPlaneStateExporter) Export(ctx context.Context) error { // nolin
 			return errors.Wrapf(err, "cannot export native resource %q", r)
 		}
 
-		nativeCounts[r] = inCount
+		cp.NativeResources[r] = inCount
+		if err = cp.save(fs, tmpDir); err != nil {
+			s.Fail(inExportNativeMsg + stepFailed)
+			return errors.Wrap(err, "cannot save export checkpoint")
+		}
 	}
 
 	total = 0
-	for _, count := range nativeCounts {
+	for _, count := range cp.NativeResources {
 		total += count
 	}
 
@@ -266,7 +341,14 @@ func (e *ControlPlaneStateExporter) Export(ctx context.Context) error { // nolin
 	// This metadata file is used during import to determine if the import is compatible with the
 	// current Crossplane version and feature flags and also enables manual inspection the exported state.
 	me := NewPersistentMetadataExporter(e.appsClient, fs, tmpDir)
-	if err = me.ExportMetadata(ctx, e.options, nativeCounts, crCounts); err != nil {
+	var delta *v1alpha1.DeltaMeta
+	if base != nil {
+		delta = &v1alpha1.DeltaMeta{
+			BaseExportedAt: base.ExportedAt,
+			Deleted:        cp.Deleted,
+		}
+	}
+	if err = me.ExportMetadata(ctx, e.options, cp.NativeResources, cp.CustomResources, cp.Versions, delta); err != nil {
 		return errors.Wrap(err, "cannot write export metadata")
 	}
 	//////////////////////
@@ -274,7 +356,7 @@ func (e *ControlPlaneStateExporter) Export(ctx context.Context) error { // nolin
 	// Archive the exported state.
 	archiveMsg := "Archiving exported state... "
 	s, _ = migration.DefaultSpinner.Start(archiveMsg)
-	if err = e.archive(ctx, fs, tmpDir); err != nil {
+	if err = e.archive(ctx, fs, tmpDir, recipients); err != nil {
 		s.Fail(archiveMsg + stepFailed)
 		return errors.Wrap(err, "cannot archive exported state")
 	}
@@ -284,7 +366,7 @@ func (e *ControlPlaneStateExporter) Export(ctx context.Context) error { // nolin
 	return nil
 }
 
-func (e *ControlPlaneStateExporter) exportCrossplaneResources(ctx context.Context, crd apiextensionsv1.CustomResourceDefinition, fs afero.Afero, tmpDir string) (schema.GroupVersionResource, int, error) {
+func (e *ControlPlaneStateExporter) exportCrossplaneResources(ctx context.Context, crd apiextensionsv1.CustomResourceDefinition, fs afero.Afero, tmpDir string, cp *checkpoint, base *v1alpha1.ExportMeta) (schema.GroupVersionResource, int, error) {
 	gvr, err := e.customResourceGVR(crd)
 	if err != nil {
 		return schema.GroupVersionResource{}, 0, errors.Wrapf(err, "cannot get GVR for %q", crd.GetName())
@@ -299,34 +381,55 @@ func (e *ControlPlaneStateExporter) exportCrossplaneResources(ctx context.Contex
 			break
 		}
 	}
-	exporter := NewUnstructuredExporter(
-		NewUnstructuredFetcher(e.dynamicClient, e.options),
-		NewFileSystemPersister(fs, tmpDir, &v1alpha1.TypeMeta{
-			Categories:            crd.Spec.Names.Categories,
-			WithStatusSubresource: sub,
-		}))
-
-	// ExportResource will fetch all resources of the given GVR and store them in the
+	// exportResources will fetch all resources of the given GVR and store them in the
 	// well-known directory structure.
-	count, err := exporter.ExportResources(ctx, gvr)
+	count, err := e.exportResources(ctx, gvr, fs, tmpDir, &v1alpha1.TypeMeta{
+		Categories:            crd.Spec.Names.Categories,
+		WithStatusSubresource: sub,
+	}, cp, base)
 	if err != nil {
 		return schema.GroupVersionResource{}, 0, errors.Wrapf(err, "cannot export resources for %q", crd.GetName())
 	}
 	return gvr, count, nil
 }
 
-func (e *ControlPlaneStateExporter) exportNativeResource(ctx context.Context, r string, fs afero.Afero, tmpDir string) (int, error) {
+func (e *ControlPlaneStateExporter) exportNativeResource(ctx context.Context, r string, fs afero.Afero, tmpDir string, cp *checkpoint, base *v1alpha1.ExportMeta) (int, error) {
 	gvr, err := e.resourceMapper.ResourceFor(schema.ParseGroupResource(r).WithVersion(""))
 	if err != nil {
 		return 0, errors.Wrapf(err, "cannot get GVR for %q", r)
 	}
+	count, err := e.exportResources(ctx, gvr, fs, tmpDir, nil, cp, base)
+	if err != nil {
+		return 0, errors.Wrapf(err, "cannot export resources for %q", r)
+	}
+	return count, nil
+}
+
+// exportResources exports the resources of the supplied GVR and records their
+// versions in the checkpoint. Anything left behind for the GVR by a previous,
+// interrupted run is removed first.
+func (e *ControlPlaneStateExporter) exportResources(ctx context.Context, gvr schema.GroupVersionResource, fs afero.Afero, tmpDir string, m *v1alpha1.TypeMeta, cp *checkpoint, base *v1alpha1.ExportMeta) (int, error) {
+	gr := gvr.GroupResource().String()
+	if err := fs.RemoveAll(filepath.Join(tmpDir, gr)); err != nil {
+		return 0, errors.Wrapf(err, "cannot clean up partially exported resources for %q", gr)
+	}
+
+	var opts []UnstructuredExporterOption
+	if base != nil {
+		opts = append(opts, WithBaseVersions(base.Versions[gr]))
+	}
 	exporter := NewUnstructuredExporter(
 		NewUnstructuredFetcher(e.dynamicClient, e.options),
-		NewFileSystemPersister(fs, tmpDir, nil))
+		NewFileSystemPersister(fs, tmpDir, m),
+		opts...)
 
 	count, err := exporter.ExportResources(ctx, gvr)
 	if err != nil {
-		return 0, errors.Wrapf(err, "cannot export resources for %q", r)
+		return 0, err
+	}
+	cp.Versions[gr] = exporter.Versions()
+	if d := exporter.Deleted(); len(d) > 0 {
+		cp.Deleted[gr] = d
 	}
 	return count, nil
 }
@@ -398,7 +501,7 @@ func (e *ControlPlaneStateExporter) customResourceGVR(in apiextensionsv1.CustomR
 	return rm.Resource, nil
 }
 
-func (e *ControlPlaneStateExporter) archive(ctx context.Context, fs afero.Afero, dir string) error {
+func (e *ControlPlaneStateExporter) archive(ctx context.Context, fs afero.Afero, dir string, recipients openpgp.EntityList) error { // nolint:gocyclo // Mostly error handling while walking the directory.
 	// Create the output file
 	out, err := fs.Create(e.options.OutputArchive)
 	if err != nil {
@@ -413,13 +516,21 @@ func (e *ControlPlaneStateExporter) archive(ctx context.Context, fs afero.Afero,
 		return errors.Wrapf(err, "cannot set permissions for %q", e.options.OutputArchive)
 	}
 
+	// Encrypt the archive if there are recipients
+	var w io.Writer = out
+	var ew io.WriteCloser
+	if len(recipients) > 0 {
+		if ew, err = encryption.Encrypt(out, recipients); err != nil {
+			return err
+		}
+		w = ew
+	}
+
 	// Create a new gzip writer
-	gw := gzip.NewWriter(out)
-	defer gw.Close()
+	gw := gzip.NewWriter(w)
 
 	// Create a new tar writer
 	tw := tar.NewWriter(gw)
-	defer tw.Close()
 
 	// Walk the directory and add each file to the tar archive
 	err = filepath.Walk(dir, func(file string, fi os.FileInfo, err error) error {
@@ -441,6 +552,11 @@ func (e *ControlPlaneStateExporter) archive(ctx context.Context, fs afero.Afero,
 			return nil
 		}
 
+		// Skip the export checkpoint, it is only relevant while exporting.
+		if filepath.Dir(file) == dir && strings.HasPrefix(fi.Name(), checkpointFile) {
+			return nil
+		}
+
 		// Get the relative path of the file from the root directory
 		relPath, err := filepath.Rel(dir, file)
 		if err != nil {
@@ -486,9 +602,76 @@ func (e *ControlPlaneStateExporter) archive(ctx context.Context, fs afero.Afero,
 
 		return nil
 	})
+	if err != nil {
+		return errors.Wrapf(err, "walking directory %q", dir)
+	}
+
+	// Close the writers explicitly and in order, each of them flushes its
+	// trailer (tar footer, gzip checksum, final packet and MDC) into the next
+	// one, so an error here means the archive is incomplete.
+	if err := tw.Close(); err != nil {
+		return errors.Wrapf(err, "cannot close tar writer for %q", e.options.OutputArchive)
+	}
+	if err := gw.Close(); err != nil {
+		return errors.Wrapf(err, "cannot close gzip writer for %q", e.options.OutputArchive)
+	}
+	if ew != nil {
+		if err := ew.Close(); err != nil {
+			return errors.Wrapf(err, "cannot close encryption writer for %q", e.options.OutputArchive)
+		}
+	}
+	return errors.Wrapf(out.Close(), "cannot close output file %q", e.options.OutputArchive)
+}
+
+// readArchiveMeta reads the export metadata from the supplied archive.
+func (e *ControlPlaneStateExporter) readArchiveMeta(fs afero.Afero, archive string) (*v1alpha1.ExportMeta, error) {
+	f, err := fs.Open(archive)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open archive %q", archive)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var ids openpgp.EntityList
+	if len(e.options.Identities) > 0 {
+		if ids, err = encryption.ReadKeys(e.options.Identities); err != nil {
+			return nil, errors.Wrap(err, "cannot read identities")
+		}
+	}
+	r, err := encryption.Decrypt(f, ids, e.options.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot create gzip reader for %q", archive)
+	}
+	defer gr.Close()
 
-	// Return any errors encountered while creating the archive
-	return errors.Wrapf(err, "walking directory %q", dir)
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.Errorf("archive %q does not contain export metadata", archive)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read archive %q", archive)
+		}
+		if hdr.Name != "export.yaml" {
+			continue
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read export metadata from %q", archive)
+		}
+		em := &v1alpha1.ExportMeta{}
+		if err := yaml.Unmarshal(b, em); err != nil {
+			return nil, errors.Wrap(err, "cannot unmarshal export metadata")
+		}
+		return em, nil
+	}
 }
 
 func fetchAllCRDs(ctx context.Context, kube apiextensionsclientset.Interface) ([]apiextensionsv1.CustomResourceDefinition, error) {
@@ -0,0 +1,75 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExport(t *testing.T) {
+	type want struct {
+		err     error
+		staged  bool
+		archive bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		opts   func(dir string) Options
+		want   want
+	}{
+		"ErrEncryptWithCheckpoint": {
+			reason: "Should refuse to encrypt the archive when staging the export in a checkpoint directory, which would keep unencrypted secrets.",
+			opts: func(dir string) Options {
+				return Options{
+					OutputArchive: filepath.Join(dir, "xp-state.tar.gz"),
+					CheckpointDir: filepath.Join(dir, "checkpoint"),
+					EncryptTo:     []string{filepath.Join(dir, "team.asc")},
+				}
+			},
+			want: want{
+				err: errors.New(errEncryptWithCheckpoint),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			opts := tc.opts(dir)
+			e := NewControlPlaneStateExporter(nil, nil, nil, nil, nil, opts)
+
+			err := e.Export(context.Background())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nExport(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			_, err = os.Stat(opts.CheckpointDir)
+			if diff := cmp.Diff(tc.want.staged, err == nil); diff != "" {
+				t.Errorf("\n%s\nExport(...): -want checkpoint directory, +got checkpoint directory:\n%s", tc.reason, diff)
+			}
+			_, err = os.Stat(opts.OutputArchive)
+			if diff := cmp.Diff(tc.want.archive, err == nil); diff != "" {
+				t.Errorf("\n%s\nExport(...): -want archive, +got archive:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
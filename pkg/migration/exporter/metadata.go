@@ -46,7 +46,7 @@ func NewPersistentMetadataExporter(apps appsv1.AppsV1Interface, fs afero.Afero,
 	}
 }
 
-func (e *PersistentMetadataExporter) ExportMetadata(ctx context.Context, opts Options, native map[string]int, custom map[string]int) error {
+func (e *PersistentMetadataExporter) ExportMetadata(ctx context.Context, opts Options, native map[string]int, custom map[string]int, versions map[string]map[string]v1alpha1.ObjectVersion, delta *v1alpha1.DeltaMeta) error {
 	xp, err := crossplane.CollectInfo(ctx, e.appsClient)
 	if err != nil {
 		return errors.Wrap(err, "cannot get Crossplane info")
@@ -62,19 +62,15 @@ func (e *PersistentMetadataExporter) ExportMetadata(ctx context.Context, opts Op
 	em := &v1alpha1.ExportMeta{
 		Version:    "v1alpha1",
 		ExportedAt: time.Now(),
-		Options: v1alpha1.ExportOptions{
-			IncludedNamespaces:     opts.IncludeNamespaces,
-			ExcludedNamespaces:     opts.ExcludeNamespaces,
-			IncludedExtraResources: opts.IncludeExtraResources,
-			ExcludedResources:      opts.ExcludeResources,
-			PausedBeforeExport:     opts.PauseBeforeExport,
-		},
+		Options:    exportOptions(opts),
 		Crossplane: *xp,
 		Stats: v1alpha1.ExportStats{
 			Total:           total,
 			NativeResources: native,
 			CustomResources: custom,
 		},
+		Versions: versions,
+		Delta:    delta,
 	}
 	b, err := yaml.Marshal(&em)
 	if err != nil {
@@ -86,3 +82,13 @@ func (e *PersistentMetadataExporter) ExportMetadata(ctx context.Context, opts Op
 	}
 	return nil
 }
+
+func exportOptions(opts Options) v1alpha1.ExportOptions {
+	return v1alpha1.ExportOptions{
+		IncludedNamespaces:     opts.IncludeNamespaces,
+		ExcludedNamespaces:     opts.ExcludeNamespaces,
+		IncludedExtraResources: opts.IncludeExtraResources,
+		ExcludedResources:      opts.ExcludeResources,
+		PausedBeforeExport:     opts.PauseBeforeExport,
+	}
+}
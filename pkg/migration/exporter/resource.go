@@ -17,11 +17,14 @@ package exporter
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
 	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/upbound/up/pkg/migration/meta/v1alpha1"
 )
 
 type ResourceExporter interface {
@@ -31,13 +34,33 @@ type ResourceExporter interface {
 type UnstructuredExporter struct {
 	fetcher   ResourceFetcher
 	persister ResourcePersister
+
+	incremental bool
+	base        map[string]v1alpha1.ObjectVersion
+	versions    map[string]v1alpha1.ObjectVersion
 }
 
-func NewUnstructuredExporter(f ResourceFetcher, p ResourcePersister) *UnstructuredExporter {
-	return &UnstructuredExporter{
+// UnstructuredExporterOption configures an UnstructuredExporter.
+type UnstructuredExporterOption func(e *UnstructuredExporter)
+
+// WithBaseVersions makes the exporter only persist resources whose version
+// differs from the supplied versions of a previous export.
+func WithBaseVersions(base map[string]v1alpha1.ObjectVersion) UnstructuredExporterOption {
+	return func(e *UnstructuredExporter) {
+		e.incremental = true
+		e.base = base
+	}
+}
+
+func NewUnstructuredExporter(f ResourceFetcher, p ResourcePersister, opts ...UnstructuredExporterOption) *UnstructuredExporter {
+	e := &UnstructuredExporter{
 		fetcher:   f,
 		persister: p,
 	}
+	for _, o := range opts {
+		o(e)
+	}
+	return e
 }
 
 func (e *UnstructuredExporter) ExportResources(ctx context.Context, gvr schema.GroupVersionResource) (int, error) {
@@ -46,11 +69,26 @@ func (e *UnstructuredExporter) ExportResources(ctx context.Context, gvr schema.G
 		return 0, errors.Wrap(err, "cannot fetch resources")
 	}
 
+	// Versions have to be recorded before the cluster specific data, which
+	// includes them, is cleaned up.
+	e.versions = make(map[string]v1alpha1.ObjectVersion, len(resources))
+	changed := resources[:0]
 	for i := range resources {
+		k := objectKey(resources[i])
+		v := v1alpha1.ObjectVersion{
+			ResourceVersion: resources[i].GetResourceVersion(),
+			Generation:      resources[i].GetGeneration(),
+		}
+		e.versions[k] = v
+		if b, ok := e.base[k]; e.incremental && ok && b == v {
+			continue
+		}
 		if err := cleanupClusterSpecificData(&resources[i]); err != nil {
 			return 0, errors.Wrap(err, "cannot cleanup cluster specific data")
 		}
+		changed = append(changed, resources[i])
 	}
+	resources = changed
 
 	if err = e.persister.PersistResources(ctx, gvr.GroupResource().String(), resources); err != nil {
 		return 0, errors.Wrap(err, "cannot persist resources")
@@ -59,6 +97,33 @@ func (e *UnstructuredExporter) ExportResources(ctx context.Context, gvr schema.G
 	return len(resources), nil
 }
 
+// Versions returns the versions of all resources fetched by the last call to
+// ExportResources, including the ones that were not persisted because they
+// did not change since the base export.
+func (e *UnstructuredExporter) Versions() map[string]v1alpha1.ObjectVersion {
+	return e.versions
+}
+
+// Deleted returns the keys of the resources of the base export that were not
+// fetched by the last call to ExportResources.
+func (e *UnstructuredExporter) Deleted() []string {
+	var deleted []string
+	for k := range e.base {
+		if _, ok := e.versions[k]; !ok {
+			deleted = append(deleted, k)
+		}
+	}
+	sort.Strings(deleted)
+	return deleted
+}
+
+func objectKey(u unstructured.Unstructured) string {
+	if u.GetNamespace() == "" {
+		return u.GetName()
+	}
+	return u.GetNamespace() + "/" + u.GetName()
+}
+
 func cleanupClusterSpecificData(u *unstructured.Unstructured) error {
 	paved := fieldpath.Pave(u.Object)
 
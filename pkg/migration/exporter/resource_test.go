@@ -0,0 +1,158 @@
+// Copyright 2024 Upbound Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/upbound/up/pkg/migration/meta/v1alpha1"
+)
+
+type fetcherFn func(ctx context.Context, gvr schema.GroupVersionResource) ([]unstructured.Unstructured, error)
+
+func (fn fetcherFn) FetchResources(ctx context.Context, gvr schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+	return fn(ctx, gvr)
+}
+
+type persisterFn func(ctx context.Context, groupResource string, resources []unstructured.Unstructured) error
+
+func (fn persisterFn) PersistResources(ctx context.Context, groupResource string, resources []unstructured.Unstructured) error {
+	return fn(ctx, groupResource, resources)
+}
+
+func secret(namespace, name, resourceVersion string) unstructured.Unstructured {
+	return unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata": map[string]interface{}{
+				"namespace":       namespace,
+				"name":            name,
+				"resourceVersion": resourceVersion,
+			},
+		},
+	}
+}
+
+func TestUnstructuredExporterExportResources(t *testing.T) {
+	type args struct {
+		resources []unstructured.Unstructured
+		opts      []UnstructuredExporterOption
+	}
+	type want struct {
+		count     int
+		persisted []string
+		versions  map[string]v1alpha1.ObjectVersion
+		deleted   []string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Full": {
+			reason: "Should persist all resources and record their versions.",
+			args: args{
+				resources: []unstructured.Unstructured{secret("a", "foo", "1"), secret("", "bar", "2")},
+			},
+			want: want{
+				count:     2,
+				persisted: []string{"a/foo", "bar"},
+				versions: map[string]v1alpha1.ObjectVersion{
+					"a/foo": {ResourceVersion: "1"},
+					"bar":   {ResourceVersion: "2"},
+				},
+			},
+		},
+		"Incremental": {
+			reason: "Should only persist resources that changed since the base export and report the deleted ones.",
+			args: args{
+				resources: []unstructured.Unstructured{secret("a", "foo", "1"), secret("a", "bar", "3"), secret("a", "new", "4")},
+				opts: []UnstructuredExporterOption{WithBaseVersions(map[string]v1alpha1.ObjectVersion{
+					"a/foo":  {ResourceVersion: "1"},
+					"a/bar":  {ResourceVersion: "2"},
+					"a/gone": {ResourceVersion: "1"},
+				})},
+			},
+			want: want{
+				count:     2,
+				persisted: []string{"a/bar", "a/new"},
+				versions: map[string]v1alpha1.ObjectVersion{
+					"a/foo": {ResourceVersion: "1"},
+					"a/bar": {ResourceVersion: "3"},
+					"a/new": {ResourceVersion: "4"},
+				},
+				deleted: []string{"a/gone"},
+			},
+		},
+		"IncrementalNewType": {
+			reason: "Should persist all resources of a type that was not part of the base export.",
+			args: args{
+				resources: []unstructured.Unstructured{secret("a", "foo", "1")},
+				opts:      []UnstructuredExporterOption{WithBaseVersions(nil)},
+			},
+			want: want{
+				count:     1,
+				persisted: []string{"a/foo"},
+				versions: map[string]v1alpha1.ObjectVersion{
+					"a/foo": {ResourceVersion: "1"},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var persisted []string
+			e := NewUnstructuredExporter(
+				fetcherFn(func(_ context.Context, _ schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+					return tc.args.resources, nil
+				}),
+				persisterFn(func(_ context.Context, _ string, resources []unstructured.Unstructured) error {
+					for _, r := range resources {
+						if r.GetResourceVersion() != "" {
+							t.Errorf("\n%s\nExportResources(...): resourceVersion of %q was not cleaned up", tc.reason, r.GetName())
+						}
+						persisted = append(persisted, objectKey(r))
+					}
+					return nil
+				}),
+				tc.args.opts...)
+
+			count, err := e.ExportResources(context.Background(), schema.GroupVersionResource{Version: "v1", Resource: "secrets"})
+			if err != nil {
+				t.Fatalf("\n%s\nExportResources(...): unexpected error: %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.count, count); diff != "" {
+				t.Errorf("\n%s\nExportResources(...): -want count, +got count:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.persisted, persisted); diff != "" {
+				t.Errorf("\n%s\nExportResources(...): -want persisted, +got persisted:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.versions, e.Versions()); diff != "" {
+				t.Errorf("\n%s\nVersions(): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.deleted, e.Deleted()); diff != "" {
+				t.Errorf("\n%s\nDeleted(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ module github.com/upbound/up/pkg/migration
 go 1.22.1
 
 require (
+	github.com/ProtonMail/go-crypto v1.0.0
 	github.com/crossplane/crossplane-runtime v1.14.0-rc.0.0.20230919042158-960a14fac774
 	github.com/google/go-cmp v0.6.0
 	github.com/pterm/pterm v0.12.62
@@ -16,9 +17,11 @@ require (
 )
 
 require (
+	github.com/cloudflare/circl v1.3.7 // indirect
 	github.com/google/uuid v1.3.0 // indirect
 	github.com/onsi/ginkgo/v2 v2.16.0 // indirect
 	github.com/onsi/gomega v1.31.1 // indirect
+	golang.org/x/crypto v0.19.0 // indirect
 	golang.org/x/term v0.17.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 	k8s.io/kube-openapi v0.0.0-20230717233707-2695361300d9 // indirect
@@ -21,9 +21,11 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
 	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
@@ -44,6 +46,7 @@ import (
 	"github.com/upbound/up/pkg/migration"
 	"github.com/upbound/up/pkg/migration/category"
 	"github.com/upbound/up/pkg/migration/crossplane"
+	"github.com/upbound/up/pkg/migration/encryption"
 	"github.com/upbound/up/pkg/migration/meta/v1alpha1"
 )
 
@@ -80,6 +83,11 @@ type Options struct {
 	InputArchive string // default: xp-state.tar.gz
 	// UnpauseAfterImport indicates whether to unpause all managed resources after import.
 	UnpauseAfterImport bool // default: false
+	// Identities are paths to OpenPGP private keys to decrypt an encrypted
+	// archive with.
+	Identities []string // default: none
+	// Passphrase returns the passphrase of a private key in Identities.
+	Passphrase encryption.PassphraseFunc // default: none
 }
 
 // ControlPlaneStateImporter is the importer for control plane state.
@@ -123,7 +131,20 @@ func (im *ControlPlaneStateImporter) Import(ctx context.Context) error { // noli
 		}
 	}
 
-	s.Success(unarchiveMsg + "Done! 👀")
+	em, err := im.exportMeta()
+	if err != nil {
+		s.Fail(unarchiveMsg + stepFailed)
+		return err
+	}
+
+	if em.Delta != nil {
+		// A delta only contains the resources that changed since its base
+		// export, which is expected to have been imported already. Applying
+		// them on top of it is no different from a full import.
+		s.Success(unarchiveMsg + fmt.Sprintf("Done, delta of the export from %s! 👀", em.Delta.BaseExportedAt.Format(time.RFC3339)))
+	} else {
+		s.Success(unarchiveMsg + "Done! 👀")
+	}
 	//////////////////////////////////////////
 
 	// Pausing resource importer will import all resources.
@@ -199,7 +220,8 @@ func (im *ControlPlaneStateImporter) Import(ctx context.Context) error { // noli
 	// Import remaining resources other than the base resources.
 	importRemainingMsg := "Importing remaining resources... "
 	s, _ = migration.DefaultSpinner.Start(importRemainingMsg)
-	grs, err := im.fs.ReadDir("/")
+	var grs []os.FileInfo
+	grs, err = im.fs.ReadDir("/")
 	if err != nil {
 		s.Fail(importRemainingMsg + stepFailed)
 		return errors.Wrap(err, "cannot list group resources")
@@ -270,6 +292,22 @@ func (im *ControlPlaneStateImporter) Import(ctx context.Context) error { // noli
 	}
 	//////////////////////////////////////////
 
+	if em.Delta != nil && len(em.Delta.Deleted) > 0 {
+		// Deleting resources that might still be paused would leave them
+		// stuck, so we leave it to the user.
+		pterm.Println("\nThe following resources were deleted since the base export and have to be deleted manually:")
+		grs := make([]string, 0, len(em.Delta.Deleted))
+		for gr := range em.Delta.Deleted {
+			grs = append(grs, gr)
+		}
+		sort.Strings(grs)
+		for _, gr := range grs {
+			for _, name := range em.Delta.Deleted[gr] {
+				pterm.Printf("- %s %s\n", gr, name)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -288,13 +326,9 @@ func (im *ControlPlaneStateImporter) PreflightChecks(ctx context.Context) []erro
 			return []error{errors.Wrap(err, "Cannot unarchive export archive")}
 		}
 	}
-	b, err := im.fs.ReadFile("export.yaml")
+	em, err := im.exportMeta()
 	if err != nil {
-		return []error{errors.Wrap(err, "Cannot read export metadata")}
-	}
-	em := &v1alpha1.ExportMeta{}
-	if err = yaml.Unmarshal(b, em); err != nil {
-		return []error{errors.Wrap(err, "Cannot unmarshal export metadata")}
+		return []error{err}
 	}
 
 	var errs []error
@@ -312,6 +346,18 @@ func (im *ControlPlaneStateImporter) PreflightChecks(ctx context.Context) []erro
 	return errs
 }
 
+func (im *ControlPlaneStateImporter) exportMeta() (*v1alpha1.ExportMeta, error) {
+	b, err := im.fs.ReadFile("export.yaml")
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot read export metadata")
+	}
+	em := &v1alpha1.ExportMeta{}
+	if err = yaml.Unmarshal(b, em); err != nil {
+		return nil, errors.Wrap(err, "Cannot unmarshal export metadata")
+	}
+	return em, nil
+}
+
 func contains(ss []string, s string) bool {
 	for _, v := range ss {
 		if v == s {
@@ -330,7 +376,18 @@ func (im *ControlPlaneStateImporter) unarchive(ctx context.Context, fs afero.Afe
 		_ = g.Close()
 	}()
 
-	gr, err := gzip.NewReader(g)
+	var ids openpgp.EntityList
+	if len(im.options.Identities) > 0 {
+		if ids, err = encryption.ReadKeys(im.options.Identities); err != nil {
+			return errors.Wrap(err, "cannot read identities")
+		}
+	}
+	r, err := encryption.Decrypt(g, ids, im.options.Passphrase)
+	if err != nil {
+		return errors.Wrapf(err, "cannot read archive %q", im.options.InputArchive)
+	}
+
+	gr, err := gzip.NewReader(r)
 	if err != nil {
 		return errors.Wrapf(err, "cannot create gzip reader for %q", im.options.InputArchive)
 	}
@@ -346,6 +403,10 @@ func (im *ControlPlaneStateImporter) unarchive(ctx context.Context, fs afero.Afe
 		}
 		hdr, err := tr.Next()
 		if err == io.EOF {
+			// Reading an encrypted archive to the end verifies its integrity.
+			if _, err := io.Copy(io.Discard, r); err != nil {
+				return errors.Wrapf(err, "cannot verify archive %q", im.options.InputArchive)
+			}
 			break // End of archive
 		}
 		if err != nil {
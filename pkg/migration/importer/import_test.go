@@ -15,10 +15,24 @@
 package importer
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"io"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
 	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/upbound/up/pkg/migration/encryption"
 )
 
 func Test_printConditions(t *testing.T) {
@@ -84,3 +98,115 @@ func Test_printConditions(t *testing.T) {
 		})
 	}
 }
+
+func TestUnarchive(t *testing.T) {
+	dir := t.TempDir()
+	e, err := openpgp.NewEntity("migration", "", "migration@example.com", &packet.Config{RSABits: 1024, DefaultHash: crypto.SHA256})
+	if err != nil {
+		t.Fatalf("NewEntity(...): %s", err)
+	}
+	identity := filepath.Join(dir, "identity.gpg")
+	writeFile(t, identity, func(w io.Writer) error { return e.SerializePrivate(w, nil) })
+
+	writeArchive := func(path string, recipients openpgp.EntityList) {
+		writeFile(t, path, func(w io.Writer) error {
+			if len(recipients) > 0 {
+				ew, err := encryption.Encrypt(w, recipients)
+				if err != nil {
+					return err
+				}
+				defer ew.Close() // nolint:errcheck // Checked by decrypting the archive.
+				w = ew
+			}
+			gw := gzip.NewWriter(w)
+			tw := tar.NewWriter(gw)
+			if err := tw.WriteHeader(&tar.Header{Name: "export.yaml", Mode: 0600, Size: 17}); err != nil {
+				return err
+			}
+			if _, err := tw.Write([]byte("version: v1alpha1")); err != nil {
+				return err
+			}
+			if err := tw.Close(); err != nil {
+				return err
+			}
+			return gw.Close()
+		})
+	}
+	plain := filepath.Join(dir, "plain.tar.gz")
+	writeArchive(plain, nil)
+	encrypted := filepath.Join(dir, "encrypted.tar.gz")
+	writeArchive(encrypted, openpgp.EntityList{e})
+
+	type want struct {
+		meta string
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		opts   Options
+		want   want
+	}{
+		"Unencrypted": {
+			reason: "Should unarchive an unencrypted archive.",
+			opts: Options{
+				InputArchive: plain,
+			},
+			want: want{
+				meta: "version: v1alpha1",
+			},
+		},
+		"Encrypted": {
+			reason: "Should decrypt an encrypted archive with the supplied identity.",
+			opts: Options{
+				InputArchive: encrypted,
+				Identities:   []string{identity},
+			},
+			want: want{
+				meta: "version: v1alpha1",
+			},
+		},
+		"ErrNoIdentity": {
+			reason: "Should return an error if an encrypted archive is imported without an identity.",
+			opts: Options{
+				InputArchive: encrypted,
+			},
+			want: want{
+				err: errors.Wrapf(errors.New("archive is encrypted, an identity is required to decrypt it"), "cannot read archive %q", encrypted),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			im := NewControlPlaneStateImporter(nil, nil, nil, nil, tc.opts)
+			fs := afero.Afero{Fs: afero.NewMemMapFs()}
+			err := im.unarchive(context.Background(), fs)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nunarchive(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			b, err := fs.ReadFile("export.yaml")
+			if err != nil {
+				t.Fatalf("\n%s\nReadFile(...): unexpected error: %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.meta, string(b)); diff != "" {
+				t.Errorf("\n%s\nunarchive(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path string, write func(w io.Writer) error) {
+	t.Helper()
+	f, err := os.Create(path) // nolint:gosec // Test files in a temporary directory.
+	if err != nil {
+		t.Fatalf("Create(...): %s", err)
+	}
+	defer f.Close() // nolint:errcheck // Nothing to do about it.
+	if err := write(f); err != nil {
+		t.Fatalf("write(...): %s", err)
+	}
+}
@@ -80,4 +80,29 @@ type ExportMeta struct {
 	Crossplane CrossplaneInfo `json:"crossplane,omitempty" yaml:"crossplane,omitempty"`
 	// Stats are the statistics about the exported resources.
 	Stats ExportStats `json:"stats,omitempty" yaml:"stats,omitempty"`
+	// Versions are the versions of all resources in scope at export time per
+	// group resource, keyed by "<namespace>/<name>" or "<name>" for cluster
+	// scoped resources. They are used as the base of incremental exports.
+	Versions map[string]map[string]ObjectVersion `json:"versions,omitempty" yaml:"versions,omitempty"`
+	// Delta is set if the export is an incremental export that only contains
+	// the resources changed since a previous export.
+	Delta *DeltaMeta `json:"delta,omitempty" yaml:"delta,omitempty"`
+}
+
+// ObjectVersion is the version of an exported resource.
+type ObjectVersion struct {
+	// ResourceVersion is the resourceVersion of the resource.
+	ResourceVersion string `json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
+	// Generation is the generation of the resource.
+	Generation int64 `json:"generation,omitempty" yaml:"generation,omitempty"`
+}
+
+// DeltaMeta is the metadata of an incremental export.
+type DeltaMeta struct {
+	// BaseExportedAt is the time at which the export the delta is based on
+	// was created.
+	BaseExportedAt time.Time `json:"baseExportedAt,omitempty" yaml:"baseExportedAt,omitempty"`
+	// Deleted are the resources per group resource that were part of the
+	// base export but no longer exist, keyed like Versions.
+	Deleted map[string][]string `json:"deleted,omitempty" yaml:"deleted,omitempty"`
 }